@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"fmt"
 	"log"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
@@ -13,9 +14,21 @@ import (
 
 	_ "github.com/lib/pq"
 	_ "github.com/raulsilva-tech/devices-api/internal/docs"
+	"github.com/raulsilva-tech/devices-api/internal/domain"
+	"github.com/raulsilva-tech/devices-api/internal/infra/cache"
 	"github.com/raulsilva-tech/devices-api/internal/infra/db/repository"
+	"github.com/raulsilva-tech/devices-api/internal/infra/graphqlserver"
+	"github.com/raulsilva-tech/devices-api/internal/infra/grpcserver"
 	"github.com/raulsilva-tech/devices-api/internal/infra/http/handlers"
 	"github.com/raulsilva-tech/devices-api/internal/infra/http/middleware"
+	"github.com/raulsilva-tech/devices-api/internal/infra/jwtauth"
+	"github.com/raulsilva-tech/devices-api/internal/infra/metrics"
+	"github.com/raulsilva-tech/devices-api/internal/infra/outbox"
+	"github.com/raulsilva-tech/devices-api/internal/infra/ratelimit"
+	"github.com/raulsilva-tech/devices-api/internal/infra/rbac"
+	"github.com/raulsilva-tech/devices-api/internal/infra/stream"
+	"github.com/raulsilva-tech/devices-api/internal/infra/tracing"
+	"github.com/raulsilva-tech/devices-api/internal/infra/webhook"
 	"github.com/raulsilva-tech/devices-api/internal/service"
 	"github.com/raulsilva-tech/devices-api/shared/env"
 	httpSwagger "github.com/swaggo/http-swagger"
@@ -23,12 +36,59 @@ import (
 
 var (
 	WebServerPort  = env.GetInt("WEBSERVER_PORT", 8080)
+	GRPCPort       = env.GetInt("GRPC_PORT", 9090)
+	MetricsPort    = env.GetInt("METRICS_PORT", 9102)     // serves /metrics apart from the API, 0 disables it
+	MetricsBrands  = env.GetInt("METRICS_TOP_BRANDS", 20) // brands with their own series, 0 disables the gauge by brand
 	DBPort         = env.GetInt("DB_PORT", 5432)
 	DBDriver       = env.GetString("DB_DRIVER", "postgres")
 	DBUser         = env.GetString("DB_USER", "myuser")
 	DBPassword     = env.GetString("DB_PASSWORD", "mypassword")
 	DBHost         = env.GetString("DB_HOST", "postgres") //dev environent: "localhost", container environment = must be container name = "postgres"
 	DBDatabaseName = env.GetString("DB_NAME", "devices-api")
+
+	WebhookWorkers     = env.GetInt("WEBHOOK_WORKERS", 4)
+	WebhookMaxAttempts = env.GetInt("WEBHOOK_MAX_ATTEMPTS", 5)
+	WebhookBaseDelay   = env.GetDuration("WEBHOOK_BASE_DELAY", time.Second)
+	WebhookMaxDelay    = env.GetDuration("WEBHOOK_MAX_DELAY", time.Minute)
+
+	OutboxPollInterval = env.GetDuration("OUTBOX_POLL_INTERVAL", time.Second)
+
+	StreamReplaySize = env.GetInt("STREAM_REPLAY_SIZE", 1000)
+
+	// BootstrapAPIKey is accepted as an admin key to issue the first keys, unset it once they exist
+	BootstrapAPIKey = env.GetString("BOOTSTRAP_API_KEY", "")
+
+	// JWTs are accepted when JWT_JWKS names the file or URL of the identity provider's JWKS
+	JWTJWKS        = env.GetString("JWT_JWKS", "")
+	JWTIssuer      = env.GetString("JWT_ISSUER", "")
+	JWTAudience    = env.GetString("JWT_AUDIENCE", "")
+	JWTJWKSRefresh = env.GetDuration("JWT_JWKS_REFRESH", time.Hour)
+	JWTLeeway      = env.GetDuration("JWT_LEEWAY", 30*time.Second)
+
+	// RBACPolicy is the YAML file granting permissions to the roles, the built-in policy is used when unset
+	RBACPolicy = env.GetString("RBAC_POLICY", "")
+
+	// requests allowed to each client per RATE_LIMIT_WINDOW on the read and the write routes, 0 disables the limit
+	RateLimitWindow     = env.GetDuration("RATE_LIMIT_WINDOW", time.Minute)
+	RateLimitReads      = env.GetInt("RATE_LIMIT_READS", 300)
+	RateLimitReadBurst  = env.GetInt("RATE_LIMIT_READ_BURST", 50)
+	RateLimitWrites     = env.GetInt("RATE_LIMIT_WRITES", 60)
+	RateLimitWriteBurst = env.GetInt("RATE_LIMIT_WRITE_BURST", 20)
+	// requests allowed to each IP per RATE_LIMIT_WINDOW before authenticating them, 0 disables the limit
+	RateLimitIP      = env.GetInt("RATE_LIMIT_IP", 600)
+	RateLimitIPBurst = env.GetInt("RATE_LIMIT_IP_BURST", 100)
+
+	// IdempotencyKeyTTL is how long the responses of the requests sent with an Idempotency-Key are replayed
+	IdempotencyKeyTTL = env.GetDuration("IDEMPOTENCY_KEY_TTL", 24*time.Hour)
+
+	// DeviceCache keeps the devices and the lists read recently in memory, for up to DEVICE_CACHE_TTL.
+	// The changes made through other instances are only seen once the entries expire.
+	DeviceCache     = env.GetBool("DEVICE_CACHE", false)
+	DeviceCacheSize = env.GetInt("DEVICE_CACHE_SIZE", 10000)
+	DeviceCacheTTL  = env.GetDuration("DEVICE_CACHE_TTL", 30*time.Second)
+
+	// TracesExporter sends the spans to an OTLP collector (otlp) or prints them (stdout), none disables tracing
+	TracesExporter = env.GetString("OTEL_TRACES_EXPORTER", tracing.ExporterNone)
 )
 
 // @title Devices API
@@ -36,6 +96,9 @@ var (
 // @description API for managing devices
 // @host localhost:8080
 // @BasePath /
+// @securityDefinitions.apikey ApiKeyAuth
+// @in header
+// @name X-API-Key
 func main() {
 
 	dbAddr := fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=disable", DBHost, DBPort, DBUser, DBPassword, DBDatabaseName)
@@ -48,36 +111,205 @@ func main() {
 		log.Fatalf("cannot connect to database: %v", err)
 	}
 
+	shutdownTracing, err := tracing.Setup(context.Background(), TracesExporter, "devices-api")
+	if err != nil {
+		log.Fatalf("failed to set up tracing: %v", err)
+	}
+
+	webhookRepo := repository.NewWebhookRepository(db)
+	webhookConfig := webhook.DefaultConfig()
+	webhookConfig.Workers = WebhookWorkers
+	webhookConfig.MaxAttempts = WebhookMaxAttempts
+	webhookConfig.BaseDelay = WebhookBaseDelay
+	webhookConfig.MaxDelay = WebhookMaxDelay
+	dispatcher := webhook.NewDispatcher(webhookRepo, webhookConfig)
+	dispatcher.Start()
+	webhookHandler := handlers.NewWebhookHandler(service.NewWebhookService(webhookRepo))
+
+	hub := stream.NewHub(StreamReplaySize)
+	streamHandler := handlers.NewStreamHandler(hub)
+
+	// device changes queue their events in the outbox, the relay of one instance hands them to the webhooks
+	// and the tail of every instance follows them to its live streams
+	outboxRepo := repository.NewOutboxRepository(db)
+	outboxConfig := outbox.DefaultConfig()
+	outboxConfig.PollInterval = OutboxPollInterval
+	relay := outbox.NewRelay(outboxRepo, dispatcher, outboxConfig)
+	relay.Start()
+	tail := outbox.NewTail(outboxRepo, hub, outboxConfig)
+	tail.Start()
+
+	policy, err := rbac.LoadPolicy(RBACPolicy)
+	if err != nil {
+		log.Fatalf("failed to load the rbac policy: %v", err)
+	}
+
 	repo := repository.NewDeviceRepository(db)
-	svc := service.NewDeviceService(repo)
-	devHandler := handlers.NewDeviceHandler(svc)
+	var devices domain.DeviceRepository = repo
+	var assignmentRepo domain.AssignmentRepository = repository.NewAssignmentRepository(db)
+	var deviceCache *cache.DeviceRepository
+	if DeviceCache {
+		deviceCache = cache.NewDeviceRepository(repo, cache.Config{Size: DeviceCacheSize, TTL: DeviceCacheTTL})
+		devices = deviceCache
+		assignmentRepo = deviceCache.Assignments(assignmentRepo)
+	}
+
+	svc := service.NewDeviceService(devices, policy)
+	assignmentSvc := service.NewAssignmentService(devices, assignmentRepo, policy)
+
+	devHandler := handlers.NewDeviceHandler(svc, assignmentSvc)
+	assignmentHandler := handlers.NewAssignmentHandler(assignmentSvc)
+
+	historySvc := service.NewHistoryService(devices, repo)
+
+	metricsRegistry := metrics.NewRegistry(db, DBDatabaseName)
+	metricsRegistry.MustRegister(metrics.NewDeviceCollector(repo, 5*time.Second, MetricsBrands))
+	if deviceCache != nil {
+		metricsRegistry.MustRegister(metrics.NewCacheCollector(deviceCache.Stats))
+	}
+	httpMetrics := metrics.NewHTTP(metricsRegistry)
+	historyHandler := handlers.NewHistoryHandler(historySvc)
+
+	apiKeySvc := service.NewAPIKeyService(repository.NewAPIKeyRepository(db), BootstrapAPIKey)
+	apiKeyHandler := handlers.NewAPIKeyHandler(apiKeySvc)
+	if BootstrapAPIKey != "" {
+		log.Println("BOOTSTRAP_API_KEY is set, unset it once the API keys are issued")
+	}
+
+	var verifier middleware.TokenVerifier
+	if JWTJWKS != "" {
+		if JWTIssuer == "" || JWTAudience == "" {
+			log.Fatalf("JWT_ISSUER and JWT_AUDIENCE are required with JWT_JWKS")
+		}
+		keySetConfig := jwtauth.DefaultKeySetConfig()
+		keySetConfig.RefreshInterval = JWTJWKSRefresh
+		verifier = jwtauth.NewVerifier(jwtauth.NewKeySet(JWTJWKS, keySetConfig), jwtauth.Config{
+			Issuer:   JWTIssuer,
+			Audience: JWTAudience,
+			Leeway:   JWTLeeway,
+		})
+	}
+
+	// every IP is limited before its credentials are looked up, then every client is limited on the reads
+	// and, separately, on the writes, which include the admin routes; gRPC takes from the same buckets
+	rateLimits := ratelimit.NewMemoryStore()
+	grpcLimits := grpcserver.RateLimits{
+		Store: rateLimits,
+		IP:    ratelimit.Limit{Requests: RateLimitIP, Per: RateLimitWindow, Burst: RateLimitIPBurst},
+		Read:  ratelimit.Limit{Requests: RateLimitReads, Per: RateLimitWindow, Burst: RateLimitReadBurst},
+		Write: ratelimit.Limit{Requests: RateLimitWrites, Per: RateLimitWindow, Burst: RateLimitWriteBurst},
+	}
+	readLimit := middleware.RateLimit(rateLimits, "read", grpcLimits.Read)
+	writeLimit := middleware.RateLimit(rateLimits, "write", grpcLimits.Write)
+
+	// the mutations sent with an Idempotency-Key run once, their retries get the first response
+	idempotent := middleware.Idempotency(service.NewIdempotencyService(repository.NewIdempotencyRepository(db), IdempotencyKeyTTL))
+
+	// every route requires a scope of the credential and a permission of the caller's roles
+	read := requireAccess(policy, domain.ScopeDevicesRead, readLimit)
+	write := requireAccess(policy, domain.ScopeDevicesWrite, writeLimit, idempotent)
+	admin := requireAccess(policy, domain.ScopeAdmin, writeLimit, idempotent)
 
 	mux := http.NewServeMux()
-	mux.HandleFunc("POST /devices", devHandler.CreateDevice)
-	mux.HandleFunc("PUT /devices/{id}", devHandler.UpdateDevice)
-	mux.HandleFunc("DELETE /devices/{id}", devHandler.DeleteDevice)
-	mux.HandleFunc("GET /devices/{id}", devHandler.GetDeviceByID)
-	mux.HandleFunc("GET /devices", devHandler.GetAllDevices)
+	mux.HandleFunc("POST /devices", write(domain.PermissionDevicesCreate)(devHandler.CreateDevice))
+	mux.HandleFunc("POST /devices/import", write(domain.PermissionDevicesCreate)(devHandler.ImportDevices))
+	// the service checks the permission of each operation of the batch
+	mux.HandleFunc("POST /devices:batch", write(domain.PermissionDevicesCreate, domain.PermissionDevicesUpdate, domain.PermissionDevicesChangeState, domain.PermissionDevicesDelete)(devHandler.BatchDevices))
+	mux.HandleFunc("PUT /devices/{id}", write(domain.PermissionDevicesUpdate)(devHandler.UpdateDevice))
+	// a patch may only change the state, the service checks the fields it actually changes
+	mux.HandleFunc("PATCH /devices/{id}", write(domain.PermissionDevicesUpdate, domain.PermissionDevicesChangeState)(devHandler.PatchDevice))
+	mux.HandleFunc("POST /devices/{id}/transitions", write(domain.PermissionDevicesChangeState)(devHandler.TransitionDevice))
+	mux.HandleFunc("DELETE /devices/{id}", write(domain.PermissionDevicesDelete)(devHandler.DeleteDevice))
+	mux.HandleFunc("POST /devices/{id}/restore", write(domain.PermissionDevicesRestore)(devHandler.RestoreDevice))
+	mux.HandleFunc("GET /devices/{id}", read(domain.PermissionDevicesRead)(devHandler.GetDeviceByID))
+	mux.HandleFunc("GET /devices", read(domain.PermissionDevicesRead)(devHandler.GetAllDevices))
+	mux.HandleFunc("GET /devices/{id}/history", read(domain.PermissionDevicesRead)(historyHandler.GetDeviceHistory))
+
+	mux.HandleFunc("POST /devices/{id}/checkout", write(domain.PermissionDevicesChangeState)(assignmentHandler.CheckOut))
+	mux.HandleFunc("POST /devices/{id}/checkin", write(domain.PermissionDevicesChangeState)(assignmentHandler.CheckIn))
+	mux.HandleFunc("GET /devices/{id}/assignments", read(domain.PermissionDevicesRead)(assignmentHandler.GetAssignments))
+	mux.HandleFunc("GET /assignees/{assignee}/devices", read(domain.PermissionDevicesRead)(assignmentHandler.GetDevicesHeldBy))
+
+	mux.HandleFunc("POST /webhooks", admin(domain.PermissionWebhooksManage)(webhookHandler.CreateWebhook))
+	mux.HandleFunc("GET /webhooks", admin(domain.PermissionWebhooksManage)(webhookHandler.GetWebhooks))
+	mux.HandleFunc("DELETE /webhooks/{id}", admin(domain.PermissionWebhooksManage)(webhookHandler.DeleteWebhook))
+	mux.HandleFunc("GET /webhooks/{id}/deliveries", admin(domain.PermissionWebhooksManage)(webhookHandler.GetDeliveries))
 
-	// swagger ui
-	mux.Handle("/swagger/", httpSwagger.WrapHandler)
+	mux.HandleFunc("POST /api-keys", admin(domain.PermissionAPIKeysManage)(apiKeyHandler.CreateAPIKey))
+	mux.HandleFunc("GET /api-keys", admin(domain.PermissionAPIKeysManage)(apiKeyHandler.GetAPIKeys))
+	mux.HandleFunc("DELETE /api-keys/{id}", admin(domain.PermissionAPIKeysManage)(apiKeyHandler.RevokeAPIKey))
+	mux.HandleFunc("POST /api-keys/{id}/rotate", admin(domain.PermissionAPIKeysManage)(apiKeyHandler.RotateAPIKey))
 
-	var handler http.Handler = mux
+	// mutations take a write token and honor Idempotency-Key like the REST writes; they also require the
+	// write scope, checked by their resolvers, and their permission, checked by the service
+	graphqlHandler := graphqlserver.NewHandler(svc).ServeHTTP
+	mutation := requireAccess(policy, domain.ScopeDevicesRead, writeLimit, idempotent)
+	mux.HandleFunc("POST /graphql", graphqlserver.ByOperation(
+		read(domain.PermissionDevicesRead)(graphqlHandler),
+		mutation(domain.PermissionDevicesRead)(graphqlHandler),
+	))
+
+	// streamed responses can outlive the request timeout, which would also buffer them whole
+	root := http.NewServeMux()
+	root.HandleFunc("GET /devices/export", read(domain.PermissionDevicesRead)(devHandler.ExportDevices))
+	root.HandleFunc("GET /devices/stream", read(domain.PermissionDevicesRead)(streamHandler.StreamDevices))
+	root.Handle("/", middleware.Timeout(10*time.Second)(mux))
+
+	// only the swagger ui is served without credentials, the tenant is resolved once authenticated
+	authenticated := middleware.APIKeyAuth(apiKeySvc)(middleware.Tenant(root))
+	if verifier != nil {
+		authenticated = middleware.JWTAuth(verifier)(authenticated)
+	}
+	authenticated = middleware.IPRateLimit(rateLimits, grpcLimits.IP)(authenticated)
+	public := http.NewServeMux()
+	public.Handle("/swagger/", middleware.Route(httpSwagger.WrapHandler))
+	public.Handle("/", authenticated)
+
+	var handler http.Handler = public
 	handler = middleware.Logger(handler)
+	handler = middleware.OnBehalfOf(handler)
 	handler = middleware.RequestID(handler)
-	handler = middleware.Timeout(10 * time.Second)(handler)
 	handler = middleware.Recover(handler)
+	handler = middleware.Tracing(handler)
+	handler = middleware.Metrics(httpMetrics)(handler)
 
 	server := http.Server{
 		Addr:    fmt.Sprintf(":%d", WebServerPort),
 		Handler: handler,
 	}
+	// open streams would otherwise hold the shutdown until its timeout
+	server.RegisterOnShutdown(hub.Close)
+
+	grpcServer := grpcserver.NewServer(grpcserver.NewDeviceServer(svc, hub), apiKeySvc, verifier, grpcLimits)
+
+	// the metrics are served on their own port, to be reached by the scrapers only
+	metricsMux := http.NewServeMux()
+	metricsMux.Handle("GET /metrics", metrics.Handler(metricsRegistry))
+	metricsServer := http.Server{
+		Addr:    fmt.Sprintf(":%d", MetricsPort),
+		Handler: metricsMux,
+	}
 
-	serverErrors := make(chan error, 1)
+	serverErrors := make(chan error, 3)
 	go func() {
 		log.Println("Starting API WebServer")
 		serverErrors <- server.ListenAndServe()
 	}()
+	go func() {
+		listener, err := net.Listen("tcp", fmt.Sprintf(":%d", GRPCPort))
+		if err != nil {
+			serverErrors <- err
+			return
+		}
+		log.Println("Starting gRPC server")
+		serverErrors <- grpcServer.Serve(listener)
+	}()
+	if MetricsPort != 0 {
+		go func() {
+			log.Println("Starting metrics server")
+			serverErrors <- metricsServer.ListenAndServe()
+		}()
+	}
 
 	shutdown := make(chan os.Signal, 1)
 	signal.Notify(shutdown, os.Interrupt, syscall.SIGTERM)
@@ -97,6 +329,54 @@ func main() {
 			log.Println("could not shutdown gracefully", err.Error())
 			server.Close()
 		}
+		metricsServer.Close()
+		// the hub is closed by now, so the open watches have returned
+		grpcStopped := make(chan struct{})
+		go func() {
+			grpcServer.GracefulStop()
+			close(grpcStopped)
+		}()
+		select {
+		case <-grpcStopped:
+		case <-ctx.Done():
+			grpcServer.Stop()
+		}
+		if err := tail.Close(ctx); err != nil {
+			log.Println("outbox tail interrupted at shutdown", err.Error())
+		}
+		// unpublished events stay in the outbox for the next start
+		if err := relay.Close(ctx); err != nil {
+			log.Println("outbox relay interrupted at shutdown", err.Error())
+		}
+		// interrupted webhook deliveries stay pending for the next start
+		if err := dispatcher.Close(ctx); err != nil {
+			log.Println("webhook attempts interrupted at shutdown", err.Error())
+		}
+		// the spans still buffered are exported
+		if err := shutdownTracing(ctx); err != nil {
+			log.Println("could not export the pending spans", err.Error())
+		}
+		if deviceCache != nil {
+			stats := deviceCache.Stats()
+			log.Printf("device cache: %d hits, %d misses", stats.Hits, stats.Misses)
+		}
 		db.Close()
 	}
 }
+
+// requireAccess returns the route guard for a scope: the client must be within its rate limit, the
+// credential must carry the scope and the roles of the caller must be granted one of the permissions.
+// The requests let through then go through the wrappers, the first one outermost. The route is
+// recorded for the metrics whether the request is let through or not.
+func requireAccess(policy *domain.Policy, scope domain.Scope, rateLimit func(http.HandlerFunc) http.HandlerFunc, wrappers ...func(http.HandlerFunc) http.HandlerFunc) func(...domain.Permission) func(http.HandlerFunc) http.HandlerFunc {
+	requireScope := middleware.RequireScope(scope)
+	return func(permissions ...domain.Permission) func(http.HandlerFunc) http.HandlerFunc {
+		requirePermission := middleware.RequirePermission(policy, permissions...)
+		return func(next http.HandlerFunc) http.HandlerFunc {
+			for i := len(wrappers) - 1; i >= 0; i-- {
+				next = wrappers[i](next)
+			}
+			return middleware.Route(rateLimit(requireScope(requirePermission(next))))
+		}
+	}
+}
@@ -0,0 +1,75 @@
+// Command purge permanently removes the devices that were soft deleted longer ago than the retention window,
+// the outbox messages published longer ago than the outbox retention, and the expired idempotency keys.
+// It is meant to run periodically,
+// e.g. from a cron job.
+package main
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+	"log"
+	"time"
+
+	_ "github.com/lib/pq"
+	"github.com/raulsilva-tech/devices-api/internal/infra/db/repository"
+	"github.com/raulsilva-tech/devices-api/internal/service"
+	"github.com/raulsilva-tech/devices-api/shared/env"
+)
+
+var (
+	DBPort         = env.GetInt("DB_PORT", 5432)
+	DBDriver       = env.GetString("DB_DRIVER", "postgres")
+	DBUser         = env.GetString("DB_USER", "myuser")
+	DBPassword     = env.GetString("DB_PASSWORD", "mypassword")
+	DBHost         = env.GetString("DB_HOST", "postgres")
+	DBDatabaseName = env.GetString("DB_NAME", "devices-api")
+	PurgeRetention = env.GetDuration("PURGE_RETENTION", 30*24*time.Hour)
+
+	OutboxRetention = env.GetDuration("OUTBOX_RETENTION", 7*24*time.Hour)
+)
+
+func main() {
+
+	retention := flag.Duration("retention", PurgeRetention, "purge devices deleted longer ago than this")
+	outboxRetention := flag.Duration("outbox-retention", OutboxRetention, "purge outbox messages published longer ago than this")
+	flag.Parse()
+
+	dbAddr := fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=disable", DBHost, DBPort, DBUser, DBPassword, DBDatabaseName)
+
+	db, err := sql.Open(DBDriver, dbAddr)
+	if err != nil {
+		log.Fatalf("failed to open db: %v", err)
+	}
+	defer db.Close()
+
+	// the purge runs on its own, outside of any authenticated request, so it has no policy to check
+	svc := service.NewDeviceService(repository.NewDeviceRepository(db), nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	purged, err := svc.PurgeDeletedDevices(ctx, *retention)
+	if err != nil {
+		log.Fatalf("failed to purge devices: %v", err)
+	}
+
+	log.Printf("purged %d devices deleted more than %v ago", purged, *retention)
+
+	// pending messages are never purged, they still have to be published
+	purged, err = repository.NewOutboxRepository(db).PurgePublishedMessages(ctx, time.Now().Add(-*outboxRetention))
+	if err != nil {
+		log.Fatalf("failed to purge outbox: %v", err)
+	}
+
+	log.Printf("purged %d outbox messages published more than %v ago", purged, *outboxRetention)
+
+	// expired keys are already ignored, they are only purged to keep the table small
+	purged, err = service.NewIdempotencyService(repository.NewIdempotencyRepository(db), 0).PurgeExpired(ctx)
+	if err != nil {
+		log.Fatalf("failed to purge idempotency keys: %v", err)
+	}
+
+	log.Printf("purged %d expired idempotency keys", purged)
+}
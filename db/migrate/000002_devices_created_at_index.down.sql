@@ -0,0 +1 @@
+DROP INDEX IF EXISTS idx_devices_created_at_id;
@@ -0,0 +1 @@
+CREATE INDEX IF NOT EXISTS idx_devices_created_at_id ON devices (created_at, id);
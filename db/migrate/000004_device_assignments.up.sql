@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS device_assignments (
+    id                  VARCHAR(36) PRIMARY KEY,
+    device_id           VARCHAR(36)  NOT NULL REFERENCES devices (id) ON DELETE CASCADE,
+    assignee            VARCHAR(255) NOT NULL,
+    checked_out_at      TIMESTAMP WITH TIME ZONE NOT NULL,
+    expected_return_at  TIMESTAMP WITH TIME ZONE,
+    returned_at         TIMESTAMP WITH TIME ZONE,
+    notes               TEXT NOT NULL DEFAULT ''
+);
+
+CREATE INDEX IF NOT EXISTS idx_device_assignments_device ON device_assignments (device_id, checked_out_at);
+CREATE INDEX IF NOT EXISTS idx_device_assignments_open_assignee ON device_assignments (assignee) WHERE returned_at IS NULL;
+-- a device can only be held by one assignee at a time
+CREATE UNIQUE INDEX IF NOT EXISTS ux_device_assignments_open_device ON device_assignments (device_id) WHERE returned_at IS NULL;
@@ -0,0 +1,13 @@
+-- append-only history of device changes, kept after the device is deleted
+CREATE TABLE IF NOT EXISTS device_events (
+    id              BIGSERIAL PRIMARY KEY,
+    device_id       VARCHAR(36)  NOT NULL,
+    event_type      VARCHAR(20)  NOT NULL,
+    device_version  BIGINT       NOT NULL,
+    changes         TEXT         NOT NULL,
+    request_id      VARCHAR(255) NOT NULL DEFAULT '',
+    actor           VARCHAR(255) NOT NULL DEFAULT '',
+    occurred_at     TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_device_events_device ON device_events (device_id, id);
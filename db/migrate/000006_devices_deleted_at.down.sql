@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_devices_deleted_at;
+DELETE FROM devices WHERE deleted_at IS NOT NULL;
+ALTER TABLE devices DROP COLUMN IF EXISTS deleted_at;
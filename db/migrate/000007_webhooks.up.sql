@@ -0,0 +1,23 @@
+CREATE TABLE IF NOT EXISTS webhooks (
+    id          VARCHAR(36)  PRIMARY KEY,
+    url         TEXT         NOT NULL,
+    secret      VARCHAR(64)  NOT NULL,
+    events      TEXT         NOT NULL DEFAULT '', -- comma separated event types, empty for all
+    created_at  TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    id                VARCHAR(36) PRIMARY KEY,
+    webhook_id        VARCHAR(36) NOT NULL REFERENCES webhooks (id) ON DELETE CASCADE,
+    event_id          VARCHAR(36) NOT NULL,
+    event_type        VARCHAR(50) NOT NULL,
+    payload           TEXT        NOT NULL,
+    status            VARCHAR(20) NOT NULL,
+    attempts          INTEGER     NOT NULL DEFAULT 0,
+    last_status_code  INTEGER     NOT NULL DEFAULT 0,
+    last_error        TEXT        NOT NULL DEFAULT '',
+    created_at        TIMESTAMP WITH TIME ZONE NOT NULL,
+    updated_at        TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_webhook ON webhook_deliveries (webhook_id, created_at);
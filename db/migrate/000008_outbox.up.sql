@@ -0,0 +1,13 @@
+-- device events waiting to be published, written in the same transaction as the change they announce
+CREATE TABLE IF NOT EXISTS outbox (
+    id            BIGSERIAL PRIMARY KEY,
+    event_id      VARCHAR(36) NOT NULL,
+    event_type    VARCHAR(50) NOT NULL,
+    payload       TEXT        NOT NULL,
+    attempts      INTEGER     NOT NULL DEFAULT 0,
+    last_error    TEXT        NOT NULL DEFAULT '',
+    created_at    TIMESTAMP WITH TIME ZONE NOT NULL,
+    published_at  TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_outbox_pending ON outbox (id) WHERE published_at IS NULL;
@@ -0,0 +1,11 @@
+-- only the SHA-256 of the keys is stored, the keys themselves are shown once when issued
+CREATE TABLE IF NOT EXISTS api_keys (
+    id          VARCHAR(36)  PRIMARY KEY,
+    name        VARCHAR(100) NOT NULL,
+    prefix      VARCHAR(20)  NOT NULL, -- start of the key, to recognize it in listings
+    key_hash    VARCHAR(64)  NOT NULL UNIQUE,
+    scopes      TEXT         NOT NULL, -- comma separated
+    expires_at  TIMESTAMP WITH TIME ZONE,
+    created_at  TIMESTAMP WITH TIME ZONE NOT NULL,
+    revoked_at  TIMESTAMP WITH TIME ZONE
+);
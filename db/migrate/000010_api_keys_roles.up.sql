@@ -0,0 +1,8 @@
+ALTER TABLE api_keys ADD COLUMN IF NOT EXISTS roles TEXT NOT NULL DEFAULT '';
+
+-- the existing keys get the role matching the broadest of their scopes
+UPDATE api_keys SET roles = CASE
+    WHEN ',' || scopes || ',' LIKE '%,admin,%' THEN 'admin'
+    WHEN ',' || scopes || ',' LIKE '%,devices:write,%' THEN 'operator'
+    ELSE 'viewer'
+END;
@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS idx_devices_tenant_created_at_id;
+CREATE INDEX IF NOT EXISTS idx_devices_created_at_id ON devices (created_at, id);
+
+ALTER TABLE api_keys DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE webhooks DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE device_events DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE devices DROP COLUMN IF EXISTS tenant_id;
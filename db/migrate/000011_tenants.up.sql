@@ -0,0 +1,11 @@
+-- the existing rows belong to the default tenant
+ALTER TABLE devices ADD COLUMN IF NOT EXISTS tenant_id VARCHAR(63) NOT NULL DEFAULT 'default';
+ALTER TABLE device_events ADD COLUMN IF NOT EXISTS tenant_id VARCHAR(63) NOT NULL DEFAULT 'default';
+ALTER TABLE webhooks ADD COLUMN IF NOT EXISTS tenant_id VARCHAR(63) NOT NULL DEFAULT 'default';
+
+-- the existing keys are not bound to a tenant, they may pick one with X-Tenant-ID
+ALTER TABLE api_keys ADD COLUMN IF NOT EXISTS tenant_id VARCHAR(63) NOT NULL DEFAULT '';
+
+-- every device query filters on the tenant first
+DROP INDEX IF EXISTS idx_devices_created_at_id;
+CREATE INDEX IF NOT EXISTS idx_devices_tenant_created_at_id ON devices (tenant_id, created_at, id);
@@ -0,0 +1,15 @@
+-- responses of the mutations sent with an Idempotency-Key, replayed when the client retries
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+    tenant_id         VARCHAR(63)  NOT NULL,
+    client            VARCHAR(255) NOT NULL, -- API key or JWT subject, or IP, the keys of each client are apart
+    idempotency_key   VARCHAR(255) NOT NULL,
+    fingerprint       VARCHAR(64)  NOT NULL, -- SHA-256 of the method, path and body of the request
+    status_code       INTEGER      NOT NULL DEFAULT 0, -- 0 while the request is in progress
+    response_headers  TEXT         NOT NULL DEFAULT '{}',
+    response_body     TEXT         NOT NULL DEFAULT '',
+    created_at        TIMESTAMP WITH TIME ZONE NOT NULL,
+    expires_at        TIMESTAMP WITH TIME ZONE NOT NULL,
+    PRIMARY KEY (tenant_id, client, idempotency_key)
+);
+
+CREATE INDEX IF NOT EXISTS idx_idempotency_keys_expires_at ON idempotency_keys (expires_at);
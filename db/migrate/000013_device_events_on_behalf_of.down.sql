@@ -0,0 +1 @@
+ALTER TABLE device_events DROP COLUMN IF EXISTS on_behalf_of;
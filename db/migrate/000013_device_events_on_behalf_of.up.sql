@@ -0,0 +1,2 @@
+-- whom the caller said it acted for, the actor is always the authenticated principal
+ALTER TABLE device_events ADD COLUMN IF NOT EXISTS on_behalf_of VARCHAR(255) NOT NULL DEFAULT '';
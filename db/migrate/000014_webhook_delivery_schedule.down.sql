@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_webhook_deliveries_due;
+DROP INDEX IF EXISTS uq_webhook_deliveries_webhook_event;
+
+ALTER TABLE webhook_deliveries DROP COLUMN IF EXISTS next_attempt_at;
@@ -0,0 +1,13 @@
+-- the deliveries are stored before their event leaves the outbox and attempted from the table,
+-- so the pending ones are resumed after a restart
+ALTER TABLE webhook_deliveries ADD COLUMN IF NOT EXISTS next_attempt_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now();
+
+-- an event published again by the outbox relay must not be delivered twice to the same webhook
+DELETE FROM webhook_deliveries a
+USING webhook_deliveries b
+WHERE a.webhook_id = b.webhook_id
+  AND a.event_id = b.event_id
+  AND (a.created_at, a.id) > (b.created_at, b.id);
+
+CREATE UNIQUE INDEX IF NOT EXISTS uq_webhook_deliveries_webhook_event ON webhook_deliveries (webhook_id, event_id);
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_due ON webhook_deliveries (next_attempt_at) WHERE status = 'pending';
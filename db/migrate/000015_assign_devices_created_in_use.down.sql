@@ -0,0 +1 @@
+DELETE FROM device_assignments WHERE notes = 'created in use, before checkouts were required';
@@ -0,0 +1,10 @@
+-- devices could be created in use before only a checkout was allowed to put them in use: they get an
+-- open assignment, so every device in use says who holds it and can be checked in
+INSERT INTO device_assignments (id, device_id, assignee, checked_out_at, notes)
+SELECT gen_random_uuid()::text, devices.id, 'unknown', devices.created_at, 'created in use, before checkouts were required'
+FROM devices
+WHERE devices.state = 'in-use'
+  AND NOT EXISTS (
+      SELECT 1 FROM device_assignments
+      WHERE device_assignments.device_id = devices.id AND device_assignments.returned_at IS NULL
+  );
@@ -0,0 +1,6 @@
+-- a single relay drains the outbox at a time, so the events keep their order with several instances
+CREATE TABLE IF NOT EXISTS outbox_lease (
+    id           INTEGER PRIMARY KEY CHECK (id = 1),
+    holder       VARCHAR(36) NOT NULL,
+    locked_until TIMESTAMP WITH TIME ZONE NOT NULL
+);
@@ -0,0 +1,2 @@
+-- every instance follows the published messages to feed its live streams
+CREATE INDEX IF NOT EXISTS idx_outbox_published ON outbox (published_at, id) WHERE published_at IS NOT NULL;
@@ -0,0 +1,18 @@
+-- name: CreateAPIKey :exec
+INSERT INTO api_keys (id, name, prefix, key_hash, scopes, roles, tenant_id, expires_at, created_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9);
+
+-- name: GetAPIKeyByID :one
+SELECT * FROM api_keys WHERE id = $1;
+
+-- name: GetAPIKeyByHash :one
+SELECT * FROM api_keys WHERE key_hash = $1;
+
+-- name: ListAPIKeys :many
+SELECT * FROM api_keys ORDER BY created_at, id;
+
+-- name: RevokeAPIKey :execrows
+UPDATE api_keys SET revoked_at = $2 WHERE id = $1 AND revoked_at IS NULL;
+
+-- name: RotateAPIKey :execrows
+UPDATE api_keys SET prefix = $2, key_hash = $3 WHERE id = $1 AND revoked_at IS NULL;
@@ -0,0 +1,29 @@
+-- name: CreateAssignment :exec
+INSERT INTO device_assignments (id, device_id, assignee, checked_out_at, expected_return_at, notes)
+VALUES ($1, $2, $3, $4, $5, $6);
+
+-- name: CloseOpenAssignments :execrows
+UPDATE device_assignments
+SET returned_at = $2
+WHERE device_id = $1 AND returned_at IS NULL;
+
+-- name: GetOpenAssignmentByDevice :one
+SELECT * FROM device_assignments
+WHERE device_id = $1 AND returned_at IS NULL;
+
+-- name: ListAssignmentsByDevice :many
+SELECT * FROM device_assignments
+WHERE device_id = $1
+ORDER BY checked_out_at DESC, id DESC
+LIMIT $2 OFFSET $3;
+
+-- name: ListDevicesHeldBy :many
+SELECT sqlc.embed(devices), sqlc.embed(device_assignments)
+FROM device_assignments
+JOIN devices ON devices.id = device_assignments.device_id
+WHERE device_assignments.assignee = $1
+  AND devices.tenant_id = $2
+  AND device_assignments.returned_at IS NULL
+  AND devices.state = 'in-use'
+  AND devices.deleted_at IS NULL
+ORDER BY device_assignments.checked_out_at ASC;
@@ -0,0 +1,9 @@
+-- name: CreateDeviceEvent :exec
+INSERT INTO device_events (device_id, event_type, device_version, changes, request_id, actor, occurred_at, tenant_id, on_behalf_of)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9);
+
+-- name: ListDeviceEvents :many
+SELECT * FROM device_events
+WHERE device_id = $1 AND tenant_id = $4
+ORDER BY id DESC
+LIMIT $2 OFFSET $3;
@@ -0,0 +1,26 @@
+-- name: CreateIdempotencyKey :execrows
+INSERT INTO idempotency_keys (tenant_id, client, idempotency_key, fingerprint, created_at, expires_at)
+VALUES ($1, $2, $3, $4, $5, $6)
+ON CONFLICT DO NOTHING;
+
+-- name: GetIdempotencyKey :one
+SELECT * FROM idempotency_keys
+WHERE tenant_id = $1 AND client = $2 AND idempotency_key = $3;
+
+-- name: CompleteIdempotencyKey :exec
+UPDATE idempotency_keys
+SET status_code = $4,
+    response_headers = $5,
+    response_body = $6
+WHERE tenant_id = $1 AND client = $2 AND idempotency_key = $3;
+
+-- name: DeleteIdempotencyKey :exec
+DELETE FROM idempotency_keys
+WHERE tenant_id = $1 AND client = $2 AND idempotency_key = $3;
+
+-- name: DeleteExpiredIdempotencyKey :exec
+DELETE FROM idempotency_keys
+WHERE tenant_id = $1 AND client = $2 AND idempotency_key = $3 AND expires_at <= $4;
+
+-- name: PurgeExpiredIdempotencyKeys :execrows
+DELETE FROM idempotency_keys WHERE expires_at <= $1;
@@ -0,0 +1,46 @@
+-- name: CreateOutboxMessage :exec
+INSERT INTO outbox (event_id, event_type, payload, created_at)
+VALUES ($1, $2, $3, $4);
+
+-- name: ListPendingOutboxMessages :many
+SELECT * FROM outbox
+WHERE published_at IS NULL
+ORDER BY id
+LIMIT $1;
+
+-- name: MarkOutboxMessagePublished :exec
+UPDATE outbox SET published_at = $2 WHERE id = $1;
+
+-- name: MarkOutboxMessageFailed :exec
+UPDATE outbox
+SET attempts = attempts + 1,
+    last_error = $2
+WHERE id = $1;
+
+-- name: PurgePublishedOutboxMessages :execrows
+DELETE FROM outbox WHERE published_at < $1;
+
+-- name: AcquireOutboxLease :execrows
+INSERT INTO outbox_lease (id, holder, locked_until)
+VALUES (1, @holder, @until)
+ON CONFLICT (id) DO UPDATE
+SET holder = excluded.holder,
+    locked_until = excluded.locked_until
+WHERE outbox_lease.holder = excluded.holder
+   OR outbox_lease.locked_until <= @now;
+
+-- name: ReleaseOutboxLease :exec
+UPDATE outbox_lease SET locked_until = @now WHERE id = 1 AND holder = @holder;
+
+-- name: ListPublishedOutboxMessages :many
+SELECT * FROM outbox
+WHERE published_at > @published_after
+   OR (published_at = @published_after AND id > @after_id)
+ORDER BY published_at, id
+LIMIT @max_messages;
+
+-- name: GetLastPublishedOutboxMessage :one
+SELECT * FROM outbox
+WHERE published_at IS NOT NULL
+ORDER BY published_at DESC, id DESC
+LIMIT 1;
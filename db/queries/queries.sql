@@ -1,29 +1,104 @@
--- name: GetAllDevices :many
-SELECT * FROM devices;
+-- name: ListDevices :many
+SELECT * FROM devices
+WHERE tenant_id = @tenant_id
+  AND (deleted_at IS NULL OR CAST(@include_deleted AS BOOLEAN))
+  AND (brand = @brand OR @brand = '')
+  AND (state = @state OR @state = '')
+  AND (LOWER(name) LIKE '%' || LOWER(CAST(@name AS TEXT)) || '%' ESCAPE '\')
+  AND (created_at >= sqlc.narg('created_from') OR sqlc.narg('created_from') IS NULL)
+  AND (created_at <= sqlc.narg('created_to') OR sqlc.narg('created_to') IS NULL)
+ORDER BY
+  CASE WHEN CAST(@sort_by AS TEXT) = 'name' AND CAST(@sort_order AS TEXT) = 'asc' THEN name END ASC,
+  CASE WHEN @sort_by = 'name' AND @sort_order = 'desc' THEN name END DESC,
+  CASE WHEN @sort_by = 'brand' AND @sort_order = 'asc' THEN brand END ASC,
+  CASE WHEN @sort_by = 'brand' AND @sort_order = 'desc' THEN brand END DESC,
+  CASE WHEN @sort_by = 'state' AND @sort_order = 'asc' THEN state END ASC,
+  CASE WHEN @sort_by = 'state' AND @sort_order = 'desc' THEN state END DESC,
+  CASE WHEN @sort_by = 'created_at' AND @sort_order = 'asc' THEN created_at END ASC,
+  CASE WHEN @sort_by = 'created_at' AND @sort_order = 'desc' THEN created_at END DESC,
+  CASE WHEN @sort_order = 'desc' THEN id END DESC,
+  id ASC
+LIMIT @page_limit OFFSET @page_offset;
 
--- name: GetAllDevicesByBrand :many
-SELECT * FROM devices 
-WHERE brand = $1;
-
--- name: GetAllDevicesByState :many
-SELECT * FROM devices 
-WHERE state = $1;
+-- name: CountDevices :one
+SELECT COUNT(*) FROM devices
+WHERE tenant_id = @tenant_id
+  AND (deleted_at IS NULL OR CAST(@include_deleted AS BOOLEAN))
+  AND (brand = @brand OR @brand = '')
+  AND (state = @state OR @state = '')
+  AND (LOWER(name) LIKE '%' || LOWER(CAST(@name AS TEXT)) || '%' ESCAPE '\')
+  AND (created_at >= sqlc.narg('created_from') OR sqlc.narg('created_from') IS NULL)
+  AND (created_at <= sqlc.narg('created_to') OR sqlc.narg('created_to') IS NULL);
 
 -- name: GetDeviceByID :one
-SELECT * FROM devices WHERE id = $1;
+SELECT * FROM devices WHERE id = $1 AND tenant_id = $2 AND deleted_at IS NULL;
+
+-- name: GetDeviceByIDIncludingDeleted :one
+SELECT * FROM devices WHERE id = $1 AND tenant_id = $2;
 
 -- name: CreateDevice :one
-INSERT INTO devices (id, name, brand, state, created_at)
-VALUES ($1, $2, $3, $4, $5)
+INSERT INTO devices (id, name, brand, state, created_at, version, tenant_id)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
 RETURNING id;
 
--- name: UpdateDevice :exec
+-- name: UpdateDevice :execrows
 UPDATE devices
 SET name = $1,
     brand = $2,
-    state = $3
-WHERE id = $4;
+    state = $3,
+    version = version + 1
+WHERE id = $4 AND version = $5 AND tenant_id = $6 AND deleted_at IS NULL;
+
+-- name: DeleteDevice :execrows
+UPDATE devices
+SET deleted_at = $3,
+    version = version + 1
+WHERE id = $1 AND version = $2 AND tenant_id = $4 AND deleted_at IS NULL;
+
+-- name: RestoreDevice :execrows
+UPDATE devices
+SET deleted_at = NULL,
+    version = version + 1
+WHERE id = $1 AND version = $2 AND tenant_id = $3 AND deleted_at IS NOT NULL;
+
+-- name: PurgeDeletedDevices :many
+-- the retention applies to every tenant, the purge command runs for the whole deployment
+DELETE FROM devices
+WHERE deleted_at IS NOT NULL AND deleted_at < $1
+RETURNING *;
+
+-- name: ListDevicesAfterCursor :many
+SELECT * FROM devices
+WHERE tenant_id = @tenant_id
+  AND (deleted_at IS NULL OR CAST(@include_deleted AS BOOLEAN))
+  AND (brand = @brand OR @brand = '')
+  AND (state = @state OR @state = '')
+  AND (LOWER(name) LIKE '%' || LOWER(CAST(@name AS TEXT)) || '%' ESCAPE '\')
+  AND (created_at >= sqlc.narg('created_from') OR sqlc.narg('created_from') IS NULL)
+  AND (created_at <= sqlc.narg('created_to') OR sqlc.narg('created_to') IS NULL)
+  AND (created_at, id) > (@cursor_created_at, CAST(@cursor_id AS TEXT))
+ORDER BY created_at ASC, id ASC
+LIMIT @page_limit;
+
+-- name: ListDevicesBeforeCursor :many
+SELECT * FROM devices
+WHERE tenant_id = @tenant_id
+  AND (deleted_at IS NULL OR CAST(@include_deleted AS BOOLEAN))
+  AND (brand = @brand OR @brand = '')
+  AND (state = @state OR @state = '')
+  AND (LOWER(name) LIKE '%' || LOWER(CAST(@name AS TEXT)) || '%' ESCAPE '\')
+  AND (created_at >= sqlc.narg('created_from') OR sqlc.narg('created_from') IS NULL)
+  AND (created_at <= sqlc.narg('created_to') OR sqlc.narg('created_to') IS NULL)
+  AND (created_at, id) < (@cursor_created_at, CAST(@cursor_id AS TEXT))
+ORDER BY created_at DESC, id DESC
+LIMIT @page_limit;
 
--- name: DeleteDevice :exec
-DELETE FROM devices WHERE id = $1;
+-- name: CountDevicesByState :many
+SELECT state, COUNT(*) AS total FROM devices
+WHERE deleted_at IS NULL
+GROUP BY state;
 
+-- name: CountDevicesByBrand :many
+SELECT brand, COUNT(*) AS total FROM devices
+WHERE deleted_at IS NULL
+GROUP BY brand;
@@ -0,0 +1,49 @@
+-- name: CreateWebhook :exec
+INSERT INTO webhooks (id, url, secret, events, created_at, tenant_id)
+VALUES ($1, $2, $3, $4, $5, $6);
+
+-- name: DeleteWebhook :execrows
+DELETE FROM webhooks WHERE id = $1 AND tenant_id = $2;
+
+-- name: GetWebhookByID :one
+SELECT * FROM webhooks WHERE id = $1 AND tenant_id = $2;
+
+-- name: ListWebhooks :many
+SELECT * FROM webhooks WHERE tenant_id = $1 ORDER BY created_at, id;
+
+-- name: CreateWebhookDelivery :exec
+INSERT INTO webhook_deliveries (id, webhook_id, event_id, event_type, payload, status, attempts, last_status_code, last_error, created_at, updated_at, next_attempt_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+ON CONFLICT (webhook_id, event_id) DO NOTHING;
+
+-- name: UpdateWebhookDelivery :exec
+UPDATE webhook_deliveries
+SET status = $2,
+    attempts = $3,
+    last_status_code = $4,
+    last_error = $5,
+    updated_at = $6,
+    next_attempt_at = $7
+WHERE id = $1;
+
+-- name: ListDueWebhookDeliveries :many
+SELECT sqlc.embed(webhook_deliveries), sqlc.embed(webhooks)
+FROM webhook_deliveries
+JOIN webhooks ON webhooks.id = webhook_deliveries.webhook_id
+WHERE webhook_deliveries.status = 'pending'
+  AND webhook_deliveries.next_attempt_at <= $1
+ORDER BY webhook_deliveries.next_attempt_at, webhook_deliveries.id
+LIMIT $2;
+
+-- name: ClaimWebhookDelivery :execrows
+UPDATE webhook_deliveries
+SET next_attempt_at = @until
+WHERE id = @id
+  AND status = 'pending'
+  AND next_attempt_at <= @now;
+
+-- name: ListWebhookDeliveries :many
+SELECT * FROM webhook_deliveries
+WHERE webhook_id = $1
+ORDER BY created_at DESC, id DESC
+LIMIT $2 OFFSET $3;
@@ -3,5 +3,118 @@ CREATE TABLE devices (
     name        VARCHAR(255) NOT NULL,
     brand       VARCHAR(255) NOT NULL,
     state       VARCHAR(20)  NOT NULL,
-    created_at  TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+    created_at  TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    version     BIGINT NOT NULL DEFAULT 1,
+    deleted_at  TIMESTAMP WITH TIME ZONE,
+    tenant_id   VARCHAR(63) NOT NULL DEFAULT 'default'
 );
+
+CREATE INDEX idx_devices_tenant_created_at_id ON devices (tenant_id, created_at, id);
+CREATE INDEX idx_devices_deleted_at ON devices (deleted_at) WHERE deleted_at IS NOT NULL;
+
+CREATE TABLE device_assignments (
+    id                  VARCHAR(36) PRIMARY KEY,
+    device_id           VARCHAR(36)  NOT NULL REFERENCES devices (id) ON DELETE CASCADE,
+    assignee            VARCHAR(255) NOT NULL,
+    checked_out_at      TIMESTAMP WITH TIME ZONE NOT NULL,
+    expected_return_at  TIMESTAMP WITH TIME ZONE,
+    returned_at         TIMESTAMP WITH TIME ZONE,
+    notes               TEXT NOT NULL DEFAULT ''
+);
+
+CREATE INDEX idx_device_assignments_device ON device_assignments (device_id, checked_out_at);
+CREATE INDEX idx_device_assignments_open_assignee ON device_assignments (assignee) WHERE returned_at IS NULL;
+CREATE UNIQUE INDEX ux_device_assignments_open_device ON device_assignments (device_id) WHERE returned_at IS NULL;
+
+-- append-only history of device changes, kept after the device is deleted
+CREATE TABLE device_events (
+    id              BIGSERIAL PRIMARY KEY,
+    device_id       VARCHAR(36)  NOT NULL,
+    event_type      VARCHAR(20)  NOT NULL,
+    device_version  BIGINT       NOT NULL,
+    changes         TEXT         NOT NULL,
+    request_id      VARCHAR(255) NOT NULL DEFAULT '',
+    actor           VARCHAR(255) NOT NULL DEFAULT '', -- authenticated principal
+    occurred_at     TIMESTAMP WITH TIME ZONE NOT NULL,
+    tenant_id       VARCHAR(63)  NOT NULL DEFAULT 'default',
+    on_behalf_of    VARCHAR(255) NOT NULL DEFAULT '' -- X-On-Behalf-Of of the request, as sent by the caller
+);
+
+CREATE INDEX idx_device_events_device ON device_events (device_id, id);
+
+CREATE TABLE webhooks (
+    id          VARCHAR(36)  PRIMARY KEY,
+    url         TEXT         NOT NULL,
+    secret      VARCHAR(64)  NOT NULL,
+    events      TEXT         NOT NULL DEFAULT '', -- comma separated event types, empty for all
+    created_at  TIMESTAMP WITH TIME ZONE NOT NULL,
+    tenant_id   VARCHAR(63)  NOT NULL DEFAULT 'default' -- only receives the events of its tenant
+);
+
+CREATE TABLE webhook_deliveries (
+    id                VARCHAR(36) PRIMARY KEY,
+    webhook_id        VARCHAR(36) NOT NULL REFERENCES webhooks (id) ON DELETE CASCADE,
+    event_id          VARCHAR(36) NOT NULL,
+    event_type        VARCHAR(50) NOT NULL,
+    payload           TEXT        NOT NULL,
+    status            VARCHAR(20) NOT NULL,
+    attempts          INTEGER     NOT NULL DEFAULT 0,
+    last_status_code  INTEGER     NOT NULL DEFAULT 0,
+    last_error        TEXT        NOT NULL DEFAULT '',
+    created_at        TIMESTAMP WITH TIME ZONE NOT NULL,
+    updated_at        TIMESTAMP WITH TIME ZONE NOT NULL,
+    next_attempt_at   TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now() -- when a pending delivery is attempted next
+);
+
+CREATE INDEX idx_webhook_deliveries_webhook ON webhook_deliveries (webhook_id, created_at);
+CREATE UNIQUE INDEX uq_webhook_deliveries_webhook_event ON webhook_deliveries (webhook_id, event_id);
+CREATE INDEX idx_webhook_deliveries_due ON webhook_deliveries (next_attempt_at) WHERE status = 'pending';
+
+CREATE TABLE outbox (
+    id            BIGSERIAL PRIMARY KEY,
+    event_id      VARCHAR(36) NOT NULL,
+    event_type    VARCHAR(50) NOT NULL,
+    payload       TEXT        NOT NULL,
+    attempts      INTEGER     NOT NULL DEFAULT 0,
+    last_error    TEXT        NOT NULL DEFAULT '',
+    created_at    TIMESTAMP WITH TIME ZONE NOT NULL,
+    published_at  TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX idx_outbox_pending ON outbox (id) WHERE published_at IS NULL;
+CREATE INDEX idx_outbox_published ON outbox (published_at, id) WHERE published_at IS NOT NULL;
+
+-- a single relay drains the outbox at a time, so the events keep their order with several instances
+CREATE TABLE outbox_lease (
+    id           INTEGER PRIMARY KEY CHECK (id = 1),
+    holder       VARCHAR(36) NOT NULL,
+    locked_until TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE TABLE api_keys (
+    id          VARCHAR(36)  PRIMARY KEY,
+    name        VARCHAR(100) NOT NULL,
+    prefix      VARCHAR(20)  NOT NULL, -- start of the key, to recognize it in listings
+    key_hash    VARCHAR(64)  NOT NULL UNIQUE,
+    scopes      TEXT         NOT NULL, -- comma separated
+    expires_at  TIMESTAMP WITH TIME ZONE,
+    created_at  TIMESTAMP WITH TIME ZONE NOT NULL,
+    revoked_at  TIMESTAMP WITH TIME ZONE,
+    roles       TEXT         NOT NULL DEFAULT '', -- comma separated
+    tenant_id   VARCHAR(63)  NOT NULL DEFAULT ''    -- empty when the key may pick the tenant
+);
+
+CREATE TABLE idempotency_keys (
+    tenant_id         VARCHAR(63)  NOT NULL,
+    client            VARCHAR(255) NOT NULL, -- API key or JWT subject, or IP, the keys of each client are apart
+    idempotency_key   VARCHAR(255) NOT NULL,
+    fingerprint       VARCHAR(64)  NOT NULL, -- SHA-256 of the method, path and body of the request
+    status_code       INTEGER      NOT NULL DEFAULT 0, -- 0 while the request is in progress
+    response_headers  TEXT         NOT NULL DEFAULT '{}',
+    response_body     TEXT         NOT NULL DEFAULT '',
+    created_at        TIMESTAMP WITH TIME ZONE NOT NULL,
+    expires_at        TIMESTAMP WITH TIME ZONE NOT NULL,
+    PRIMARY KEY (tenant_id, client, idempotency_key)
+);
+
+CREATE INDEX idx_idempotency_keys_expires_at ON idempotency_keys (expires_at);
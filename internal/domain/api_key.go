@@ -0,0 +1,162 @@
+package domain
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type Scope string
+
+const (
+	ScopeDevicesRead  Scope = "devices:read"
+	ScopeDevicesWrite Scope = "devices:write"
+	// ScopeAdmin manages webhooks and API keys, and grants every other scope
+	ScopeAdmin Scope = "admin"
+)
+
+func (s Scope) IsValid() bool {
+	switch s {
+	case ScopeDevicesRead, ScopeDevicesWrite, ScopeAdmin:
+		return true
+	}
+	return false
+}
+
+const (
+	// APIKeyPrefix starts every key, so leaked keys are easy to recognize
+	APIKeyPrefix = "dak_"
+	// apiKeyDisplayLength is how much of the key is kept in clear to tell the keys apart
+	apiKeyDisplayLength = len(APIKeyPrefix) + 8
+)
+
+// APIKey authenticates a client. Only the hash of the key is kept.
+type APIKey struct {
+	ID        string
+	Name      string
+	Prefix    string
+	Hash      string
+	Scopes    []Scope
+	Roles     []Role
+	TenantID  string    // empty when the key is not bound to a tenant
+	ExpiresAt time.Time // zero never expires
+	CreatedAt time.Time
+	RevokedAt time.Time
+}
+
+// NewAPIKey creates a key and returns it along with the secret, which cannot be recovered later
+func NewAPIKey(name string, scopes []Scope, roles []Role, expiresAt time.Time) (*APIKey, string, error) {
+
+	key := &APIKey{
+		ID:        uuid.New().String(),
+		Name:      name,
+		Scopes:    scopes,
+		Roles:     roles,
+		ExpiresAt: expiresAt,
+		CreatedAt: time.Now().UTC(),
+	}
+
+	if err := key.Validate(); err != nil {
+		return nil, "", err
+	}
+
+	secret, err := key.Rotate()
+	if err != nil {
+		return nil, "", err
+	}
+
+	return key, secret, nil
+}
+
+func (k *APIKey) Validate() error {
+
+	if strings.TrimSpace(k.Name) == "" {
+		return ErrNameIsRequired
+	}
+
+	if len(k.Scopes) == 0 {
+		return ErrScopeIsRequired
+	}
+	for _, s := range k.Scopes {
+		if !s.IsValid() {
+			return ErrInvalidScope
+		}
+	}
+
+	if len(k.Roles) == 0 {
+		return ErrRoleIsRequired
+	}
+	for _, r := range k.Roles {
+		if !r.IsValid() {
+			return ErrInvalidRole
+		}
+	}
+
+	if k.TenantID != "" && !IsValidTenant(k.TenantID) {
+		return ErrInvalidTenant
+	}
+
+	if !k.ExpiresAt.IsZero() && !k.ExpiresAt.After(k.CreatedAt) {
+		return ErrInvalidExpiry
+	}
+
+	return nil
+}
+
+// Rotate replaces the secret of the key, the previous one stops working once stored
+func (k *APIKey) Rotate() (string, error) {
+
+	random := make([]byte, 32)
+	if _, err := rand.Read(random); err != nil {
+		return "", err
+	}
+
+	secret := APIKeyPrefix + hex.EncodeToString(random)
+	k.Prefix = secret[:apiKeyDisplayLength]
+	k.Hash = HashAPIKey(secret)
+
+	return secret, nil
+}
+
+// HashAPIKey is how the keys are stored and looked up. The keys are random enough that a
+// fast hash cannot be brute forced, and it lets them be found by an index.
+func HashAPIKey(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// IsActive tells whether the key can still be used
+func (k *APIKey) IsActive(now time.Time) bool {
+	return k.RevokedAt.IsZero() && (k.ExpiresAt.IsZero() || now.Before(k.ExpiresAt))
+}
+
+// HasScope tells whether the key grants the scope
+func (k *APIKey) HasScope(scope Scope) bool {
+	return k.Principal().HasScope(scope)
+}
+
+// Principal is the caller authenticated by the key
+func (k *APIKey) Principal() *Principal {
+	return &Principal{
+		Subject: "apikey:" + k.ID,
+		Scopes:  k.Scopes,
+		Roles:   k.Roles,
+		Tenant:  k.TenantID,
+	}
+}
+
+type APIKeyRepository interface {
+	CreateAPIKey(ctx context.Context, key *APIKey) error
+	GetAPIKeyById(ctx context.Context, id string) (*APIKey, error)
+	GetAPIKeyByHash(ctx context.Context, hash string) (*APIKey, error)
+	GetAPIKeys(ctx context.Context) ([]APIKey, error)
+	// RevokeAPIKey fails with sql.ErrNoRows when the key does not exist or is already revoked
+	RevokeAPIKey(ctx context.Context, id string, revokedAt time.Time) error
+	// RotateAPIKey stores the new prefix and hash, with the same rules as RevokeAPIKey
+	RotateAPIKey(ctx context.Context, key *APIKey) error
+}
@@ -0,0 +1,99 @@
+package domain
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewAPIKey(t *testing.T) {
+	//act
+	key, secret, err := NewAPIKey("ci", []Scope{ScopeDevicesRead}, []Role{RoleViewer}, time.Time{})
+
+	//assert
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(secret, APIKeyPrefix))
+	assert.True(t, strings.HasPrefix(secret, key.Prefix))
+	assert.Equal(t, HashAPIKey(secret), key.Hash)
+	assert.NotContains(t, key.Hash, secret)
+	assert.True(t, key.IsActive(time.Now()))
+}
+
+func TestNewAPIKey_WhenInvalid(t *testing.T) {
+	tests := []struct {
+		name      string
+		keyName   string
+		scopes    []Scope
+		roles     []Role
+		expiresAt time.Time
+		err       error
+	}{
+		{"missing name", " ", []Scope{ScopeDevicesRead}, []Role{RoleViewer}, time.Time{}, ErrNameIsRequired},
+		{"missing scopes", "ci", nil, []Role{RoleViewer}, time.Time{}, ErrScopeIsRequired},
+		{"invalid scope", "ci", []Scope{"devices:delete"}, []Role{RoleViewer}, time.Time{}, ErrInvalidScope},
+		{"missing roles", "ci", []Scope{ScopeDevicesRead}, nil, time.Time{}, ErrRoleIsRequired},
+		{"invalid role", "ci", []Scope{ScopeDevicesRead}, []Role{"owner"}, time.Time{}, ErrInvalidRole},
+		{"expired", "ci", []Scope{ScopeDevicesRead}, []Role{RoleViewer}, time.Now().Add(-time.Hour), ErrInvalidExpiry},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			//act
+			_, _, err := NewAPIKey(tt.keyName, tt.scopes, tt.roles, tt.expiresAt)
+
+			//assert
+			assert.ErrorIs(t, err, tt.err)
+		})
+	}
+}
+
+func TestAPIKey_Rotate(t *testing.T) {
+	//arrange
+	key, secret, _ := NewAPIKey("ci", []Scope{ScopeDevicesRead}, []Role{RoleViewer}, time.Time{})
+
+	//act
+	rotated, err := key.Rotate()
+
+	//assert
+	assert.NoError(t, err)
+	assert.NotEqual(t, secret, rotated)
+	assert.Equal(t, HashAPIKey(rotated), key.Hash)
+}
+
+func TestAPIKey_Principal(t *testing.T) {
+	//arrange
+	first, _, _ := NewAPIKey("ci", []Scope{ScopeDevicesRead}, []Role{RoleViewer}, time.Time{})
+	second, _, _ := NewAPIKey("ci", []Scope{ScopeDevicesRead}, []Role{RoleViewer}, time.Time{})
+
+	//act
+	principal := first.Principal()
+
+	//assert
+	assert.Equal(t, "apikey:"+first.ID, principal.Subject)
+	assert.NotEqual(t, principal.Subject, second.Principal().Subject, "keys sharing a name must not share an actor or a rate limit")
+}
+
+func TestAPIKey_IsActive(t *testing.T) {
+	//arrange
+	now := time.Now()
+	expiring := APIKey{ExpiresAt: now.Add(time.Hour)}
+	revoked := APIKey{RevokedAt: now}
+
+	//assert
+	assert.True(t, expiring.IsActive(now))
+	assert.False(t, expiring.IsActive(now.Add(time.Hour)))
+	assert.False(t, revoked.IsActive(now))
+}
+
+func TestAPIKey_HasScope(t *testing.T) {
+	//arrange
+	reader := APIKey{Scopes: []Scope{ScopeDevicesRead}}
+	admin := APIKey{Scopes: []Scope{ScopeAdmin}}
+
+	//assert
+	assert.True(t, reader.HasScope(ScopeDevicesRead))
+	assert.False(t, reader.HasScope(ScopeDevicesWrite))
+	assert.True(t, admin.HasScope(ScopeDevicesWrite))
+}
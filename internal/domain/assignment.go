@@ -0,0 +1,80 @@
+package domain
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Assignment records who holds a device while it is in use
+type Assignment struct {
+	ID               string
+	DeviceID         string
+	Assignee         string
+	CheckedOutAt     time.Time
+	ExpectedReturnAt time.Time // zero when no return date was agreed
+	ReturnedAt       time.Time // zero while the device is still held
+	Notes            string
+}
+
+// HeldDevice is a device together with its open assignment
+type HeldDevice struct {
+	Device     Device
+	Assignment Assignment
+}
+
+func NewAssignment(deviceID, assignee string, checkedOutAt, expectedReturnAt time.Time, notes string) (*Assignment, error) {
+
+	if checkedOutAt.IsZero() {
+		checkedOutAt = time.Now()
+	}
+
+	assignment := &Assignment{
+		ID:               uuid.New().String(),
+		DeviceID:         deviceID,
+		Assignee:         assignee,
+		CheckedOutAt:     checkedOutAt,
+		ExpectedReturnAt: expectedReturnAt,
+		Notes:            notes,
+	}
+
+	if err := assignment.Validate(); err != nil {
+		return nil, err
+	}
+
+	return assignment, nil
+}
+
+func (a *Assignment) Validate() error {
+
+	if _, err := uuid.Parse(a.DeviceID); err != nil {
+		return ErrInvalidID
+	}
+
+	if a.Assignee == "" {
+		return ErrAssigneeIsRequired
+	}
+
+	if !a.ExpectedReturnAt.IsZero() && !a.ExpectedReturnAt.After(a.CheckedOutAt) {
+		return ErrInvalidExpectedReturn
+	}
+
+	return nil
+}
+
+func (a *Assignment) IsOpen() bool {
+	return a.ReturnedAt.IsZero()
+}
+
+// AssignmentRepository stores assignments. Check-out and check-in change the device
+// and its assignment together, so they must be persisted in a single transaction.
+type AssignmentRepository interface {
+	// CheckOutDevice saves the device (already moved to in-use) and opens the assignment
+	CheckOutDevice(ctx context.Context, device *Device, assignment *Assignment) error
+	// CheckInDevice saves the device (already moved back to available) and closes its open assignment
+	CheckInDevice(ctx context.Context, device *Device, returnedAt time.Time) error
+	GetOpenAssignment(ctx context.Context, deviceID string) (*Assignment, error)
+	GetAssignmentsByDevice(ctx context.Context, deviceID string, limit, offset int) ([]Assignment, error)
+	GetDevicesHeldBy(ctx context.Context, assignee string) ([]HeldDevice, error)
+}
@@ -0,0 +1,45 @@
+package domain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewAssignment(t *testing.T) {
+	//arrange
+	deviceID, now := uuid.New().String(), time.Now()
+
+	//act
+	a, err := NewAssignment(deviceID, "jane", now, now.Add(time.Hour), "notes")
+
+	//assert
+	assert.Nil(t, err)
+	assert.NotNil(t, a)
+	assert.Equal(t, a.DeviceID, deviceID)
+	assert.Equal(t, a.Assignee, "jane")
+	assert.True(t, a.IsOpen())
+}
+
+func TestNewAssignment_WhenAssigneeIsRequired(t *testing.T) {
+	//arrange, act
+	a, err := NewAssignment(uuid.New().String(), "", time.Now(), time.Time{}, "")
+
+	//assert
+	assert.Nil(t, a)
+	assert.Equal(t, err, ErrAssigneeIsRequired)
+}
+
+func TestNewAssignment_WhenExpectedReturnIsBeforeCheckout(t *testing.T) {
+	//arrange
+	now := time.Now()
+
+	//act
+	a, err := NewAssignment(uuid.New().String(), "jane", now, now.Add(-time.Hour), "")
+
+	//assert
+	assert.Nil(t, a)
+	assert.Equal(t, err, ErrInvalidExpectedReturn)
+}
@@ -0,0 +1,55 @@
+package domain
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DeviceCursor marks the position of a device in a list ordered by creation time.
+// The id breaks ties between devices created at the same instant.
+type DeviceCursor struct {
+	CreatedAt time.Time
+	ID        string
+}
+
+type cursorPayload struct {
+	CreatedAt time.Time `json:"t"`
+	ID        string    `json:"id"`
+}
+
+// CursorAfter returns the cursor pointing right after the given device
+func CursorAfter(d Device) DeviceCursor {
+	return DeviceCursor{CreatedAt: d.CreatedAt, ID: d.ID}
+}
+
+// Encode turns the cursor into an opaque token that is safe to use in a URL
+func (c DeviceCursor) Encode() string {
+	payload, _ := json.Marshal(cursorPayload{CreatedAt: c.CreatedAt, ID: c.ID})
+	return base64.RawURLEncoding.EncodeToString(payload)
+}
+
+// DecodeDeviceCursor parses a token produced by Encode
+func DecodeDeviceCursor(token string) (DeviceCursor, error) {
+
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return DeviceCursor{}, ErrInvalidCursor
+	}
+
+	var payload cursorPayload
+	if err := json.Unmarshal(raw, &payload); err != nil {
+		return DeviceCursor{}, ErrInvalidCursor
+	}
+
+	if payload.CreatedAt.IsZero() {
+		return DeviceCursor{}, ErrInvalidCursor
+	}
+	if _, err := uuid.Parse(payload.ID); err != nil {
+		return DeviceCursor{}, ErrInvalidCursor
+	}
+
+	return DeviceCursor{CreatedAt: payload.CreatedAt, ID: payload.ID}, nil
+}
@@ -0,0 +1,29 @@
+package domain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeviceCursor_EncodeDecode(t *testing.T) {
+	//arrange
+	c := DeviceCursor{CreatedAt: time.Now().UTC(), ID: uuid.New().String()}
+
+	//act
+	decoded, err := DecodeDeviceCursor(c.Encode())
+
+	//assert
+	assert.Nil(t, err)
+	assert.True(t, c.CreatedAt.Equal(decoded.CreatedAt))
+	assert.Equal(t, c.ID, decoded.ID)
+}
+
+func TestDecodeDeviceCursor_WhenInvalid(t *testing.T) {
+	for _, token := range []string{"", "%%%", "bm90IGpzb24", DeviceCursor{CreatedAt: time.Now(), ID: "x"}.Encode()} {
+		_, err := DecodeDeviceCursor(token)
+		assert.Equal(t, ErrInvalidCursor, err)
+	}
+}
@@ -21,6 +21,9 @@ type Device struct {
 	Brand     string `json:"brand"`
 	State     DeviceState
 	CreatedAt time.Time `json:"created_at"`
+	Version   int64     `json:"version"`    // incremented on every update, used for optimistic concurrency
+	DeletedAt time.Time `json:"deleted_at"` // zero unless the device was soft deleted
+	TenantID  string    `json:"tenant_id"`  // set by the repository from the tenant of the context
 }
 
 func NewDevice(id, name, brand string, state DeviceState, createdAt time.Time) (*Device, error) {
@@ -39,6 +42,7 @@ func NewDevice(id, name, brand string, state DeviceState, createdAt time.Time) (
 		Brand:     brand,
 		State:     state,
 		CreatedAt: createdAt,
+		Version:   1,
 	}
 
 	if err := device.Validate(); err != nil {
@@ -72,14 +76,29 @@ func (d *Device) Validate() error {
 	return nil
 }
 
+// SetState moves the device to s, following the transition table of the state machine.
+// A device goes in and out of use only through a checkout or a check-in, never through SetState.
 func (d *Device) SetState(s DeviceState) error {
 	if !s.IsValid() {
 		return ErrInvalidState
 	}
+	if s == d.State {
+		return nil
+	}
+	if s == DeviceInUse || d.State == DeviceInUse {
+		return ErrStateNeedsAssignment
+	}
+	if !d.State.CanTransitionTo(s) {
+		return &IllegalTransitionError{From: d.State, To: s}
+	}
 	d.State = s
 	return nil
 }
 
+func (d *Device) IsDeleted() bool {
+	return !d.DeletedAt.IsZero()
+}
+
 func (s DeviceState) IsValid() bool {
 	switch s {
 	case DeviceAvailable, DeviceInUse, DeviceInactive:
@@ -91,10 +110,31 @@ func (s DeviceState) IsValid() bool {
 // DeviceRepository defines the interface that the Service layer will use
 type DeviceRepository interface {
 	CreateDevice(ctx context.Context, device *Device) (string, error)
+	// CreateDevices stores all the devices atomically
+	CreateDevices(ctx context.Context, devices []*Device) error
+	// UpdateDevice only succeeds if the stored version still matches device.Version,
+	// otherwise ErrVersionMismatch is returned. On success device.Version is incremented.
 	UpdateDevice(ctx context.Context, device *Device) error
-	DeleteDevice(ctx context.Context, id string) error
+	// DeleteDevice soft deletes the device. It only succeeds if the stored version matches,
+	// otherwise ErrVersionMismatch is returned.
+	DeleteDevice(ctx context.Context, id string, version int64) error
+	// RestoreDevice brings back a soft deleted device, with the same version rules as UpdateDevice
+	RestoreDevice(ctx context.Context, device *Device) error
+	// PurgeDeletedDevices permanently removes the devices soft deleted before the given time
+	PurgeDeletedDevices(ctx context.Context, deletedBefore time.Time) (int64, error)
+	// GetDeviceById ignores soft deleted devices, GetDeviceByIdIncludingDeleted does not
 	GetDeviceById(ctx context.Context, id string) (*Device, error)
-	GetDevices(ctx context.Context) ([]Device, error)
-	GetDevicesByBrand(ctx context.Context, brand string) ([]Device, error)
-	GetDevicesByState(ctx context.Context, state string) ([]Device, error)
+	GetDeviceByIdIncludingDeleted(ctx context.Context, id string) (*Device, error)
+	GetDevices(ctx context.Context, filter DeviceFilter) ([]Device, error)
+	CountDevices(ctx context.Context, filter DeviceFilter) (int64, error)
+	GetDevicesAfterCursor(ctx context.Context, filter DeviceFilter, cursor DeviceCursor) ([]Device, error)
+	// StreamDevices calls fn for each device matching the filter without loading them all in memory,
+	// ignoring the pagination of the filter. It stops at the first error returned by fn.
+	StreamDevices(ctx context.Context, filter DeviceFilter, fn func(Device) error) error
+}
+
+// DeviceStatsRepository counts the devices that are not deleted, across every tenant
+type DeviceStatsRepository interface {
+	CountDevicesByState(ctx context.Context) (map[DeviceState]int64, error)
+	CountDevicesByBrand(ctx context.Context) (map[string]int64, error)
 }
@@ -23,6 +23,7 @@ func TestNewDevice(t *testing.T) {
 	assert.Equal(t, d.Name, name)
 	assert.Equal(t, d.State, DeviceAvailable)
 	assert.Equal(t, d.CreatedAt, createdAt)
+	assert.Equal(t, d.Version, int64(1))
 }
 
 func TestNewDevice_WhenInvalidId(t *testing.T) {
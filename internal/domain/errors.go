@@ -1,6 +1,9 @@
 package domain
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+)
 
 var (
 	ErrInvalidState      = errors.New("invalid state")
@@ -10,4 +13,42 @@ var (
 	ErrNameIsRequired    = errors.New("name is required")
 	ErrInvalidID         = errors.New("invalid uuid")
 	ErrDeleteDeviceInUse = errors.New("cannot delete a device in use")
+	ErrInvalidSortField  = errors.New("invalid sort field")
+	ErrInvalidSortOrder  = errors.New("invalid sort order")
+	ErrInvalidLimit      = errors.New("invalid limit")
+	ErrInvalidOffset     = errors.New("invalid offset")
+	ErrInvalidDateRange  = errors.New("created_from must not be after created_to")
+	ErrInvalidCursor     = errors.New("invalid cursor")
+	ErrCursorSort        = errors.New("cursor pagination only supports sorting by created_at")
+	ErrCursorWithOffset  = errors.New("cursor and offset cannot be used together")
+	ErrVersionMismatch   = errors.New("device version does not match")
+	ErrIllegalTransition = errors.New("illegal state transition")
+	ErrInvalidAction     = errors.New("invalid action")
+	ErrDeviceNotDeleted  = errors.New("device is not deleted")
+
+	ErrAssigneeIsRequired    = errors.New("assignee is required")
+	ErrInvalidExpectedReturn = errors.New("expected return must be after the checkout time")
+	// ErrStateNeedsAssignment is an illegal transition: only a checkout puts a device in use, a new device included,
+	// and only a check-in returns it
+	ErrStateNeedsAssignment = fmt.Errorf("%w: a device goes in use by a checkout and back by a check-in", ErrIllegalTransition)
+
+	ErrInvalidWebhookURL = errors.New("webhook url must be an absolute http or https url")
+	ErrPrivateWebhookURL = errors.New("webhook url must not target a loopback, private or link-local address")
+	ErrInvalidEventType  = errors.New("invalid event type")
+
+	ErrScopeIsRequired = errors.New("at least one scope is required")
+	ErrInvalidScope    = errors.New("invalid scope")
+	ErrInvalidExpiry   = errors.New("expires_at must be in the future")
+
+	ErrRoleIsRequired    = errors.New("at least one role is required")
+	ErrInvalidRole       = errors.New("invalid role")
+	ErrInvalidPermission = errors.New("invalid permission")
+	ErrPermissionDenied  = errors.New("permission denied")
+
+	ErrInvalidTenant   = errors.New("invalid tenant")
+	ErrTenantForbidden = errors.New("tenant does not match the credentials")
+
+	ErrInvalidIdempotencyKey    = errors.New("idempotency key must have between 1 and 255 printable characters")
+	ErrIdempotencyKeyReused     = errors.New("idempotency key was already used with another request")
+	ErrIdempotencyKeyInProgress = errors.New("a request with this idempotency key is still in progress")
 )
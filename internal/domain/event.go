@@ -0,0 +1,83 @@
+package domain
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type EventType string
+
+const (
+	EventDeviceCreated      EventType = "device.created"
+	EventDeviceUpdated      EventType = "device.updated"
+	EventDeviceStateChanged EventType = "device.state_changed"
+	EventDeviceDeleted      EventType = "device.deleted"
+	EventDeviceRestored     EventType = "device.restored"
+)
+
+func (t EventType) IsValid() bool {
+	switch t {
+	case EventDeviceCreated, EventDeviceUpdated, EventDeviceStateChanged, EventDeviceDeleted, EventDeviceRestored:
+		return true
+	}
+	return false
+}
+
+// Event tells the outside world that a device changed
+type Event struct {
+	ID            string
+	Type          EventType
+	Device        Device      // the device after the change, or as it was when deleted
+	PreviousState DeviceState // only set on state changes
+	OccurredAt    time.Time
+}
+
+func NewEvent(eventType EventType, device Device) Event {
+	return Event{
+		ID:         uuid.New().String(),
+		Type:       eventType,
+		Device:     device,
+		OccurredAt: time.Now().UTC(),
+	}
+}
+
+// DeviceChangeEvents returns the events for an update of before into after:
+// device.updated, plus device.state_changed when the state moved
+func DeviceChangeEvents(before, after Device) []Event {
+
+	events := []Event{NewEvent(EventDeviceUpdated, after)}
+
+	if before.State != after.State {
+		stateChanged := NewEvent(EventDeviceStateChanged, after)
+		stateChanged.PreviousState = before.State
+		events = append(events, stateChanged)
+	}
+
+	return events
+}
+
+// EventsForChange returns the events announcing a change recorded in the device history.
+// Purges are not announced, the device was already announced as deleted.
+func EventsForChange(changeType ChangeType, before, after *Device) []Event {
+
+	switch changeType {
+	case ChangeCreated:
+		return []Event{NewEvent(EventDeviceCreated, *after)}
+	case ChangeUpdated:
+		return DeviceChangeEvents(*before, *after)
+	case ChangeDeleted:
+		return []Event{NewEvent(EventDeviceDeleted, *after)}
+	case ChangeRestored:
+		return []Event{NewEvent(EventDeviceRestored, *after)}
+	}
+
+	return nil
+}
+
+// EventPublisher hands the events drained from the outbox to the outside world. An event is only
+// removed from the outbox once Publish succeeds, so it can be published more than once.
+type EventPublisher interface {
+	Publish(ctx context.Context, event Event) error
+}
@@ -0,0 +1,64 @@
+package domain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeviceChangeEvents_WhenStateIsUnchanged(t *testing.T) {
+	//arrange
+	before, _ := NewDevice(uuid.New().String(), "Device1", "Brand1", DeviceAvailable, time.Now())
+	after := *before
+	after.Name = "Device2"
+
+	//act
+	events := DeviceChangeEvents(*before, after)
+
+	//assert
+	assert.Len(t, events, 1)
+	assert.Equal(t, EventDeviceUpdated, events[0].Type)
+	assert.Equal(t, "Device2", events[0].Device.Name)
+	assert.Empty(t, events[0].PreviousState)
+}
+
+func TestDeviceChangeEvents_WhenStateChanged(t *testing.T) {
+	//arrange
+	before, _ := NewDevice(uuid.New().String(), "Device1", "Brand1", DeviceAvailable, time.Now())
+	after := *before
+	after.State = DeviceInUse
+
+	//act
+	events := DeviceChangeEvents(*before, after)
+
+	//assert
+	assert.Len(t, events, 2)
+	assert.Equal(t, EventDeviceUpdated, events[0].Type)
+	assert.Equal(t, EventDeviceStateChanged, events[1].Type)
+	assert.Equal(t, DeviceAvailable, events[1].PreviousState)
+	assert.Equal(t, DeviceInUse, events[1].Device.State)
+	assert.NotEqual(t, events[0].ID, events[1].ID)
+}
+
+func TestEventsForChange(t *testing.T) {
+	//arrange
+	before, _ := NewDevice(uuid.New().String(), "Device1", "Brand1", DeviceAvailable, time.Now())
+	deleted := *before
+	deleted.DeletedAt = time.Now()
+
+	//act
+	created := EventsForChange(ChangeCreated, nil, before)
+	removed := EventsForChange(ChangeDeleted, before, &deleted)
+	purged := EventsForChange(ChangePurged, &deleted, nil)
+
+	//assert
+	assert.Len(t, created, 1)
+	assert.Equal(t, EventDeviceCreated, created[0].Type)
+	assert.Len(t, removed, 1)
+	assert.Equal(t, EventDeviceDeleted, removed[0].Type)
+	assert.True(t, removed[0].Device.IsDeleted())
+	assert.Empty(t, purged)
+}
@@ -0,0 +1,78 @@
+package domain
+
+import "time"
+
+const (
+	DefaultPageLimit = 20
+	MaxPageLimit     = 100
+)
+
+type SortOrder string
+
+const (
+	SortAsc  SortOrder = "asc"
+	SortDesc SortOrder = "desc"
+)
+
+// sortableFields lists the device columns a list can be ordered by
+var sortableFields = map[string]bool{
+	"name":       true,
+	"brand":      true,
+	"state":      true,
+	"created_at": true,
+}
+
+// DeviceFilter holds the criteria used to list devices.
+// Empty fields are not applied, so the zero value matches every device.
+type DeviceFilter struct {
+	Brand       string
+	State       DeviceState
+	Name        string // case-insensitive substring match, a LIKE pattern escaped with '\'
+	CreatedFrom time.Time
+	CreatedTo   time.Time
+	SortBy      string
+	Order       SortOrder
+	Limit       int
+	Offset      int
+
+	IncludeDeleted bool
+}
+
+// Normalize fills the defaults for sorting and pagination and validates the filter
+func (f *DeviceFilter) Normalize() error {
+
+	if f.State != "" && !f.State.IsValid() {
+		return ErrInvalidState
+	}
+
+	if !f.CreatedFrom.IsZero() && !f.CreatedTo.IsZero() && f.CreatedFrom.After(f.CreatedTo) {
+		return ErrInvalidDateRange
+	}
+
+	if f.SortBy == "" {
+		f.SortBy = "created_at"
+	}
+	if !sortableFields[f.SortBy] {
+		return ErrInvalidSortField
+	}
+
+	if f.Order == "" {
+		f.Order = SortAsc
+	}
+	if f.Order != SortAsc && f.Order != SortDesc {
+		return ErrInvalidSortOrder
+	}
+
+	if f.Limit == 0 {
+		f.Limit = DefaultPageLimit
+	}
+	if f.Limit < 0 || f.Limit > MaxPageLimit {
+		return ErrInvalidLimit
+	}
+
+	if f.Offset < 0 {
+		return ErrInvalidOffset
+	}
+
+	return nil
+}
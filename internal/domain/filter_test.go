@@ -0,0 +1,46 @@
+package domain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeviceFilter_NormalizeDefaults(t *testing.T) {
+	//arrange
+	f := DeviceFilter{}
+
+	//act
+	err := f.Normalize()
+
+	//assert
+	assert.Nil(t, err)
+	assert.Equal(t, "created_at", f.SortBy)
+	assert.Equal(t, SortAsc, f.Order)
+	assert.Equal(t, DefaultPageLimit, f.Limit)
+	assert.Equal(t, 0, f.Offset)
+}
+
+func TestDeviceFilter_NormalizeInvalid(t *testing.T) {
+	now := time.Now()
+
+	cases := map[string]struct {
+		filter DeviceFilter
+		err    error
+	}{
+		"state":      {DeviceFilter{State: "broken"}, ErrInvalidState},
+		"sort field": {DeviceFilter{SortBy: "id"}, ErrInvalidSortField},
+		"sort order": {DeviceFilter{Order: "up"}, ErrInvalidSortOrder},
+		"limit":      {DeviceFilter{Limit: MaxPageLimit + 1}, ErrInvalidLimit},
+		"offset":     {DeviceFilter{Offset: -1}, ErrInvalidOffset},
+		"date range": {DeviceFilter{CreatedFrom: now, CreatedTo: now.Add(-time.Hour)}, ErrInvalidDateRange},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			err := c.filter.Normalize()
+			assert.Equal(t, c.err, err)
+		})
+	}
+}
@@ -0,0 +1,76 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+type ChangeType string
+
+const (
+	ChangeCreated  ChangeType = "created"
+	ChangeUpdated  ChangeType = "updated"
+	ChangeDeleted  ChangeType = "deleted"
+	ChangeRestored ChangeType = "restored"
+	ChangePurged   ChangeType = "purged"
+)
+
+// FieldChange holds the value of a device field before and after a change
+type FieldChange struct {
+	Old string `json:"old,omitempty"`
+	New string `json:"new,omitempty"`
+}
+
+// DeviceChange is an entry of the append-only history of a device
+type DeviceChange struct {
+	ID         int64
+	DeviceID   string
+	Type       ChangeType
+	Version    int64 // version of the device right after the change, or the deleted version
+	Fields     map[string]FieldChange
+	RequestID  string
+	Actor      string // subject of the authenticated principal
+	OnBehalfOf string // whom the caller said it acted for, not verified
+	OccurredAt time.Time
+}
+
+type DeviceHistoryRepository interface {
+	GetDeviceHistory(ctx context.Context, deviceID string, limit, offset int) ([]DeviceChange, error)
+}
+
+// DiffDevices returns the fields that differ between two snapshots of a device.
+// A nil before means the device was created and a nil after means it was purged.
+func DiffDevices(before, after *Device) map[string]FieldChange {
+
+	var old, new Device
+	if before != nil {
+		old = *before
+	}
+	if after != nil {
+		new = *after
+	}
+
+	fields := map[string]FieldChange{}
+
+	if old.Name != new.Name {
+		fields["name"] = FieldChange{Old: old.Name, New: new.Name}
+	}
+	if old.Brand != new.Brand {
+		fields["brand"] = FieldChange{Old: old.Brand, New: new.Brand}
+	}
+	if old.State != new.State {
+		fields["state"] = FieldChange{Old: string(old.State), New: string(new.State)}
+	}
+	if !old.DeletedAt.Equal(new.DeletedAt) {
+		fields["deleted_at"] = FieldChange{Old: formatChangeTime(old.DeletedAt), New: formatChangeTime(new.DeletedAt)}
+	}
+
+	return fields
+}
+
+func formatChangeTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
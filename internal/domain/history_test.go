@@ -0,0 +1,66 @@
+package domain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiffDevices_WhenCreated(t *testing.T) {
+	//arrange
+	d, _ := NewDevice(uuid.New().String(), "Device1", "Brand1", DeviceAvailable, time.Now())
+
+	//act
+	fields := DiffDevices(nil, d)
+
+	//assert
+	assert.Len(t, fields, 3)
+	assert.Equal(t, FieldChange{New: "Device1"}, fields["name"])
+	assert.Equal(t, FieldChange{New: "Brand1"}, fields["brand"])
+	assert.Equal(t, FieldChange{New: "available"}, fields["state"])
+}
+
+func TestDiffDevices_WhenUpdated(t *testing.T) {
+	//arrange
+	before, _ := NewDevice(uuid.New().String(), "Device1", "Brand1", DeviceAvailable, time.Now())
+	after := *before
+	after.Name = "Device2"
+	after.State = DeviceInUse
+
+	//act
+	fields := DiffDevices(before, &after)
+
+	//assert
+	assert.Len(t, fields, 2)
+	assert.Equal(t, FieldChange{Old: "Device1", New: "Device2"}, fields["name"])
+	assert.Equal(t, FieldChange{Old: "available", New: "in-use"}, fields["state"])
+}
+
+func TestDiffDevices_WhenPurged(t *testing.T) {
+	//arrange
+	d, _ := NewDevice(uuid.New().String(), "Device1", "Brand1", DeviceInactive, time.Now())
+
+	//act
+	fields := DiffDevices(d, nil)
+
+	//assert
+	assert.Len(t, fields, 3)
+	assert.Equal(t, FieldChange{Old: "inactive"}, fields["state"])
+}
+
+func TestDiffDevices_WhenSoftDeleted(t *testing.T) {
+	//arrange
+	before, _ := NewDevice(uuid.New().String(), "Device1", "Brand1", DeviceAvailable, time.Now())
+	after := *before
+	after.DeletedAt = time.Date(2025, 2, 1, 10, 0, 0, 0, time.UTC)
+
+	//act
+	fields := DiffDevices(before, &after)
+
+	//assert
+	assert.Len(t, fields, 1)
+	assert.Equal(t, FieldChange{New: "2025-02-01T10:00:00Z"}, fields["deleted_at"])
+}
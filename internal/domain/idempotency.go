@@ -0,0 +1,86 @@
+package domain
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+)
+
+// maxIdempotencyKeyLength is the size of the idempotency_key column
+const maxIdempotencyKeyLength = 255
+
+// IdempotencyRecord remembers a mutation sent with an Idempotency-Key, so a retry of the same
+// request gets the original response instead of running it again
+type IdempotencyRecord struct {
+	TenantID    string // set by the repository from the tenant of the context
+	Client      string // the keys of each client are apart
+	Key         string
+	Fingerprint string
+	Response    *IdempotentResponse // nil while the request is in progress
+	CreatedAt   time.Time
+	ExpiresAt   time.Time
+}
+
+// IdempotentResponse is what is replayed to the retries
+type IdempotentResponse struct {
+	StatusCode int
+	Headers    map[string]string
+	Body       []byte
+}
+
+func NewIdempotencyRecord(client, key, fingerprint string, ttl time.Duration) (*IdempotencyRecord, error) {
+
+	if !IsValidIdempotencyKey(key) {
+		return nil, ErrInvalidIdempotencyKey
+	}
+
+	now := time.Now().UTC()
+	return &IdempotencyRecord{
+		Client:      client,
+		Key:         key,
+		Fingerprint: fingerprint,
+		CreatedAt:   now,
+		ExpiresAt:   now.Add(ttl),
+	}, nil
+}
+
+// IsValidIdempotencyKey accepts up to 255 printable ASCII characters, UUIDs are the usual choice
+func IsValidIdempotencyKey(key string) bool {
+
+	if key == "" || len(key) > maxIdempotencyKeyLength {
+		return false
+	}
+	for i := 0; i < len(key); i++ {
+		if key[i] < ' ' || key[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+// IsExpired tells whether the key can be used again for another request
+func (r *IdempotencyRecord) IsExpired(now time.Time) bool {
+	return !now.Before(r.ExpiresAt)
+}
+
+// RequestFingerprint tells the requests reusing a key apart from the retries of the same request
+func RequestFingerprint(method, uri string, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(method + " " + uri + "\n"))
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+type IdempotencyRepository interface {
+	// CreateIdempotencyRecord stores the record unless the client already used the key, telling whether it was stored
+	CreateIdempotencyRecord(ctx context.Context, record *IdempotencyRecord) (bool, error)
+	GetIdempotencyRecord(ctx context.Context, client, key string) (*IdempotencyRecord, error)
+	// CompleteIdempotencyRecord stores the response of the request
+	CompleteIdempotencyRecord(ctx context.Context, record *IdempotencyRecord) error
+	DeleteIdempotencyRecord(ctx context.Context, client, key string) error
+	// DeleteExpiredIdempotencyRecord only deletes the record when it expired at now
+	DeleteExpiredIdempotencyRecord(ctx context.Context, client, key string, now time.Time) error
+	// PurgeExpiredIdempotencyRecords deletes the records of every tenant expired at now
+	PurgeExpiredIdempotencyRecords(ctx context.Context, now time.Time) (int64, error)
+}
@@ -0,0 +1,49 @@
+package domain
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsValidIdempotencyKey(t *testing.T) {
+	assert.True(t, IsValidIdempotencyKey("8e0f7a2c-1d5b-4c1e-9a57-3f2d6b4c8e11"))
+	assert.True(t, IsValidIdempotencyKey("order 42/retry"))
+	assert.True(t, IsValidIdempotencyKey(strings.Repeat("k", 255)))
+	assert.False(t, IsValidIdempotencyKey(""))
+	assert.False(t, IsValidIdempotencyKey(strings.Repeat("k", 256)))
+	assert.False(t, IsValidIdempotencyKey("key\n"))
+	assert.False(t, IsValidIdempotencyKey("clé"))
+}
+
+func TestNewIdempotencyRecord(t *testing.T) {
+	//act
+	record, err := NewIdempotencyRecord("sub:ci", "key-1", "fingerprint", time.Hour)
+
+	//assert
+	assert.NoError(t, err)
+	assert.Equal(t, time.Hour, record.ExpiresAt.Sub(record.CreatedAt))
+	assert.Nil(t, record.Response)
+	assert.False(t, record.IsExpired(record.CreatedAt))
+	assert.True(t, record.IsExpired(record.ExpiresAt))
+
+	_, err = NewIdempotencyRecord("sub:ci", "", "fingerprint", time.Hour)
+	assert.ErrorIs(t, err, ErrInvalidIdempotencyKey)
+}
+
+func TestRequestFingerprint(t *testing.T) {
+	//arrange
+	body := []byte(`{"name":"sensor","brand":"acme"}`)
+
+	//act
+	fingerprint := RequestFingerprint("POST", "/devices", body)
+
+	//assert
+	assert.Len(t, fingerprint, 64)
+	assert.Equal(t, fingerprint, RequestFingerprint("POST", "/devices", body))
+	assert.NotEqual(t, fingerprint, RequestFingerprint("POST", "/devices", []byte(`{"name":"sensor","brand":"globex"}`)))
+	assert.NotEqual(t, fingerprint, RequestFingerprint("PUT", "/devices", body))
+	assert.NotEqual(t, fingerprint, RequestFingerprint("POST", "/devices/batch", body))
+}
@@ -0,0 +1,34 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// OutboxMessage is an event stored with the change it announces, waiting to be published
+type OutboxMessage struct {
+	ID          int64
+	Event       Event
+	Attempts    int // failed attempts to publish it
+	CreatedAt   time.Time
+	PublishedAt time.Time // zero while it is pending
+}
+
+// OutboxRepository reads the outbox. Messages are written by the DeviceRepository in the
+// transaction of the change.
+type OutboxRepository interface {
+	// GetPendingMessages returns the oldest messages not yet published, in the order they were written
+	GetPendingMessages(ctx context.Context, limit int) ([]OutboxMessage, error)
+	MarkPublished(ctx context.Context, id int64, publishedAt time.Time) error
+	// GetPublishedMessages returns the messages published after the given one, in the order they were published
+	GetPublishedMessages(ctx context.Context, after OutboxMessage, limit int) ([]OutboxMessage, error)
+	// GetLastPublishedMessage returns the message published last, sql.ErrNoRows when none was
+	GetLastPublishedMessage(ctx context.Context) (*OutboxMessage, error)
+	MarkFailed(ctx context.Context, id int64, reason string) error
+	PurgePublishedMessages(ctx context.Context, publishedBefore time.Time) (int64, error)
+	// AcquireLease makes holder the only relay draining the outbox until the given time. It reports
+	// false while the lease of another holder runs; a holder renews its own lease.
+	AcquireLease(ctx context.Context, holder string, now, until time.Time) (bool, error)
+	// ReleaseLease ends the lease of holder, so another relay can take over without waiting for it to expire
+	ReleaseLease(ctx context.Context, holder string, now time.Time) error
+}
@@ -0,0 +1,44 @@
+package domain
+
+import "context"
+
+// Principal is the authenticated caller of a request, either an API key or the subject of a JWT
+type Principal struct {
+	Subject string
+	Scopes  []Scope
+	Roles   []Role
+	Tenant  string         // tenant the principal is bound to, empty when it may pick one
+	Claims  map[string]any // claims of the token, nil for API keys
+}
+
+// HasScope tells whether the principal was granted the scope
+func (p *Principal) HasScope(scope Scope) bool {
+	for _, s := range p.Scopes {
+		if s == scope || s == ScopeAdmin {
+			return true
+		}
+	}
+	return false
+}
+
+type principalKey struct{}
+
+// WithPrincipal returns a copy of ctx carrying the principal
+func WithPrincipal(ctx context.Context, principal *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+// PrincipalFromContext returns the principal of the request, or nil when it is not authenticated
+func PrincipalFromContext(ctx context.Context) *Principal {
+	principal, _ := ctx.Value(principalKey{}).(*Principal)
+	return principal
+}
+
+// ActorFromContext returns the subject of the principal changes are attributed to, or an empty
+// string when the request is not authenticated
+func ActorFromContext(ctx context.Context) string {
+	if principal := PrincipalFromContext(ctx); principal != nil {
+		return principal.Subject
+	}
+	return ""
+}
@@ -0,0 +1,100 @@
+package domain
+
+import (
+	"context"
+	"fmt"
+)
+
+// Role is what a principal is allowed to do, the Policy lists the permissions of each role
+type Role string
+
+const (
+	RoleViewer   Role = "viewer"
+	RoleOperator Role = "operator"
+	RoleAdmin    Role = "admin"
+)
+
+func (r Role) IsValid() bool {
+	switch r {
+	case RoleViewer, RoleOperator, RoleAdmin:
+		return true
+	}
+	return false
+}
+
+// Permission is an operation guarded by the Policy
+type Permission string
+
+const (
+	PermissionDevicesRead        Permission = "devices.read"
+	PermissionDevicesCreate      Permission = "devices.create"
+	PermissionDevicesUpdate      Permission = "devices.update" // name and brand
+	PermissionDevicesChangeState Permission = "devices.change_state"
+	PermissionDevicesDelete      Permission = "devices.delete"
+	PermissionDevicesRestore     Permission = "devices.restore"
+	PermissionWebhooksManage     Permission = "webhooks.manage"
+	PermissionAPIKeysManage      Permission = "api_keys.manage"
+)
+
+func (p Permission) IsValid() bool {
+	switch p {
+	case PermissionDevicesRead, PermissionDevicesCreate, PermissionDevicesUpdate, PermissionDevicesChangeState,
+		PermissionDevicesDelete, PermissionDevicesRestore, PermissionWebhooksManage, PermissionAPIKeysManage:
+		return true
+	}
+	return false
+}
+
+// Policy grants permissions to the roles. A role missing from the policy has no permission.
+type Policy struct {
+	grants map[Role]map[Permission]bool
+}
+
+func NewPolicy(grants map[Role][]Permission) (*Policy, error) {
+
+	policy := &Policy{
+		grants: make(map[Role]map[Permission]bool, len(grants)),
+	}
+
+	for role, permissions := range grants {
+		if !role.IsValid() {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidRole, role)
+		}
+		policy.grants[role] = make(map[Permission]bool, len(permissions))
+		for _, p := range permissions {
+			if !p.IsValid() {
+				return nil, fmt.Errorf("%w: %s", ErrInvalidPermission, p)
+			}
+			policy.grants[role][p] = true
+		}
+	}
+
+	return policy, nil
+}
+
+// Allows tells whether any of the roles was granted the permission
+func (p *Policy) Allows(roles []Role, permission Permission) bool {
+	for _, role := range roles {
+		if p.grants[role][permission] {
+			return true
+		}
+	}
+	return false
+}
+
+// Authorize checks the permission against the roles of the principal on the context. Calls without
+// a principal come from inside the process, e.g. the purge command, and are let through: every
+// front-end authenticates its callers before reaching the services.
+func (p *Policy) Authorize(ctx context.Context, permission Permission) error {
+
+	principal := PrincipalFromContext(ctx)
+	if principal == nil {
+		return nil
+	}
+
+	if !p.Allows(principal.Roles, permission) {
+		return fmt.Errorf("%w: %s", ErrPermissionDenied, permission)
+	}
+
+	return nil
+}
@@ -0,0 +1,45 @@
+package domain
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPolicy_Allows(t *testing.T) {
+	//arrange
+	policy, err := NewPolicy(map[Role][]Permission{
+		RoleViewer:   {PermissionDevicesRead},
+		RoleOperator: {PermissionDevicesRead, PermissionDevicesChangeState},
+	})
+
+	//assert
+	assert.NoError(t, err)
+	assert.True(t, policy.Allows([]Role{RoleViewer}, PermissionDevicesRead))
+	assert.False(t, policy.Allows([]Role{RoleViewer}, PermissionDevicesChangeState))
+	assert.True(t, policy.Allows([]Role{RoleViewer, RoleOperator}, PermissionDevicesChangeState))
+	assert.False(t, policy.Allows([]Role{RoleAdmin}, PermissionDevicesDelete))
+	assert.False(t, policy.Allows(nil, PermissionDevicesRead))
+}
+
+func TestNewPolicy_WhenInvalid(t *testing.T) {
+	//act
+	_, roleErr := NewPolicy(map[Role][]Permission{"owner": {PermissionDevicesRead}})
+	_, permissionErr := NewPolicy(map[Role][]Permission{RoleViewer: {"devices.everything"}})
+
+	//assert
+	assert.ErrorIs(t, roleErr, ErrInvalidRole)
+	assert.ErrorIs(t, permissionErr, ErrInvalidPermission)
+}
+
+func TestPolicy_Authorize(t *testing.T) {
+	//arrange
+	policy, _ := NewPolicy(map[Role][]Permission{RoleViewer: {PermissionDevicesRead}})
+	viewer := WithPrincipal(context.Background(), &Principal{Subject: "user-1", Roles: []Role{RoleViewer}})
+
+	//act & assert
+	assert.NoError(t, policy.Authorize(viewer, PermissionDevicesRead))
+	assert.ErrorIs(t, policy.Authorize(viewer, PermissionDevicesDelete), ErrPermissionDenied)
+	assert.NoError(t, policy.Authorize(context.Background(), PermissionDevicesDelete))
+}
@@ -0,0 +1,30 @@
+package domain
+
+import "context"
+
+type requestIDKey struct{}
+
+// WithRequestID returns a copy of ctx carrying the ID of the request it serves
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestIDFromContext returns the ID of the request, or an empty string outside of a request
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+type onBehalfOfKey struct{}
+
+// WithOnBehalfOf returns a copy of ctx carrying whom the caller says it acts for. It is recorded
+// next to the actor and never replaces it, as the caller is free to send any value.
+func WithOnBehalfOf(ctx context.Context, onBehalfOf string) context.Context {
+	return context.WithValue(ctx, onBehalfOfKey{}, onBehalfOf)
+}
+
+// OnBehalfOfFromContext returns whom the caller says it acts for, or an empty string
+func OnBehalfOfFromContext(ctx context.Context) string {
+	onBehalfOf, _ := ctx.Value(onBehalfOfKey{}).(string)
+	return onBehalfOf
+}
@@ -0,0 +1,55 @@
+package domain
+
+import (
+	"context"
+	"regexp"
+)
+
+// DefaultTenant owns the devices created before tenants existed, and is used when no tenant is given
+const DefaultTenant = "default"
+
+var tenantPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,62}$`)
+
+// IsValidTenant tells whether id can name a tenant: lowercase letters, digits, '-' and '_', up to 63 characters
+func IsValidTenant(id string) bool {
+	return tenantPattern.MatchString(id)
+}
+
+// ResolveTenant returns the tenant a principal works on. A principal bound to a tenant always works
+// on it and can only request that one. An unbound principal with the admin scope works on the
+// requested tenant, or on DefaultTenant when none is requested; the other principals are pinned
+// to DefaultTenant.
+func ResolveTenant(principal *Principal, requested string) (string, error) {
+
+	if requested != "" && !IsValidTenant(requested) {
+		return "", ErrInvalidTenant
+	}
+
+	tenant := DefaultTenant
+	if principal != nil && principal.Tenant != "" {
+		tenant = principal.Tenant
+	}
+
+	if requested == "" || requested == tenant {
+		return tenant, nil
+	}
+	if principal != nil && principal.Tenant == "" && principal.HasScope(ScopeAdmin) {
+		return requested, nil
+	}
+	return "", ErrTenantForbidden
+}
+
+type tenantKey struct{}
+
+// WithTenant returns a copy of ctx scoped to the tenant
+func WithTenant(ctx context.Context, tenant string) context.Context {
+	return context.WithValue(ctx, tenantKey{}, tenant)
+}
+
+// TenantFromContext returns the tenant the context is scoped to, DefaultTenant when it is not
+func TenantFromContext(ctx context.Context) string {
+	if tenant, ok := ctx.Value(tenantKey{}).(string); ok && tenant != "" {
+		return tenant
+	}
+	return DefaultTenant
+}
@@ -0,0 +1,68 @@
+package domain
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsValidTenant(t *testing.T) {
+	assert.True(t, IsValidTenant("acme"))
+	assert.True(t, IsValidTenant("acme-eu_1"))
+	assert.True(t, IsValidTenant(strings.Repeat("a", 63)))
+	assert.False(t, IsValidTenant(""))
+	assert.False(t, IsValidTenant("Acme"))
+	assert.False(t, IsValidTenant("-acme"))
+	assert.False(t, IsValidTenant("acme corp"))
+	assert.False(t, IsValidTenant(strings.Repeat("a", 64)))
+}
+
+func TestResolveTenant(t *testing.T) {
+	//arrange
+	unbound := &Principal{Subject: "ops", Scopes: []Scope{ScopeDevicesRead}}
+	admin := &Principal{Subject: "root", Scopes: []Scope{ScopeAdmin}}
+	bound := &Principal{Subject: "acme-sync", Tenant: "acme"}
+	boundAdmin := &Principal{Subject: "acme-admin", Scopes: []Scope{ScopeAdmin}, Tenant: "acme"}
+
+	tests := []struct {
+		name      string
+		principal *Principal
+		requested string
+		tenant    string
+		err       error
+	}{
+		{"unbound without request", unbound, "", DefaultTenant, nil},
+		{"unbound requests the default tenant", unbound, DefaultTenant, DefaultTenant, nil},
+		{"unbound cannot pick the tenant", unbound, "globex", "", ErrTenantForbidden},
+		{"anonymous cannot pick the tenant", nil, "globex", "", ErrTenantForbidden},
+		{"admin without request", admin, "", DefaultTenant, nil},
+		{"admin picks the tenant", admin, "globex", "globex", nil},
+		{"bound without request", bound, "", "acme", nil},
+		{"bound requests its tenant", bound, "acme", "acme", nil},
+		{"bound requests another tenant", bound, "globex", "", ErrTenantForbidden},
+		{"bound admin requests another tenant", boundAdmin, "globex", "", ErrTenantForbidden},
+		{"invalid tenant", admin, "Globex Corp", "", ErrInvalidTenant},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			//act
+			tenant, err := ResolveTenant(tt.principal, tt.requested)
+
+			//assert
+			assert.ErrorIs(t, err, tt.err)
+			assert.Equal(t, tt.tenant, tenant)
+		})
+	}
+}
+
+func TestTenantFromContext(t *testing.T) {
+	//act
+	ctx := WithTenant(context.Background(), "acme")
+
+	//assert
+	assert.Equal(t, "acme", TenantFromContext(ctx))
+	assert.Equal(t, DefaultTenant, TenantFromContext(context.Background()))
+}
@@ -0,0 +1,83 @@
+package domain
+
+import "fmt"
+
+// DeviceAction names a move of the device through its lifecycle
+type DeviceAction string
+
+const (
+	ActionActivate   DeviceAction = "activate"
+	ActionDeactivate DeviceAction = "deactivate"
+	ActionCheckOut   DeviceAction = "check-out"
+	ActionReturn     DeviceAction = "return"
+)
+
+type transition struct {
+	From DeviceState
+	To   DeviceState
+	// Assignment marks the moves in and out of use, made only by a checkout or a check-in
+	// so the assignment of the device is opened or closed along with them
+	Assignment bool
+}
+
+// actions is the transition table of the device state machine.
+// Any move between states that is not listed here is illegal, e.g. an inactive
+// device has to be activated before it can be checked out.
+var actions = map[DeviceAction]transition{
+	ActionActivate:   {From: DeviceInactive, To: DeviceAvailable},
+	ActionDeactivate: {From: DeviceAvailable, To: DeviceInactive},
+	ActionCheckOut:   {From: DeviceAvailable, To: DeviceInUse, Assignment: true},
+	ActionReturn:     {From: DeviceInUse, To: DeviceAvailable, Assignment: true},
+}
+
+// IllegalTransitionError reports a state change the state machine does not allow.
+// It matches ErrIllegalTransition with errors.Is.
+type IllegalTransitionError struct {
+	From DeviceState
+	To   DeviceState
+}
+
+func (e *IllegalTransitionError) Error() string {
+	return fmt.Sprintf("%s: %s -> %s", ErrIllegalTransition, e.From, e.To)
+}
+
+func (e *IllegalTransitionError) Is(target error) bool {
+	return target == ErrIllegalTransition
+}
+
+func (a DeviceAction) IsValid() bool {
+	_, ok := actions[a]
+	return ok
+}
+
+// ChangesAssignment tells whether the action puts the device in use or takes it out of use,
+// which only a checkout or a check-in may do
+func (a DeviceAction) ChangesAssignment() bool {
+	return actions[a].Assignment
+}
+
+// CanTransitionTo tells whether the state machine allows moving from s to target
+func (s DeviceState) CanTransitionTo(target DeviceState) bool {
+	for _, t := range actions {
+		if t.From == s && t.To == target {
+			return true
+		}
+	}
+	return false
+}
+
+// Apply moves the device to the state the action leads to
+func (d *Device) Apply(action DeviceAction) error {
+
+	t, ok := actions[action]
+	if !ok {
+		return ErrInvalidAction
+	}
+
+	if d.State != t.From {
+		return &IllegalTransitionError{From: d.State, To: t.To}
+	}
+
+	d.State = t.To
+	return nil
+}
@@ -0,0 +1,80 @@
+package domain
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDevice_Apply(t *testing.T) {
+	cases := []struct {
+		action DeviceAction
+		from   DeviceState
+		to     DeviceState
+	}{
+		{ActionActivate, DeviceInactive, DeviceAvailable},
+		{ActionDeactivate, DeviceAvailable, DeviceInactive},
+		{ActionCheckOut, DeviceAvailable, DeviceInUse},
+		{ActionReturn, DeviceInUse, DeviceAvailable},
+	}
+
+	for _, c := range cases {
+		t.Run(string(c.action), func(t *testing.T) {
+			//arrange
+			d, _ := NewDevice(uuid.New().String(), "Device 1", "Telec LTDA", c.from, time.Now())
+
+			//act
+			err := d.Apply(c.action)
+
+			//assert
+			assert.Nil(t, err)
+			assert.Equal(t, c.to, d.State)
+		})
+	}
+}
+
+func TestDevice_Apply_WhenIllegal(t *testing.T) {
+	//arrange
+	d, _ := NewDevice(uuid.New().String(), "Device 1", "Telec LTDA", DeviceInactive, time.Now())
+
+	//act
+	err := d.Apply(ActionCheckOut)
+
+	//assert
+	assert.True(t, errors.Is(err, ErrIllegalTransition))
+	var illegal *IllegalTransitionError
+	assert.True(t, errors.As(err, &illegal))
+	assert.Equal(t, DeviceInactive, illegal.From)
+	assert.Equal(t, DeviceInUse, illegal.To)
+	assert.Equal(t, DeviceInactive, d.State)
+
+	assert.Equal(t, ErrInvalidAction, d.Apply("explode"))
+}
+
+func TestDevice_SetState(t *testing.T) {
+	//arrange
+	d, _ := NewDevice(uuid.New().String(), "Device 1", "Telec LTDA", DeviceInUse, time.Now())
+
+	//act, assert
+	assert.True(t, errors.Is(d.SetState(DeviceInactive), ErrIllegalTransition))
+	assert.Equal(t, ErrInvalidState, d.SetState("broken"))
+	assert.Nil(t, d.SetState(DeviceInUse))
+	assert.Equal(t, ErrStateNeedsAssignment, d.SetState(DeviceAvailable))
+	assert.Equal(t, DeviceInUse, d.State)
+
+	d.State = DeviceAvailable
+	assert.Equal(t, ErrStateNeedsAssignment, d.SetState(DeviceInUse))
+	assert.Nil(t, d.SetState(DeviceInactive))
+	assert.Equal(t, DeviceInactive, d.State)
+}
+
+func TestDeviceAction_ChangesAssignment(t *testing.T) {
+	assert.True(t, ActionCheckOut.ChangesAssignment())
+	assert.True(t, ActionReturn.ChangesAssignment())
+	assert.False(t, ActionActivate.ChangesAssignment())
+	assert.False(t, ActionDeactivate.ChangesAssignment())
+	assert.False(t, DeviceAction("explode").ChangesAssignment())
+}
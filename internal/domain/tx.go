@@ -0,0 +1,9 @@
+package domain
+
+import "context"
+
+// Transactor is implemented by repositories able to group several operations in one transaction.
+// Repository calls made with the context given to fn are part of the transaction.
+type Transactor interface {
+	WithinTx(ctx context.Context, fn func(ctx context.Context) error) error
+}
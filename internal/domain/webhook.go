@@ -0,0 +1,160 @@
+package domain
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/netip"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Webhook is a subscription of an HTTP endpoint to device events
+type Webhook struct {
+	ID        string
+	URL       string
+	Secret    string      // key of the HMAC signature of every delivery
+	Events    []EventType // empty means every event
+	CreatedAt time.Time
+	TenantID  string // set by the repository from the tenant of the context
+}
+
+type DeliveryStatus string
+
+const (
+	DeliveryPending   DeliveryStatus = "pending"
+	DeliverySucceeded DeliveryStatus = "succeeded"
+	DeliveryFailed    DeliveryStatus = "failed"
+)
+
+// WebhookDelivery records the attempts to deliver one event to one webhook
+type WebhookDelivery struct {
+	ID             string
+	WebhookID      string
+	EventID        string
+	EventType      EventType
+	Payload        string
+	Status         DeliveryStatus
+	Attempts       int
+	LastStatusCode int
+	LastError      string
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+	NextAttemptAt  time.Time // when the delivery is attempted next while it is pending
+}
+
+// DueDelivery is a pending delivery whose next attempt is due, together with its webhook
+type DueDelivery struct {
+	Webhook  Webhook
+	Delivery WebhookDelivery
+}
+
+func NewWebhook(rawURL string, events []EventType) (*Webhook, error) {
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, err
+	}
+
+	webhook := &Webhook{
+		ID:        uuid.New().String(),
+		URL:       rawURL,
+		Secret:    hex.EncodeToString(secret),
+		Events:    events,
+		CreatedAt: time.Now().UTC(),
+	}
+
+	if err := webhook.Validate(); err != nil {
+		return nil, err
+	}
+
+	return webhook, nil
+}
+
+func (w *Webhook) Validate() error {
+
+	u, err := url.Parse(w.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return ErrInvalidWebhookURL
+	}
+
+	// names are only resolved when delivering, the dispatcher checks the addresses they resolve to
+	host := strings.ToLower(strings.TrimSuffix(u.Hostname(), "."))
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return ErrPrivateWebhookURL
+	}
+	if addr, err := netip.ParseAddr(host); err == nil && !IsPublicAddress(addr) {
+		return ErrPrivateWebhookURL
+	}
+
+	for _, e := range w.Events {
+		if !e.IsValid() {
+			return ErrInvalidEventType
+		}
+	}
+
+	return nil
+}
+
+// IsPublicAddress tells whether a webhook may be delivered to the address. Loopback, private
+// (RFC 1918 and IPv6 unique local), link-local, such as the 169.254.169.254 metadata endpoint,
+// unspecified and multicast addresses are internal to the network the API runs in.
+func IsPublicAddress(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	return addr.IsValid() &&
+		!addr.IsLoopback() &&
+		!addr.IsPrivate() &&
+		!addr.IsLinkLocalUnicast() &&
+		!addr.IsLinkLocalMulticast() &&
+		!addr.IsInterfaceLocalMulticast() &&
+		!addr.IsMulticast() &&
+		!addr.IsUnspecified()
+}
+
+// Matches tells whether the webhook subscribed to the event type
+func (w *Webhook) Matches(eventType EventType) bool {
+	if len(w.Events) == 0 {
+		return true
+	}
+	for _, e := range w.Events {
+		if e == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+func NewWebhookDelivery(webhookID string, event Event, payload string) *WebhookDelivery {
+	now := time.Now().UTC()
+	return &WebhookDelivery{
+		ID:            uuid.New().String(),
+		WebhookID:     webhookID,
+		EventID:       event.ID,
+		EventType:     event.Type,
+		Payload:       payload,
+		Status:        DeliveryPending,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+		NextAttemptAt: now,
+	}
+}
+
+type WebhookRepository interface {
+	CreateWebhook(ctx context.Context, webhook *Webhook) error
+	DeleteWebhook(ctx context.Context, id string) error
+	GetWebhookById(ctx context.Context, id string) (*Webhook, error)
+	GetWebhooks(ctx context.Context) ([]Webhook, error)
+	// CreateDelivery stores a pending delivery, nothing is stored when the webhook already has one for the event
+	CreateDelivery(ctx context.Context, delivery *WebhookDelivery) error
+	// UpdateDelivery stores the outcome of the latest attempt
+	UpdateDelivery(ctx context.Context, delivery *WebhookDelivery) error
+	GetDeliveries(ctx context.Context, webhookID string, limit, offset int) ([]WebhookDelivery, error)
+	// GetDueDeliveries lists the pending deliveries of every tenant due by now, the earliest first
+	GetDueDeliveries(ctx context.Context, now time.Time, limit int) ([]DueDelivery, error)
+	// ClaimDelivery postpones a delivery still due by now to until, so the other instances leave it
+	// alone while it is attempted. It reports false when another instance claimed it first.
+	ClaimDelivery(ctx context.Context, id string, now, until time.Time) (bool, error)
+}
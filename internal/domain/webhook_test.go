@@ -0,0 +1,76 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewWebhook(t *testing.T) {
+	//act
+	w, err := NewWebhook("https://example.com/hook", []EventType{EventDeviceCreated})
+
+	//assert
+	assert.NoError(t, err)
+	assert.Len(t, w.Secret, 64)
+	assert.True(t, w.Matches(EventDeviceCreated))
+	assert.False(t, w.Matches(EventDeviceDeleted))
+}
+
+func TestNewWebhook_WhenEventsAreEmpty_MatchesEverything(t *testing.T) {
+	//act
+	w, err := NewWebhook("http://hooks.example.com:9000/hook", nil)
+
+	//assert
+	assert.NoError(t, err)
+	assert.True(t, w.Matches(EventDeviceCreated))
+	assert.True(t, w.Matches(EventDeviceStateChanged))
+}
+
+func TestNewWebhook_WhenURLIsInvalid(t *testing.T) {
+	for _, rawURL := range []string{"", "example.com/hook", "ftp://example.com/hook", "https://"} {
+		//act
+		_, err := NewWebhook(rawURL, nil)
+
+		//assert
+		assert.ErrorIs(t, err, ErrInvalidWebhookURL, rawURL)
+	}
+}
+
+func TestNewWebhook_WhenURLIsInternal(t *testing.T) {
+	internal := []string{
+		"http://localhost:9000/hook",
+		"http://api.localhost/hook",
+		"http://127.0.0.1/hook",
+		"http://10.0.0.8/hook",
+		"http://172.16.4.2/hook",
+		"http://192.168.1.10/hook",
+		"http://169.254.169.254/latest/meta-data",
+		"http://0.0.0.0/hook",
+		"http://[::1]/hook",
+		"http://[fd00::1]/hook",
+		"http://[fe80::1]/hook",
+		"http://[::ffff:127.0.0.1]/hook",
+	}
+	for _, rawURL := range internal {
+		//act
+		_, err := NewWebhook(rawURL, nil)
+
+		//assert
+		assert.ErrorIs(t, err, ErrPrivateWebhookURL, rawURL)
+	}
+
+	//act
+	_, err := NewWebhook("https://93.184.216.34/hook", nil)
+
+	//assert
+	assert.NoError(t, err)
+}
+
+func TestNewWebhook_WhenEventIsInvalid(t *testing.T) {
+	//act
+	_, err := NewWebhook("https://example.com/hook", []EventType{EventDeviceCreated, "device.exploded"})
+
+	//assert
+	assert.ErrorIs(t, err, ErrInvalidEventType)
+}
@@ -10,6 +10,15 @@ type DeviceRequest struct {
 	State string `json:"state" example:"available"`
 }
 
+// TransitionRequest names the lifecycle action to apply to a device
+// @Description Device transition payload. The assignee, expected return and notes are those of a check-out.
+type TransitionRequest struct {
+	Action           string     `json:"action" example:"check-out" enums:"activate,deactivate,check-out,return"`
+	Assignee         string     `json:"assignee,omitempty" example:"jane.doe@example.com"`
+	ExpectedReturnAt *time.Time `json:"expected_return_at,omitempty" example:"2025-01-17T18:00:00Z"`
+	Notes            string     `json:"notes,omitempty" example:"field test in the Lisbon office"`
+}
+
 // CreateDeviceResponse represents the response returned after a device is created
 // @Description Response containing the created device ID
 type CreateDeviceResponse struct {
@@ -27,11 +36,64 @@ type UpdateDeviceResponse struct {
 // DeviceResponse represents a device stored in the system
 // @Description Device full information
 type DeviceResponse struct {
-	ID        string    `json:"id" example:"49e6d977-58a6-4424-a058-8d025991b325"`
-	Name      string    `json:"name" example:"Galaxy S21"`
-	Brand     string    `json:"brand" example:"Samsung"`
-	State     string    `json:"state" example:"in-use"`
-	CreatedAt time.Time `json:"created_at" example:"2025-01-10T15:04:05Z"`
+	ID        string     `json:"id" example:"49e6d977-58a6-4424-a058-8d025991b325"`
+	Name      string     `json:"name" example:"Galaxy S21"`
+	Brand     string     `json:"brand" example:"Samsung"`
+	State     string     `json:"state" example:"in-use"`
+	CreatedAt time.Time  `json:"created_at" example:"2025-01-10T15:04:05Z"`
+	Version   int64      `json:"version" example:"3"`
+	DeletedAt *time.Time `json:"deleted_at,omitempty" example:"2025-02-01T10:00:00Z"`
+}
+
+// DeviceListResponse represents a page of devices
+// @Description Page of devices with the total number of matches (omitted when paginating with a cursor) and the cursor of the next page
+type DeviceListResponse struct {
+	Items      []DeviceResponse `json:"items"`
+	Total      *int64           `json:"total,omitempty" example:"42"`
+	Limit      int              `json:"limit" example:"20"`
+	Offset     int              `json:"offset" example:"0"`
+	NextCursor string           `json:"next_cursor,omitempty" example:"eyJ0IjoiMjAyNS0wMS0xMFQxNTowNDowNVoiLCJpZCI6IjQ5ZTZkOTc3LTU4YTYtNDQyNC1hMDU4LThkMDI1OTkxYjMyNSJ9"`
+}
+
+// CheckOutRequest represents the payload to hand a device to someone
+// @Description Checkout payload
+type CheckOutRequest struct {
+	Assignee         string     `json:"assignee" example:"jane.doe@example.com"`
+	ExpectedReturnAt *time.Time `json:"expected_return_at,omitempty" example:"2025-01-17T18:00:00Z"`
+	Notes            string     `json:"notes" example:"field test in the Lisbon office"`
+}
+
+// AssignmentResponse represents who held a device and when
+// @Description Device assignment
+type AssignmentResponse struct {
+	ID               string     `json:"id" example:"8f0b2c8e-4d2a-4f7e-9a53-7d1c1b0e6a11"`
+	DeviceID         string     `json:"device_id" example:"49e6d977-58a6-4424-a058-8d025991b325"`
+	Assignee         string     `json:"assignee" example:"jane.doe@example.com"`
+	CheckedOutAt     time.Time  `json:"checked_out_at" example:"2025-01-10T15:04:05Z"`
+	ExpectedReturnAt *time.Time `json:"expected_return_at,omitempty" example:"2025-01-17T18:00:00Z"`
+	ReturnedAt       *time.Time `json:"returned_at,omitempty" example:"2025-01-16T09:30:00Z"`
+	Notes            string     `json:"notes" example:"field test in the Lisbon office"`
+}
+
+// CheckOutResponse represents the device and the assignment opened by a checkout
+// @Description Result of a checkout
+type CheckOutResponse struct {
+	Device     DeviceResponse     `json:"device"`
+	Assignment AssignmentResponse `json:"assignment"`
+}
+
+// CheckInResponse represents the device and the assignment closed by a check-in
+// @Description Result of a check-in
+type CheckInResponse struct {
+	Device     DeviceResponse     `json:"device"`
+	Assignment AssignmentResponse `json:"assignment"`
+}
+
+// HeldDeviceResponse represents a device currently held by an assignee
+// @Description Device with its open assignment
+type HeldDeviceResponse struct {
+	Device     DeviceResponse     `json:"device"`
+	Assignment AssignmentResponse `json:"assignment"`
 }
 
 // ErrorResponse represents an error message
@@ -39,3 +101,138 @@ type DeviceResponse struct {
 type ErrorResponse struct {
 	Error string `json:"error" example:"error description"`
 }
+
+// FieldChangeResponse represents the value of a device field before and after a change
+// @Description Field change
+type FieldChangeResponse struct {
+	Old string `json:"old,omitempty" example:"available"`
+	New string `json:"new,omitempty" example:"in-use"`
+}
+
+// DeviceChangeResponse represents an entry of the device history
+// @Description Device history entry
+type DeviceChangeResponse struct {
+	ID         int64                          `json:"id" example:"17"`
+	DeviceID   string                         `json:"device_id" example:"49e6d977-58a6-4424-a058-8d025991b325"`
+	Type       string                         `json:"type" example:"updated" enums:"created,updated,deleted"`
+	Version    int64                          `json:"version" example:"3"`
+	Changes    map[string]FieldChangeResponse `json:"changes"`
+	RequestID  string                         `json:"request_id" example:"0b6a3c5e-2f11-4bb4-9a8e-5d3f7c1e9a20"`
+	Actor      string                         `json:"actor" example:"jane.doe@example.com"`
+	OnBehalfOf string                         `json:"on_behalf_of,omitempty" example:"front-desk"`
+	OccurredAt time.Time                      `json:"occurred_at" example:"2025-01-10T15:04:05Z"`
+}
+
+// ImportRowResponse reports the outcome of one row of an import
+// @Description Import row result
+type ImportRowResponse struct {
+	Line  int    `json:"line" example:"2"`
+	ID    string `json:"id,omitempty" example:"49e6d977-58a6-4424-a058-8d025991b325"`
+	Error string `json:"error,omitempty" example:"brand is required"`
+}
+
+// ImportDevicesResponse reports the outcome of a device import
+// @Description Import report with one entry per row
+type ImportDevicesResponse struct {
+	Mode    string              `json:"mode" example:"best-effort" enums:"atomic,best-effort"`
+	Created int                 `json:"created" example:"41"`
+	Failed  int                 `json:"failed" example:"1"`
+	Rows    []ImportRowResponse `json:"rows"`
+}
+
+// BatchOperationRequest is one operation of a batch
+// @Description Batch operation: create needs device, update needs id and device, delete needs id. version works like If-Match.
+type BatchOperationRequest struct {
+	Op      string         `json:"op" example:"update" enums:"create,update,delete"`
+	ID      string         `json:"id,omitempty" example:"49e6d977-58a6-4424-a058-8d025991b325"`
+	Version *int64         `json:"version,omitempty" example:"3"`
+	Device  *DeviceRequest `json:"device,omitempty"`
+}
+
+// BatchRequest represents a list of operations to run together
+// @Description Batch payload
+type BatchRequest struct {
+	Mode       string                  `json:"mode" example:"atomic" enums:"atomic,partial"`
+	Operations []BatchOperationRequest `json:"operations"`
+}
+
+// BatchOperationResponse is the outcome of one operation, with the status and body the single call would have returned
+// @Description Batch operation result
+type BatchOperationResponse struct {
+	Status int         `json:"status" example:"200"`
+	ETag   string      `json:"etag,omitempty" example:"\"4\""`
+	Body   interface{} `json:"body,omitempty"`
+}
+
+// BatchResponse holds one result per operation, in request order
+// @Description Batch results
+type BatchResponse struct {
+	Mode    string                   `json:"mode" example:"atomic"`
+	Results []BatchOperationResponse `json:"results"`
+}
+
+// DeviceEventResponse is the payload announcing a device change
+// @Description Device event
+type DeviceEventResponse struct {
+	ID            string         `json:"id" example:"2f6e0c1a-7b8d-4c3e-9f1a-5d6b7c8e9f00"`
+	Type          string         `json:"type" example:"device.state_changed" enums:"device.created,device.updated,device.state_changed,device.deleted,device.restored"`
+	OccurredAt    time.Time      `json:"occurred_at" example:"2025-01-10T15:04:05Z"`
+	PreviousState string         `json:"previous_state,omitempty" example:"available"`
+	Data          DeviceResponse `json:"data"`
+}
+
+// WebhookRequest represents the payload to subscribe a URL to device events
+// @Description Webhook request payload, no events subscribes to every event
+type WebhookRequest struct {
+	URL    string   `json:"url" example:"https://dashboards.example.com/hooks/devices"`
+	Events []string `json:"events" example:"device.created,device.state_changed"`
+}
+
+// WebhookResponse represents a webhook subscription
+// @Description Webhook subscription, the secret is only returned on creation
+type WebhookResponse struct {
+	ID        string    `json:"id" example:"c1d2e3f4-5a6b-4c7d-8e9f-0a1b2c3d4e5f"`
+	URL       string    `json:"url" example:"https://dashboards.example.com/hooks/devices"`
+	Secret    string    `json:"secret,omitempty" example:"9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"`
+	Events    []string  `json:"events" example:"device.created,device.state_changed"`
+	CreatedAt time.Time `json:"created_at" example:"2025-01-10T15:04:05Z"`
+}
+
+// WebhookDeliveryResponse represents the attempts to deliver an event to a webhook
+// @Description Webhook delivery
+type WebhookDeliveryResponse struct {
+	ID             string    `json:"id" example:"0e1f2a3b-4c5d-4e6f-8a9b-0c1d2e3f4a5b"`
+	EventID        string    `json:"event_id" example:"2f6e0c1a-7b8d-4c3e-9f1a-5d6b7c8e9f00"`
+	EventType      string    `json:"event_type" example:"device.created"`
+	Status         string    `json:"status" example:"succeeded" enums:"pending,succeeded,failed"`
+	Attempts       int       `json:"attempts" example:"2"`
+	LastStatusCode int       `json:"last_status_code,omitempty" example:"200"`
+	LastError      string    `json:"last_error,omitempty" example:"unexpected status 503"`
+	CreatedAt      time.Time `json:"created_at" example:"2025-01-10T15:04:05Z"`
+	UpdatedAt      time.Time `json:"updated_at" example:"2025-01-10T15:04:07Z"`
+}
+
+// APIKeyRequest represents the payload to issue an API key
+// @Description API key request payload, without expires_at the key never expires and without tenant_id it is not bound to a tenant
+type APIKeyRequest struct {
+	Name      string     `json:"name" example:"inventory-sync"`
+	Scopes    []string   `json:"scopes" example:"devices:read,devices:write" enums:"devices:read,devices:write,admin"`
+	Roles     []string   `json:"roles" example:"operator" enums:"viewer,operator,admin"`
+	TenantID  string     `json:"tenant_id,omitempty" example:"acme"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty" example:"2026-01-01T00:00:00Z"`
+}
+
+// APIKeyResponse represents an API key
+// @Description API key, the key itself is only returned when it is issued or rotated
+type APIKeyResponse struct {
+	ID        string     `json:"id" example:"5b6c7d8e-9f0a-4b1c-8d2e-3f4a5b6c7d8e"`
+	Name      string     `json:"name" example:"inventory-sync"`
+	Prefix    string     `json:"prefix" example:"dak_3f9a1c2e"`
+	Key       string     `json:"key,omitempty" example:"dak_3f9a1c2e7b4d5e6f8a9b0c1d2e3f4a5b6c7d8e9f0a1b2c3d4e5f6a7b8c9d0e1f"`
+	Scopes    []string   `json:"scopes" example:"devices:read,devices:write"`
+	Roles     []string   `json:"roles" example:"operator"`
+	TenantID  string     `json:"tenant_id,omitempty" example:"acme"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty" example:"2026-01-01T00:00:00Z"`
+	CreatedAt time.Time  `json:"created_at" example:"2025-01-10T15:04:05Z"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+}
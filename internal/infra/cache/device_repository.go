@@ -0,0 +1,384 @@
+// Package cache keeps the devices read recently in memory, in front of the repositories storing them.
+package cache
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/raulsilva-tech/devices-api/internal/domain"
+)
+
+// Repository is what DeviceRepository wraps, the SQL repository implements it
+type Repository interface {
+	domain.DeviceRepository
+	domain.Transactor
+}
+
+type Config struct {
+	Size int           // entries kept, devices and lists together
+	TTL  time.Duration // how long an entry is served, it bounds how stale the changes made by other instances are
+}
+
+func DefaultConfig() Config {
+	return Config{
+		Size: 10000,
+		TTL:  30 * time.Second,
+	}
+}
+
+// Stats counts the reads served from the cache (hits) and from the wrapped repository (misses)
+type Stats struct {
+	Hits    uint64
+	Misses  uint64
+	Entries int
+}
+
+type entryKind int
+
+const (
+	kindDevice entryKind = iota
+	kindDeviceIncludingDeleted
+	kindList
+	kindCount
+	kindPage
+)
+
+// entryKey identifies a cached read. The lists carry the generation of their tenant, so a change
+// to any device of the tenant leaves them behind, to be evicted.
+type entryKey struct {
+	kind       entryKind
+	tenant     string
+	generation generation
+	id         string
+	filter     domain.DeviceFilter
+	cursor     domain.DeviceCursor
+}
+
+// generation changes with every change made to the devices of a tenant, and for every tenant with a purge
+type generation struct {
+	purges  uint64
+	changes uint64
+}
+
+// DeviceRepository is a read-through cache of the devices and the lists of devices, bounded in size
+// and time. Every change made through it drops the entries it affects. The reads made within a
+// transaction skip the cache, as they may see changes that are not committed.
+type DeviceRepository struct {
+	inner   Repository
+	entries *lru[entryKey, any]
+
+	mu      sync.Mutex // guards the generations, and orders the invalidations with the stores
+	purges  uint64
+	changes map[string]uint64
+
+	hits   atomic.Uint64
+	misses atomic.Uint64
+}
+
+func NewDeviceRepository(inner Repository, config Config) *DeviceRepository {
+	return &DeviceRepository{
+		inner:   inner,
+		entries: newLRU[entryKey, any](config.Size, config.TTL),
+		changes: map[string]uint64{},
+	}
+}
+
+func (c *DeviceRepository) Stats() Stats {
+	return Stats{
+		Hits:    c.hits.Load(),
+		Misses:  c.misses.Load(),
+		Entries: c.entries.len(),
+	}
+}
+
+func (c *DeviceRepository) CreateDevice(ctx context.Context, device *domain.Device) (string, error) {
+	id, err := c.inner.CreateDevice(ctx, device)
+	c.invalidate(ctx, id)
+	return id, err
+}
+
+func (c *DeviceRepository) CreateDevices(ctx context.Context, devices []*domain.Device) error {
+	err := c.inner.CreateDevices(ctx, devices)
+	ids := make([]string, len(devices))
+	for i, d := range devices {
+		ids[i] = d.ID
+	}
+	c.invalidate(ctx, ids...)
+	return err
+}
+
+// UpdateDevice drops the device even when the update fails, a version mismatch may come from a stale entry
+func (c *DeviceRepository) UpdateDevice(ctx context.Context, device *domain.Device) error {
+	err := c.inner.UpdateDevice(ctx, device)
+	c.invalidate(ctx, device.ID)
+	return err
+}
+
+func (c *DeviceRepository) DeleteDevice(ctx context.Context, id string, version int64) error {
+	err := c.inner.DeleteDevice(ctx, id, version)
+	c.invalidate(ctx, id)
+	return err
+}
+
+func (c *DeviceRepository) RestoreDevice(ctx context.Context, device *domain.Device) error {
+	err := c.inner.RestoreDevice(ctx, device)
+	c.invalidate(ctx, device.ID)
+	return err
+}
+
+// PurgeDeletedDevices spans every tenant, so the whole cache is dropped
+func (c *DeviceRepository) PurgeDeletedDevices(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	purged, err := c.inner.PurgeDeletedDevices(ctx, deletedBefore)
+	c.invalidateAll(ctx)
+	return purged, err
+}
+
+func (c *DeviceRepository) GetDeviceById(ctx context.Context, id string) (*domain.Device, error) {
+	return c.getDevice(ctx, kindDevice, id, c.inner.GetDeviceById)
+}
+
+func (c *DeviceRepository) GetDeviceByIdIncludingDeleted(ctx context.Context, id string) (*domain.Device, error) {
+	return c.getDevice(ctx, kindDeviceIncludingDeleted, id, c.inner.GetDeviceByIdIncludingDeleted)
+}
+
+func (c *DeviceRepository) GetDevices(ctx context.Context, filter domain.DeviceFilter) ([]domain.Device, error) {
+
+	if inTx(ctx) {
+		return c.inner.GetDevices(ctx, filter)
+	}
+
+	key, gen := c.listKey(ctx, kindList)
+	key.filter = filter
+
+	if devices, ok := c.lookup(key); ok {
+		return slices.Clone(devices.([]domain.Device)), nil
+	}
+
+	devices, err := c.inner.GetDevices(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	c.store(key, gen, slices.Clone(devices))
+
+	return devices, nil
+}
+
+func (c *DeviceRepository) CountDevices(ctx context.Context, filter domain.DeviceFilter) (int64, error) {
+
+	if inTx(ctx) {
+		return c.inner.CountDevices(ctx, filter)
+	}
+
+	key, gen := c.listKey(ctx, kindCount)
+	key.filter = filter
+
+	if total, ok := c.lookup(key); ok {
+		return total.(int64), nil
+	}
+
+	total, err := c.inner.CountDevices(ctx, filter)
+	if err != nil {
+		return 0, err
+	}
+	c.store(key, gen, total)
+
+	return total, nil
+}
+
+func (c *DeviceRepository) GetDevicesAfterCursor(ctx context.Context, filter domain.DeviceFilter, cursor domain.DeviceCursor) ([]domain.Device, error) {
+
+	if inTx(ctx) {
+		return c.inner.GetDevicesAfterCursor(ctx, filter, cursor)
+	}
+
+	key, gen := c.listKey(ctx, kindPage)
+	key.filter = filter
+	key.cursor = cursor
+
+	if devices, ok := c.lookup(key); ok {
+		return slices.Clone(devices.([]domain.Device)), nil
+	}
+
+	devices, err := c.inner.GetDevicesAfterCursor(ctx, filter, cursor)
+	if err != nil {
+		return nil, err
+	}
+	c.store(key, gen, slices.Clone(devices))
+
+	return devices, nil
+}
+
+// StreamDevices is not cached, the exports read too many devices to keep them
+func (c *DeviceRepository) StreamDevices(ctx context.Context, filter domain.DeviceFilter, fn func(domain.Device) error) error {
+	return c.inner.StreamDevices(ctx, filter, fn)
+}
+
+// WithinTx drops the entries of the devices changed by fn once the transaction is over: the reads
+// made meanwhile outside of it may have cached the devices as they were before the commit
+func (c *DeviceRepository) WithinTx(ctx context.Context, fn func(ctx context.Context) error) error {
+
+	if inTx(ctx) {
+		return c.inner.WithinTx(ctx, fn)
+	}
+
+	changes := &txChanges{devices: map[string][]string{}}
+	err := c.inner.WithinTx(context.WithValue(ctx, txKey{}, changes), fn)
+
+	changes.mu.Lock()
+	defer changes.mu.Unlock()
+	if changes.purged {
+		c.forgetAll()
+	}
+	for tenant, ids := range changes.devices {
+		c.forget(tenant, ids)
+	}
+
+	return err
+}
+
+// Assignments wraps the repository of the assignments, whose check-outs and check-ins change the devices too
+func (c *DeviceRepository) Assignments(repo domain.AssignmentRepository) domain.AssignmentRepository {
+	return &assignmentRepository{AssignmentRepository: repo, devices: c}
+}
+
+type assignmentRepository struct {
+	domain.AssignmentRepository
+	devices *DeviceRepository
+}
+
+func (r *assignmentRepository) CheckOutDevice(ctx context.Context, device *domain.Device, assignment *domain.Assignment) error {
+	err := r.AssignmentRepository.CheckOutDevice(ctx, device, assignment)
+	r.devices.invalidate(ctx, device.ID)
+	return err
+}
+
+func (r *assignmentRepository) CheckInDevice(ctx context.Context, device *domain.Device, returnedAt time.Time) error {
+	err := r.AssignmentRepository.CheckInDevice(ctx, device, returnedAt)
+	r.devices.invalidate(ctx, device.ID)
+	return err
+}
+
+func (c *DeviceRepository) getDevice(ctx context.Context, kind entryKind, id string, load func(context.Context, string) (*domain.Device, error)) (*domain.Device, error) {
+
+	if inTx(ctx) {
+		return load(ctx, id)
+	}
+
+	tenant := domain.TenantFromContext(ctx)
+	key := entryKey{kind: kind, tenant: tenant, id: id}
+
+	if device, ok := c.lookup(key); ok {
+		d := device.(domain.Device)
+		return &d, nil
+	}
+
+	gen := c.generation(tenant)
+	device, err := load(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	c.store(key, gen, *device)
+
+	return device, nil
+}
+
+// listKey returns the key of a list of the tenant of ctx, and the generation it was taken at
+func (c *DeviceRepository) listKey(ctx context.Context, kind entryKind) (entryKey, generation) {
+	tenant := domain.TenantFromContext(ctx)
+	gen := c.generation(tenant)
+	return entryKey{kind: kind, tenant: tenant, generation: gen}, gen
+}
+
+func (c *DeviceRepository) lookup(key entryKey) (any, bool) {
+	value, ok := c.entries.get(key)
+	if ok {
+		c.hits.Add(1)
+	} else {
+		c.misses.Add(1)
+	}
+	return value, ok
+}
+
+// store caches the value read at the generation gen, unless the devices of the tenant changed since
+func (c *DeviceRepository) store(key entryKey, gen generation, value any) {
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.generationLocked(key.tenant) != gen {
+		return
+	}
+	c.entries.add(key, value)
+}
+
+func (c *DeviceRepository) generation(tenant string) generation {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.generationLocked(tenant)
+}
+
+func (c *DeviceRepository) generationLocked(tenant string) generation {
+	return generation{purges: c.purges, changes: c.changes[tenant]}
+}
+
+// invalidate drops the devices of the tenant of ctx and its lists, again after the transaction of ctx if any
+func (c *DeviceRepository) invalidate(ctx context.Context, ids ...string) {
+
+	tenant := domain.TenantFromContext(ctx)
+	c.forget(tenant, ids)
+
+	if changes, ok := ctx.Value(txKey{}).(*txChanges); ok {
+		changes.mu.Lock()
+		changes.devices[tenant] = append(changes.devices[tenant], ids...)
+		changes.mu.Unlock()
+	}
+}
+
+func (c *DeviceRepository) invalidateAll(ctx context.Context) {
+
+	c.forgetAll()
+
+	if changes, ok := ctx.Value(txKey{}).(*txChanges); ok {
+		changes.mu.Lock()
+		changes.purged = true
+		changes.mu.Unlock()
+	}
+}
+
+func (c *DeviceRepository) forget(tenant string, ids []string) {
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.changes[tenant]++
+	for _, id := range ids {
+		c.entries.remove(entryKey{kind: kindDevice, tenant: tenant, id: id})
+		c.entries.remove(entryKey{kind: kindDeviceIncludingDeleted, tenant: tenant, id: id})
+	}
+}
+
+func (c *DeviceRepository) forgetAll() {
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.purges++
+	c.entries.purge()
+}
+
+type txKey struct{}
+
+// txChanges collects the devices changed within a transaction started by WithinTx
+type txChanges struct {
+	mu      sync.Mutex
+	devices map[string][]string // ids by tenant
+	purged  bool
+}
+
+func inTx(ctx context.Context) bool {
+	_, ok := ctx.Value(txKey{}).(*txChanges)
+	return ok
+}
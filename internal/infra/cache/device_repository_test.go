@@ -0,0 +1,244 @@
+package cache
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/raulsilva-tech/devices-api/internal/domain"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryRepository stores the devices by tenant and counts the reads reaching it
+type memoryRepository struct {
+	domain.DeviceRepository
+	devices map[string]map[string]domain.Device
+	reads   int
+}
+
+func newMemoryRepository() *memoryRepository {
+	return &memoryRepository{devices: map[string]map[string]domain.Device{}}
+}
+
+func (m *memoryRepository) tenant(ctx context.Context) map[string]domain.Device {
+	tenant := domain.TenantFromContext(ctx)
+	if m.devices[tenant] == nil {
+		m.devices[tenant] = map[string]domain.Device{}
+	}
+	return m.devices[tenant]
+}
+
+func (m *memoryRepository) CreateDevice(ctx context.Context, device *domain.Device) (string, error) {
+	m.tenant(ctx)[device.ID] = *device
+	return device.ID, nil
+}
+
+func (m *memoryRepository) UpdateDevice(ctx context.Context, device *domain.Device) error {
+	stored, ok := m.tenant(ctx)[device.ID]
+	if !ok {
+		return sql.ErrNoRows
+	}
+	if stored.Version != device.Version {
+		return domain.ErrVersionMismatch
+	}
+	device.Version++
+	m.tenant(ctx)[device.ID] = *device
+	return nil
+}
+
+func (m *memoryRepository) GetDeviceById(ctx context.Context, id string) (*domain.Device, error) {
+	m.reads++
+	device, ok := m.tenant(ctx)[id]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	return &device, nil
+}
+
+func (m *memoryRepository) GetDevices(ctx context.Context, filter domain.DeviceFilter) ([]domain.Device, error) {
+	m.reads++
+	var devices []domain.Device
+	for _, d := range m.tenant(ctx) {
+		if filter.Brand == "" || d.Brand == filter.Brand {
+			devices = append(devices, d)
+		}
+	}
+	return devices, nil
+}
+
+func (m *memoryRepository) CountDevices(ctx context.Context, filter domain.DeviceFilter) (int64, error) {
+	devices, err := m.GetDevices(ctx, filter)
+	return int64(len(devices)), err
+}
+
+func (m *memoryRepository) PurgeDeletedDevices(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	return 0, nil
+}
+
+func (m *memoryRepository) WithinTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	return fn(ctx)
+}
+
+func newTestDevice(t *testing.T, brand string) *domain.Device {
+	device, err := domain.NewDevice("", "sensor", brand, domain.DeviceAvailable, time.Now())
+	require.NoError(t, err)
+	return device
+}
+
+func TestDeviceRepository_CachesDevices(t *testing.T) {
+
+	ctx := context.Background()
+	inner := newMemoryRepository()
+	repo := NewDeviceRepository(inner, DefaultConfig())
+
+	device := newTestDevice(t, "acme")
+	_, err := repo.CreateDevice(ctx, device)
+	require.NoError(t, err)
+
+	for range 3 {
+		stored, err := repo.GetDeviceById(ctx, device.ID)
+		require.NoError(t, err)
+		require.Equal(t, "sensor", stored.Name)
+		// the callers may change what they get without changing the cache
+		stored.Name = "changed"
+	}
+	require.Equal(t, 1, inner.reads)
+
+	// the devices not found are not cached
+	_, err = repo.GetDeviceById(ctx, "missing")
+	require.ErrorIs(t, err, sql.ErrNoRows)
+
+	require.Equal(t, Stats{Hits: 2, Misses: 2, Entries: 1}, repo.Stats())
+
+	// an update drops the device
+	device.Name = "camera"
+	require.NoError(t, repo.UpdateDevice(ctx, device))
+	stored, err := repo.GetDeviceById(ctx, device.ID)
+	require.NoError(t, err)
+	require.Equal(t, "camera", stored.Name)
+	require.Equal(t, 3, inner.reads)
+}
+
+func TestDeviceRepository_CachesLists(t *testing.T) {
+
+	ctx := context.Background()
+	inner := newMemoryRepository()
+	repo := NewDeviceRepository(inner, DefaultConfig())
+
+	_, err := repo.CreateDevice(ctx, newTestDevice(t, "acme"))
+	require.NoError(t, err)
+
+	acme := domain.DeviceFilter{Brand: "acme"}
+	for range 2 {
+		devices, err := repo.GetDevices(ctx, acme)
+		require.NoError(t, err)
+		require.Len(t, devices, 1)
+	}
+	require.Equal(t, 1, inner.reads)
+
+	// another filter is another list
+	_, err = repo.GetDevices(ctx, domain.DeviceFilter{Brand: "globex"})
+	require.NoError(t, err)
+	require.Equal(t, 2, inner.reads)
+
+	// any change to the devices of the tenant drops its lists
+	_, err = repo.CreateDevice(ctx, newTestDevice(t, "acme"))
+	require.NoError(t, err)
+	total, err := repo.CountDevices(ctx, acme)
+	require.NoError(t, err)
+	require.EqualValues(t, 2, total)
+	devices, err := repo.GetDevices(ctx, acme)
+	require.NoError(t, err)
+	require.Len(t, devices, 2)
+
+	// and a purge drops every list
+	reads := inner.reads
+	_, err = repo.PurgeDeletedDevices(ctx, time.Now())
+	require.NoError(t, err)
+	_, err = repo.GetDevices(ctx, acme)
+	require.NoError(t, err)
+	require.Equal(t, reads+1, inner.reads)
+}
+
+func TestDeviceRepository_TenantsAreApart(t *testing.T) {
+
+	acme := domain.WithTenant(context.Background(), "acme")
+	globex := domain.WithTenant(context.Background(), "globex")
+	inner := newMemoryRepository()
+	repo := NewDeviceRepository(inner, DefaultConfig())
+
+	device := newTestDevice(t, "acme")
+	_, err := repo.CreateDevice(acme, device)
+	require.NoError(t, err)
+	_, err = repo.GetDeviceById(acme, device.ID)
+	require.NoError(t, err)
+
+	_, err = repo.GetDeviceById(globex, device.ID)
+	require.ErrorIs(t, err, sql.ErrNoRows)
+	devices, err := repo.GetDevices(globex, domain.DeviceFilter{})
+	require.NoError(t, err)
+	require.Empty(t, devices)
+
+	// the changes of a tenant leave the lists of the others cached
+	_, err = repo.CreateDevice(acme, newTestDevice(t, "acme"))
+	require.NoError(t, err)
+	reads := inner.reads
+	_, err = repo.GetDevices(globex, domain.DeviceFilter{})
+	require.NoError(t, err)
+	require.Equal(t, reads, inner.reads)
+}
+
+func TestDeviceRepository_Transactions(t *testing.T) {
+
+	ctx := context.Background()
+	inner := newMemoryRepository()
+	repo := NewDeviceRepository(inner, DefaultConfig())
+
+	device := newTestDevice(t, "acme")
+	_, err := repo.CreateDevice(ctx, device)
+	require.NoError(t, err)
+
+	errRollback := errors.New("rollback")
+	err = repo.WithinTx(ctx, func(ctx context.Context) error {
+		// the reads within the transaction skip the cache
+		stored, err := repo.GetDeviceById(ctx, device.ID)
+		require.NoError(t, err)
+		stored.Name = "camera"
+		require.NoError(t, repo.UpdateDevice(ctx, stored))
+
+		// a read outside of the transaction caches the device meanwhile
+		_, err = repo.GetDeviceById(context.Background(), device.ID)
+		require.NoError(t, err)
+		return errRollback
+	})
+	require.ErrorIs(t, err, errRollback)
+	require.Zero(t, repo.Stats().Entries)
+	require.Zero(t, repo.Stats().Hits)
+}
+
+func TestDeviceRepository_Assignments(t *testing.T) {
+
+	ctx := context.Background()
+	repo := NewDeviceRepository(newMemoryRepository(), DefaultConfig())
+	assignments := repo.Assignments(nopAssignments{})
+
+	device := newTestDevice(t, "acme")
+	_, err := repo.CreateDevice(ctx, device)
+	require.NoError(t, err)
+	_, err = repo.GetDeviceById(ctx, device.ID)
+	require.NoError(t, err)
+	require.Equal(t, 1, repo.Stats().Entries)
+
+	require.NoError(t, assignments.CheckOutDevice(ctx, device, &domain.Assignment{}))
+	require.Zero(t, repo.Stats().Entries)
+}
+
+type nopAssignments struct {
+	domain.AssignmentRepository
+}
+
+func (nopAssignments) CheckOutDevice(ctx context.Context, device *domain.Device, assignment *domain.Assignment) error {
+	return nil
+}
@@ -0,0 +1,51 @@
+package cache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLRU_EvictsLeastRecentlyUsed(t *testing.T) {
+
+	c := newLRU[string, int](2, time.Minute)
+	c.add("a", 1)
+	c.add("b", 2)
+
+	// reading a makes b the least recently used
+	_, ok := c.get("a")
+	require.True(t, ok)
+	c.add("c", 3)
+
+	_, ok = c.get("b")
+	require.False(t, ok)
+	value, ok := c.get("a")
+	require.True(t, ok)
+	require.Equal(t, 1, value)
+	require.Equal(t, 2, c.len())
+
+	c.remove("a")
+	_, ok = c.get("a")
+	require.False(t, ok)
+
+	c.purge()
+	require.Zero(t, c.len())
+}
+
+func TestLRU_Expires(t *testing.T) {
+
+	now := time.Now()
+	c := newLRU[string, int](2, time.Minute)
+	c.now = func() time.Time { return now }
+
+	c.add("a", 1)
+	now = now.Add(59 * time.Second)
+	_, ok := c.get("a")
+	require.True(t, ok)
+
+	now = now.Add(time.Second)
+	_, ok = c.get("a")
+	require.False(t, ok)
+	require.Zero(t, c.len())
+}
@@ -0,0 +1,147 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+
+	"github.com/raulsilva-tech/devices-api/internal/domain"
+	"github.com/raulsilva-tech/devices-api/internal/infra/db/sqlc"
+)
+
+type APIKeyRepository struct {
+	db      *sql.DB
+	Queries *sqlc.Queries
+}
+
+func NewAPIKeyRepository(dbConn *sql.DB) *APIKeyRepository {
+	return &APIKeyRepository{
+		db:      dbConn,
+		Queries: sqlc.New(traced(dbConn)),
+	}
+}
+
+func (repo *APIKeyRepository) CreateAPIKey(ctx context.Context, key *domain.APIKey) error {
+
+	scopes := make([]string, len(key.Scopes))
+	for i, s := range key.Scopes {
+		scopes[i] = string(s)
+	}
+
+	roles := make([]string, len(key.Roles))
+	for i, r := range key.Roles {
+		roles[i] = string(r)
+	}
+
+	return queriesFor(ctx, repo.Queries).CreateAPIKey(ctx, sqlc.CreateAPIKeyParams{
+		ID:        key.ID,
+		Name:      key.Name,
+		Prefix:    key.Prefix,
+		KeyHash:   key.Hash,
+		Scopes:    strings.Join(scopes, ","),
+		Roles:     strings.Join(roles, ","),
+		TenantID:  key.TenantID,
+		ExpiresAt: toNullTime(key.ExpiresAt),
+		CreatedAt: key.CreatedAt,
+	})
+}
+
+func (repo *APIKeyRepository) GetAPIKeyById(ctx context.Context, id string) (*domain.APIKey, error) {
+
+	keyDB, err := queriesFor(ctx, repo.Queries).GetAPIKeyByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	key := mapDBToDomainAPIKey(keyDB)
+	return &key, nil
+}
+
+func (repo *APIKeyRepository) GetAPIKeyByHash(ctx context.Context, hash string) (*domain.APIKey, error) {
+
+	keyDB, err := queriesFor(ctx, repo.Queries).GetAPIKeyByHash(ctx, hash)
+	if err != nil {
+		return nil, err
+	}
+
+	key := mapDBToDomainAPIKey(keyDB)
+	return &key, nil
+}
+
+func (repo *APIKeyRepository) GetAPIKeys(ctx context.Context) ([]domain.APIKey, error) {
+
+	keyDBList, err := queriesFor(ctx, repo.Queries).ListAPIKeys(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	resultList := make([]domain.APIKey, len(keyDBList))
+
+	for i, keyDB := range keyDBList {
+		resultList[i] = mapDBToDomainAPIKey(keyDB)
+	}
+
+	return resultList, nil
+}
+
+func (repo *APIKeyRepository) RevokeAPIKey(ctx context.Context, id string, revokedAt time.Time) error {
+
+	rows, err := queriesFor(ctx, repo.Queries).RevokeAPIKey(ctx, sqlc.RevokeAPIKeyParams{
+		ID:        id,
+		RevokedAt: toNullTime(revokedAt),
+	})
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+func (repo *APIKeyRepository) RotateAPIKey(ctx context.Context, key *domain.APIKey) error {
+
+	rows, err := queriesFor(ctx, repo.Queries).RotateAPIKey(ctx, sqlc.RotateAPIKeyParams{
+		ID:      key.ID,
+		Prefix:  key.Prefix,
+		KeyHash: key.Hash,
+	})
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+func mapDBToDomainAPIKey(k sqlc.ApiKey) domain.APIKey {
+
+	var scopes []domain.Scope
+	for _, s := range strings.Split(k.Scopes, ",") {
+		scopes = append(scopes, domain.Scope(s))
+	}
+
+	var roles []domain.Role
+	for _, r := range strings.Split(k.Roles, ",") {
+		if r != "" {
+			roles = append(roles, domain.Role(r))
+		}
+	}
+
+	return domain.APIKey{
+		ID:        k.ID,
+		Name:      k.Name,
+		Prefix:    k.Prefix,
+		Hash:      k.KeyHash,
+		Scopes:    scopes,
+		Roles:     roles,
+		TenantID:  k.TenantID,
+		ExpiresAt: k.ExpiresAt.Time,
+		CreatedAt: k.CreatedAt,
+		RevokedAt: k.RevokedAt.Time,
+	}
+}
@@ -0,0 +1,48 @@
+package repository
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/raulsilva-tech/devices-api/internal/domain"
+)
+
+func (suite *DeviceRepositoryTestSuite) TestAPIKeys() {
+
+	repo := NewAPIKeyRepository(suite.DB)
+
+	key, secret, err := domain.NewAPIKey("ci", []domain.Scope{domain.ScopeDevicesRead, domain.ScopeDevicesWrite}, []domain.Role{domain.RoleOperator}, time.Now().Add(time.Hour))
+	suite.NoError(err)
+	suite.NoError(repo.CreateAPIKey(suite.ctx, key))
+
+	stored, err := repo.GetAPIKeyByHash(suite.ctx, domain.HashAPIKey(secret))
+	suite.NoError(err)
+	suite.Equal(key.ID, stored.ID)
+	suite.Equal(key.Prefix, stored.Prefix)
+	suite.Equal(key.Scopes, stored.Scopes)
+	suite.Equal(key.Roles, stored.Roles)
+	suite.WithinDuration(key.ExpiresAt, stored.ExpiresAt, time.Second)
+	suite.True(stored.RevokedAt.IsZero())
+
+	// the previous secret no longer finds the key once rotated
+	rotated, err := key.Rotate()
+	suite.NoError(err)
+	suite.NoError(repo.RotateAPIKey(suite.ctx, key))
+
+	_, err = repo.GetAPIKeyByHash(suite.ctx, domain.HashAPIKey(secret))
+	suite.ErrorIs(err, sql.ErrNoRows)
+	_, err = repo.GetAPIKeyByHash(suite.ctx, domain.HashAPIKey(rotated))
+	suite.NoError(err)
+
+	suite.NoError(repo.RevokeAPIKey(suite.ctx, key.ID, time.Now()))
+	suite.ErrorIs(repo.RevokeAPIKey(suite.ctx, key.ID, time.Now()), sql.ErrNoRows)
+	suite.ErrorIs(repo.RotateAPIKey(suite.ctx, key), sql.ErrNoRows)
+
+	stored, err = repo.GetAPIKeyById(suite.ctx, key.ID)
+	suite.NoError(err)
+	suite.False(stored.RevokedAt.IsZero())
+
+	list, err := repo.GetAPIKeys(suite.ctx)
+	suite.NoError(err)
+	suite.Len(list, 1)
+}
@@ -0,0 +1,137 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/raulsilva-tech/devices-api/internal/domain"
+	"github.com/raulsilva-tech/devices-api/internal/infra/db/sqlc"
+)
+
+type AssignmentRepository struct {
+	db      *sql.DB
+	Queries *sqlc.Queries
+}
+
+func NewAssignmentRepository(dbConn *sql.DB) *AssignmentRepository {
+	return &AssignmentRepository{
+		db:      dbConn,
+		Queries: sqlc.New(traced(dbConn)),
+	}
+}
+
+func (repo *AssignmentRepository) CheckOutDevice(ctx context.Context, device *domain.Device, assignment *domain.Assignment) error {
+
+	version := device.Version
+
+	err := execTx(ctx, repo.db, func(q *sqlc.Queries) error {
+
+		if err := updateDevice(ctx, q, device); err != nil {
+			return err
+		}
+
+		return q.CreateAssignment(ctx, sqlc.CreateAssignmentParams{
+			ID:               assignment.ID,
+			DeviceID:         assignment.DeviceID,
+			Assignee:         assignment.Assignee,
+			CheckedOutAt:     assignment.CheckedOutAt,
+			ExpectedReturnAt: toNullTime(assignment.ExpectedReturnAt),
+			Notes:            assignment.Notes,
+		})
+	})
+	if err != nil {
+		// nothing was stored, so the device keeps the version it was read with
+		device.Version = version
+	}
+
+	return err
+}
+
+func (repo *AssignmentRepository) CheckInDevice(ctx context.Context, device *domain.Device, returnedAt time.Time) error {
+
+	version := device.Version
+
+	err := execTx(ctx, repo.db, func(q *sqlc.Queries) error {
+
+		if err := updateDevice(ctx, q, device); err != nil {
+			return err
+		}
+
+		_, err := q.CloseOpenAssignments(ctx, sqlc.CloseOpenAssignmentsParams{
+			DeviceID:   device.ID,
+			ReturnedAt: toNullTime(returnedAt),
+		})
+		return err
+	})
+	if err != nil {
+		device.Version = version
+	}
+
+	return err
+}
+
+func (repo *AssignmentRepository) GetOpenAssignment(ctx context.Context, deviceID string) (*domain.Assignment, error) {
+
+	assignmentDB, err := queriesFor(ctx, repo.Queries).GetOpenAssignmentByDevice(ctx, deviceID)
+	if err != nil {
+		return nil, err
+	}
+
+	assignment := mapDBToDomainAssignment(assignmentDB)
+	return &assignment, nil
+}
+
+func (repo *AssignmentRepository) GetAssignmentsByDevice(ctx context.Context, deviceID string, limit, offset int) ([]domain.Assignment, error) {
+
+	assignmentDBList, err := queriesFor(ctx, repo.Queries).ListAssignmentsByDevice(ctx, sqlc.ListAssignmentsByDeviceParams{
+		DeviceID: deviceID,
+		Limit:    int32(limit),
+		Offset:   int32(offset),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	resultList := make([]domain.Assignment, len(assignmentDBList))
+
+	for i, assignmentDB := range assignmentDBList {
+		resultList[i] = mapDBToDomainAssignment(assignmentDB)
+	}
+
+	return resultList, nil
+}
+
+func (repo *AssignmentRepository) GetDevicesHeldBy(ctx context.Context, assignee string) ([]domain.HeldDevice, error) {
+
+	rows, err := queriesFor(ctx, repo.Queries).ListDevicesHeldBy(ctx, sqlc.ListDevicesHeldByParams{
+		Assignee: assignee,
+		TenantID: domain.TenantFromContext(ctx),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	resultList := make([]domain.HeldDevice, len(rows))
+
+	for i, row := range rows {
+		resultList[i] = domain.HeldDevice{
+			Device:     mapDBToDomainDevice(row.Device),
+			Assignment: mapDBToDomainAssignment(row.DeviceAssignment),
+		}
+	}
+
+	return resultList, nil
+}
+
+func mapDBToDomainAssignment(a sqlc.DeviceAssignment) domain.Assignment {
+	return domain.Assignment{
+		ID:               a.ID,
+		DeviceID:         a.DeviceID,
+		Assignee:         a.Assignee,
+		CheckedOutAt:     a.CheckedOutAt,
+		ExpectedReturnAt: a.ExpectedReturnAt.Time,
+		ReturnedAt:       a.ReturnedAt.Time,
+		Notes:            a.Notes,
+	}
+}
@@ -0,0 +1,106 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/raulsilva-tech/devices-api/internal/domain"
+	"github.com/stretchr/testify/suite"
+)
+
+type AssignmentRepositoryTestSuite struct {
+	DB  *sql.DB
+	ctx context.Context
+	suite.Suite
+}
+
+func TestAssignmentRepositorySuite(t *testing.T) {
+	suite.Run(t, new(AssignmentRepositoryTestSuite))
+}
+
+func (suite *AssignmentRepositoryTestSuite) TearDownTest() {
+	suite.DB.Close()
+}
+
+func (suite *AssignmentRepositoryTestSuite) SetupTest() {
+	dbConn, err := migrateDB()
+	suite.NoError(err)
+	suite.DB = dbConn
+	suite.ctx = context.Background()
+}
+
+func (suite *AssignmentRepositoryTestSuite) TestCheckOutAndCheckIn() {
+
+	devRepo, d, err := createDevice(suite.ctx, suite.DB)
+	suite.NoError(err)
+	repo := NewAssignmentRepository(suite.DB)
+
+	assignment, err := domain.NewAssignment(d.ID, "jane", time.Now().UTC(), time.Now().UTC().Add(24*time.Hour), "demo")
+	suite.NoError(err)
+	suite.NoError(d.Apply(domain.ActionCheckOut))
+
+	err = repo.CheckOutDevice(suite.ctx, d, assignment)
+	suite.NoError(err)
+	suite.Equal(int64(2), d.Version)
+
+	dbDevice, err := devRepo.GetDeviceById(suite.ctx, d.ID)
+	suite.NoError(err)
+	suite.Equal(domain.DeviceInUse, dbDevice.State)
+
+	open, err := repo.GetOpenAssignment(suite.ctx, d.ID)
+	suite.NoError(err)
+	suite.Equal("jane", open.Assignee)
+	suite.Equal("demo", open.Notes)
+	suite.True(open.IsOpen())
+
+	held, err := repo.GetDevicesHeldBy(suite.ctx, "jane")
+	suite.NoError(err)
+	suite.Len(held, 1)
+	suite.Equal(d.ID, held[0].Device.ID)
+	suite.Equal(assignment.ID, held[0].Assignment.ID)
+
+	suite.NoError(d.Apply(domain.ActionReturn))
+	err = repo.CheckInDevice(suite.ctx, d, time.Now().UTC())
+	suite.NoError(err)
+
+	_, err = repo.GetOpenAssignment(suite.ctx, d.ID)
+	suite.ErrorIs(err, sql.ErrNoRows)
+
+	held, err = repo.GetDevicesHeldBy(suite.ctx, "jane")
+	suite.NoError(err)
+	suite.Empty(held)
+
+	history, err := repo.GetAssignmentsByDevice(suite.ctx, d.ID, 10, 0)
+	suite.NoError(err)
+	suite.Len(history, 1)
+	suite.False(history[0].IsOpen())
+}
+
+func (suite *AssignmentRepositoryTestSuite) TestCheckOut_RollsBackWhenDeviceIsStale() {
+
+	devRepo, d, err := createDevice(suite.ctx, suite.DB)
+	suite.NoError(err)
+	repo := NewAssignmentRepository(suite.DB)
+
+	// somebody else changes the device after it was read
+	concurrent := *d
+	concurrent.Name = "Renamed"
+	suite.NoError(devRepo.UpdateDevice(suite.ctx, &concurrent))
+
+	assignment, err := domain.NewAssignment(d.ID, "jane", time.Now().UTC(), time.Time{}, "")
+	suite.NoError(err)
+	suite.NoError(d.Apply(domain.ActionCheckOut))
+
+	err = repo.CheckOutDevice(suite.ctx, d, assignment)
+	suite.ErrorIs(err, domain.ErrVersionMismatch)
+	suite.Equal(int64(1), d.Version)
+
+	_, err = repo.GetOpenAssignment(suite.ctx, d.ID)
+	suite.ErrorIs(err, sql.ErrNoRows)
+
+	dbDevice, err := devRepo.GetDeviceById(suite.ctx, d.ID)
+	suite.NoError(err)
+	suite.Equal(domain.DeviceAvailable, dbDevice.State)
+}
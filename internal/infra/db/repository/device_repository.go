@@ -3,11 +3,14 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"time"
 
 	"github.com/raulsilva-tech/devices-api/internal/domain"
 	"github.com/raulsilva-tech/devices-api/internal/infra/db/sqlc"
 )
 
+// DeviceRepository reads and changes the devices of the tenant of the context (domain.TenantFromContext).
+// Devices of other tenants are never returned nor changed, they are reported as not found.
 type DeviceRepository struct {
 	db      *sql.DB
 	Queries *sqlc.Queries
@@ -16,64 +19,282 @@ type DeviceRepository struct {
 func NewDeviceRepository(dbConn *sql.DB) *DeviceRepository {
 	return &DeviceRepository{
 		db:      dbConn,
-		Queries: sqlc.New(dbConn),
+		Queries: sqlc.New(traced(dbConn)),
 	}
 }
 
 func (repo *DeviceRepository) CreateDevice(ctx context.Context, device *domain.Device) (string, error) {
 
-	return repo.Queries.CreateDevice(ctx, sqlc.CreateDeviceParams{
+	err := execTx(ctx, repo.db, func(q *sqlc.Queries) error {
+		return insertDevice(ctx, q, device)
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return device.ID, nil
+}
+
+// CreateDevices stores all the devices in one transaction, so either every device is created or none is
+func (repo *DeviceRepository) CreateDevices(ctx context.Context, devices []*domain.Device) error {
+
+	return execTx(ctx, repo.db, func(q *sqlc.Queries) error {
+		for _, device := range devices {
+			if err := insertDevice(ctx, q, device); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func insertDevice(ctx context.Context, q *sqlc.Queries, device *domain.Device) error {
+
+	device.TenantID = domain.TenantFromContext(ctx)
+
+	_, err := q.CreateDevice(ctx, sqlc.CreateDeviceParams{
 		ID:        device.ID,
 		Name:      device.Name,
 		Brand:     device.Brand,
 		State:     string(device.State),
 		CreatedAt: device.CreatedAt,
+		Version:   device.Version,
+		TenantID:  device.TenantID,
 	})
+	if err != nil {
+		return err
+	}
+
+	return recordDeviceChange(ctx, q, domain.ChangeCreated, nil, device)
 }
 
 func (repo *DeviceRepository) UpdateDevice(ctx context.Context, device *domain.Device) error {
 
-	return repo.Queries.UpdateDevice(ctx, sqlc.UpdateDeviceParams{
-		ID:    device.ID,
-		Name:  device.Name,
-		Brand: device.Brand,
-		State: string(device.State),
+	version := device.Version
+
+	err := execTx(ctx, repo.db, func(q *sqlc.Queries) error {
+		return updateDevice(ctx, q, device)
 	})
+	if err != nil {
+		device.Version = version
+	}
+
+	return err
 }
 
-func (repo *DeviceRepository) DeleteDevice(ctx context.Context, id string) error {
-	return repo.Queries.DeleteDevice(ctx, id)
+// updateDevice performs the compare-and-swap on the device version with the given queries
+// and records the change in the device history, so it can be part of a larger transaction
+func updateDevice(ctx context.Context, q *sqlc.Queries, device *domain.Device) error {
+
+	before, err := getDeviceForChange(ctx, q, device.ID, device.Version)
+	if err != nil {
+		return err
+	}
+
+	rows, err := q.UpdateDevice(ctx, sqlc.UpdateDeviceParams{
+		ID:       device.ID,
+		Name:     device.Name,
+		Brand:    device.Brand,
+		State:    string(device.State),
+		Version:  device.Version,
+		TenantID: before.TenantID,
+	})
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return domain.ErrVersionMismatch
+	}
+
+	device.Version++
+
+	return recordDeviceChange(ctx, q, domain.ChangeUpdated, before, device)
 }
 
-func (repo *DeviceRepository) GetDeviceById(ctx context.Context, id string) (*domain.Device, error) {
+// DeleteDevice soft deletes the device, it is kept until purged and can be restored meanwhile
+func (repo *DeviceRepository) DeleteDevice(ctx context.Context, id string, version int64) error {
+
+	return execTx(ctx, repo.db, func(q *sqlc.Queries) error {
+
+		before, err := getDeviceForChange(ctx, q, id, version)
+		if err != nil {
+			return err
+		}
+
+		deletedAt := time.Now().UTC()
+		rows, err := q.DeleteDevice(ctx, sqlc.DeleteDeviceParams{
+			ID:        id,
+			Version:   version,
+			DeletedAt: toNullTime(deletedAt),
+			TenantID:  before.TenantID,
+		})
+		if err != nil {
+			return err
+		}
+		if rows == 0 {
+			return domain.ErrVersionMismatch
+		}
+
+		after := *before
+		after.DeletedAt = deletedAt
+		after.Version++
+
+		return recordDeviceChange(ctx, q, domain.ChangeDeleted, before, &after)
+	})
+}
+
+func (repo *DeviceRepository) RestoreDevice(ctx context.Context, device *domain.Device) error {
+
+	before := *device
+
+	err := execTx(ctx, repo.db, func(q *sqlc.Queries) error {
+
+		rows, err := q.RestoreDevice(ctx, sqlc.RestoreDeviceParams{
+			ID:       device.ID,
+			Version:  device.Version,
+			TenantID: domain.TenantFromContext(ctx),
+		})
+		if err != nil {
+			return err
+		}
+		if rows == 0 {
+			return domain.ErrVersionMismatch
+		}
+
+		device.DeletedAt = time.Time{}
+		device.Version++
+
+		return recordDeviceChange(ctx, q, domain.ChangeRestored, &before, device)
+	})
+	if err != nil {
+		*device = before
+	}
+
+	return err
+}
+
+// PurgeDeletedDevices hard deletes the devices soft deleted before deletedBefore and returns how many were removed.
+// Their history is kept. Unlike the other methods it spans every tenant.
+func (repo *DeviceRepository) PurgeDeletedDevices(ctx context.Context, deletedBefore time.Time) (int64, error) {
+
+	var purged int64
+
+	err := execTx(ctx, repo.db, func(q *sqlc.Queries) error {
+
+		devDBList, err := q.PurgeDeletedDevices(ctx, toNullTime(deletedBefore))
+		if err != nil {
+			return err
+		}
+
+		for _, devDB := range devDBList {
+			device := mapDBToDomainDevice(devDB)
+			if err := recordDeviceChange(ctx, q, domain.ChangePurged, &device, nil); err != nil {
+				return err
+			}
+		}
 
-	devDB, err := repo.Queries.GetDeviceByID(ctx, id)
+		purged = int64(len(devDBList))
+		return nil
+	})
+
+	return purged, err
+}
+
+// CountDevicesByState counts the devices that are not deleted in each state, across every tenant
+func (repo *DeviceRepository) CountDevicesByState(ctx context.Context) (map[domain.DeviceState]int64, error) {
+
+	rows, err := queriesFor(ctx, repo.Queries).CountDevicesByState(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[domain.DeviceState]int64, len(rows))
+	for _, row := range rows {
+		counts[domain.DeviceState(row.State)] = row.Total
+	}
+
+	return counts, nil
+}
+
+// CountDevicesByBrand counts the devices that are not deleted of each brand, across every tenant
+func (repo *DeviceRepository) CountDevicesByBrand(ctx context.Context) (map[string]int64, error) {
+
+	rows, err := queriesFor(ctx, repo.Queries).CountDevicesByBrand(ctx)
 	if err != nil {
 		return nil, err
 	}
+
+	counts := make(map[string]int64, len(rows))
+	for _, row := range rows {
+		counts[row.Brand] = row.Total
+	}
+
+	return counts, nil
+}
+
+// getDeviceForChange reads the stored device about to be changed, failing with ErrVersionMismatch
+// when it was changed or removed since the caller read it
+func getDeviceForChange(ctx context.Context, q *sqlc.Queries, id string, version int64) (*domain.Device, error) {
+
+	devDB, err := q.GetDeviceByIDIncludingDeleted(ctx, sqlc.GetDeviceByIDIncludingDeletedParams{
+		ID:       id,
+		TenantID: domain.TenantFromContext(ctx),
+	})
+	if err == sql.ErrNoRows {
+		return nil, domain.ErrVersionMismatch
+	}
+	if err != nil {
+		return nil, err
+	}
+	if devDB.Version != version {
+		return nil, domain.ErrVersionMismatch
+	}
+
 	device := mapDBToDomainDevice(devDB)
 	return &device, nil
 }
 
-func (repo *DeviceRepository) GetDevices(ctx context.Context) ([]domain.Device, error) {
+func (repo *DeviceRepository) GetDeviceById(ctx context.Context, id string) (*domain.Device, error) {
 
-	devDBList, err := repo.Queries.GetAllDevices(ctx)
+	devDB, err := queriesFor(ctx, repo.Queries).GetDeviceByID(ctx, sqlc.GetDeviceByIDParams{
+		ID:       id,
+		TenantID: domain.TenantFromContext(ctx),
+	})
 	if err != nil {
 		return nil, err
 	}
+	device := mapDBToDomainDevice(devDB)
+	return &device, nil
+}
 
-	resultList := make([]domain.Device, len(devDBList))
+func (repo *DeviceRepository) GetDeviceByIdIncludingDeleted(ctx context.Context, id string) (*domain.Device, error) {
 
-	for i, devDB := range devDBList {
-		resultList[i] = mapDBToDomainDevice(devDB)
+	devDB, err := queriesFor(ctx, repo.Queries).GetDeviceByIDIncludingDeleted(ctx, sqlc.GetDeviceByIDIncludingDeletedParams{
+		ID:       id,
+		TenantID: domain.TenantFromContext(ctx),
+	})
+	if err != nil {
+		return nil, err
 	}
-
-	return resultList, nil
+	device := mapDBToDomainDevice(devDB)
+	return &device, nil
 }
 
-func (repo *DeviceRepository) GetDevicesByBrand(ctx context.Context, brand string) ([]domain.Device, error) {
+func (repo *DeviceRepository) GetDevices(ctx context.Context, filter domain.DeviceFilter) ([]domain.Device, error) {
 
-	devDBList, err := repo.Queries.GetAllDevicesByBrand(ctx, brand)
+	devDBList, err := queriesFor(ctx, repo.Queries).ListDevices(ctx, sqlc.ListDevicesParams{
+		TenantID:       domain.TenantFromContext(ctx),
+		IncludeDeleted: filter.IncludeDeleted,
+		Brand:          filter.Brand,
+		State:          string(filter.State),
+		Name:           filter.Name,
+		CreatedFrom:    toNullTime(filter.CreatedFrom),
+		CreatedTo:      toNullTime(filter.CreatedTo),
+		SortBy:         filter.SortBy,
+		SortOrder:      string(filter.Order),
+		PageLimit:      int32(filter.Limit),
+		PageOffset:     int32(filter.Offset),
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -87,9 +308,53 @@ func (repo *DeviceRepository) GetDevicesByBrand(ctx context.Context, brand strin
 	return resultList, nil
 }
 
-func (repo *DeviceRepository) GetDevicesByState(ctx context.Context, state string) ([]domain.Device, error) {
+func (repo *DeviceRepository) CountDevices(ctx context.Context, filter domain.DeviceFilter) (int64, error) {
+
+	return queriesFor(ctx, repo.Queries).CountDevices(ctx, sqlc.CountDevicesParams{
+		TenantID:       domain.TenantFromContext(ctx),
+		IncludeDeleted: filter.IncludeDeleted,
+		Brand:          filter.Brand,
+		State:          string(filter.State),
+		Name:           filter.Name,
+		CreatedFrom:    toNullTime(filter.CreatedFrom),
+		CreatedTo:      toNullTime(filter.CreatedTo),
+	})
+}
+
+// GetDevicesAfterCursor walks the devices ordered by (created_at, id) starting right after the cursor,
+// in the direction given by the filter order. It relies on the idx_devices_tenant_created_at_id index.
+func (repo *DeviceRepository) GetDevicesAfterCursor(ctx context.Context, filter domain.DeviceFilter, cursor domain.DeviceCursor) ([]domain.Device, error) {
 
-	devDBList, err := repo.Queries.GetAllDevicesByState(ctx, state)
+	var devDBList []sqlc.Device
+	var err error
+
+	if filter.Order == domain.SortDesc {
+		devDBList, err = queriesFor(ctx, repo.Queries).ListDevicesBeforeCursor(ctx, sqlc.ListDevicesBeforeCursorParams{
+			TenantID:        domain.TenantFromContext(ctx),
+			IncludeDeleted:  filter.IncludeDeleted,
+			Brand:           filter.Brand,
+			State:           string(filter.State),
+			Name:            filter.Name,
+			CreatedFrom:     toNullTime(filter.CreatedFrom),
+			CreatedTo:       toNullTime(filter.CreatedTo),
+			CursorCreatedAt: cursor.CreatedAt,
+			CursorID:        cursor.ID,
+			PageLimit:       int32(filter.Limit),
+		})
+	} else {
+		devDBList, err = queriesFor(ctx, repo.Queries).ListDevicesAfterCursor(ctx, sqlc.ListDevicesAfterCursorParams{
+			TenantID:        domain.TenantFromContext(ctx),
+			IncludeDeleted:  filter.IncludeDeleted,
+			Brand:           filter.Brand,
+			State:           string(filter.State),
+			Name:            filter.Name,
+			CreatedFrom:     toNullTime(filter.CreatedFrom),
+			CreatedTo:       toNullTime(filter.CreatedTo),
+			CursorCreatedAt: cursor.CreatedAt,
+			CursorID:        cursor.ID,
+			PageLimit:       int32(filter.Limit),
+		})
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -110,5 +375,18 @@ func mapDBToDomainDevice(d sqlc.Device) domain.Device {
 		Brand:     d.Brand,
 		State:     domain.DeviceState(d.State),
 		CreatedAt: d.CreatedAt,
+		Version:   d.Version,
+		DeletedAt: d.DeletedAt.Time,
+		TenantID:  d.TenantID,
 	}
 }
+
+func toNullTime(t time.Time) sql.NullTime {
+	return sql.NullTime{Time: t, Valid: !t.IsZero()}
+}
+
+// WithinTx runs fn in a database transaction. Repository calls made with the context given to fn
+// join that transaction, so several service operations can be committed or rolled back together.
+func (repo *DeviceRepository) WithinTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	return withinTx(ctx, repo.db, fn)
+}
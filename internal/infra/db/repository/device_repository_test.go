@@ -3,11 +3,13 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"database/sql/driver"
+	"regexp"
 	"testing"
 	"time"
 
 	"github.com/google/uuid"
-	_ "github.com/mattn/go-sqlite3"
+	"github.com/mattn/go-sqlite3"
 	"github.com/raulsilva-tech/devices-api/internal/domain"
 	"github.com/stretchr/testify/suite"
 )
@@ -22,11 +24,12 @@ func TestDeviceRepositorySuite(t *testing.T) {
 	suite.Run(t, new(DeviceRepositoryTestSuite))
 }
 
-func (suite *DeviceRepositoryTestSuite) TearDownSuite() {
+func (suite *DeviceRepositoryTestSuite) TearDownTest() {
 	suite.DB.Close()
 }
 
-func (suite *DeviceRepositoryTestSuite) SetupSuite() {
+// SetupTest gives every test its own in-memory database so counts are not affected by other tests
+func (suite *DeviceRepositoryTestSuite) SetupTest() {
 	dbConn, err := migrateDB()
 	suite.NoError(err)
 	suite.DB = dbConn
@@ -52,9 +55,35 @@ func (suite *DeviceRepositoryTestSuite) TestUpdate() {
 	dbDevice, err := repo.GetDeviceById(suite.ctx, d.ID)
 	suite.NoError(err)
 	suite.Equal(dbDevice.Name, d.Name)
+	suite.Equal(int64(2), dbDevice.Version)
+	suite.Equal(dbDevice.Version, d.Version)
 
 }
 
+func (suite *DeviceRepositoryTestSuite) TestUpdateAndDelete_WhenVersionIsStale() {
+
+	repo, d, err := createDevice(suite.ctx, suite.DB)
+	suite.NoError(err)
+
+	stale := *d
+
+	d.Name = "First Writer"
+	suite.NoError(repo.UpdateDevice(suite.ctx, d))
+
+	// the second writer still holds version 1 and must not overwrite the first one
+	stale.Name = "Second Writer"
+	err = repo.UpdateDevice(suite.ctx, &stale)
+	suite.ErrorIs(err, domain.ErrVersionMismatch)
+	suite.Equal(int64(1), stale.Version)
+
+	err = repo.DeleteDevice(suite.ctx, d.ID, stale.Version)
+	suite.ErrorIs(err, domain.ErrVersionMismatch)
+
+	dbDevice, err := repo.GetDeviceById(suite.ctx, d.ID)
+	suite.NoError(err)
+	suite.Equal("First Writer", dbDevice.Name)
+}
+
 func (suite *DeviceRepositoryTestSuite) TestGetByID() {
 
 	repo, d, err := createDevice(suite.ctx, suite.DB)
@@ -73,9 +102,12 @@ func (suite *DeviceRepositoryTestSuite) TestDelete() {
 	suite.NoError(err)
 
 	suite.NoError(err)
-	err = repo.DeleteDevice(suite.ctx, d.ID)
+	err = repo.DeleteDevice(suite.ctx, d.ID, d.Version)
 	suite.NoError(err)
 
+	_, err = repo.GetDeviceById(suite.ctx, d.ID)
+	suite.ErrorIs(err, sql.ErrNoRows)
+
 }
 
 func (suite *DeviceRepositoryTestSuite) TestGetAll() {
@@ -83,7 +115,10 @@ func (suite *DeviceRepositoryTestSuite) TestGetAll() {
 	repo, _, err := createDevice(suite.ctx, suite.DB)
 	suite.NoError(err)
 
-	deviceList, err := repo.Queries.GetAllDevices(suite.ctx)
+	filter := domain.DeviceFilter{}
+	suite.NoError(filter.Normalize())
+
+	deviceList, err := repo.GetDevices(suite.ctx, filter)
 	suite.NoError(err)
 	suite.NotEmpty(deviceList)
 	suite.Equal(len(deviceList), 1)
@@ -94,7 +129,7 @@ func (suite *DeviceRepositoryTestSuite) TestGetAllByBrand() {
 	repo, _, err := createDevice(suite.ctx, suite.DB)
 	suite.NoError(err)
 
-	deviceList, err := repo.Queries.GetAllDevicesByBrand(suite.ctx, "Brand")
+	deviceList, err := repo.GetDevices(suite.ctx, newFilter(domain.DeviceFilter{Brand: "Brand"}))
 	suite.NoError(err)
 	suite.NotEmpty(deviceList)
 	suite.Equal(len(deviceList), 1)
@@ -105,7 +140,7 @@ func (suite *DeviceRepositoryTestSuite) TestGetAllByBrand_WhenBrandNotFound() {
 	repo, _, err := createDevice(suite.ctx, suite.DB)
 	suite.NoError(err)
 
-	deviceList, err := repo.Queries.GetAllDevicesByBrand(suite.ctx, "not_found")
+	deviceList, err := repo.GetDevices(suite.ctx, newFilter(domain.DeviceFilter{Brand: "not_found"}))
 	suite.NoError(err)
 	suite.Empty(deviceList)
 	suite.Equal(len(deviceList), 0)
@@ -116,7 +151,7 @@ func (suite *DeviceRepositoryTestSuite) TestGetAllByState() {
 	repo, _, err := createDevice(suite.ctx, suite.DB)
 	suite.NoError(err)
 
-	deviceList, err := repo.Queries.GetAllDevicesByState(suite.ctx, string(domain.DeviceAvailable))
+	deviceList, err := repo.GetDevices(suite.ctx, newFilter(domain.DeviceFilter{State: domain.DeviceAvailable}))
 	suite.NoError(err)
 	suite.NotEmpty(deviceList)
 	suite.Equal(len(deviceList), 1)
@@ -127,12 +162,157 @@ func (suite *DeviceRepositoryTestSuite) TestGetAllByState_WhenStateNotFound() {
 	repo, _, err := createDevice(suite.ctx, suite.DB)
 	suite.NoError(err)
 
-	deviceList, err := repo.Queries.GetAllDevicesByState(suite.ctx, "not_found")
+	deviceList, err := repo.GetDevices(suite.ctx, newFilter(domain.DeviceFilter{State: domain.DeviceInUse}))
 	suite.NoError(err)
 	suite.Empty(deviceList)
 	suite.Equal(len(deviceList), 0)
 }
 
+func (suite *DeviceRepositoryTestSuite) TestGetAll_CombinedFilterSortAndPage() {
+
+	repo := NewDeviceRepository(suite.DB)
+	base := time.Now().Add(-time.Hour)
+
+	devices := []struct {
+		name  string
+		brand string
+		state domain.DeviceState
+	}{
+		{"Galaxy S21", "Samsung", domain.DeviceAvailable},
+		{"Galaxy S22", "Samsung", domain.DeviceAvailable},
+		{"Galaxy Tab", "Samsung", domain.DeviceInUse},
+		{"iPhone 13", "Apple", domain.DeviceAvailable},
+		{"Galaxy S23", "Samsung", domain.DeviceAvailable},
+	}
+	for i, d := range devices {
+		device, err := domain.NewDevice(uuid.New().String(), d.name, d.brand, d.state, base.Add(time.Duration(i)*time.Minute))
+		suite.NoError(err)
+		_, err = repo.CreateDevice(suite.ctx, device)
+		suite.NoError(err)
+	}
+
+	filter := newFilter(domain.DeviceFilter{
+		Brand:  "Samsung",
+		State:  domain.DeviceAvailable,
+		Name:   "galaxy s",
+		SortBy: "name",
+		Order:  domain.SortDesc,
+		Limit:  2,
+		Offset: 1,
+	})
+
+	deviceList, err := repo.GetDevices(suite.ctx, filter)
+	suite.NoError(err)
+	suite.Len(deviceList, 2)
+	suite.Equal("Galaxy S22", deviceList[0].Name)
+	suite.Equal("Galaxy S21", deviceList[1].Name)
+
+	total, err := repo.CountDevices(suite.ctx, filter)
+	suite.NoError(err)
+	suite.Equal(int64(3), total)
+
+	// created_at range only keeps the devices created in the middle of the window
+	rangeFilter := newFilter(domain.DeviceFilter{
+		CreatedFrom: base.Add(time.Minute),
+		CreatedTo:   base.Add(3 * time.Minute),
+	})
+	deviceList, err = repo.GetDevices(suite.ctx, rangeFilter)
+	suite.NoError(err)
+	suite.Len(deviceList, 3)
+	suite.Equal("Galaxy S22", deviceList[0].Name)
+	suite.Equal("iPhone 13", deviceList[2].Name)
+}
+
+func (suite *DeviceRepositoryTestSuite) TestGetAll_NameWithEscapedWildcards() {
+
+	repo := NewDeviceRepository(suite.DB)
+
+	for _, name := range []string{"50% off", "500 off", "usb_c", "usbxc", `back\slash`} {
+		device, err := domain.NewDevice(uuid.New().String(), name, "Brand", domain.DeviceAvailable, time.Now())
+		suite.NoError(err)
+		_, err = repo.CreateDevice(suite.ctx, device)
+		suite.NoError(err)
+	}
+
+	// the service escapes the wildcards, they match themselves
+	for pattern, name := range map[string]string{`50\%`: "50% off", `usb\_`: "usb_c", `k\\s`: `back\slash`} {
+		filter := newFilter(domain.DeviceFilter{Name: pattern})
+
+		deviceList, err := repo.GetDevices(suite.ctx, filter)
+		suite.NoError(err)
+		suite.Len(deviceList, 1, pattern)
+		suite.Equal(name, deviceList[0].Name)
+
+		total, err := repo.CountDevices(suite.ctx, filter)
+		suite.NoError(err)
+		suite.Equal(int64(1), total)
+
+		var streamed []string
+		suite.NoError(repo.StreamDevices(suite.ctx, filter, func(d domain.Device) error {
+			streamed = append(streamed, d.Name)
+			return nil
+		}))
+		suite.Equal([]string{name}, streamed)
+	}
+}
+
+func (suite *DeviceRepositoryTestSuite) TestGetAfterCursor() {
+
+	repo := NewDeviceRepository(suite.DB)
+	base := time.Now().UTC().Add(-time.Hour)
+
+	ids := make([]string, 5)
+	for i := range ids {
+		device, err := domain.NewDevice(uuid.New().String(), "Device", "Brand", domain.DeviceAvailable, base.Add(time.Duration(i)*time.Minute))
+		suite.NoError(err)
+		ids[i], err = repo.CreateDevice(suite.ctx, device)
+		suite.NoError(err)
+	}
+
+	first, err := repo.GetDeviceById(suite.ctx, ids[1])
+	suite.NoError(err)
+
+	// ascending walks forward from the cursor
+	deviceList, err := repo.GetDevicesAfterCursor(suite.ctx, newFilter(domain.DeviceFilter{Limit: 2}), domain.CursorAfter(*first))
+	suite.NoError(err)
+	suite.Len(deviceList, 2)
+	suite.Equal(ids[2], deviceList[0].ID)
+	suite.Equal(ids[3], deviceList[1].ID)
+
+	// descending walks backwards from the cursor
+	deviceList, err = repo.GetDevicesAfterCursor(suite.ctx, newFilter(domain.DeviceFilter{Order: domain.SortDesc}), domain.CursorAfter(*first))
+	suite.NoError(err)
+	suite.Len(deviceList, 1)
+	suite.Equal(ids[0], deviceList[0].ID)
+}
+
+func (suite *DeviceRepositoryTestSuite) TestCountDevicesByStateAndBrand() {
+
+	repo, d, err := createDevice(suite.ctx, suite.DB)
+	suite.NoError(err)
+	_, _, err = createDevice(domain.WithTenant(suite.ctx, "acme"), suite.DB)
+	suite.NoError(err)
+	_, deleted, err := createDevice(suite.ctx, suite.DB)
+	suite.NoError(err)
+	suite.NoError(repo.DeleteDevice(suite.ctx, deleted.ID, deleted.Version))
+
+	// every tenant is counted, the deleted devices are not
+	byState, err := repo.CountDevicesByState(suite.ctx)
+	suite.NoError(err)
+	suite.Equal(map[domain.DeviceState]int64{d.State: 2}, byState)
+
+	byBrand, err := repo.CountDevicesByBrand(suite.ctx)
+	suite.NoError(err)
+	suite.Equal(map[string]int64{d.Brand: 2}, byBrand)
+}
+
+func newFilter(filter domain.DeviceFilter) domain.DeviceFilter {
+	if err := filter.Normalize(); err != nil {
+		panic(err)
+	}
+	return filter
+}
+
 func createDevice(ctx context.Context, db *sql.DB) (*DeviceRepository, *domain.Device, error) {
 	device, err := domain.NewDevice(uuid.New().String(), "Device", "Brand", domain.DeviceAvailable, time.Now())
 	if err != nil {
@@ -150,18 +330,263 @@ func createDevice(ctx context.Context, db *sql.DB) (*DeviceRepository, *domain.D
 
 func migrateDB() (*sql.DB, error) {
 
-	db, err := sql.Open("sqlite3", ":memory:")
+	db, err := sql.Open(sqliteDriverName, ":memory:")
 	if err != nil {
 		return nil, err
 	}
+	// every connection to :memory: is a brand new database
+	db.SetMaxOpenConns(1)
 
 	_, err = db.Exec(`CREATE TABLE devices (
     id         TEXT PRIMARY KEY,
     name       TEXT NOT NULL,
     brand      TEXT NOT NULL,
     state      TEXT NOT NULL,
-    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    version    INTEGER NOT NULL DEFAULT 1,
+    deleted_at DATETIME,
+    tenant_id  TEXT NOT NULL DEFAULT 'default'
+);
+
+CREATE TABLE device_assignments (
+    id                 TEXT PRIMARY KEY,
+    device_id          TEXT NOT NULL REFERENCES devices (id) ON DELETE CASCADE,
+    assignee           TEXT NOT NULL,
+    checked_out_at     DATETIME NOT NULL,
+    expected_return_at DATETIME,
+    returned_at        DATETIME,
+    notes              TEXT NOT NULL DEFAULT ''
+);
+CREATE UNIQUE INDEX ux_device_assignments_open_device ON device_assignments (device_id) WHERE returned_at IS NULL;
+
+CREATE TABLE device_events (
+    id             INTEGER PRIMARY KEY AUTOINCREMENT,
+    device_id      TEXT NOT NULL,
+    event_type     TEXT NOT NULL,
+    device_version INTEGER NOT NULL,
+    changes        TEXT NOT NULL,
+    request_id     TEXT NOT NULL DEFAULT '',
+    actor          TEXT NOT NULL DEFAULT '',
+    occurred_at    DATETIME NOT NULL,
+    tenant_id      TEXT NOT NULL DEFAULT 'default',
+    on_behalf_of   TEXT NOT NULL DEFAULT ''
+);
+
+CREATE TABLE webhooks (
+    id         TEXT PRIMARY KEY,
+    url        TEXT NOT NULL,
+    secret     TEXT NOT NULL,
+    events     TEXT NOT NULL DEFAULT '',
+    created_at DATETIME NOT NULL,
+    tenant_id  TEXT NOT NULL DEFAULT 'default'
+);
+
+CREATE TABLE webhook_deliveries (
+    id               TEXT PRIMARY KEY,
+    webhook_id       TEXT NOT NULL REFERENCES webhooks (id) ON DELETE CASCADE,
+    event_id         TEXT NOT NULL,
+    event_type       TEXT NOT NULL,
+    payload          TEXT NOT NULL,
+    status           TEXT NOT NULL,
+    attempts         INTEGER NOT NULL DEFAULT 0,
+    last_status_code INTEGER NOT NULL DEFAULT 0,
+    last_error       TEXT NOT NULL DEFAULT '',
+    created_at       DATETIME NOT NULL,
+    updated_at       DATETIME NOT NULL,
+    next_attempt_at  DATETIME NOT NULL,
+    UNIQUE (webhook_id, event_id)
+);
+
+CREATE TABLE outbox (
+    id           INTEGER PRIMARY KEY AUTOINCREMENT,
+    event_id     TEXT NOT NULL,
+    event_type   TEXT NOT NULL,
+    payload      TEXT NOT NULL,
+    attempts     INTEGER NOT NULL DEFAULT 0,
+    last_error   TEXT NOT NULL DEFAULT '',
+    created_at   DATETIME NOT NULL,
+    published_at DATETIME
+);
+
+CREATE TABLE outbox_lease (
+    id           INTEGER PRIMARY KEY CHECK (id = 1),
+    holder       TEXT NOT NULL,
+    locked_until DATETIME NOT NULL
+);
+
+CREATE TABLE api_keys (
+    id         TEXT PRIMARY KEY,
+    name       TEXT NOT NULL,
+    prefix     TEXT NOT NULL,
+    key_hash   TEXT NOT NULL UNIQUE,
+    scopes     TEXT NOT NULL,
+    expires_at DATETIME,
+    created_at DATETIME NOT NULL,
+    revoked_at DATETIME,
+    roles      TEXT NOT NULL DEFAULT '',
+    tenant_id  TEXT NOT NULL DEFAULT ''
+);
+
+CREATE TABLE idempotency_keys (
+    tenant_id        TEXT NOT NULL,
+    client           TEXT NOT NULL,
+    idempotency_key  TEXT NOT NULL,
+    fingerprint      TEXT NOT NULL,
+    status_code      INTEGER NOT NULL DEFAULT 0,
+    response_headers TEXT NOT NULL DEFAULT '{}',
+    response_body    TEXT NOT NULL DEFAULT '',
+    created_at       DATETIME NOT NULL,
+    expires_at       DATETIME NOT NULL,
+    PRIMARY KEY (tenant_id, client, idempotency_key)
 );`)
 
 	return db, err
 }
+
+// sqliteDriverName is a sqlite3 driver that understands the postgres "$N" placeholders
+// emitted by sqlc. SQLite reads "$N" as a named parameter and numbers it by first appearance,
+// so it is rewritten to "?N", which SQLite binds by position just like postgres does.
+const sqliteDriverName = "sqlite3_pg_placeholders"
+
+var pgPlaceholder = regexp.MustCompile(`\$(\d+)`)
+
+func init() {
+	sql.Register(sqliteDriverName, &pgPlaceholderDriver{})
+}
+
+type pgPlaceholderDriver struct {
+	sqlite3.SQLiteDriver
+}
+
+func (d *pgPlaceholderDriver) Open(name string) (driver.Conn, error) {
+	conn, err := d.SQLiteDriver.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return &pgPlaceholderConn{conn.(*sqlite3.SQLiteConn)}, nil
+}
+
+type pgPlaceholderConn struct {
+	*sqlite3.SQLiteConn
+}
+
+func (c *pgPlaceholderConn) Prepare(query string) (driver.Stmt, error) {
+	return c.SQLiteConn.Prepare(rewritePlaceholders(query))
+}
+
+func (c *pgPlaceholderConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	return c.SQLiteConn.PrepareContext(ctx, rewritePlaceholders(query))
+}
+
+func (c *pgPlaceholderConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	return c.SQLiteConn.QueryContext(ctx, rewritePlaceholders(query), args)
+}
+
+func (c *pgPlaceholderConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	return c.SQLiteConn.ExecContext(ctx, rewritePlaceholders(query), args)
+}
+
+func rewritePlaceholders(query string) string {
+	return pgPlaceholder.ReplaceAllString(query, "?$1")
+}
+
+func (suite *DeviceRepositoryTestSuite) TestSoftDeleteAndRestore() {
+
+	repo, d, err := createDevice(suite.ctx, suite.DB)
+	suite.NoError(err)
+
+	suite.NoError(repo.DeleteDevice(suite.ctx, d.ID, d.Version))
+
+	// hidden from reads and lists by default
+	_, err = repo.GetDeviceById(suite.ctx, d.ID)
+	suite.ErrorIs(err, sql.ErrNoRows)
+
+	list, err := repo.GetDevices(suite.ctx, newFilter(domain.DeviceFilter{}))
+	suite.NoError(err)
+	suite.Empty(list)
+
+	count, err := repo.CountDevices(suite.ctx, newFilter(domain.DeviceFilter{}))
+	suite.NoError(err)
+	suite.Equal(int64(0), count)
+
+	list, err = repo.GetDevices(suite.ctx, newFilter(domain.DeviceFilter{IncludeDeleted: true}))
+	suite.NoError(err)
+	suite.Len(list, 1)
+	suite.True(list[0].IsDeleted())
+
+	// a deleted device cannot be updated
+	d.Name = "Renamed"
+	suite.ErrorIs(repo.UpdateDevice(suite.ctx, d), domain.ErrVersionMismatch)
+
+	deleted, err := repo.GetDeviceByIdIncludingDeleted(suite.ctx, d.ID)
+	suite.NoError(err)
+	suite.Equal(int64(2), deleted.Version)
+	suite.True(deleted.IsDeleted())
+
+	suite.NoError(repo.RestoreDevice(suite.ctx, deleted))
+	suite.Equal(int64(3), deleted.Version)
+	suite.False(deleted.IsDeleted())
+
+	restored, err := repo.GetDeviceById(suite.ctx, d.ID)
+	suite.NoError(err)
+	suite.Equal("Device", restored.Name)
+	suite.Equal(d.CreatedAt.Unix(), restored.CreatedAt.Unix())
+
+	// restoring again fails, the device is not deleted anymore
+	suite.ErrorIs(repo.RestoreDevice(suite.ctx, restored), domain.ErrVersionMismatch)
+	suite.Equal(int64(3), restored.Version)
+}
+
+func (suite *DeviceRepositoryTestSuite) TestPurgeDeletedDevices() {
+
+	repo, old, err := createDevice(suite.ctx, suite.DB)
+	suite.NoError(err)
+	_, recent, err := createDevice(suite.ctx, suite.DB)
+	suite.NoError(err)
+	_, live, err := createDevice(suite.ctx, suite.DB)
+	suite.NoError(err)
+
+	suite.NoError(repo.DeleteDevice(suite.ctx, old.ID, old.Version))
+	suite.NoError(repo.DeleteDevice(suite.ctx, recent.ID, recent.Version))
+
+	// the first one was deleted two days ago
+	_, err = suite.DB.Exec("UPDATE devices SET deleted_at = $1 WHERE id = $2", time.Now().Add(-48*time.Hour), old.ID)
+	suite.NoError(err)
+
+	purged, err := repo.PurgeDeletedDevices(suite.ctx, time.Now().Add(-24*time.Hour))
+	suite.NoError(err)
+	suite.Equal(int64(1), purged)
+
+	_, err = repo.GetDeviceByIdIncludingDeleted(suite.ctx, old.ID)
+	suite.ErrorIs(err, sql.ErrNoRows)
+
+	_, err = repo.GetDeviceByIdIncludingDeleted(suite.ctx, recent.ID)
+	suite.NoError(err)
+
+	_, err = repo.GetDeviceById(suite.ctx, live.ID)
+	suite.NoError(err)
+}
+
+func (suite *DeviceRepositoryTestSuite) TestCreateDevices_IsAtomic() {
+
+	repo := NewDeviceRepository(suite.DB)
+
+	first, err := domain.NewDevice(uuid.New().String(), "Device 1", "Brand", domain.DeviceAvailable, time.Now())
+	suite.NoError(err)
+	second, err := domain.NewDevice(uuid.New().String(), "Device 2", "Brand", domain.DeviceAvailable, time.Now())
+	suite.NoError(err)
+
+	// the duplicated ID makes the last insert fail, so nothing is stored
+	err = repo.CreateDevices(suite.ctx, []*domain.Device{first, second, first})
+	suite.Error(err)
+
+	count, err := repo.CountDevices(suite.ctx, newFilter(domain.DeviceFilter{}))
+	suite.NoError(err)
+	suite.Equal(int64(0), count)
+
+	suite.NoError(repo.CreateDevices(suite.ctx, []*domain.Device{first, second}))
+
+	count, err = repo.CountDevices(suite.ctx, newFilter(domain.DeviceFilter{}))
+	suite.NoError(err)
+	suite.Equal(int64(2), count)
+}
@@ -0,0 +1,70 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/raulsilva-tech/devices-api/internal/domain"
+)
+
+// streamDevicesSQL mirrors the ListDevices query in db/queries/queries.sql without the pagination.
+// It is kept here because sqlc always loads :many results into a slice.
+const streamDevicesSQL = `-- name: StreamDevices :many
+SELECT id, name, brand, state, created_at, version, deleted_at, tenant_id FROM devices
+WHERE tenant_id = $9
+  AND (deleted_at IS NULL OR CAST($1 AS BOOLEAN))
+  AND (brand = $2 OR $2 = '')
+  AND (state = $3 OR $3 = '')
+  AND (LOWER(name) LIKE '%' || LOWER(CAST($4 AS TEXT)) || '%' ESCAPE '\')
+  AND (created_at >= $5 OR $5 IS NULL)
+  AND (created_at <= $6 OR $6 IS NULL)
+ORDER BY
+  CASE WHEN CAST($7 AS TEXT) = 'name' AND CAST($8 AS TEXT) = 'asc' THEN name END ASC,
+  CASE WHEN $7 = 'name' AND $8 = 'desc' THEN name END DESC,
+  CASE WHEN $7 = 'brand' AND $8 = 'asc' THEN brand END ASC,
+  CASE WHEN $7 = 'brand' AND $8 = 'desc' THEN brand END DESC,
+  CASE WHEN $7 = 'state' AND $8 = 'asc' THEN state END ASC,
+  CASE WHEN $7 = 'state' AND $8 = 'desc' THEN state END DESC,
+  CASE WHEN $7 = 'created_at' AND $8 = 'asc' THEN created_at END ASC,
+  CASE WHEN $7 = 'created_at' AND $8 = 'desc' THEN created_at END DESC,
+  CASE WHEN $8 = 'desc' THEN id END DESC,
+  id ASC`
+
+// StreamDevices calls fn for every device matching the filter, reading them one at a time from
+// the database instead of loading the whole result. Only the devices of the tenant of the context
+// are read. Limit and offset are ignored.
+// Iteration stops at the first error returned by fn.
+func (repo *DeviceRepository) StreamDevices(ctx context.Context, filter domain.DeviceFilter, fn func(domain.Device) error) error {
+
+	rows, err := dbFor(ctx, repo.db).QueryContext(ctx, streamDevicesSQL,
+		filter.IncludeDeleted,
+		filter.Brand,
+		string(filter.State),
+		filter.Name,
+		toNullTime(filter.CreatedFrom),
+		toNullTime(filter.CreatedTo),
+		filter.SortBy,
+		string(filter.Order),
+		domain.TenantFromContext(ctx),
+	)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var d domain.Device
+		var deletedAt sql.NullTime
+
+		if err := rows.Scan(&d.ID, &d.Name, &d.Brand, &d.State, &d.CreatedAt, &d.Version, &deletedAt, &d.TenantID); err != nil {
+			return err
+		}
+		d.DeletedAt = deletedAt.Time
+
+		if err := fn(d); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
@@ -0,0 +1,47 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/raulsilva-tech/devices-api/internal/domain"
+)
+
+func (suite *DeviceRepositoryTestSuite) TestStreamDevices() {
+
+	repo := NewDeviceRepository(suite.DB)
+	base := time.Now().Add(-time.Hour)
+
+	for i, name := range []string{"Galaxy S21", "iPhone 13", "Galaxy S22", "Galaxy S23"} {
+		brand := "Samsung"
+		if name == "iPhone 13" {
+			brand = "Apple"
+		}
+		device, err := domain.NewDevice(uuid.New().String(), name, brand, domain.DeviceAvailable, base.Add(time.Duration(i)*time.Minute))
+		suite.NoError(err)
+		_, err = repo.CreateDevice(suite.ctx, device)
+		suite.NoError(err)
+	}
+
+	// pagination is ignored, every match is streamed
+	filter := newFilter(domain.DeviceFilter{Brand: "Samsung", SortBy: "name", Order: domain.SortDesc, Limit: 1})
+
+	var names []string
+	err := repo.StreamDevices(suite.ctx, filter, func(d domain.Device) error {
+		names = append(names, d.Name)
+		return nil
+	})
+	suite.NoError(err)
+	suite.Equal([]string{"Galaxy S23", "Galaxy S22", "Galaxy S21"}, names)
+
+	// an error from the callback stops the iteration
+	stop := errors.New("stop")
+	calls := 0
+	err = repo.StreamDevices(suite.ctx, newFilter(domain.DeviceFilter{}), func(d domain.Device) error {
+		calls++
+		return stop
+	})
+	suite.ErrorIs(err, stop)
+	suite.Equal(1, calls)
+}
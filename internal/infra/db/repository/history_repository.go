@@ -0,0 +1,94 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/raulsilva-tech/devices-api/internal/domain"
+	"github.com/raulsilva-tech/devices-api/internal/infra/db/sqlc"
+)
+
+// recordDeviceChange appends an entry to the device history and queues the events announcing
+// the change in the outbox with the given queries, so both are written in the same transaction
+// as the change itself. The request ID, the actor and whom it acted for are taken from the context. Nothing is recorded
+// when no field changed.
+func recordDeviceChange(ctx context.Context, q *sqlc.Queries, changeType domain.ChangeType, before, after *domain.Device) error {
+
+	device := after
+	if device == nil {
+		device = before
+	}
+
+	fields := domain.DiffDevices(before, after)
+	if len(fields) == 0 {
+		return nil
+	}
+
+	changes, err := json.Marshal(fields)
+	if err != nil {
+		return err
+	}
+
+	err = q.CreateDeviceEvent(ctx, sqlc.CreateDeviceEventParams{
+		DeviceID:      device.ID,
+		EventType:     string(changeType),
+		DeviceVersion: device.Version,
+		Changes:       string(changes),
+		RequestID:     domain.RequestIDFromContext(ctx),
+		Actor:         domain.ActorFromContext(ctx),
+		OccurredAt:    time.Now().UTC(),
+		TenantID:      device.TenantID,
+		OnBehalfOf:    domain.OnBehalfOfFromContext(ctx),
+	})
+	if err != nil {
+		return err
+	}
+
+	return recordOutboxEvents(ctx, q, domain.EventsForChange(changeType, before, after))
+}
+
+// GetDeviceHistory lists the changes of a device of the tenant of the context, most recent first.
+// The history outlives the device.
+func (repo *DeviceRepository) GetDeviceHistory(ctx context.Context, deviceID string, limit, offset int) ([]domain.DeviceChange, error) {
+
+	eventDBList, err := queriesFor(ctx, repo.Queries).ListDeviceEvents(ctx, sqlc.ListDeviceEventsParams{
+		DeviceID: deviceID,
+		Limit:    int32(limit),
+		Offset:   int32(offset),
+		TenantID: domain.TenantFromContext(ctx),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	resultList := make([]domain.DeviceChange, len(eventDBList))
+
+	for i, eventDB := range eventDBList {
+		if resultList[i], err = mapDBToDomainDeviceChange(eventDB); err != nil {
+			return nil, err
+		}
+	}
+
+	return resultList, nil
+}
+
+func mapDBToDomainDeviceChange(e sqlc.DeviceEvent) (domain.DeviceChange, error) {
+
+	var fields map[string]domain.FieldChange
+	if err := json.Unmarshal([]byte(e.Changes), &fields); err != nil {
+		return domain.DeviceChange{}, err
+	}
+
+	return domain.DeviceChange{
+		ID:         e.ID,
+		DeviceID:   e.DeviceID,
+		Type:       domain.ChangeType(e.EventType),
+		Version:    e.DeviceVersion,
+		Fields:     fields,
+		RequestID:  e.RequestID,
+		Actor:      e.Actor,
+		OnBehalfOf: e.OnBehalfOf,
+		OccurredAt: e.OccurredAt,
+	}, nil
+}
@@ -0,0 +1,108 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/raulsilva-tech/devices-api/internal/domain"
+)
+
+func (suite *DeviceRepositoryTestSuite) TestHistory_RecordsEveryChange() {
+
+	ctx := domain.WithRequestID(suite.ctx, "req-1")
+	ctx = domain.WithPrincipal(ctx, &domain.Principal{Subject: "apikey:1"})
+	ctx = domain.WithOnBehalfOf(ctx, "jane")
+
+	repo, d, err := createDevice(ctx, suite.DB)
+	suite.NoError(err)
+
+	d.Name = "Renamed"
+	d.State = domain.DeviceInUse
+	suite.NoError(repo.UpdateDevice(ctx, d))
+
+	d.State = domain.DeviceAvailable
+	suite.NoError(repo.UpdateDevice(ctx, d))
+	suite.NoError(repo.DeleteDevice(ctx, d.ID, d.Version))
+
+	history, err := repo.GetDeviceHistory(suite.ctx, d.ID, 10, 0)
+	suite.NoError(err)
+	suite.Len(history, 4)
+
+	// most recent first
+	suite.Equal(domain.ChangeDeleted, history[0].Type)
+	suite.Equal(int64(4), history[0].Version)
+	suite.Len(history[0].Fields, 1)
+	suite.NotEmpty(history[0].Fields["deleted_at"].New)
+
+	suite.Equal(domain.ChangeUpdated, history[1].Type)
+	suite.Equal(map[string]domain.FieldChange{"state": {Old: "in-use", New: "available"}}, history[1].Fields)
+
+	suite.Equal(domain.ChangeUpdated, history[2].Type)
+	suite.Equal(int64(2), history[2].Version)
+	suite.Equal(domain.FieldChange{Old: "Device", New: "Renamed"}, history[2].Fields["name"])
+	suite.Equal(domain.FieldChange{Old: "available", New: "in-use"}, history[2].Fields["state"])
+
+	suite.Equal(domain.ChangeCreated, history[3].Type)
+	suite.Equal(int64(1), history[3].Version)
+
+	for _, change := range history {
+		suite.Equal("req-1", change.RequestID)
+		suite.Equal("apikey:1", change.Actor)
+		suite.Equal("jane", change.OnBehalfOf)
+	}
+
+	page, err := repo.GetDeviceHistory(suite.ctx, d.ID, 2, 2)
+	suite.NoError(err)
+	suite.Len(page, 2)
+	suite.Equal(history[2].ID, page[0].ID)
+}
+
+func (suite *DeviceRepositoryTestSuite) TestHistory_NotRecordedWhenVersionIsStale() {
+
+	repo, d, err := createDevice(suite.ctx, suite.DB)
+	suite.NoError(err)
+
+	stale := *d
+	stale.Version = 7
+	stale.Name = "Stale"
+	suite.ErrorIs(repo.UpdateDevice(suite.ctx, &stale), domain.ErrVersionMismatch)
+	suite.Equal(int64(7), stale.Version)
+	suite.ErrorIs(repo.DeleteDevice(suite.ctx, d.ID, 7), domain.ErrVersionMismatch)
+
+	history, err := repo.GetDeviceHistory(suite.ctx, d.ID, 10, 0)
+	suite.NoError(err)
+	suite.Len(history, 1)
+	suite.Equal(domain.ChangeCreated, history[0].Type)
+}
+
+func (suite *DeviceRepositoryTestSuite) TestHistory_NotRecordedWhenNothingChanged() {
+
+	repo, d, err := createDevice(suite.ctx, suite.DB)
+	suite.NoError(err)
+	suite.NoError(repo.UpdateDevice(suite.ctx, d))
+
+	history, err := repo.GetDeviceHistory(suite.ctx, d.ID, 10, 0)
+	suite.NoError(err)
+	suite.Len(history, 1)
+	suite.Equal(domain.ChangeCreated, history[0].Type)
+
+	messages, err := NewOutboxRepository(suite.DB).GetPendingMessages(suite.ctx, 10)
+	suite.NoError(err)
+	suite.Len(messages, 1)
+}
+
+func (suite *DeviceRepositoryTestSuite) TestHistory_KeptAfterPurge() {
+
+	repo, d, err := createDevice(suite.ctx, suite.DB)
+	suite.NoError(err)
+	suite.NoError(repo.DeleteDevice(suite.ctx, d.ID, d.Version))
+
+	purged, err := repo.PurgeDeletedDevices(suite.ctx, time.Now().Add(time.Minute))
+	suite.NoError(err)
+	suite.Equal(int64(1), purged)
+
+	history, err := repo.GetDeviceHistory(suite.ctx, d.ID, 10, 0)
+	suite.NoError(err)
+	suite.Len(history, 3)
+	suite.Equal(domain.ChangePurged, history[0].Type)
+	suite.Equal(domain.FieldChange{Old: "Device"}, history[0].Fields["name"])
+}
@@ -0,0 +1,122 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/raulsilva-tech/devices-api/internal/domain"
+	"github.com/raulsilva-tech/devices-api/internal/infra/db/sqlc"
+)
+
+// IdempotencyRepository keeps the records of the tenant of the context, except PurgeExpiredIdempotencyRecords
+type IdempotencyRepository struct {
+	db      *sql.DB
+	Queries *sqlc.Queries
+}
+
+func NewIdempotencyRepository(dbConn *sql.DB) *IdempotencyRepository {
+	return &IdempotencyRepository{
+		db:      dbConn,
+		Queries: sqlc.New(traced(dbConn)),
+	}
+}
+
+func (repo *IdempotencyRepository) CreateIdempotencyRecord(ctx context.Context, record *domain.IdempotencyRecord) (bool, error) {
+
+	record.TenantID = domain.TenantFromContext(ctx)
+
+	rows, err := queriesFor(ctx, repo.Queries).CreateIdempotencyKey(ctx, sqlc.CreateIdempotencyKeyParams{
+		TenantID:       record.TenantID,
+		Client:         record.Client,
+		IdempotencyKey: record.Key,
+		Fingerprint:    record.Fingerprint,
+		CreatedAt:      record.CreatedAt,
+		ExpiresAt:      record.ExpiresAt,
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return rows > 0, nil
+}
+
+func (repo *IdempotencyRepository) GetIdempotencyRecord(ctx context.Context, client, key string) (*domain.IdempotencyRecord, error) {
+
+	recordDB, err := queriesFor(ctx, repo.Queries).GetIdempotencyKey(ctx, sqlc.GetIdempotencyKeyParams{
+		TenantID:       domain.TenantFromContext(ctx),
+		Client:         client,
+		IdempotencyKey: key,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return mapDBToDomainIdempotencyRecord(recordDB)
+}
+
+func (repo *IdempotencyRepository) CompleteIdempotencyRecord(ctx context.Context, record *domain.IdempotencyRecord) error {
+
+	headers, err := json.Marshal(record.Response.Headers)
+	if err != nil {
+		return err
+	}
+
+	return queriesFor(ctx, repo.Queries).CompleteIdempotencyKey(ctx, sqlc.CompleteIdempotencyKeyParams{
+		TenantID:        domain.TenantFromContext(ctx),
+		Client:          record.Client,
+		IdempotencyKey:  record.Key,
+		StatusCode:      int32(record.Response.StatusCode),
+		ResponseHeaders: string(headers),
+		ResponseBody:    string(record.Response.Body),
+	})
+}
+
+func (repo *IdempotencyRepository) DeleteIdempotencyRecord(ctx context.Context, client, key string) error {
+
+	return queriesFor(ctx, repo.Queries).DeleteIdempotencyKey(ctx, sqlc.DeleteIdempotencyKeyParams{
+		TenantID:       domain.TenantFromContext(ctx),
+		Client:         client,
+		IdempotencyKey: key,
+	})
+}
+
+func (repo *IdempotencyRepository) DeleteExpiredIdempotencyRecord(ctx context.Context, client, key string, now time.Time) error {
+
+	return queriesFor(ctx, repo.Queries).DeleteExpiredIdempotencyKey(ctx, sqlc.DeleteExpiredIdempotencyKeyParams{
+		TenantID:       domain.TenantFromContext(ctx),
+		Client:         client,
+		IdempotencyKey: key,
+		ExpiresAt:      now,
+	})
+}
+
+func (repo *IdempotencyRepository) PurgeExpiredIdempotencyRecords(ctx context.Context, now time.Time) (int64, error) {
+	return queriesFor(ctx, repo.Queries).PurgeExpiredIdempotencyKeys(ctx, now)
+}
+
+func mapDBToDomainIdempotencyRecord(r sqlc.IdempotencyKey) (*domain.IdempotencyRecord, error) {
+
+	record := &domain.IdempotencyRecord{
+		TenantID:    r.TenantID,
+		Client:      r.Client,
+		Key:         r.IdempotencyKey,
+		Fingerprint: r.Fingerprint,
+		CreatedAt:   r.CreatedAt,
+		ExpiresAt:   r.ExpiresAt,
+	}
+
+	// a zero status code means the request is still in progress
+	if r.StatusCode != 0 {
+		record.Response = &domain.IdempotentResponse{
+			StatusCode: int(r.StatusCode),
+			Body:       []byte(r.ResponseBody),
+		}
+		if err := json.Unmarshal([]byte(r.ResponseHeaders), &record.Response.Headers); err != nil {
+			return nil, err
+		}
+	}
+
+	return record, nil
+}
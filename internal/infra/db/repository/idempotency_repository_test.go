@@ -0,0 +1,87 @@
+package repository
+
+import (
+	"database/sql"
+	"net/http"
+	"time"
+
+	"github.com/raulsilva-tech/devices-api/internal/domain"
+)
+
+func (suite *DeviceRepositoryTestSuite) TestIdempotencyRecords() {
+
+	repo := NewIdempotencyRepository(suite.DB)
+
+	record, err := domain.NewIdempotencyRecord("sub:ci", "key-1", "fingerprint", time.Hour)
+	suite.NoError(err)
+
+	created, err := repo.CreateIdempotencyRecord(suite.ctx, record)
+	suite.NoError(err)
+	suite.True(created)
+	suite.Equal(domain.DefaultTenant, record.TenantID)
+
+	// the key is taken until the record is released
+	created, err = repo.CreateIdempotencyRecord(suite.ctx, record)
+	suite.NoError(err)
+	suite.False(created)
+
+	stored, err := repo.GetIdempotencyRecord(suite.ctx, "sub:ci", "key-1")
+	suite.NoError(err)
+	suite.Equal("fingerprint", stored.Fingerprint)
+	suite.Nil(stored.Response)
+
+	record.Response = &domain.IdempotentResponse{
+		StatusCode: http.StatusCreated,
+		Headers:    map[string]string{"Content-Type": "application/json"},
+		Body:       []byte(`{"id":"1"}`),
+	}
+	suite.NoError(repo.CompleteIdempotencyRecord(suite.ctx, record))
+
+	stored, err = repo.GetIdempotencyRecord(suite.ctx, "sub:ci", "key-1")
+	suite.NoError(err)
+	suite.Equal(record.Response, stored.Response)
+
+	// the other clients and tenants have their own keys
+	_, err = repo.GetIdempotencyRecord(suite.ctx, "sub:other", "key-1")
+	suite.ErrorIs(err, sql.ErrNoRows)
+	_, err = repo.GetIdempotencyRecord(domain.WithTenant(suite.ctx, "acme"), "sub:ci", "key-1")
+	suite.ErrorIs(err, sql.ErrNoRows)
+
+	suite.NoError(repo.DeleteIdempotencyRecord(suite.ctx, "sub:ci", "key-1"))
+	_, err = repo.GetIdempotencyRecord(suite.ctx, "sub:ci", "key-1")
+	suite.ErrorIs(err, sql.ErrNoRows)
+}
+
+func (suite *DeviceRepositoryTestSuite) TestIdempotencyRecords_Expired() {
+
+	repo := NewIdempotencyRepository(suite.DB)
+	acme := domain.WithTenant(suite.ctx, "acme")
+
+	record, err := domain.NewIdempotencyRecord("sub:ci", "key-1", "fingerprint", time.Minute)
+	suite.NoError(err)
+	_, err = repo.CreateIdempotencyRecord(suite.ctx, record)
+	suite.NoError(err)
+
+	other, err := domain.NewIdempotencyRecord("sub:ci", "key-2", "fingerprint", time.Minute)
+	suite.NoError(err)
+	_, err = repo.CreateIdempotencyRecord(acme, other)
+	suite.NoError(err)
+
+	// not expired yet
+	suite.NoError(repo.DeleteExpiredIdempotencyRecord(suite.ctx, "sub:ci", "key-1", record.CreatedAt))
+	_, err = repo.GetIdempotencyRecord(suite.ctx, "sub:ci", "key-1")
+	suite.NoError(err)
+
+	later := record.ExpiresAt.Add(time.Second)
+	suite.NoError(repo.DeleteExpiredIdempotencyRecord(suite.ctx, "sub:ci", "key-1", later))
+	_, err = repo.GetIdempotencyRecord(suite.ctx, "sub:ci", "key-1")
+	suite.ErrorIs(err, sql.ErrNoRows)
+
+	// the purge spans every tenant
+	purged, err := repo.PurgeExpiredIdempotencyRecords(suite.ctx, record.CreatedAt)
+	suite.NoError(err)
+	suite.Zero(purged)
+	purged, err = repo.PurgeExpiredIdempotencyRecords(suite.ctx, later)
+	suite.NoError(err)
+	suite.EqualValues(1, purged)
+}
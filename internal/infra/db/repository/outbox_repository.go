@@ -0,0 +1,226 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/raulsilva-tech/devices-api/internal/domain"
+	"github.com/raulsilva-tech/devices-api/internal/infra/db/sqlc"
+)
+
+type OutboxRepository struct {
+	db      *sql.DB
+	Queries *sqlc.Queries
+}
+
+func NewOutboxRepository(dbConn *sql.DB) *OutboxRepository {
+	return &OutboxRepository{
+		db:      dbConn,
+		Queries: sqlc.New(traced(dbConn)),
+	}
+}
+
+// outboxEvent is the stored form of a domain.Event
+type outboxEvent struct {
+	ID            string       `json:"id"`
+	Type          string       `json:"type"`
+	PreviousState string       `json:"previous_state,omitempty"`
+	OccurredAt    time.Time    `json:"occurred_at"`
+	Device        outboxDevice `json:"device"`
+}
+
+type outboxDevice struct {
+	ID        string     `json:"id"`
+	Name      string     `json:"name"`
+	Brand     string     `json:"brand"`
+	State     string     `json:"state"`
+	CreatedAt time.Time  `json:"created_at"`
+	Version   int64      `json:"version"`
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+	TenantID  string     `json:"tenant_id,omitempty"` // missing from the messages stored before the tenants
+}
+
+// recordOutboxEvents stores the events with the given queries, so they are only published
+// if the transaction of the change they announce commits
+func recordOutboxEvents(ctx context.Context, q *sqlc.Queries, events []domain.Event) error {
+
+	for _, event := range events {
+
+		payload, err := json.Marshal(mapDomainToOutboxEvent(event))
+		if err != nil {
+			return err
+		}
+
+		if err := q.CreateOutboxMessage(ctx, sqlc.CreateOutboxMessageParams{
+			EventID:   event.ID,
+			EventType: string(event.Type),
+			Payload:   string(payload),
+			CreatedAt: event.OccurredAt,
+		}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (repo *OutboxRepository) GetPendingMessages(ctx context.Context, limit int) ([]domain.OutboxMessage, error) {
+
+	messageDBList, err := queriesFor(ctx, repo.Queries).ListPendingOutboxMessages(ctx, int32(limit))
+	if err != nil {
+		return nil, err
+	}
+
+	resultList := make([]domain.OutboxMessage, len(messageDBList))
+
+	for i, messageDB := range messageDBList {
+		if resultList[i], err = mapDBToDomainOutboxMessage(messageDB); err != nil {
+			return nil, err
+		}
+	}
+
+	return resultList, nil
+}
+
+func (repo *OutboxRepository) MarkPublished(ctx context.Context, id int64, publishedAt time.Time) error {
+	return queriesFor(ctx, repo.Queries).MarkOutboxMessagePublished(ctx, sqlc.MarkOutboxMessagePublishedParams{
+		ID:          id,
+		PublishedAt: toNullTime(publishedAt),
+	})
+}
+
+func (repo *OutboxRepository) GetPublishedMessages(ctx context.Context, after domain.OutboxMessage, limit int) ([]domain.OutboxMessage, error) {
+
+	messageDBList, err := queriesFor(ctx, repo.Queries).ListPublishedOutboxMessages(ctx, sqlc.ListPublishedOutboxMessagesParams{
+		// a zero time is still compared, every message is published after it
+		PublishedAfter: sql.NullTime{Time: after.PublishedAt, Valid: true},
+		AfterID:        after.ID,
+		MaxMessages:    int32(limit),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	resultList := make([]domain.OutboxMessage, len(messageDBList))
+
+	for i, messageDB := range messageDBList {
+		if resultList[i], err = mapDBToDomainOutboxMessage(messageDB); err != nil {
+			return nil, err
+		}
+	}
+
+	return resultList, nil
+}
+
+func (repo *OutboxRepository) GetLastPublishedMessage(ctx context.Context) (*domain.OutboxMessage, error) {
+
+	messageDB, err := queriesFor(ctx, repo.Queries).GetLastPublishedOutboxMessage(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	message, err := mapDBToDomainOutboxMessage(messageDB)
+	if err != nil {
+		return nil, err
+	}
+
+	return &message, nil
+}
+
+func (repo *OutboxRepository) MarkFailed(ctx context.Context, id int64, reason string) error {
+	return queriesFor(ctx, repo.Queries).MarkOutboxMessageFailed(ctx, sqlc.MarkOutboxMessageFailedParams{
+		ID:        id,
+		LastError: reason,
+	})
+}
+
+// PurgePublishedMessages deletes the messages published before the given time; pending ones are kept
+func (repo *OutboxRepository) PurgePublishedMessages(ctx context.Context, publishedBefore time.Time) (int64, error) {
+	return queriesFor(ctx, repo.Queries).PurgePublishedOutboxMessages(ctx, toNullTime(publishedBefore))
+}
+
+func (repo *OutboxRepository) AcquireLease(ctx context.Context, holder string, now, until time.Time) (bool, error) {
+
+	rows, err := queriesFor(ctx, repo.Queries).AcquireOutboxLease(ctx, sqlc.AcquireOutboxLeaseParams{
+		Holder: holder,
+		Until:  until,
+		Now:    now,
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return rows == 1, nil
+}
+
+func (repo *OutboxRepository) ReleaseLease(ctx context.Context, holder string, now time.Time) error {
+	return queriesFor(ctx, repo.Queries).ReleaseOutboxLease(ctx, sqlc.ReleaseOutboxLeaseParams{
+		Holder: holder,
+		Now:    now,
+	})
+}
+
+func mapDomainToOutboxEvent(e domain.Event) outboxEvent {
+
+	device := outboxDevice{
+		ID:        e.Device.ID,
+		Name:      e.Device.Name,
+		Brand:     e.Device.Brand,
+		State:     string(e.Device.State),
+		CreatedAt: e.Device.CreatedAt,
+		Version:   e.Device.Version,
+		TenantID:  e.Device.TenantID,
+	}
+	if e.Device.IsDeleted() {
+		deletedAt := e.Device.DeletedAt
+		device.DeletedAt = &deletedAt
+	}
+
+	return outboxEvent{
+		ID:            e.ID,
+		Type:          string(e.Type),
+		PreviousState: string(e.PreviousState),
+		OccurredAt:    e.OccurredAt,
+		Device:        device,
+	}
+}
+
+func mapDBToDomainOutboxMessage(m sqlc.Outbox) (domain.OutboxMessage, error) {
+
+	var stored outboxEvent
+	if err := json.Unmarshal([]byte(m.Payload), &stored); err != nil {
+		return domain.OutboxMessage{}, err
+	}
+
+	device := domain.Device{
+		ID:        stored.Device.ID,
+		Name:      stored.Device.Name,
+		Brand:     stored.Device.Brand,
+		State:     domain.DeviceState(stored.Device.State),
+		CreatedAt: stored.Device.CreatedAt,
+		Version:   stored.Device.Version,
+		TenantID:  stored.Device.TenantID,
+	}
+	if stored.Device.DeletedAt != nil {
+		device.DeletedAt = *stored.Device.DeletedAt
+	}
+	if device.TenantID == "" {
+		device.TenantID = domain.DefaultTenant
+	}
+
+	return domain.OutboxMessage{
+		ID: m.ID,
+		Event: domain.Event{
+			ID:            stored.ID,
+			Type:          domain.EventType(stored.Type),
+			Device:        device,
+			PreviousState: domain.DeviceState(stored.PreviousState),
+			OccurredAt:    stored.OccurredAt,
+		},
+		Attempts:    int(m.Attempts),
+		CreatedAt:   m.CreatedAt,
+		PublishedAt: m.PublishedAt.Time,
+	}, nil
+}
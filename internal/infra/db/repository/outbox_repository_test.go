@@ -0,0 +1,196 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/raulsilva-tech/devices-api/internal/domain"
+)
+
+func (suite *DeviceRepositoryTestSuite) TestOutbox_WrittenWithEveryChange() {
+
+	repo, d, err := createDevice(suite.ctx, suite.DB)
+	suite.NoError(err)
+
+	d.State = domain.DeviceInUse
+	suite.NoError(repo.UpdateDevice(suite.ctx, d))
+
+	d.State = domain.DeviceAvailable
+	suite.NoError(repo.UpdateDevice(suite.ctx, d))
+	suite.NoError(repo.DeleteDevice(suite.ctx, d.ID, d.Version))
+
+	outbox := NewOutboxRepository(suite.DB)
+
+	messages, err := outbox.GetPendingMessages(suite.ctx, 10)
+	suite.NoError(err)
+
+	types := make([]domain.EventType, len(messages))
+	for i, m := range messages {
+		types[i] = m.Event.Type
+	}
+	suite.Equal([]domain.EventType{
+		domain.EventDeviceCreated,
+		domain.EventDeviceUpdated,
+		domain.EventDeviceStateChanged,
+		domain.EventDeviceUpdated,
+		domain.EventDeviceStateChanged,
+		domain.EventDeviceDeleted,
+	}, types)
+
+	stateChanged := messages[2].Event
+	suite.Equal(d.ID, stateChanged.Device.ID)
+	suite.Equal(domain.DeviceAvailable, stateChanged.PreviousState)
+	suite.Equal(domain.DeviceInUse, stateChanged.Device.State)
+	suite.Equal(int64(2), stateChanged.Device.Version)
+	suite.Equal(d.CreatedAt.Unix(), stateChanged.Device.CreatedAt.Unix())
+
+	deleted := messages[5].Event
+	suite.True(deleted.Device.IsDeleted())
+	suite.Equal(int64(4), deleted.Device.Version)
+}
+
+func (suite *DeviceRepositoryTestSuite) TestOutbox_NotWrittenWhenTxRollsBack() {
+
+	repo := NewDeviceRepository(suite.DB)
+	device, err := domain.NewDevice(uuid.New().String(), "Device", "Brand", domain.DeviceAvailable, time.Now())
+	suite.NoError(err)
+
+	failure := errors.New("failure")
+	err = repo.WithinTx(suite.ctx, func(ctx context.Context) error {
+		if _, err := repo.CreateDevice(ctx, device); err != nil {
+			return err
+		}
+		return failure
+	})
+	suite.ErrorIs(err, failure)
+
+	messages, err := NewOutboxRepository(suite.DB).GetPendingMessages(suite.ctx, 10)
+	suite.NoError(err)
+	suite.Empty(messages)
+}
+
+func (suite *DeviceRepositoryTestSuite) TestOutbox_PublishAndPurge() {
+
+	_, first, err := createDevice(suite.ctx, suite.DB)
+	suite.NoError(err)
+	_, second, err := createDevice(suite.ctx, suite.DB)
+	suite.NoError(err)
+
+	outbox := NewOutboxRepository(suite.DB)
+
+	messages, err := outbox.GetPendingMessages(suite.ctx, 1)
+	suite.NoError(err)
+	suite.Len(messages, 1)
+	suite.Equal(first.ID, messages[0].Event.Device.ID)
+
+	suite.NoError(outbox.MarkFailed(suite.ctx, messages[0].ID, "queue full"))
+	suite.NoError(outbox.MarkFailed(suite.ctx, messages[0].ID, "queue full"))
+
+	// a failed message is still pending
+	messages, err = outbox.GetPendingMessages(suite.ctx, 10)
+	suite.NoError(err)
+	suite.Len(messages, 2)
+	suite.Equal(2, messages[0].Attempts)
+
+	publishedAt := time.Now().Add(-time.Hour)
+	suite.NoError(outbox.MarkPublished(suite.ctx, messages[0].ID, publishedAt))
+
+	messages, err = outbox.GetPendingMessages(suite.ctx, 10)
+	suite.NoError(err)
+	suite.Len(messages, 1)
+	suite.Equal(second.ID, messages[0].Event.Device.ID)
+
+	purged, err := outbox.PurgePublishedMessages(suite.ctx, publishedAt.Add(-time.Minute))
+	suite.NoError(err)
+	suite.Equal(int64(0), purged)
+
+	purged, err = outbox.PurgePublishedMessages(suite.ctx, time.Now())
+	suite.NoError(err)
+	suite.Equal(int64(1), purged)
+
+	// the pending message is kept
+	messages, err = outbox.GetPendingMessages(suite.ctx, 10)
+	suite.NoError(err)
+	suite.Len(messages, 1)
+}
+
+func (suite *DeviceRepositoryTestSuite) TestOutbox_Lease() {
+
+	outbox := NewOutboxRepository(suite.DB)
+	now := time.Now().UTC()
+
+	acquired, err := outbox.AcquireLease(suite.ctx, "relay-1", now, now.Add(time.Minute))
+	suite.NoError(err)
+	suite.True(acquired)
+
+	// another relay waits while the lease runs, its holder renews it
+	acquired, err = outbox.AcquireLease(suite.ctx, "relay-2", now.Add(time.Second), now.Add(time.Minute))
+	suite.NoError(err)
+	suite.False(acquired)
+
+	acquired, err = outbox.AcquireLease(suite.ctx, "relay-1", now.Add(time.Second), now.Add(2*time.Minute))
+	suite.NoError(err)
+	suite.True(acquired)
+
+	// an expired lease is taken over
+	acquired, err = outbox.AcquireLease(suite.ctx, "relay-2", now.Add(2*time.Minute), now.Add(3*time.Minute))
+	suite.NoError(err)
+	suite.True(acquired)
+
+	// a released lease is free at once, only its holder can release it
+	suite.NoError(outbox.ReleaseLease(suite.ctx, "relay-1", now.Add(2*time.Minute)))
+	acquired, err = outbox.AcquireLease(suite.ctx, "relay-1", now.Add(2*time.Minute), now.Add(3*time.Minute))
+	suite.NoError(err)
+	suite.False(acquired)
+
+	suite.NoError(outbox.ReleaseLease(suite.ctx, "relay-2", now.Add(2*time.Minute)))
+	acquired, err = outbox.AcquireLease(suite.ctx, "relay-1", now.Add(2*time.Minute), now.Add(3*time.Minute))
+	suite.NoError(err)
+	suite.True(acquired)
+}
+
+func (suite *DeviceRepositoryTestSuite) TestOutbox_PublishedMessages() {
+
+	outbox := NewOutboxRepository(suite.DB)
+
+	_, err := outbox.GetLastPublishedMessage(suite.ctx)
+	suite.ErrorIs(err, sql.ErrNoRows)
+
+	for range 3 {
+		_, _, err := createDevice(suite.ctx, suite.DB)
+		suite.NoError(err)
+	}
+	pending, err := outbox.GetPendingMessages(suite.ctx, 10)
+	suite.NoError(err)
+	suite.Len(pending, 3)
+
+	// the messages are followed in the order they were published
+	now := time.Now().UTC()
+	suite.NoError(outbox.MarkPublished(suite.ctx, pending[1].ID, now))
+	suite.NoError(outbox.MarkPublished(suite.ctx, pending[0].ID, now.Add(time.Second)))
+
+	published, err := outbox.GetPublishedMessages(suite.ctx, domain.OutboxMessage{}, 10)
+	suite.NoError(err)
+	suite.Len(published, 2)
+	suite.Equal(pending[1].ID, published[0].ID)
+	suite.Equal(pending[0].ID, published[1].ID)
+
+	last, err := outbox.GetLastPublishedMessage(suite.ctx)
+	suite.NoError(err)
+	suite.Equal(pending[0].ID, last.ID)
+
+	published, err = outbox.GetPublishedMessages(suite.ctx, published[0], 10)
+	suite.NoError(err)
+	suite.Len(published, 1)
+	suite.Equal(pending[0].Event.ID, published[0].Event.ID)
+
+	// a message published at the same time comes after by its id
+	suite.NoError(outbox.MarkPublished(suite.ctx, pending[2].ID, now.Add(time.Second)))
+	published, err = outbox.GetPublishedMessages(suite.ctx, *last, 10)
+	suite.NoError(err)
+	suite.Len(published, 1)
+	suite.Equal(pending[2].ID, published[0].ID)
+}
@@ -0,0 +1,128 @@
+package repository
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/raulsilva-tech/devices-api/internal/domain"
+)
+
+func (suite *DeviceRepositoryTestSuite) TestTenants_DevicesAreIsolated() {
+
+	acme := domain.WithTenant(suite.ctx, "acme")
+	globex := domain.WithTenant(suite.ctx, "globex")
+
+	repo, d, err := createDevice(acme, suite.DB)
+	suite.NoError(err)
+	suite.Equal("acme", d.TenantID)
+
+	// the other tenant cannot read the device
+	_, err = repo.GetDeviceById(globex, d.ID)
+	suite.ErrorIs(err, sql.ErrNoRows)
+	_, err = repo.GetDeviceByIdIncludingDeleted(globex, d.ID)
+	suite.ErrorIs(err, sql.ErrNoRows)
+
+	filter := newFilter(domain.DeviceFilter{IncludeDeleted: true})
+	deviceList, err := repo.GetDevices(globex, filter)
+	suite.NoError(err)
+	suite.Empty(deviceList)
+	total, err := repo.CountDevices(globex, filter)
+	suite.NoError(err)
+	suite.Zero(total)
+	deviceList, err = repo.GetDevicesAfterCursor(globex, filter, domain.DeviceCursor{})
+	suite.NoError(err)
+	suite.Empty(deviceList)
+
+	var streamed int
+	suite.NoError(repo.StreamDevices(globex, filter, func(domain.Device) error {
+		streamed++
+		return nil
+	}))
+	suite.Zero(streamed)
+
+	history, err := repo.GetDeviceHistory(globex, d.ID, 10, 0)
+	suite.NoError(err)
+	suite.Empty(history)
+
+	// nor change it, even knowing its id and version
+	stolen := *d
+	stolen.Name = "Stolen"
+	suite.ErrorIs(repo.UpdateDevice(globex, &stolen), domain.ErrVersionMismatch)
+	suite.ErrorIs(repo.DeleteDevice(globex, d.ID, d.Version), domain.ErrVersionMismatch)
+
+	suite.NoError(repo.DeleteDevice(acme, d.ID, d.Version))
+	deleted, err := repo.GetDeviceByIdIncludingDeleted(acme, d.ID)
+	suite.NoError(err)
+	restored := *deleted
+	suite.ErrorIs(repo.RestoreDevice(globex, &restored), domain.ErrVersionMismatch)
+
+	// the owner still sees its device untouched
+	dbDevice, err := repo.GetDeviceByIdIncludingDeleted(acme, d.ID)
+	suite.NoError(err)
+	suite.Equal("Device", dbDevice.Name)
+	suite.True(dbDevice.IsDeleted())
+	history, err = repo.GetDeviceHistory(acme, d.ID, 10, 0)
+	suite.NoError(err)
+	suite.Len(history, 2)
+
+	// a context without tenant works on the default tenant
+	_, err = repo.GetDeviceByIdIncludingDeleted(suite.ctx, d.ID)
+	suite.ErrorIs(err, sql.ErrNoRows)
+}
+
+func (suite *DeviceRepositoryTestSuite) TestTenants_HeldDevicesAreIsolated() {
+
+	acme := domain.WithTenant(suite.ctx, "acme")
+	globex := domain.WithTenant(suite.ctx, "globex")
+
+	_, d, err := createDevice(acme, suite.DB)
+	suite.NoError(err)
+	repo := NewAssignmentRepository(suite.DB)
+
+	assignment, err := domain.NewAssignment(d.ID, "jane", time.Now().UTC(), time.Time{}, "")
+	suite.NoError(err)
+	suite.NoError(d.Apply(domain.ActionCheckOut))
+	suite.NoError(repo.CheckOutDevice(acme, d, assignment))
+
+	held, err := repo.GetDevicesHeldBy(acme, "jane")
+	suite.NoError(err)
+	suite.Len(held, 1)
+
+	held, err = repo.GetDevicesHeldBy(globex, "jane")
+	suite.NoError(err)
+	suite.Empty(held)
+
+	// checking out a device of another tenant fails like a stale device
+	other, err := domain.NewDevice(uuid.New().String(), "Device", "Brand", domain.DeviceAvailable, time.Now())
+	suite.NoError(err)
+	_, err = NewDeviceRepository(suite.DB).CreateDevice(acme, other)
+	suite.NoError(err)
+	suite.NoError(other.Apply(domain.ActionCheckOut))
+	assignment, err = domain.NewAssignment(other.ID, "john", time.Now().UTC(), time.Time{}, "")
+	suite.NoError(err)
+	suite.ErrorIs(repo.CheckOutDevice(globex, other, assignment), domain.ErrVersionMismatch)
+}
+
+func (suite *DeviceRepositoryTestSuite) TestTenants_WebhooksAreIsolated() {
+
+	acme := domain.WithTenant(suite.ctx, "acme")
+	globex := domain.WithTenant(suite.ctx, "globex")
+	repo := NewWebhookRepository(suite.DB)
+
+	webhook, err := domain.NewWebhook("https://acme.example.com/hooks", nil)
+	suite.NoError(err)
+	suite.NoError(repo.CreateWebhook(acme, webhook))
+
+	webhooks, err := repo.GetWebhooks(globex)
+	suite.NoError(err)
+	suite.Empty(webhooks)
+	_, err = repo.GetWebhookById(globex, webhook.ID)
+	suite.ErrorIs(err, sql.ErrNoRows)
+	suite.ErrorIs(repo.DeleteWebhook(globex, webhook.ID), sql.ErrNoRows)
+
+	webhooks, err = repo.GetWebhooks(acme)
+	suite.NoError(err)
+	suite.Len(webhooks, 1)
+	suite.Equal("acme", webhooks[0].TenantID)
+}
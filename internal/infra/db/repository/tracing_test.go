@@ -0,0 +1,58 @@
+package repository
+
+import (
+	"context"
+	"sync"
+
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+var (
+	spansOnce sync.Once
+	spans     *tracetest.InMemoryExporter
+)
+
+// recordSpans installs a tracer provider keeping the spans in memory, only once as the tracer
+// of the package binds to the first provider installed
+func recordSpans() *tracetest.InMemoryExporter {
+	spansOnce.Do(func() {
+		spans = tracetest.NewInMemoryExporter()
+		otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(spans)))
+	})
+	spans.Reset()
+	return spans
+}
+
+func (suite *DeviceRepositoryTestSuite) TestQueriesAreTraced() {
+
+	exporter := recordSpans()
+	repo, d, err := createDevice(suite.ctx, suite.DB)
+	suite.NoError(err)
+
+	// outside of a trace nothing is recorded
+	suite.Empty(exporter.GetSpans())
+
+	ctx, parent := otel.Tracer("test").Start(suite.ctx, "request")
+	_, err = repo.GetDeviceById(ctx, d.ID)
+	suite.NoError(err)
+	suite.NoError(repo.WithinTx(ctx, func(ctx context.Context) error {
+		_, err := repo.GetDeviceById(ctx, d.ID)
+		return err
+	}))
+	parent.End()
+
+	recorded := exporter.GetSpans()
+	suite.Len(recorded, 3)
+	for _, span := range recorded[:2] {
+		suite.Equal("GetDeviceByID", span.Name)
+		suite.Equal(parent.SpanContext().SpanID(), span.Parent.SpanID())
+	}
+}
+
+func (suite *DeviceRepositoryTestSuite) TestQueryName() {
+	suite.Equal("GetDeviceByID", queryName("-- name: GetDeviceByID :one\nSELECT * FROM devices"))
+	suite.Equal("StreamDevices", queryName(streamDevicesSQL))
+	suite.Equal("query", queryName("SELECT 1"))
+}
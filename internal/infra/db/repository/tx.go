@@ -0,0 +1,55 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/raulsilva-tech/devices-api/internal/infra/db/sqlc"
+)
+
+type txKey struct{}
+
+// withinTx runs fn inside a database transaction carried by the context given to fn, and commits
+// only if fn succeeds. When ctx already carries a transaction fn joins it, and the outermost call
+// decides whether it is committed.
+func withinTx(ctx context.Context, db *sql.DB, fn func(ctx context.Context) error) error {
+
+	if _, ok := ctx.Value(txKey{}).(*sql.Tx); ok {
+		return fn(ctx)
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	if err := fn(context.WithValue(ctx, txKey{}, tx)); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// execTx runs fn with queries bound to a transaction, see withinTx
+func execTx(ctx context.Context, db *sql.DB, fn func(q *sqlc.Queries) error) error {
+	return withinTx(ctx, db, func(ctx context.Context) error {
+		return fn(sqlc.New(traced(ctx.Value(txKey{}).(*sql.Tx))))
+	})
+}
+
+// queriesFor returns q bound to the transaction carried by ctx, if any
+func queriesFor(ctx context.Context, q *sqlc.Queries) *sqlc.Queries {
+	if tx, ok := ctx.Value(txKey{}).(*sql.Tx); ok {
+		return sqlc.New(traced(tx))
+	}
+	return q
+}
+
+// dbFor returns the transaction carried by ctx, or db when there is none
+func dbFor(ctx context.Context, db *sql.DB) sqlc.DBTX {
+	if tx, ok := ctx.Value(txKey{}).(*sql.Tx); ok {
+		return traced(tx)
+	}
+	return traced(db)
+}
@@ -0,0 +1,74 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/raulsilva-tech/devices-api/internal/domain"
+)
+
+func (suite *DeviceRepositoryTestSuite) TestWithinTx_RollsBackEveryCall() {
+
+	repo, existing, err := createDevice(suite.ctx, suite.DB)
+	suite.NoError(err)
+
+	created, err := domain.NewDevice(uuid.New().String(), "Created", "Brand", domain.DeviceAvailable, time.Now())
+	suite.NoError(err)
+
+	failure := errors.New("failure")
+	err = repo.WithinTx(suite.ctx, func(ctx context.Context) error {
+
+		if _, err := repo.CreateDevice(ctx, created); err != nil {
+			return err
+		}
+
+		// reads made with the transaction context see its writes
+		if _, err := repo.GetDeviceById(ctx, created.ID); err != nil {
+			return err
+		}
+
+		existing.Name = "Renamed"
+		if err := repo.UpdateDevice(ctx, existing); err != nil {
+			return err
+		}
+
+		return failure
+	})
+	suite.ErrorIs(err, failure)
+
+	_, err = repo.GetDeviceById(suite.ctx, created.ID)
+	suite.ErrorIs(err, sql.ErrNoRows)
+
+	stored, err := repo.GetDeviceById(suite.ctx, existing.ID)
+	suite.NoError(err)
+	suite.Equal("Device", stored.Name)
+	suite.Equal(int64(1), stored.Version)
+
+	history, err := repo.GetDeviceHistory(suite.ctx, created.ID, 10, 0)
+	suite.NoError(err)
+	suite.Empty(history)
+}
+
+func (suite *DeviceRepositoryTestSuite) TestWithinTx_Commits() {
+
+	repo := NewDeviceRepository(suite.DB)
+
+	first, _ := domain.NewDevice(uuid.New().String(), "First", "Brand", domain.DeviceAvailable, time.Now())
+	second, _ := domain.NewDevice(uuid.New().String(), "Second", "Brand", domain.DeviceAvailable, time.Now())
+
+	err := repo.WithinTx(suite.ctx, func(ctx context.Context) error {
+		if _, err := repo.CreateDevice(ctx, first); err != nil {
+			return err
+		}
+		_, err := repo.CreateDevice(ctx, second)
+		return err
+	})
+	suite.NoError(err)
+
+	count, err := repo.CountDevices(suite.ctx, newFilter(domain.DeviceFilter{}))
+	suite.NoError(err)
+	suite.Equal(int64(2), count)
+}
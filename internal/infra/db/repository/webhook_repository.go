@@ -0,0 +1,211 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+
+	"github.com/raulsilva-tech/devices-api/internal/domain"
+	"github.com/raulsilva-tech/devices-api/internal/infra/db/sqlc"
+)
+
+type WebhookRepository struct {
+	db      *sql.DB
+	Queries *sqlc.Queries
+}
+
+func NewWebhookRepository(dbConn *sql.DB) *WebhookRepository {
+	return &WebhookRepository{
+		db:      dbConn,
+		Queries: sqlc.New(traced(dbConn)),
+	}
+}
+
+func (repo *WebhookRepository) CreateWebhook(ctx context.Context, webhook *domain.Webhook) error {
+
+	webhook.TenantID = domain.TenantFromContext(ctx)
+
+	events := make([]string, len(webhook.Events))
+	for i, e := range webhook.Events {
+		events[i] = string(e)
+	}
+
+	return queriesFor(ctx, repo.Queries).CreateWebhook(ctx, sqlc.CreateWebhookParams{
+		ID:        webhook.ID,
+		Url:       webhook.URL,
+		Secret:    webhook.Secret,
+		Events:    strings.Join(events, ","),
+		CreatedAt: webhook.CreatedAt,
+		TenantID:  webhook.TenantID,
+	})
+}
+
+func (repo *WebhookRepository) DeleteWebhook(ctx context.Context, id string) error {
+
+	rows, err := queriesFor(ctx, repo.Queries).DeleteWebhook(ctx, sqlc.DeleteWebhookParams{
+		ID:       id,
+		TenantID: domain.TenantFromContext(ctx),
+	})
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+func (repo *WebhookRepository) GetWebhookById(ctx context.Context, id string) (*domain.Webhook, error) {
+
+	webhookDB, err := queriesFor(ctx, repo.Queries).GetWebhookByID(ctx, sqlc.GetWebhookByIDParams{
+		ID:       id,
+		TenantID: domain.TenantFromContext(ctx),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	webhook := mapDBToDomainWebhook(webhookDB)
+	return &webhook, nil
+}
+
+func (repo *WebhookRepository) GetWebhooks(ctx context.Context) ([]domain.Webhook, error) {
+
+	webhookDBList, err := queriesFor(ctx, repo.Queries).ListWebhooks(ctx, domain.TenantFromContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+
+	resultList := make([]domain.Webhook, len(webhookDBList))
+
+	for i, webhookDB := range webhookDBList {
+		resultList[i] = mapDBToDomainWebhook(webhookDB)
+	}
+
+	return resultList, nil
+}
+
+func (repo *WebhookRepository) CreateDelivery(ctx context.Context, delivery *domain.WebhookDelivery) error {
+
+	return queriesFor(ctx, repo.Queries).CreateWebhookDelivery(ctx, sqlc.CreateWebhookDeliveryParams{
+		ID:             delivery.ID,
+		WebhookID:      delivery.WebhookID,
+		EventID:        delivery.EventID,
+		EventType:      string(delivery.EventType),
+		Payload:        delivery.Payload,
+		Status:         string(delivery.Status),
+		Attempts:       int32(delivery.Attempts),
+		LastStatusCode: int32(delivery.LastStatusCode),
+		LastError:      delivery.LastError,
+		CreatedAt:      delivery.CreatedAt,
+		UpdatedAt:      delivery.UpdatedAt,
+		NextAttemptAt:  delivery.NextAttemptAt,
+	})
+}
+
+func (repo *WebhookRepository) UpdateDelivery(ctx context.Context, delivery *domain.WebhookDelivery) error {
+
+	return queriesFor(ctx, repo.Queries).UpdateWebhookDelivery(ctx, sqlc.UpdateWebhookDeliveryParams{
+		ID:             delivery.ID,
+		Status:         string(delivery.Status),
+		Attempts:       int32(delivery.Attempts),
+		LastStatusCode: int32(delivery.LastStatusCode),
+		LastError:      delivery.LastError,
+		UpdatedAt:      delivery.UpdatedAt,
+		NextAttemptAt:  delivery.NextAttemptAt,
+	})
+}
+
+func (repo *WebhookRepository) GetDeliveries(ctx context.Context, webhookID string, limit, offset int) ([]domain.WebhookDelivery, error) {
+
+	deliveryDBList, err := queriesFor(ctx, repo.Queries).ListWebhookDeliveries(ctx, sqlc.ListWebhookDeliveriesParams{
+		WebhookID: webhookID,
+		Limit:     int32(limit),
+		Offset:    int32(offset),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	resultList := make([]domain.WebhookDelivery, len(deliveryDBList))
+
+	for i, deliveryDB := range deliveryDBList {
+		resultList[i] = mapDBToDomainWebhookDelivery(deliveryDB)
+	}
+
+	return resultList, nil
+}
+
+func (repo *WebhookRepository) GetDueDeliveries(ctx context.Context, now time.Time, limit int) ([]domain.DueDelivery, error) {
+
+	rows, err := queriesFor(ctx, repo.Queries).ListDueWebhookDeliveries(ctx, sqlc.ListDueWebhookDeliveriesParams{
+		NextAttemptAt: now,
+		Limit:         int32(limit),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	resultList := make([]domain.DueDelivery, len(rows))
+
+	for i, row := range rows {
+		resultList[i] = domain.DueDelivery{
+			Webhook:  mapDBToDomainWebhook(row.Webhook),
+			Delivery: mapDBToDomainWebhookDelivery(row.WebhookDelivery),
+		}
+	}
+
+	return resultList, nil
+}
+
+func (repo *WebhookRepository) ClaimDelivery(ctx context.Context, id string, now, until time.Time) (bool, error) {
+
+	rows, err := queriesFor(ctx, repo.Queries).ClaimWebhookDelivery(ctx, sqlc.ClaimWebhookDeliveryParams{
+		ID:    id,
+		Now:   now,
+		Until: until,
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return rows == 1, nil
+}
+
+func mapDBToDomainWebhook(w sqlc.Webhook) domain.Webhook {
+
+	var events []domain.EventType
+	if w.Events != "" {
+		for _, e := range strings.Split(w.Events, ",") {
+			events = append(events, domain.EventType(e))
+		}
+	}
+
+	return domain.Webhook{
+		ID:        w.ID,
+		URL:       w.Url,
+		Secret:    w.Secret,
+		Events:    events,
+		CreatedAt: w.CreatedAt,
+		TenantID:  w.TenantID,
+	}
+}
+
+func mapDBToDomainWebhookDelivery(d sqlc.WebhookDelivery) domain.WebhookDelivery {
+	return domain.WebhookDelivery{
+		ID:             d.ID,
+		WebhookID:      d.WebhookID,
+		EventID:        d.EventID,
+		EventType:      domain.EventType(d.EventType),
+		Payload:        d.Payload,
+		Status:         domain.DeliveryStatus(d.Status),
+		Attempts:       int(d.Attempts),
+		LastStatusCode: int(d.LastStatusCode),
+		LastError:      d.LastError,
+		CreatedAt:      d.CreatedAt,
+		UpdatedAt:      d.UpdatedAt,
+		NextAttemptAt:  d.NextAttemptAt,
+	}
+}
@@ -0,0 +1,164 @@
+package repository
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/raulsilva-tech/devices-api/internal/domain"
+)
+
+func (suite *DeviceRepositoryTestSuite) TestWebhooks() {
+
+	repo := NewWebhookRepository(suite.DB)
+
+	all, err := domain.NewWebhook("https://example.com/all", nil)
+	suite.NoError(err)
+	suite.NoError(repo.CreateWebhook(suite.ctx, all))
+
+	filtered, err := domain.NewWebhook("https://example.com/created", []domain.EventType{domain.EventDeviceCreated, domain.EventDeviceDeleted})
+	suite.NoError(err)
+	suite.NoError(repo.CreateWebhook(suite.ctx, filtered))
+
+	stored, err := repo.GetWebhookById(suite.ctx, filtered.ID)
+	suite.NoError(err)
+	suite.Equal(filtered.URL, stored.URL)
+	suite.Equal(filtered.Secret, stored.Secret)
+	suite.Equal(filtered.Events, stored.Events)
+
+	list, err := repo.GetWebhooks(suite.ctx)
+	suite.NoError(err)
+	suite.Len(list, 2)
+
+	stored, err = repo.GetWebhookById(suite.ctx, all.ID)
+	suite.NoError(err)
+	suite.Empty(stored.Events)
+
+	suite.NoError(repo.DeleteWebhook(suite.ctx, all.ID))
+	suite.ErrorIs(repo.DeleteWebhook(suite.ctx, all.ID), sql.ErrNoRows)
+
+	_, err = repo.GetWebhookById(suite.ctx, all.ID)
+	suite.ErrorIs(err, sql.ErrNoRows)
+}
+
+func (suite *DeviceRepositoryTestSuite) TestWebhookDeliveries() {
+
+	repo := NewWebhookRepository(suite.DB)
+
+	webhook, err := domain.NewWebhook("https://example.com/hook", nil)
+	suite.NoError(err)
+	suite.NoError(repo.CreateWebhook(suite.ctx, webhook))
+
+	device, err := domain.NewDevice(uuid.New().String(), "Device", "Brand", domain.DeviceAvailable, time.Now())
+	suite.NoError(err)
+
+	first := domain.NewWebhookDelivery(webhook.ID, domain.NewEvent(domain.EventDeviceCreated, *device), `{"n":1}`)
+	suite.NoError(repo.CreateDelivery(suite.ctx, first))
+
+	second := domain.NewWebhookDelivery(webhook.ID, domain.NewEvent(domain.EventDeviceUpdated, *device), `{"n":2}`)
+	second.CreatedAt = first.CreatedAt.Add(time.Second)
+	suite.NoError(repo.CreateDelivery(suite.ctx, second))
+
+	first.Status = domain.DeliveryFailed
+	first.Attempts = 3
+	first.LastStatusCode = 503
+	first.LastError = "unexpected status 503"
+	first.UpdatedAt = time.Now().UTC()
+	suite.NoError(repo.UpdateDelivery(suite.ctx, first))
+
+	deliveries, err := repo.GetDeliveries(suite.ctx, webhook.ID, 10, 0)
+	suite.NoError(err)
+	suite.Len(deliveries, 2)
+
+	// most recent first
+	suite.Equal(second.ID, deliveries[0].ID)
+	suite.Equal(domain.DeliveryPending, deliveries[0].Status)
+
+	suite.Equal(first.ID, deliveries[1].ID)
+	suite.Equal(domain.DeliveryFailed, deliveries[1].Status)
+	suite.Equal(3, deliveries[1].Attempts)
+	suite.Equal(503, deliveries[1].LastStatusCode)
+	suite.Equal("unexpected status 503", deliveries[1].LastError)
+	suite.Equal(domain.EventDeviceCreated, deliveries[1].EventType)
+	suite.Equal(`{"n":1}`, deliveries[1].Payload)
+
+	page, err := repo.GetDeliveries(suite.ctx, webhook.ID, 1, 1)
+	suite.NoError(err)
+	suite.Len(page, 1)
+	suite.Equal(first.ID, page[0].ID)
+}
+
+func (suite *DeviceRepositoryTestSuite) TestWebhookDeliveries_Due() {
+
+	repo := NewWebhookRepository(suite.DB)
+
+	webhook, err := domain.NewWebhook("https://example.com/hook", nil)
+	suite.NoError(err)
+	suite.NoError(repo.CreateWebhook(suite.ctx, webhook))
+
+	other, err := domain.NewWebhook("https://example.com/other", nil)
+	suite.NoError(err)
+	suite.NoError(repo.CreateWebhook(domain.WithTenant(suite.ctx, "acme"), other))
+
+	device, err := domain.NewDevice(uuid.New().String(), "Device", "Brand", domain.DeviceAvailable, time.Now())
+	suite.NoError(err)
+	event := domain.NewEvent(domain.EventDeviceCreated, *device)
+
+	due := domain.NewWebhookDelivery(webhook.ID, event, `{"n":1}`)
+	suite.NoError(repo.CreateDelivery(suite.ctx, due))
+
+	// an event published again keeps its first delivery
+	suite.NoError(repo.CreateDelivery(suite.ctx, domain.NewWebhookDelivery(webhook.ID, event, `{"n":1}`)))
+
+	retried := domain.NewWebhookDelivery(webhook.ID, domain.NewEvent(domain.EventDeviceUpdated, *device), `{"n":2}`)
+	retried.NextAttemptAt = due.NextAttemptAt.Add(time.Minute)
+	suite.NoError(repo.CreateDelivery(suite.ctx, retried))
+
+	// deliveries of every tenant are attempted
+	otherTenant := domain.NewWebhookDelivery(other.ID, event, `{"n":3}`)
+	otherTenant.NextAttemptAt = due.NextAttemptAt.Add(time.Second)
+	suite.NoError(repo.CreateDelivery(suite.ctx, otherTenant))
+
+	done := domain.NewWebhookDelivery(webhook.ID, domain.NewEvent(domain.EventDeviceDeleted, *device), `{"n":4}`)
+	suite.NoError(repo.CreateDelivery(suite.ctx, done))
+	done.Status = domain.DeliverySucceeded
+	suite.NoError(repo.UpdateDelivery(suite.ctx, done))
+
+	deliveries, err := repo.GetDeliveries(suite.ctx, webhook.ID, 10, 0)
+	suite.NoError(err)
+	suite.Len(deliveries, 3)
+
+	list, err := repo.GetDueDeliveries(suite.ctx, due.NextAttemptAt.Add(time.Second), 10)
+	suite.NoError(err)
+	suite.Len(list, 2)
+	suite.Equal(due.ID, list[0].Delivery.ID)
+	suite.Equal(webhook.URL, list[0].Webhook.URL)
+	suite.Equal(webhook.Secret, list[0].Webhook.Secret)
+	suite.Equal(otherTenant.ID, list[1].Delivery.ID)
+	suite.Equal("acme", list[1].Webhook.TenantID)
+
+	list, err = repo.GetDueDeliveries(suite.ctx, retried.NextAttemptAt, 1)
+	suite.NoError(err)
+	suite.Len(list, 1)
+	suite.Equal(due.ID, list[0].Delivery.ID)
+
+	// a claimed delivery is not due for the other instances until the claim expires
+	now := due.NextAttemptAt.Add(time.Second)
+	claimed, err := repo.ClaimDelivery(suite.ctx, due.ID, now, now.Add(time.Minute))
+	suite.NoError(err)
+	suite.True(claimed)
+
+	claimed, err = repo.ClaimDelivery(suite.ctx, due.ID, now, now.Add(time.Minute))
+	suite.NoError(err)
+	suite.False(claimed)
+
+	list, err = repo.GetDueDeliveries(suite.ctx, now, 10)
+	suite.NoError(err)
+	suite.Len(list, 1)
+	suite.Equal(otherTenant.ID, list[0].Delivery.ID)
+
+	// nor is a finished one
+	claimed, err = repo.ClaimDelivery(suite.ctx, done.ID, now, now.Add(time.Minute))
+	suite.NoError(err)
+	suite.False(claimed)
+}
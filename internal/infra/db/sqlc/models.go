@@ -5,13 +5,106 @@
 package sqlc
 
 import (
+	"database/sql"
 	"time"
 )
 
+type ApiKey struct {
+	ID        string
+	Name      string
+	Prefix    string
+	KeyHash   string
+	Scopes    string
+	ExpiresAt sql.NullTime
+	CreatedAt time.Time
+	RevokedAt sql.NullTime
+	Roles     string
+	TenantID  string
+}
+
 type Device struct {
 	ID        string
 	Name      string
 	Brand     string
 	State     string
 	CreatedAt time.Time
+	Version   int64
+	DeletedAt sql.NullTime
+	TenantID  string
+}
+
+type DeviceAssignment struct {
+	ID               string
+	DeviceID         string
+	Assignee         string
+	CheckedOutAt     time.Time
+	ExpectedReturnAt sql.NullTime
+	ReturnedAt       sql.NullTime
+	Notes            string
+}
+
+type DeviceEvent struct {
+	ID            int64
+	DeviceID      string
+	EventType     string
+	DeviceVersion int64
+	Changes       string
+	RequestID     string
+	Actor         string
+	OccurredAt    time.Time
+	TenantID      string
+	OnBehalfOf    string
+}
+
+type IdempotencyKey struct {
+	TenantID        string
+	Client          string
+	IdempotencyKey  string
+	Fingerprint     string
+	StatusCode      int32
+	ResponseHeaders string
+	ResponseBody    string
+	CreatedAt       time.Time
+	ExpiresAt       time.Time
+}
+
+type Outbox struct {
+	ID          int64
+	EventID     string
+	EventType   string
+	Payload     string
+	Attempts    int32
+	LastError   string
+	CreatedAt   time.Time
+	PublishedAt sql.NullTime
+}
+
+type OutboxLease struct {
+	ID          int32
+	Holder      string
+	LockedUntil time.Time
+}
+
+type Webhook struct {
+	ID        string
+	Url       string
+	Secret    string
+	Events    string
+	CreatedAt time.Time
+	TenantID  string
+}
+
+type WebhookDelivery struct {
+	ID             string
+	WebhookID      string
+	EventID        string
+	EventType      string
+	Payload        string
+	Status         string
+	Attempts       int32
+	LastStatusCode int32
+	LastError      string
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+	NextAttemptAt  time.Time
 }
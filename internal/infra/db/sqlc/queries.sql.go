@@ -7,12 +7,117 @@ package sqlc
 
 import (
 	"context"
+	"database/sql"
 	"time"
 )
 
+const countDevices = `-- name: CountDevices :one
+SELECT COUNT(*) FROM devices
+WHERE tenant_id = $1
+  AND (deleted_at IS NULL OR CAST($2 AS BOOLEAN))
+  AND (brand = $3 OR $3 = '')
+  AND (state = $4 OR $4 = '')
+  AND (LOWER(name) LIKE '%' || LOWER(CAST($5 AS TEXT)) || '%' ESCAPE '\')
+  AND (created_at >= $6 OR $6 IS NULL)
+  AND (created_at <= $7 OR $7 IS NULL)
+`
+
+type CountDevicesParams struct {
+	TenantID       string
+	IncludeDeleted bool
+	Brand          string
+	State          string
+	Name           string
+	CreatedFrom    sql.NullTime
+	CreatedTo      sql.NullTime
+}
+
+func (q *Queries) CountDevices(ctx context.Context, arg CountDevicesParams) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countDevices,
+		arg.TenantID,
+		arg.IncludeDeleted,
+		arg.Brand,
+		arg.State,
+		arg.Name,
+		arg.CreatedFrom,
+		arg.CreatedTo,
+	)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const countDevicesByBrand = `-- name: CountDevicesByBrand :many
+SELECT brand, COUNT(*) AS total FROM devices
+WHERE deleted_at IS NULL
+GROUP BY brand
+`
+
+type CountDevicesByBrandRow struct {
+	Brand string
+	Total int64
+}
+
+func (q *Queries) CountDevicesByBrand(ctx context.Context) ([]CountDevicesByBrandRow, error) {
+	rows, err := q.db.QueryContext(ctx, countDevicesByBrand)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []CountDevicesByBrandRow
+	for rows.Next() {
+		var i CountDevicesByBrandRow
+		if err := rows.Scan(&i.Brand, &i.Total); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const countDevicesByState = `-- name: CountDevicesByState :many
+SELECT state, COUNT(*) AS total FROM devices
+WHERE deleted_at IS NULL
+GROUP BY state
+`
+
+type CountDevicesByStateRow struct {
+	State string
+	Total int64
+}
+
+func (q *Queries) CountDevicesByState(ctx context.Context) ([]CountDevicesByStateRow, error) {
+	rows, err := q.db.QueryContext(ctx, countDevicesByState)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []CountDevicesByStateRow
+	for rows.Next() {
+		var i CountDevicesByStateRow
+		if err := rows.Scan(&i.State, &i.Total); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const createDevice = `-- name: CreateDevice :one
-INSERT INTO devices (id, name, brand, state, created_at)
-VALUES ($1, $2, $3, $4, $5)
+INSERT INTO devices (id, name, brand, state, created_at, version, tenant_id)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
 RETURNING id
 `
 
@@ -22,6 +127,8 @@ type CreateDeviceParams struct {
 	Brand     string
 	State     string
 	CreatedAt time.Time
+	Version   int64
+	TenantID  string
 }
 
 func (q *Queries) CreateDevice(ctx context.Context, arg CreateDeviceParams) (string, error) {
@@ -31,27 +138,142 @@ func (q *Queries) CreateDevice(ctx context.Context, arg CreateDeviceParams) (str
 		arg.Brand,
 		arg.State,
 		arg.CreatedAt,
+		arg.Version,
+		arg.TenantID,
 	)
 	var id string
 	err := row.Scan(&id)
 	return id, err
 }
 
-const deleteDevice = `-- name: DeleteDevice :exec
-DELETE FROM devices WHERE id = $1
+const deleteDevice = `-- name: DeleteDevice :execrows
+UPDATE devices
+SET deleted_at = $3,
+    version = version + 1
+WHERE id = $1 AND version = $2 AND tenant_id = $4 AND deleted_at IS NULL
+`
+
+type DeleteDeviceParams struct {
+	ID        string
+	Version   int64
+	DeletedAt sql.NullTime
+	TenantID  string
+}
+
+func (q *Queries) DeleteDevice(ctx context.Context, arg DeleteDeviceParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteDevice,
+		arg.ID,
+		arg.Version,
+		arg.DeletedAt,
+		arg.TenantID,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const getDeviceByID = `-- name: GetDeviceByID :one
+SELECT id, name, brand, state, created_at, version, deleted_at, tenant_id FROM devices WHERE id = $1 AND tenant_id = $2 AND deleted_at IS NULL
 `
 
-func (q *Queries) DeleteDevice(ctx context.Context, id string) error {
-	_, err := q.db.ExecContext(ctx, deleteDevice, id)
-	return err
+type GetDeviceByIDParams struct {
+	ID       string
+	TenantID string
+}
+
+func (q *Queries) GetDeviceByID(ctx context.Context, arg GetDeviceByIDParams) (Device, error) {
+	row := q.db.QueryRowContext(ctx, getDeviceByID, arg.ID, arg.TenantID)
+	var i Device
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Brand,
+		&i.State,
+		&i.CreatedAt,
+		&i.Version,
+		&i.DeletedAt,
+		&i.TenantID,
+	)
+	return i, err
 }
 
-const getAllDevices = `-- name: GetAllDevices :many
-SELECT id, name, brand, state, created_at FROM devices
+const getDeviceByIDIncludingDeleted = `-- name: GetDeviceByIDIncludingDeleted :one
+SELECT id, name, brand, state, created_at, version, deleted_at, tenant_id FROM devices WHERE id = $1 AND tenant_id = $2
 `
 
-func (q *Queries) GetAllDevices(ctx context.Context) ([]Device, error) {
-	rows, err := q.db.QueryContext(ctx, getAllDevices)
+type GetDeviceByIDIncludingDeletedParams struct {
+	ID       string
+	TenantID string
+}
+
+func (q *Queries) GetDeviceByIDIncludingDeleted(ctx context.Context, arg GetDeviceByIDIncludingDeletedParams) (Device, error) {
+	row := q.db.QueryRowContext(ctx, getDeviceByIDIncludingDeleted, arg.ID, arg.TenantID)
+	var i Device
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Brand,
+		&i.State,
+		&i.CreatedAt,
+		&i.Version,
+		&i.DeletedAt,
+		&i.TenantID,
+	)
+	return i, err
+}
+
+const listDevices = `-- name: ListDevices :many
+SELECT id, name, brand, state, created_at, version, deleted_at, tenant_id FROM devices
+WHERE tenant_id = $1
+  AND (deleted_at IS NULL OR CAST($2 AS BOOLEAN))
+  AND (brand = $3 OR $3 = '')
+  AND (state = $4 OR $4 = '')
+  AND (LOWER(name) LIKE '%' || LOWER(CAST($5 AS TEXT)) || '%' ESCAPE '\')
+  AND (created_at >= $6 OR $6 IS NULL)
+  AND (created_at <= $7 OR $7 IS NULL)
+ORDER BY
+  CASE WHEN CAST($8 AS TEXT) = 'name' AND CAST($9 AS TEXT) = 'asc' THEN name END ASC,
+  CASE WHEN $8 = 'name' AND $9 = 'desc' THEN name END DESC,
+  CASE WHEN $8 = 'brand' AND $9 = 'asc' THEN brand END ASC,
+  CASE WHEN $8 = 'brand' AND $9 = 'desc' THEN brand END DESC,
+  CASE WHEN $8 = 'state' AND $9 = 'asc' THEN state END ASC,
+  CASE WHEN $8 = 'state' AND $9 = 'desc' THEN state END DESC,
+  CASE WHEN $8 = 'created_at' AND $9 = 'asc' THEN created_at END ASC,
+  CASE WHEN $8 = 'created_at' AND $9 = 'desc' THEN created_at END DESC,
+  CASE WHEN $9 = 'desc' THEN id END DESC,
+  id ASC
+LIMIT $11 OFFSET $10
+`
+
+type ListDevicesParams struct {
+	TenantID       string
+	IncludeDeleted bool
+	Brand          string
+	State          string
+	Name           string
+	CreatedFrom    sql.NullTime
+	CreatedTo      sql.NullTime
+	SortBy         string
+	SortOrder      string
+	PageOffset     int32
+	PageLimit      int32
+}
+
+func (q *Queries) ListDevices(ctx context.Context, arg ListDevicesParams) ([]Device, error) {
+	rows, err := q.db.QueryContext(ctx, listDevices,
+		arg.TenantID,
+		arg.IncludeDeleted,
+		arg.Brand,
+		arg.State,
+		arg.Name,
+		arg.CreatedFrom,
+		arg.CreatedTo,
+		arg.SortBy,
+		arg.SortOrder,
+		arg.PageOffset,
+		arg.PageLimit,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -65,6 +287,9 @@ func (q *Queries) GetAllDevices(ctx context.Context) ([]Device, error) {
 			&i.Brand,
 			&i.State,
 			&i.CreatedAt,
+			&i.Version,
+			&i.DeletedAt,
+			&i.TenantID,
 		); err != nil {
 			return nil, err
 		}
@@ -79,13 +304,46 @@ func (q *Queries) GetAllDevices(ctx context.Context) ([]Device, error) {
 	return items, nil
 }
 
-const getAllDevicesByBrand = `-- name: GetAllDevicesByBrand :many
-SELECT id, name, brand, state, created_at FROM devices 
-WHERE brand = $1
+const listDevicesAfterCursor = `-- name: ListDevicesAfterCursor :many
+SELECT id, name, brand, state, created_at, version, deleted_at, tenant_id FROM devices
+WHERE tenant_id = $1
+  AND (deleted_at IS NULL OR CAST($2 AS BOOLEAN))
+  AND (brand = $3 OR $3 = '')
+  AND (state = $4 OR $4 = '')
+  AND (LOWER(name) LIKE '%' || LOWER(CAST($5 AS TEXT)) || '%' ESCAPE '\')
+  AND (created_at >= $6 OR $6 IS NULL)
+  AND (created_at <= $7 OR $7 IS NULL)
+  AND (created_at, id) > ($8, CAST($9 AS TEXT))
+ORDER BY created_at ASC, id ASC
+LIMIT $10
 `
 
-func (q *Queries) GetAllDevicesByBrand(ctx context.Context, brand string) ([]Device, error) {
-	rows, err := q.db.QueryContext(ctx, getAllDevicesByBrand, brand)
+type ListDevicesAfterCursorParams struct {
+	TenantID        string
+	IncludeDeleted  bool
+	Brand           string
+	State           string
+	Name            string
+	CreatedFrom     sql.NullTime
+	CreatedTo       sql.NullTime
+	CursorCreatedAt time.Time
+	CursorID        string
+	PageLimit       int32
+}
+
+func (q *Queries) ListDevicesAfterCursor(ctx context.Context, arg ListDevicesAfterCursorParams) ([]Device, error) {
+	rows, err := q.db.QueryContext(ctx, listDevicesAfterCursor,
+		arg.TenantID,
+		arg.IncludeDeleted,
+		arg.Brand,
+		arg.State,
+		arg.Name,
+		arg.CreatedFrom,
+		arg.CreatedTo,
+		arg.CursorCreatedAt,
+		arg.CursorID,
+		arg.PageLimit,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -99,6 +357,9 @@ func (q *Queries) GetAllDevicesByBrand(ctx context.Context, brand string) ([]Dev
 			&i.Brand,
 			&i.State,
 			&i.CreatedAt,
+			&i.Version,
+			&i.DeletedAt,
+			&i.TenantID,
 		); err != nil {
 			return nil, err
 		}
@@ -113,13 +374,46 @@ func (q *Queries) GetAllDevicesByBrand(ctx context.Context, brand string) ([]Dev
 	return items, nil
 }
 
-const getAllDevicesByState = `-- name: GetAllDevicesByState :many
-SELECT id, name, brand, state, created_at FROM devices 
-WHERE state = $1
+const listDevicesBeforeCursor = `-- name: ListDevicesBeforeCursor :many
+SELECT id, name, brand, state, created_at, version, deleted_at, tenant_id FROM devices
+WHERE tenant_id = $1
+  AND (deleted_at IS NULL OR CAST($2 AS BOOLEAN))
+  AND (brand = $3 OR $3 = '')
+  AND (state = $4 OR $4 = '')
+  AND (LOWER(name) LIKE '%' || LOWER(CAST($5 AS TEXT)) || '%' ESCAPE '\')
+  AND (created_at >= $6 OR $6 IS NULL)
+  AND (created_at <= $7 OR $7 IS NULL)
+  AND (created_at, id) < ($8, CAST($9 AS TEXT))
+ORDER BY created_at DESC, id DESC
+LIMIT $10
 `
 
-func (q *Queries) GetAllDevicesByState(ctx context.Context, state string) ([]Device, error) {
-	rows, err := q.db.QueryContext(ctx, getAllDevicesByState, state)
+type ListDevicesBeforeCursorParams struct {
+	TenantID        string
+	IncludeDeleted  bool
+	Brand           string
+	State           string
+	Name            string
+	CreatedFrom     sql.NullTime
+	CreatedTo       sql.NullTime
+	CursorCreatedAt time.Time
+	CursorID        string
+	PageLimit       int32
+}
+
+func (q *Queries) ListDevicesBeforeCursor(ctx context.Context, arg ListDevicesBeforeCursorParams) ([]Device, error) {
+	rows, err := q.db.QueryContext(ctx, listDevicesBeforeCursor,
+		arg.TenantID,
+		arg.IncludeDeleted,
+		arg.Brand,
+		arg.State,
+		arg.Name,
+		arg.CreatedFrom,
+		arg.CreatedTo,
+		arg.CursorCreatedAt,
+		arg.CursorID,
+		arg.PageLimit,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -133,6 +427,9 @@ func (q *Queries) GetAllDevicesByState(ctx context.Context, state string) ([]Dev
 			&i.Brand,
 			&i.State,
 			&i.CreatedAt,
+			&i.Version,
+			&i.DeletedAt,
+			&i.TenantID,
 		); err != nil {
 			return nil, err
 		}
@@ -147,44 +444,95 @@ func (q *Queries) GetAllDevicesByState(ctx context.Context, state string) ([]Dev
 	return items, nil
 }
 
-const getDeviceByID = `-- name: GetDeviceByID :one
-SELECT id, name, brand, state, created_at FROM devices WHERE id = $1
+const purgeDeletedDevices = `-- name: PurgeDeletedDevices :many
+DELETE FROM devices
+WHERE deleted_at IS NOT NULL AND deleted_at < $1
+RETURNING id, name, brand, state, created_at, version, deleted_at, tenant_id
 `
 
-func (q *Queries) GetDeviceByID(ctx context.Context, id string) (Device, error) {
-	row := q.db.QueryRowContext(ctx, getDeviceByID, id)
-	var i Device
-	err := row.Scan(
-		&i.ID,
-		&i.Name,
-		&i.Brand,
-		&i.State,
-		&i.CreatedAt,
-	)
-	return i, err
+// the retention applies to every tenant, the purge command runs for the whole deployment
+func (q *Queries) PurgeDeletedDevices(ctx context.Context, deletedAt sql.NullTime) ([]Device, error) {
+	rows, err := q.db.QueryContext(ctx, purgeDeletedDevices, deletedAt)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Device
+	for rows.Next() {
+		var i Device
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Brand,
+			&i.State,
+			&i.CreatedAt,
+			&i.Version,
+			&i.DeletedAt,
+			&i.TenantID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const restoreDevice = `-- name: RestoreDevice :execrows
+UPDATE devices
+SET deleted_at = NULL,
+    version = version + 1
+WHERE id = $1 AND version = $2 AND tenant_id = $3 AND deleted_at IS NOT NULL
+`
+
+type RestoreDeviceParams struct {
+	ID       string
+	Version  int64
+	TenantID string
+}
+
+func (q *Queries) RestoreDevice(ctx context.Context, arg RestoreDeviceParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, restoreDevice, arg.ID, arg.Version, arg.TenantID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
 }
 
-const updateDevice = `-- name: UpdateDevice :exec
+const updateDevice = `-- name: UpdateDevice :execrows
 UPDATE devices
 SET name = $1,
     brand = $2,
-    state = $3
-WHERE id = $4
+    state = $3,
+    version = version + 1
+WHERE id = $4 AND version = $5 AND tenant_id = $6 AND deleted_at IS NULL
 `
 
 type UpdateDeviceParams struct {
-	Name  string
-	Brand string
-	State string
-	ID    string
+	Name     string
+	Brand    string
+	State    string
+	ID       string
+	Version  int64
+	TenantID string
 }
 
-func (q *Queries) UpdateDevice(ctx context.Context, arg UpdateDeviceParams) error {
-	_, err := q.db.ExecContext(ctx, updateDevice,
+func (q *Queries) UpdateDevice(ctx context.Context, arg UpdateDeviceParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, updateDevice,
 		arg.Name,
 		arg.Brand,
 		arg.State,
 		arg.ID,
+		arg.Version,
+		arg.TenantID,
 	)
-	return err
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
 }
@@ -0,0 +1,83 @@
+package graphqlserver
+
+import (
+	"context"
+	"errors"
+
+	"github.com/raulsilva-tech/devices-api/internal/domain"
+	"github.com/raulsilva-tech/devices-api/internal/service"
+)
+
+// Error codes sent in the extensions of the GraphQL errors, so clients can branch on them
+// instead of parsing the messages
+const (
+	CodeNotFound          = "NOT_FOUND"
+	CodeInvalidInput      = "INVALID_INPUT"
+	CodeVersionMismatch   = "VERSION_MISMATCH"
+	CodeDeviceInUse       = "DEVICE_IN_USE"
+	CodeIllegalTransition = "ILLEGAL_TRANSITION"
+	CodeForbidden         = "FORBIDDEN"
+	CodeInternal          = "INTERNAL"
+)
+
+// Error is a resolver error carrying its code in the extensions
+type Error struct {
+	Code string
+	Err  error
+}
+
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+func (e *Error) Extensions() map[string]any {
+	return map[string]any{
+		"code": e.Code,
+	}
+}
+
+// deviceError maps the service errors to the codes matching the HTTP statuses of the REST API
+func deviceError(err error) error {
+
+	switch {
+	case errors.Is(err, domain.ErrPermissionDenied):
+		return &Error{Code: CodeForbidden, Err: err}
+	case errors.Is(err, service.ErrDeviceNotFound):
+		return &Error{Code: CodeNotFound, Err: err}
+	case errors.Is(err, domain.ErrVersionMismatch):
+		return &Error{Code: CodeVersionMismatch, Err: err}
+	case errors.Is(err, domain.ErrDeleteDeviceInUse):
+		return &Error{Code: CodeDeviceInUse, Err: err}
+	case errors.Is(err, domain.ErrIllegalTransition):
+		return &Error{Code: CodeIllegalTransition, Err: err}
+	case errors.Is(err, domain.ErrInvalidState),
+		errors.Is(err, domain.ErrStateIsRequired),
+		errors.Is(err, domain.ErrNameIsRequired),
+		errors.Is(err, domain.ErrBrandIsRequired),
+		errors.Is(err, domain.ErrInvalidID),
+		errors.Is(err, domain.ErrInvalidSortField),
+		errors.Is(err, domain.ErrInvalidSortOrder),
+		errors.Is(err, domain.ErrInvalidLimit),
+		errors.Is(err, domain.ErrInvalidOffset),
+		errors.Is(err, domain.ErrInvalidDateRange),
+		errors.Is(err, domain.ErrInvalidCursor),
+		errors.Is(err, domain.ErrCursorSort),
+		errors.Is(err, domain.ErrCursorWithOffset):
+		return &Error{Code: CodeInvalidInput, Err: err}
+	}
+
+	return &Error{Code: CodeInternal, Err: err}
+}
+
+// requireScope guards the mutations, the endpoint itself only requires the read scope
+func requireScope(ctx context.Context, scope domain.Scope) error {
+	principal := domain.PrincipalFromContext(ctx)
+	if principal == nil || !principal.HasScope(scope) {
+		return &Error{Code: CodeForbidden, Err: errors.New("missing the " + string(scope) + " scope")}
+	}
+	return nil
+}
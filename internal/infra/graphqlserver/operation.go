@@ -0,0 +1,206 @@
+package graphqlserver
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+)
+
+// maxBodyBytes bounds the GraphQL requests, read whole to find the operation they run
+const maxBodyBytes = 1 << 20
+
+// ByOperation serves the queries with query and everything else with mutation, so the mutations
+// go through the same write rate limit and Idempotency-Key handling as the REST writes. A request
+// whose operation cannot be told is treated as a mutation, the schema rejects it afterwards.
+func ByOperation(query, mutation http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBodyBytes))
+		if err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				http.Error(w, "request body too large", http.StatusRequestEntityTooLarge)
+				return
+			}
+			http.Error(w, "cannot read the request body", http.StatusBadRequest)
+			return
+		}
+		r.Body.Close()
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		var params struct {
+			Query         string `json:"query"`
+			OperationName string `json:"operationName"`
+		}
+		if json.Unmarshal(body, &params) == nil {
+			if operation, ok := operationType(params.Query, params.OperationName); ok && operation == "query" {
+				query(w, r)
+				return
+			}
+		}
+
+		mutation(w, r)
+	}
+}
+
+// operationType returns the type of the operation a document runs: "query", "mutation" or
+// "subscription". Only the top level of the document is read, the schema validates the rest.
+// It reports false when the document cannot be read or the operation to run is ambiguous.
+func operationType(document, operationName string) (string, bool) {
+
+	type operation struct{ kind, name string }
+
+	var (
+		operations []operation
+		kind, name string
+		expectName bool
+		depth      int
+	)
+
+	l := lexer{src: document}
+	for {
+		token, ok := l.next()
+		if !ok {
+			return "", false
+		}
+		if token == "" {
+			break
+		}
+
+		switch token {
+		case "{":
+			if depth == 0 {
+				if kind == "" {
+					kind = "query" // the shorthand of an anonymous query
+				}
+				if kind != "fragment" {
+					operations = append(operations, operation{kind, name})
+				}
+				kind, name = "", ""
+			}
+			depth++
+		case "(", "[":
+			depth++
+		case "}", ")", "]":
+			if depth--; depth < 0 {
+				return "", false
+			}
+		default:
+			if depth == 0 && isNameStart(token[0]) {
+				switch {
+				case kind == "":
+					if token != "query" && token != "mutation" && token != "subscription" && token != "fragment" {
+						return "", false
+					}
+					kind = token
+					expectName = true
+					continue
+				case expectName:
+					name = token
+				}
+			}
+		}
+		expectName = false
+	}
+
+	if depth != 0 || kind != "" {
+		return "", false
+	}
+
+	for _, op := range operations {
+		if operationName != "" && op.name == operationName {
+			return op.kind, true
+		}
+	}
+	if operationName == "" && len(operations) == 1 {
+		return operations[0].kind, true
+	}
+	return "", false
+}
+
+// lexer splits a GraphQL document into the tokens operationType needs: names, brackets and the
+// other punctuators one character at a time. A string is a single token, so the brackets it
+// holds are not counted, and comments are skipped.
+type lexer struct {
+	src string
+	pos int
+}
+
+// next returns the next token, an empty one at the end of the document, or false on an
+// unterminated string
+func (l *lexer) next() (string, bool) {
+
+	for l.pos < len(l.src) {
+		c := l.src[l.pos]
+		switch {
+		case c == '#':
+			for l.pos < len(l.src) && l.src[l.pos] != '\n' && l.src[l.pos] != '\r' {
+				l.pos++
+			}
+		case c == '"':
+			if !l.skipString() {
+				return "", false
+			}
+			return `"`, true
+		case c == '{' || c == '}' || c == '(' || c == ')' || c == '[' || c == ']':
+			l.pos++
+			return string(c), true
+		case isNameStart(c):
+			start := l.pos
+			for l.pos < len(l.src) && (isNameStart(l.src[l.pos]) || (l.src[l.pos] >= '0' && l.src[l.pos] <= '9')) {
+				l.pos++
+			}
+			return l.src[start:l.pos], true
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == ',':
+			l.pos++
+		default:
+			// $, @, :, =, !, ..., |, & and the digits of numbers
+			l.pos++
+			return string(c), true
+		}
+	}
+
+	return "", true
+}
+
+// skipString moves past a string or a block string starting at the current position
+func (l *lexer) skipString() bool {
+
+	if len(l.src)-l.pos >= 3 && l.src[l.pos:l.pos+3] == `"""` {
+		l.pos += 3
+		for l.pos < len(l.src) {
+			switch {
+			case len(l.src)-l.pos >= 4 && l.src[l.pos:l.pos+4] == `\"""`:
+				l.pos += 4
+			case len(l.src)-l.pos >= 3 && l.src[l.pos:l.pos+3] == `"""`:
+				l.pos += 3
+				return true
+			default:
+				l.pos++
+			}
+		}
+		return false
+	}
+
+	l.pos++
+	for l.pos < len(l.src) {
+		switch l.src[l.pos] {
+		case '\\':
+			l.pos += 2
+		case '"':
+			l.pos++
+			return true
+		case '\n', '\r':
+			return false
+		default:
+			l.pos++
+		}
+	}
+	return false
+}
+
+func isNameStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
@@ -0,0 +1,80 @@
+package graphqlserver
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestOperationType(t *testing.T) {
+
+	tests := []struct {
+		name          string
+		document      string
+		operationName string
+		kind          string
+		ok            bool
+	}{
+		{"shorthand query", `{ devices { items { id } } }`, "", "query", true},
+		{"named query", `query List($limit: Int = 10) { devices(limit: $limit) { total } }`, "", "query", true},
+		{"mutation", `mutation { deleteDevice(id: "1") }`, "", "mutation", true},
+		{"mutation with directives and variables", `mutation Rename($input: UpdateDeviceInput! = {name: "}"}) @trace { updateDevice(id: "1", input: $input) { updatedFields } }`, "", "mutation", true},
+		{"comment hiding a mutation keyword", "# mutation\n{ device(id: \"1\") { id } }", "", "query", true},
+		{"string holding brackets", `{ device(id: "{ } ) mutation") { id } }`, "", "query", true},
+		{"block string", `query { devices(name: """ "{" \""" """) { total } }`, "", "query", true},
+		{"fragment before a mutation", `fragment F on Device { id } mutation M { createDevice(input: {name: "a", brand: "b"}) { ...F } }`, "", "mutation", true},
+		{"operation picked by name", `query Q { devices { total } } mutation M { deleteDevice(id: "1") }`, "M", "mutation", true},
+		{"query picked by name", `query Q { devices { total } } mutation M { deleteDevice(id: "1") }`, "Q", "query", true},
+		{"several operations without a name", `query Q { devices { total } } mutation M { deleteDevice(id: "1") }`, "", "", false},
+		{"unknown operation name", `query Q { devices { total } }`, "M", "", false},
+		{"unbalanced brackets", `{ devices { total }`, "", "", false},
+		{"unterminated string", `{ device(id: "1) { id } }`, "", "", false},
+		{"not an operation", `type Device { id: ID }`, "", "", false},
+		{"empty", ``, "", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kind, ok := operationType(tt.document, tt.operationName)
+			require.Equal(t, tt.ok, ok)
+			require.Equal(t, tt.kind, kind)
+		})
+	}
+}
+
+func TestByOperation(t *testing.T) {
+
+	var served string
+	serve := func(name string) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			body, _ := io.ReadAll(r.Body)
+			require.NotEmpty(t, body, "the body is handed on")
+			served = name
+		}
+	}
+	handler := ByOperation(serve("query"), serve("mutation"))
+
+	post := func(body string) string {
+		served = ""
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/graphql", bytes.NewBufferString(body)))
+		return served
+	}
+	request := func(query, operationName string) string {
+		body, err := json.Marshal(map[string]any{"query": query, "operationName": operationName})
+		require.NoError(t, err)
+		return string(body)
+	}
+
+	require.Equal(t, "query", post(request(`{ devices { total } }`, "")))
+	require.Equal(t, "mutation", post(request(`mutation { deleteDevice(id: "1") }`, "")))
+	require.Equal(t, "mutation", post(request(`query Q { devices { total } } mutation M { deleteDevice(id: "1") }`, "M")))
+
+	// what cannot be told apart gets the limits of a mutation
+	require.Equal(t, "mutation", post(request(`query Q { devices { total } } mutation M { deleteDevice(id: "1") }`, "")))
+	require.Equal(t, "mutation", post(`not json`))
+}
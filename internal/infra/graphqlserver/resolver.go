@@ -0,0 +1,288 @@
+package graphqlserver
+
+import (
+	"context"
+	_ "embed"
+	"net/http"
+
+	graphql "github.com/graph-gophers/graphql-go"
+	"github.com/graph-gophers/graphql-go/relay"
+	"github.com/raulsilva-tech/devices-api/internal/domain"
+	"github.com/raulsilva-tech/devices-api/internal/service"
+)
+
+//go:embed schema.graphql
+var schema string
+
+// maxDepth stops queries nested deeper than the schema allows from being parsed at all
+const maxDepth = 5
+
+// Resolver resolves the queries and mutations through the same service the REST handlers use
+type Resolver struct {
+	Service *service.DeviceService
+}
+
+func NewResolver(svc *service.DeviceService) *Resolver {
+	return &Resolver{
+		Service: svc,
+	}
+}
+
+// NewHandler serves the GraphQL requests, sent as POST with a JSON body holding the query
+func NewHandler(svc *service.DeviceService) http.Handler {
+	return &relay.Handler{
+		Schema: graphql.MustParseSchema(schema, NewResolver(svc), graphql.UseStringDescriptions(), graphql.MaxDepth(maxDepth)),
+	}
+}
+
+type deviceArgs struct {
+	ID graphql.ID
+}
+
+func (r *Resolver) Device(ctx context.Context, args deviceArgs) (*deviceResolver, error) {
+
+	device, err := r.Service.GetDeviceById(ctx, string(args.ID))
+	if err != nil {
+		return nil, deviceError(err)
+	}
+
+	return &deviceResolver{device: *device}, nil
+}
+
+type deviceFilterInput struct {
+	Brand          *string
+	State          *string
+	Name           *string
+	CreatedFrom    *graphql.Time
+	CreatedTo      *graphql.Time
+	IncludeDeleted *bool
+}
+
+type devicesArgs struct {
+	Filter *deviceFilterInput
+	Sort   *string
+	Order  *string
+	Limit  *int32
+	Offset *int32
+	Cursor *string
+}
+
+func (r *Resolver) Devices(ctx context.Context, args devicesArgs) (*deviceListResolver, error) {
+
+	input := service.ListDevicesInput{
+		SortBy: value(args.Sort),
+		Order:  value(args.Order),
+		Limit:  int(value(args.Limit)),
+		Offset: int(value(args.Offset)),
+		Cursor: value(args.Cursor),
+	}
+	if filter := args.Filter; filter != nil {
+		input.Brand = value(filter.Brand)
+		input.State = domain.DeviceState(value(filter.State))
+		input.Name = value(filter.Name)
+		input.IncludeDeleted = value(filter.IncludeDeleted)
+		if filter.CreatedFrom != nil {
+			input.CreatedFrom = filter.CreatedFrom.Time
+		}
+		if filter.CreatedTo != nil {
+			input.CreatedTo = filter.CreatedTo.Time
+		}
+	}
+
+	output, err := r.Service.GetDevices(ctx, input)
+	if err != nil {
+		return nil, deviceError(err)
+	}
+
+	return &deviceListResolver{output: *output}, nil
+}
+
+type createDeviceArgs struct {
+	Input struct {
+		Name  string
+		Brand string
+		State string
+	}
+}
+
+func (r *Resolver) CreateDevice(ctx context.Context, args createDeviceArgs) (*deviceResolver, error) {
+
+	if err := requireScope(ctx, domain.ScopeDevicesWrite); err != nil {
+		return nil, err
+	}
+
+	id, err := r.Service.CreateDevice(ctx, service.CreateDeviceInput{
+		Name:  args.Input.Name,
+		Brand: args.Input.Brand,
+		State: domain.DeviceState(args.Input.State),
+	})
+	if err != nil {
+		return nil, deviceError(err)
+	}
+
+	device, err := r.Service.GetDeviceById(ctx, id)
+	if err != nil {
+		return nil, deviceError(err)
+	}
+
+	return &deviceResolver{device: *device}, nil
+}
+
+type updateDeviceArgs struct {
+	ID    graphql.ID
+	Input struct {
+		Name  *string
+		Brand *string
+		State *string
+	}
+	ExpectedVersion *int32
+}
+
+func (r *Resolver) UpdateDevice(ctx context.Context, args updateDeviceArgs) (*updateDevicePayloadResolver, error) {
+
+	if err := requireScope(ctx, domain.ScopeDevicesWrite); err != nil {
+		return nil, err
+	}
+
+	input := service.UpdateDeviceInput{
+		ID:              string(args.ID),
+		Name:            args.Input.Name,
+		Brand:           args.Input.Brand,
+		ExpectedVersion: version(args.ExpectedVersion),
+	}
+	if args.Input.State != nil {
+		state := domain.DeviceState(*args.Input.State)
+		input.State = &state
+	}
+
+	output, err := r.Service.UpdateDevice(ctx, input)
+	if err != nil {
+		return nil, deviceError(err)
+	}
+
+	return &updateDevicePayloadResolver{output: *output}, nil
+}
+
+type deleteDeviceArgs struct {
+	ID              graphql.ID
+	ExpectedVersion *int32
+}
+
+func (r *Resolver) DeleteDevice(ctx context.Context, args deleteDeviceArgs) (graphql.ID, error) {
+
+	if err := requireScope(ctx, domain.ScopeDevicesWrite); err != nil {
+		return "", err
+	}
+
+	err := r.Service.DeleteDevice(ctx, service.DeleteDeviceInput{
+		ID:              string(args.ID),
+		ExpectedVersion: version(args.ExpectedVersion),
+	})
+	if err != nil {
+		return "", deviceError(err)
+	}
+
+	return args.ID, nil
+}
+
+type deviceResolver struct {
+	device service.DeviceOutput
+}
+
+func (r *deviceResolver) ID() graphql.ID {
+	return graphql.ID(r.device.ID)
+}
+
+func (r *deviceResolver) Name() string {
+	return r.device.Name
+}
+
+func (r *deviceResolver) Brand() string {
+	return r.device.Brand
+}
+
+func (r *deviceResolver) State() string {
+	return string(r.device.State)
+}
+
+func (r *deviceResolver) CreatedAt() graphql.Time {
+	return graphql.Time{Time: r.device.CreatedAt}
+}
+
+func (r *deviceResolver) Version() int32 {
+	return int32(r.device.Version)
+}
+
+func (r *deviceResolver) DeletedAt() *graphql.Time {
+	if r.device.DeletedAt.IsZero() {
+		return nil
+	}
+	return &graphql.Time{Time: r.device.DeletedAt}
+}
+
+type deviceListResolver struct {
+	output service.ListDevicesOutput
+}
+
+func (r *deviceListResolver) Items() []*deviceResolver {
+	items := make([]*deviceResolver, len(r.output.Devices))
+	for i, device := range r.output.Devices {
+		items[i] = &deviceResolver{device: device}
+	}
+	return items
+}
+
+func (r *deviceListResolver) Total() *int32 {
+	if r.output.Total == nil {
+		return nil
+	}
+	total := int32(*r.output.Total)
+	return &total
+}
+
+func (r *deviceListResolver) Limit() int32 {
+	return int32(r.output.Limit)
+}
+
+func (r *deviceListResolver) Offset() int32 {
+	return int32(r.output.Offset)
+}
+
+func (r *deviceListResolver) NextCursor() *string {
+	if r.output.NextCursor == "" {
+		return nil
+	}
+	return &r.output.NextCursor
+}
+
+type updateDevicePayloadResolver struct {
+	output service.UpdateDeviceOutput
+}
+
+func (r *updateDevicePayloadResolver) Device() *deviceResolver {
+	return &deviceResolver{device: r.output.Device}
+}
+
+func (r *updateDevicePayloadResolver) UpdatedFields() []string {
+	return r.output.UpdatedFields
+}
+
+func (r *updateDevicePayloadResolver) IgnoredFields() []string {
+	return r.output.IgnoredFields
+}
+
+func value[T any](p *T) T {
+	var zero T
+	if p == nil {
+		return zero
+	}
+	return *p
+}
+
+func version(v *int32) *int64 {
+	if v == nil {
+		return nil
+	}
+	version := int64(*v)
+	return &version
+}
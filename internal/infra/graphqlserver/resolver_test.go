@@ -0,0 +1,146 @@
+package graphqlserver
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/raulsilva-tech/devices-api/internal/domain"
+	"github.com/raulsilva-tech/devices-api/internal/infra/rbac"
+	"github.com/raulsilva-tech/devices-api/internal/service"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryDevices keeps the devices in memory, the methods the tests do not use panic through the nil interface
+type memoryDevices struct {
+	domain.DeviceRepository
+
+	mu      sync.Mutex
+	devices map[string]domain.Device
+}
+
+func (m *memoryDevices) CreateDevice(ctx context.Context, device *domain.Device) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.devices[device.ID] = *device
+	return device.ID, nil
+}
+
+func (m *memoryDevices) UpdateDevice(ctx context.Context, device *domain.Device) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	device.Version++
+	m.devices[device.ID] = *device
+	return nil
+}
+
+func (m *memoryDevices) GetDeviceById(ctx context.Context, id string) (*domain.Device, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	device, ok := m.devices[id]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	return &device, nil
+}
+
+type graphqlResponse struct {
+	Data   map[string]json.RawMessage `json:"data"`
+	Errors []struct {
+		Message    string         `json:"message"`
+		Path       []any          `json:"path"`
+		Extensions map[string]any `json:"extensions"`
+	} `json:"errors"`
+}
+
+var (
+	writer   = &domain.APIKey{ID: "1", Name: "writer", Scopes: []domain.Scope{domain.ScopeDevicesRead, domain.ScopeDevicesWrite}, Roles: []domain.Role{domain.RoleAdmin}}
+	reader   = &domain.APIKey{ID: "2", Name: "reader", Scopes: []domain.Scope{domain.ScopeDevicesRead}, Roles: []domain.Role{domain.RoleViewer}}
+	operator = &domain.APIKey{ID: "3", Name: "operator", Scopes: []domain.Scope{domain.ScopeDevicesRead, domain.ScopeDevicesWrite}, Roles: []domain.Role{domain.RoleOperator}}
+)
+
+// execute runs the query as if authenticated with the key
+func execute(t *testing.T, handler http.Handler, key *domain.APIKey, query string, variables map[string]any) graphqlResponse {
+
+	body, err := json.Marshal(map[string]any{"query": query, "variables": variables})
+	require.NoError(t, err)
+
+	req := httptest.NewRequest(http.MethodPost, "/graphql", bytes.NewReader(body))
+	req = req.WithContext(domain.WithPrincipal(req.Context(), key.Principal()))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var resp graphqlResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	return resp
+}
+
+func TestGraphQL_CreateUpdateQuery(t *testing.T) {
+
+	handler := NewHandler(service.NewDeviceService(&memoryDevices{devices: map[string]domain.Device{}}, nil))
+
+	resp := execute(t, handler, writer, `mutation($input: CreateDeviceInput!) {
+		createDevice(input: $input) { id state version }
+	}`, map[string]any{"input": map[string]any{"name": "iPhone", "brand": "Apple", "state": "available"}})
+	require.Empty(t, resp.Errors)
+
+	var created struct {
+		ID      string `json:"id"`
+		State   string `json:"state"`
+		Version int    `json:"version"`
+	}
+	require.NoError(t, json.Unmarshal(resp.Data["createDevice"], &created))
+	require.Equal(t, "available", created.State)
+
+	resp = execute(t, handler, writer, `mutation($id: ID!, $version: Int) {
+		updateDevice(id: $id, input: {name: "iPhone 15"}, expectedVersion: $version) { updatedFields device { name } }
+	}`, map[string]any{"id": created.ID, "version": created.Version})
+	require.Empty(t, resp.Errors)
+	require.JSONEq(t, `{"updatedFields":["name"],"device":{"name":"iPhone 15"}}`, string(resp.Data["updateDevice"]))
+
+	// only the requested fields are returned
+	resp = execute(t, handler, writer, `query($id: ID!) { device(id: $id) { name deletedAt } }`, map[string]any{"id": created.ID})
+	require.Empty(t, resp.Errors)
+	require.JSONEq(t, `{"name":"iPhone 15","deletedAt":null}`, string(resp.Data["device"]))
+}
+
+func TestGraphQL_TypedErrors(t *testing.T) {
+
+	device, err := domain.NewDevice(uuid.New().String(), "iPhone", "Apple", domain.DeviceInUse, time.Now())
+	require.NoError(t, err)
+	policy, err := rbac.LoadPolicy("")
+	require.NoError(t, err)
+	handler := NewHandler(service.NewDeviceService(&memoryDevices{devices: map[string]domain.Device{device.ID: *device}}, policy))
+
+	tests := []struct {
+		name  string
+		key   *domain.APIKey
+		query string
+		code  string
+	}{
+		{"not found", writer, `{ device(id: "` + uuid.New().String() + `") { id } }`, CodeNotFound},
+		{"delete in use", writer, `mutation { deleteDevice(id: "` + device.ID + `") }`, CodeDeviceInUse},
+		{"version mismatch", writer, `mutation { deleteDevice(id: "` + device.ID + `", expectedVersion: 7) }`, CodeVersionMismatch},
+		{"invalid state", writer, `mutation { createDevice(input: {name: "Pixel", brand: "Google", state: "broken"}) { id } }`, CodeInvalidInput},
+		{"invalid filter", writer, `{ devices(sort: "color") { total } }`, CodeInvalidInput},
+		{"missing scope", reader, `mutation { deleteDevice(id: "` + device.ID + `") }`, CodeForbidden},
+		{"missing permission", operator, `mutation { deleteDevice(id: "` + device.ID + `") }`, CodeForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := execute(t, handler, tt.key, tt.query, nil)
+			require.Len(t, resp.Errors, 1)
+			require.Equal(t, tt.code, resp.Errors[0].Extensions["code"])
+		})
+	}
+}
@@ -0,0 +1,126 @@
+package grpcserver
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	devicev1 "github.com/raulsilva-tech/devices-api/api/device/v1"
+	"github.com/raulsilva-tech/devices-api/internal/domain"
+	"github.com/raulsilva-tech/devices-api/internal/infra/jwtauth"
+	"github.com/raulsilva-tech/devices-api/internal/service"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	apiKeyMetadata = "x-api-key"
+	// tenantMetadata is the counterpart of the X-Tenant-ID header
+	tenantMetadata = "x-tenant-id"
+)
+
+// Authenticator finds the API key matching a secret, service.APIKeyService implements it
+type Authenticator interface {
+	Authenticate(ctx context.Context, secret string) (*domain.APIKey, error)
+}
+
+// TokenVerifier validates a bearer token, jwtauth.Verifier implements it
+type TokenVerifier interface {
+	Verify(ctx context.Context, token string) (*domain.Principal, error)
+}
+
+// methodScopes is the scope each RPC requires, the methods not listed require the admin scope
+var methodScopes = map[string]domain.Scope{
+	devicev1.DeviceService_CreateDevice_FullMethodName: domain.ScopeDevicesWrite,
+	devicev1.DeviceService_UpdateDevice_FullMethodName: domain.ScopeDevicesWrite,
+	devicev1.DeviceService_DeleteDevice_FullMethodName: domain.ScopeDevicesWrite,
+	devicev1.DeviceService_GetDevice_FullMethodName:    domain.ScopeDevicesRead,
+	devicev1.DeviceService_ListDevices_FullMethodName:  domain.ScopeDevicesRead,
+	devicev1.DeviceService_WatchDevices_FullMethodName: domain.ScopeDevicesRead,
+}
+
+// AuthUnary is the counterpart of middleware.JWTAuth, middleware.APIKeyAuth and middleware.RequireScope.
+// A JWT or an API key is sent in the authorization metadata as "Bearer <token>", an API key also in
+// x-api-key. The tenant is resolved as middleware.Tenant does, from x-tenant-id. verifier is nil when
+// JWTs are not accepted.
+func AuthUnary(auth Authenticator, verifier TokenVerifier) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+
+		ctx, err := authenticate(ctx, auth, verifier, info.FullMethod)
+		if err != nil {
+			return nil, err
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+func AuthStream(auth Authenticator, verifier TokenVerifier) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+
+		ctx, err := authenticate(ss.Context(), auth, verifier, info.FullMethod)
+		if err != nil {
+			return err
+		}
+
+		return handler(srv, &contextStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+func authenticate(ctx context.Context, auth Authenticator, verifier TokenVerifier, method string) (context.Context, error) {
+
+	principal, err := principalFor(ctx, auth, verifier)
+	if err != nil {
+		return nil, err
+	}
+
+	scope, ok := methodScopes[method]
+	if !ok {
+		scope = domain.ScopeAdmin
+	}
+	if !principal.HasScope(scope) {
+		return nil, status.Error(codes.PermissionDenied, "missing the "+string(scope)+" scope")
+	}
+
+	tenant, err := domain.ResolveTenant(principal, firstMetadata(ctx, tenantMetadata))
+	if err != nil {
+		if errors.Is(err, domain.ErrTenantForbidden) {
+			return nil, status.Error(codes.PermissionDenied, err.Error())
+		}
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	return domain.WithTenant(domain.WithPrincipal(ctx, principal), tenant), nil
+}
+
+func principalFor(ctx context.Context, auth Authenticator, verifier TokenVerifier) (*domain.Principal, error) {
+
+	token, _ := strings.CutPrefix(firstMetadata(ctx, "authorization"), "Bearer ")
+	token = strings.TrimSpace(token)
+
+	if verifier != nil && jwtauth.IsJWT(token) {
+		principal, err := verifier.Verify(ctx, token)
+		if err != nil {
+			if errors.Is(err, jwtauth.ErrInvalidToken) {
+				return nil, status.Error(codes.Unauthenticated, err.Error())
+			}
+			return nil, status.Error(codes.Unavailable, err.Error())
+		}
+		return principal, nil
+	}
+
+	if token == "" {
+		token = firstMetadata(ctx, apiKeyMetadata)
+	}
+
+	key, err := auth.Authenticate(ctx, token)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidAPIKey) {
+			return nil, status.Error(codes.Unauthenticated, err.Error())
+		}
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	return key.Principal(), nil
+}
@@ -0,0 +1,43 @@
+package grpcserver
+
+import (
+	"errors"
+
+	"github.com/raulsilva-tech/devices-api/internal/domain"
+	"github.com/raulsilva-tech/devices-api/internal/service"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// deviceError maps the service errors to the gRPC codes matching the HTTP statuses of the REST API
+func deviceError(err error) error {
+
+	switch {
+	case errors.Is(err, domain.ErrPermissionDenied):
+		return status.Error(codes.PermissionDenied, err.Error())
+	case errors.Is(err, service.ErrDeviceNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, domain.ErrVersionMismatch):
+		return status.Error(codes.Aborted, "device version does not match expected_version")
+	case errors.Is(err, domain.ErrDeleteDeviceInUse):
+		return status.Error(codes.FailedPrecondition, "device is in use and cannot be deleted")
+	case errors.Is(err, domain.ErrIllegalTransition):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	case errors.Is(err, domain.ErrInvalidState),
+		errors.Is(err, domain.ErrStateIsRequired),
+		errors.Is(err, domain.ErrNameIsRequired),
+		errors.Is(err, domain.ErrBrandIsRequired),
+		errors.Is(err, domain.ErrInvalidID),
+		errors.Is(err, domain.ErrInvalidSortField),
+		errors.Is(err, domain.ErrInvalidSortOrder),
+		errors.Is(err, domain.ErrInvalidLimit),
+		errors.Is(err, domain.ErrInvalidOffset),
+		errors.Is(err, domain.ErrInvalidDateRange),
+		errors.Is(err, domain.ErrInvalidCursor),
+		errors.Is(err, domain.ErrCursorSort),
+		errors.Is(err, domain.ErrCursorWithOffset):
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	return status.Error(codes.Internal, err.Error())
+}
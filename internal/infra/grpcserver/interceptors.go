@@ -0,0 +1,144 @@
+package grpcserver
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/raulsilva-tech/devices-api/internal/domain"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// The interceptors mirror the HTTP middleware chain and put the request ID and whom the caller
+// acts for on the context with the same domain helpers, so the device history records gRPC
+// changes the same way.
+
+const (
+	requestIDMetadata  = "x-request-id"
+	onBehalfOfMetadata = "x-on-behalf-of"
+)
+
+// RecoverUnary turns a panic in a handler into an INTERNAL error
+func RecoverUnary(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp any, err error) {
+
+	defer func() {
+		if rec := recover(); rec != nil {
+			log.Printf("[PANIC] requestID=%v err=%v", domain.RequestIDFromContext(ctx), rec)
+			err = status.Error(codes.Internal, "internal server error")
+		}
+	}()
+
+	return handler(ctx, req)
+}
+
+func RecoverStream(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+
+	defer func() {
+		if rec := recover(); rec != nil {
+			log.Printf("[PANIC] requestID=%v err=%v", domain.RequestIDFromContext(ss.Context()), rec)
+			err = status.Error(codes.Internal, "internal server error")
+		}
+	}()
+
+	return handler(srv, ss)
+}
+
+// RequestIDUnary takes the request ID from the x-request-id metadata, or generates one,
+// and sends it back in the response header
+func RequestIDUnary(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	return handler(withRequestID(ctx), req)
+}
+
+func RequestIDStream(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	return handler(srv, &contextStream{ServerStream: ss, ctx: withRequestID(ss.Context())})
+}
+
+func withRequestID(ctx context.Context) context.Context {
+
+	id := firstMetadata(ctx, requestIDMetadata)
+	if id == "" {
+		id = uuid.New().String()
+	}
+
+	// fails only outside of an RPC
+	_ = grpc.SetHeader(ctx, metadata.Pairs(requestIDMetadata, id))
+
+	return domain.WithRequestID(ctx, id)
+}
+
+// OnBehalfOfUnary places whom the caller says it acts for, sent in the x-on-behalf-of metadata, on the context
+func OnBehalfOfUnary(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	return handler(withOnBehalfOf(ctx), req)
+}
+
+func OnBehalfOfStream(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	return handler(srv, &contextStream{ServerStream: ss, ctx: withOnBehalfOf(ss.Context())})
+}
+
+func withOnBehalfOf(ctx context.Context) context.Context {
+
+	onBehalfOf := firstMetadata(ctx, onBehalfOfMetadata)
+	if onBehalfOf == "" {
+		return ctx
+	}
+
+	return domain.WithOnBehalfOf(ctx, onBehalfOf)
+}
+
+// LoggerUnary logs every call like the HTTP logger, with the method and the status code
+func LoggerUnary(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+
+	start := time.Now()
+
+	resp, err := handler(ctx, req)
+
+	log.Printf("[%v] %s %s %v",
+		domain.RequestIDFromContext(ctx),
+		info.FullMethod,
+		status.Code(err),
+		time.Since(start),
+	)
+
+	return resp, err
+}
+
+func LoggerStream(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+
+	start := time.Now()
+
+	err := handler(srv, ss)
+
+	log.Printf("[%v] %s %s %v",
+		domain.RequestIDFromContext(ss.Context()),
+		info.FullMethod,
+		status.Code(err),
+		time.Since(start),
+	)
+
+	return err
+}
+
+func firstMetadata(ctx context.Context, key string) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+	if values := md.Get(key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// contextStream replaces the context of a server stream
+type contextStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *contextStream) Context() context.Context {
+	return s.ctx
+}
@@ -0,0 +1,137 @@
+package grpcserver
+
+import (
+	"context"
+	"log"
+	"math"
+	"net"
+	"strconv"
+
+	"github.com/raulsilva-tech/devices-api/internal/domain"
+	"github.com/raulsilva-tech/devices-api/internal/infra/ratelimit"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// retryAfterMetadata is the counterpart of the Retry-After header, sent in the trailer of the rejected calls
+const retryAfterMetadata = "retry-after"
+
+// RateLimits are the limits of the calls, the same as the HTTP ones and sharing their buckets when they
+// share the store: IP is taken before the authentication, Read or Write after it, by the scope of the
+// method. The zero value disables them.
+type RateLimits struct {
+	Store ratelimit.Store
+	IP    ratelimit.Limit
+	Read  ratelimit.Limit
+	Write ratelimit.Limit
+}
+
+// IPRateLimitUnary is the counterpart of middleware.IPRateLimit, the calls over the limit get
+// RESOURCE_EXHAUSTED before their credentials are looked up
+func IPRateLimitUnary(limits RateLimits) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+
+		if err := take(ctx, limits.Store, "ip:"+peerIP(ctx), limits.IP); err != nil {
+			return nil, err
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+func IPRateLimitStream(limits RateLimits) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+
+		if err := take(ss.Context(), limits.Store, "ip:"+peerIP(ss.Context()), limits.IP); err != nil {
+			return err
+		}
+
+		return handler(srv, ss)
+	}
+}
+
+// RateLimitUnary is the counterpart of middleware.RateLimit, the callers are told apart by their subject.
+// A watch takes a single token when it starts.
+func RateLimitUnary(limits RateLimits) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+
+		if err := takeForMethod(ctx, limits, info.FullMethod); err != nil {
+			return nil, err
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+func RateLimitStream(limits RateLimits) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+
+		if err := takeForMethod(ss.Context(), limits, info.FullMethod); err != nil {
+			return err
+		}
+
+		return handler(srv, ss)
+	}
+}
+
+// takeForMethod takes from the read bucket of the caller for the methods requiring the read scope,
+// and from its write bucket for the others, like the admin routes do over HTTP
+func takeForMethod(ctx context.Context, limits RateLimits, method string) error {
+
+	group, limit := "write", limits.Write
+	if methodScopes[method] == domain.ScopeDevicesRead {
+		group, limit = "read", limits.Read
+	}
+
+	return take(ctx, limits.Store, group+":"+clientKey(ctx), limit)
+}
+
+// take removes a token from the bucket of key. When the store fails the call is let through.
+func take(ctx context.Context, store ratelimit.Store, key string, limit ratelimit.Limit) error {
+
+	if !limit.IsEnabled() {
+		return nil
+	}
+
+	result, err := store.Take(ctx, key, limit)
+	if err != nil {
+		log.Printf("[%v] rate limit: %v", domain.RequestIDFromContext(ctx), err)
+		return nil
+	}
+	if result.Allowed {
+		return nil
+	}
+
+	// fails only outside of an RPC
+	_ = grpc.SetTrailer(ctx, metadata.Pairs(retryAfterMetadata, strconv.Itoa(int(math.Ceil(result.RetryAfter.Seconds())))))
+
+	return status.Error(codes.ResourceExhausted, "rate limit exceeded")
+}
+
+// clientKey mirrors middleware.ClientKey
+func clientKey(ctx context.Context) string {
+
+	if principal := domain.PrincipalFromContext(ctx); principal != nil {
+		return "sub:" + principal.Subject
+	}
+
+	return "ip:" + peerIP(ctx)
+}
+
+// peerIP is the IP the call comes from, without its port
+func peerIP(ctx context.Context) string {
+
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return ""
+	}
+
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		return p.Addr.String()
+	}
+	return host
+}
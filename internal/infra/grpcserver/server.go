@@ -0,0 +1,253 @@
+package grpcserver
+
+import (
+	"context"
+	"time"
+
+	devicev1 "github.com/raulsilva-tech/devices-api/api/device/v1"
+	"github.com/raulsilva-tech/devices-api/internal/domain"
+	"github.com/raulsilva-tech/devices-api/internal/infra/stream"
+	"github.com/raulsilva-tech/devices-api/internal/service"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// DeviceServer implements the gRPC DeviceService on top of the same service the REST handlers use
+type DeviceServer struct {
+	devicev1.UnimplementedDeviceServiceServer
+
+	Service *service.DeviceService
+	Hub     *stream.Hub
+}
+
+func NewDeviceServer(svc *service.DeviceService, hub *stream.Hub) *DeviceServer {
+	return &DeviceServer{
+		Service: svc,
+		Hub:     hub,
+	}
+}
+
+// NewServer creates a gRPC server with the device service registered and the interceptors
+// that mirror the HTTP middleware chain. verifier is nil when JWTs are not accepted.
+func NewServer(devices *DeviceServer, auth Authenticator, verifier TokenVerifier, limits RateLimits) *grpc.Server {
+
+	server := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(RecoverUnary, RequestIDUnary, OnBehalfOfUnary, LoggerUnary,
+			IPRateLimitUnary(limits), AuthUnary(auth, verifier), RateLimitUnary(limits)),
+		grpc.ChainStreamInterceptor(RecoverStream, RequestIDStream, OnBehalfOfStream, LoggerStream,
+			IPRateLimitStream(limits), AuthStream(auth, verifier), RateLimitStream(limits)),
+	)
+	devicev1.RegisterDeviceServiceServer(server, devices)
+
+	return server
+}
+
+func (s *DeviceServer) CreateDevice(ctx context.Context, req *devicev1.CreateDeviceRequest) (*devicev1.CreateDeviceResponse, error) {
+
+	if req.GetName() == "" || req.GetBrand() == "" || req.GetState() == "" {
+		return nil, status.Error(codes.InvalidArgument, "name, brand and state are required")
+	}
+
+	id, err := s.Service.CreateDevice(ctx, service.CreateDeviceInput{
+		Name:  req.GetName(),
+		Brand: req.GetBrand(),
+		State: domain.DeviceState(req.GetState()),
+	})
+	if err != nil {
+		return nil, deviceError(err)
+	}
+
+	return &devicev1.CreateDeviceResponse{Id: id}, nil
+}
+
+func (s *DeviceServer) UpdateDevice(ctx context.Context, req *devicev1.UpdateDeviceRequest) (*devicev1.UpdateDeviceResponse, error) {
+
+	if req.GetId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "id is required")
+	}
+
+	input := service.UpdateDeviceInput{
+		ID:              req.GetId(),
+		Name:            req.Name,
+		Brand:           req.Brand,
+		ExpectedVersion: req.ExpectedVersion,
+	}
+	if req.State != nil {
+		state := domain.DeviceState(req.GetState())
+		input.State = &state
+	}
+
+	output, err := s.Service.UpdateDevice(ctx, input)
+	if err != nil {
+		return nil, deviceError(err)
+	}
+
+	return &devicev1.UpdateDeviceResponse{
+		UpdatedFields: output.UpdatedFields,
+		IgnoredFields: output.IgnoredFields,
+		Device:        mapServiceDeviceToProto(output.Device),
+	}, nil
+}
+
+func (s *DeviceServer) DeleteDevice(ctx context.Context, req *devicev1.DeleteDeviceRequest) (*devicev1.DeleteDeviceResponse, error) {
+
+	if req.GetId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "id is required")
+	}
+
+	err := s.Service.DeleteDevice(ctx, service.DeleteDeviceInput{
+		ID:              req.GetId(),
+		ExpectedVersion: req.ExpectedVersion,
+	})
+	if err != nil {
+		return nil, deviceError(err)
+	}
+
+	return &devicev1.DeleteDeviceResponse{}, nil
+}
+
+func (s *DeviceServer) GetDevice(ctx context.Context, req *devicev1.GetDeviceRequest) (*devicev1.GetDeviceResponse, error) {
+
+	if req.GetId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "id is required")
+	}
+
+	device, err := s.Service.GetDeviceById(ctx, req.GetId())
+	if err != nil {
+		return nil, deviceError(err)
+	}
+
+	return &devicev1.GetDeviceResponse{Device: mapServiceDeviceToProto(*device)}, nil
+}
+
+func (s *DeviceServer) ListDevices(ctx context.Context, req *devicev1.ListDevicesRequest) (*devicev1.ListDevicesResponse, error) {
+
+	input := service.ListDevicesInput{
+		Brand:  req.GetBrand(),
+		State:  domain.DeviceState(req.GetState()),
+		Name:   req.GetName(),
+		SortBy: req.GetSort(),
+		Order:  req.GetOrder(),
+		Limit:  int(req.GetLimit()),
+		Offset: int(req.GetOffset()),
+		Cursor: req.GetCursor(),
+
+		IncludeDeleted: req.GetIncludeDeleted(),
+	}
+	if req.CreatedFrom != nil {
+		input.CreatedFrom = req.GetCreatedFrom().AsTime()
+	}
+	if req.CreatedTo != nil {
+		input.CreatedTo = req.GetCreatedTo().AsTime()
+	}
+
+	output, err := s.Service.GetDevices(ctx, input)
+	if err != nil {
+		return nil, deviceError(err)
+	}
+
+	devices := make([]*devicev1.Device, len(output.Devices))
+	for i, device := range output.Devices {
+		devices[i] = mapServiceDeviceToProto(device)
+	}
+
+	return &devicev1.ListDevicesResponse{
+		Devices:    devices,
+		Total:      output.Total,
+		Limit:      int32(output.Limit),
+		Offset:     int32(output.Offset),
+		NextCursor: output.NextCursor,
+	}, nil
+}
+
+// WatchDevices follows the same rules as GET /devices/stream: last_event_id resumes from the
+// replay buffer, and a watcher that falls too far behind is disconnected with UNAVAILABLE
+func (s *DeviceServer) WatchDevices(req *devicev1.WatchDevicesRequest, srv grpc.ServerStreamingServer[devicev1.DeviceEvent]) error {
+
+	// the events do not go through the service, so its policy is checked here
+	if err := s.Service.Authorize(srv.Context(), domain.PermissionDevicesRead); err != nil {
+		return deviceError(err)
+	}
+
+	brand := req.GetBrand()
+	state := domain.DeviceState(req.GetState())
+	if state != "" && !state.IsValid() {
+		return status.Error(codes.InvalidArgument, domain.ErrInvalidState.Error())
+	}
+
+	// the hub carries the events of every tenant
+	tenant := domain.TenantFromContext(srv.Context())
+	matches := func(event domain.Event) bool {
+		return event.Device.TenantID == tenant &&
+			(brand == "" || event.Device.Brand == brand) && (state == "" || event.Device.State == state)
+	}
+
+	sub, replay := s.Hub.Subscribe(req.GetLastEventId())
+	defer sub.Close()
+
+	for _, event := range replay {
+		if !matches(event) {
+			continue
+		}
+		if err := srv.Send(mapDomainEventToProto(event)); err != nil {
+			return err
+		}
+	}
+
+	for {
+		select {
+		case <-srv.Context().Done():
+			return nil
+
+		case event, ok := <-sub.Events:
+			if !ok {
+				return status.Error(codes.Unavailable, "watch closed, resume with the last event id")
+			}
+			if !matches(event) {
+				continue
+			}
+			if err := srv.Send(mapDomainEventToProto(event)); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func mapServiceDeviceToProto(device service.DeviceOutput) *devicev1.Device {
+	return &devicev1.Device{
+		Id:        device.ID,
+		Name:      device.Name,
+		Brand:     device.Brand,
+		State:     string(device.State),
+		CreatedAt: timestamppb.New(device.CreatedAt),
+		Version:   device.Version,
+		DeletedAt: optionalTimestamp(device.DeletedAt),
+	}
+}
+
+func mapDomainEventToProto(event domain.Event) *devicev1.DeviceEvent {
+	return &devicev1.DeviceEvent{
+		Id:            event.ID,
+		Type:          string(event.Type),
+		OccurredAt:    timestamppb.New(event.OccurredAt),
+		PreviousState: string(event.PreviousState),
+		Device: &devicev1.Device{
+			Id:        event.Device.ID,
+			Name:      event.Device.Name,
+			Brand:     event.Device.Brand,
+			State:     string(event.Device.State),
+			CreatedAt: timestamppb.New(event.Device.CreatedAt),
+			Version:   event.Device.Version,
+			DeletedAt: optionalTimestamp(event.Device.DeletedAt),
+		},
+	}
+}
+
+func optionalTimestamp(t time.Time) *timestamppb.Timestamp {
+	if t.IsZero() {
+		return nil
+	}
+	return timestamppb.New(t)
+}
@@ -0,0 +1,292 @@
+package grpcserver
+
+import (
+	"context"
+	"database/sql"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	devicev1 "github.com/raulsilva-tech/devices-api/api/device/v1"
+	"github.com/raulsilva-tech/devices-api/internal/domain"
+	"github.com/raulsilva-tech/devices-api/internal/infra/ratelimit"
+	"github.com/raulsilva-tech/devices-api/internal/infra/rbac"
+	"github.com/raulsilva-tech/devices-api/internal/infra/stream"
+	"github.com/raulsilva-tech/devices-api/internal/service"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// memoryDevices keeps the devices in memory, the methods the tests do not use panic through the nil interface
+type memoryDevices struct {
+	domain.DeviceRepository
+
+	mu      sync.Mutex
+	devices map[string]domain.Device
+	lastCtx context.Context
+}
+
+func (m *memoryDevices) CreateDevice(ctx context.Context, device *domain.Device) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.devices[device.ID] = *device
+	m.lastCtx = ctx
+	return device.ID, nil
+}
+
+func (m *memoryDevices) UpdateDevice(ctx context.Context, device *domain.Device) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	device.Version++
+	m.devices[device.ID] = *device
+	return nil
+}
+
+func (m *memoryDevices) GetDeviceById(ctx context.Context, id string) (*domain.Device, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	device, ok := m.devices[id]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	return &device, nil
+}
+
+type staticAuthenticator map[string]*domain.APIKey
+
+func (a staticAuthenticator) Authenticate(ctx context.Context, secret string) (*domain.APIKey, error) {
+	if key, ok := a[secret]; ok {
+		return key, nil
+	}
+	return nil, service.ErrInvalidAPIKey
+}
+
+var testKeys = staticAuthenticator{
+	"dak_writer":   {ID: "1", Name: "writer", Scopes: []domain.Scope{domain.ScopeDevicesRead, domain.ScopeDevicesWrite}, Roles: []domain.Role{domain.RoleAdmin}},
+	"dak_reader":   {ID: "2", Name: "reader", Scopes: []domain.Scope{domain.ScopeDevicesRead}, Roles: []domain.Role{domain.RoleViewer}},
+	"dak_operator": {ID: "3", Name: "operator", Scopes: []domain.Scope{domain.ScopeDevicesRead, domain.ScopeDevicesWrite}, Roles: []domain.Role{domain.RoleOperator}},
+	"dak_acme":     {ID: "4", Name: "acme", Scopes: []domain.Scope{domain.ScopeDevicesRead}, Roles: []domain.Role{domain.RoleViewer}, TenantID: "acme"},
+}
+
+// withKey authenticates the calls made with the context
+func withKey(ctx context.Context, secret string) context.Context {
+	return metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+secret)
+}
+
+func newTestClient(t *testing.T, repo domain.DeviceRepository, hub *stream.Hub) devicev1.DeviceServiceClient {
+	return newLimitedTestClient(t, repo, hub, RateLimits{})
+}
+
+func newLimitedTestClient(t *testing.T, repo domain.DeviceRepository, hub *stream.Hub, limits RateLimits) devicev1.DeviceServiceClient {
+
+	policy, err := rbac.LoadPolicy("")
+	require.NoError(t, err)
+
+	listener := bufconn.Listen(1 << 20)
+	server := NewServer(NewDeviceServer(service.NewDeviceService(repo, policy), hub), testKeys, nil, limits)
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	return devicev1.NewDeviceServiceClient(conn)
+}
+
+func TestDeviceServer_CreateUpdateGet(t *testing.T) {
+
+	repo := &memoryDevices{devices: map[string]domain.Device{}}
+	client := newTestClient(t, repo, stream.NewHub(10))
+
+	ctx := metadata.AppendToOutgoingContext(withKey(context.Background(), "dak_writer"), "x-request-id", "req-1", "x-on-behalf-of", "alice")
+
+	var header metadata.MD
+	created, err := client.CreateDevice(ctx, &devicev1.CreateDeviceRequest{Name: "iPhone", Brand: "Apple", State: "available"}, grpc.Header(&header))
+	require.NoError(t, err)
+	require.Equal(t, []string{"req-1"}, header.Get("x-request-id"))
+
+	// the repository sees the same context values as with the HTTP middleware
+	require.Equal(t, "req-1", domain.RequestIDFromContext(repo.lastCtx))
+	require.Equal(t, "apikey:1", domain.ActorFromContext(repo.lastCtx))
+	require.Equal(t, "alice", domain.OnBehalfOfFromContext(repo.lastCtx))
+
+	name := "iPhone 15"
+	updated, err := client.UpdateDevice(ctx, &devicev1.UpdateDeviceRequest{Id: created.GetId(), Name: &name})
+	require.NoError(t, err)
+	require.Equal(t, []string{"name"}, updated.GetUpdatedFields())
+
+	got, err := client.GetDevice(ctx, &devicev1.GetDeviceRequest{Id: created.GetId()})
+	require.NoError(t, err)
+	require.Equal(t, "iPhone 15", got.GetDevice().GetName())
+	require.Equal(t, updated.GetDevice().GetVersion(), got.GetDevice().GetVersion())
+	require.Nil(t, got.GetDevice().GetDeletedAt())
+}
+
+func TestDeviceServer_ErrorCodes(t *testing.T) {
+
+	device, err := domain.NewDevice(uuid.New().String(), "iPhone", "Apple", domain.DeviceInUse, time.Now())
+	require.NoError(t, err)
+	repo := &memoryDevices{devices: map[string]domain.Device{device.ID: *device}}
+	client := newTestClient(t, repo, stream.NewHub(10))
+
+	ctx := withKey(context.Background(), "dak_writer")
+	stale := int64(7)
+
+	tests := []struct {
+		name string
+		call func() error
+		code codes.Code
+	}{
+		{"missing fields", func() error {
+			_, err := client.CreateDevice(ctx, &devicev1.CreateDeviceRequest{Name: "iPhone"})
+			return err
+		}, codes.InvalidArgument},
+		{"invalid state", func() error {
+			_, err := client.CreateDevice(ctx, &devicev1.CreateDeviceRequest{Name: "iPhone", Brand: "Apple", State: "broken"})
+			return err
+		}, codes.InvalidArgument},
+		{"not found", func() error {
+			_, err := client.GetDevice(ctx, &devicev1.GetDeviceRequest{Id: uuid.New().String()})
+			return err
+		}, codes.NotFound},
+		{"version mismatch", func() error {
+			_, err := client.DeleteDevice(ctx, &devicev1.DeleteDeviceRequest{Id: device.ID, ExpectedVersion: &stale})
+			return err
+		}, codes.Aborted},
+		{"delete in use", func() error {
+			_, err := client.DeleteDevice(ctx, &devicev1.DeleteDeviceRequest{Id: device.ID})
+			return err
+		}, codes.FailedPrecondition},
+		{"invalid filter", func() error {
+			_, err := client.ListDevices(ctx, &devicev1.ListDevicesRequest{Sort: "color"})
+			return err
+		}, codes.InvalidArgument},
+		{"missing api key", func() error {
+			_, err := client.GetDevice(context.Background(), &devicev1.GetDeviceRequest{Id: device.ID})
+			return err
+		}, codes.Unauthenticated},
+		{"missing scope", func() error {
+			_, err := client.DeleteDevice(withKey(context.Background(), "dak_reader"), &devicev1.DeleteDeviceRequest{Id: device.ID})
+			return err
+		}, codes.PermissionDenied},
+		{"missing permission", func() error {
+			_, err := client.DeleteDevice(withKey(context.Background(), "dak_operator"), &devicev1.DeleteDeviceRequest{Id: device.ID})
+			return err
+		}, codes.PermissionDenied},
+		{"invalid tenant", func() error {
+			ctx := metadata.AppendToOutgoingContext(withKey(context.Background(), "dak_reader"), "x-tenant-id", "Acme Corp")
+			_, err := client.GetDevice(ctx, &devicev1.GetDeviceRequest{Id: device.ID})
+			return err
+		}, codes.InvalidArgument},
+		{"tenant of another key", func() error {
+			ctx := metadata.AppendToOutgoingContext(withKey(context.Background(), "dak_acme"), "x-tenant-id", "globex")
+			_, err := client.GetDevice(ctx, &devicev1.GetDeviceRequest{Id: device.ID})
+			return err
+		}, codes.PermissionDenied},
+		{"tenant picked without the admin scope", func() error {
+			ctx := metadata.AppendToOutgoingContext(withKey(context.Background(), "dak_reader"), "x-tenant-id", "acme")
+			_, err := client.GetDevice(ctx, &devicev1.GetDeviceRequest{Id: device.ID})
+			return err
+		}, codes.PermissionDenied},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.code, status.Code(tt.call()))
+		})
+	}
+}
+
+func TestDeviceServer_WatchDevices(t *testing.T) {
+
+	hub := stream.NewHub(10)
+	client := newTestClient(t, &memoryDevices{devices: map[string]domain.Device{}}, hub)
+
+	newEvent := func(brand string) domain.Event {
+		device, err := domain.NewDevice(uuid.New().String(), "Device", brand, domain.DeviceAvailable, time.Now())
+		require.NoError(t, err)
+		device.TenantID = domain.DefaultTenant
+		return domain.NewEvent(domain.EventDeviceCreated, *device)
+	}
+
+	ctx, cancel := context.WithTimeout(withKey(context.Background(), "dak_reader"), 5*time.Second)
+	defer cancel()
+
+	seen := newEvent("Apple")
+	missed := newEvent("Apple")
+	require.NoError(t, hub.Publish(ctx, seen))
+	require.NoError(t, hub.Publish(ctx, newEvent("Samsung")))
+	require.NoError(t, hub.Publish(ctx, missed))
+
+	watch, err := client.WatchDevices(ctx, &devicev1.WatchDevicesRequest{Brand: "Apple", LastEventId: seen.ID})
+	require.NoError(t, err)
+
+	// the event published after last_event_id is replayed, the other brand is filtered out
+	event, err := watch.Recv()
+	require.NoError(t, err)
+	require.Equal(t, missed.ID, event.GetId())
+	require.Equal(t, "device.created", event.GetType())
+	require.Equal(t, missed.Device.ID, event.GetDevice().GetId())
+
+	// the subscription is open once the replay was sent, so live events follow, except the ones of other tenants
+	otherTenant := newEvent("Apple")
+	otherTenant.Device.TenantID = "globex"
+	live := newEvent("Apple")
+	require.NoError(t, hub.Publish(ctx, otherTenant))
+	require.NoError(t, hub.Publish(ctx, live))
+	event, err = watch.Recv()
+	require.NoError(t, err)
+	require.Equal(t, live.ID, event.GetId())
+}
+
+func TestRecoverUnary(t *testing.T) {
+
+	ctx := domain.WithRequestID(context.Background(), "req-1")
+
+	_, err := RecoverUnary(ctx, nil, &grpc.UnaryServerInfo{FullMethod: "/device.v1.DeviceService/GetDevice"}, func(ctx context.Context, req any) (any, error) {
+		panic("boom")
+	})
+
+	require.Equal(t, codes.Internal, status.Code(err))
+}
+
+func TestRateLimit(t *testing.T) {
+
+	repo := &memoryDevices{devices: map[string]domain.Device{}}
+	limits := RateLimits{
+		Store: ratelimit.NewMemoryStore(),
+		IP:    ratelimit.Limit{Requests: 60, Per: time.Minute, Burst: 4},
+		Read:  ratelimit.Limit{Requests: 60, Per: time.Minute, Burst: 1},
+		Write: ratelimit.Limit{Requests: 60, Per: time.Minute, Burst: 1},
+	}
+	client := newLimitedTestClient(t, repo, stream.NewHub(10), limits)
+	ctx := withKey(context.Background(), "dak_writer")
+
+	// each caller has a bucket for the reads and another for the writes
+	_, err := client.GetDevice(ctx, &devicev1.GetDeviceRequest{Id: "missing"})
+	require.Equal(t, codes.NotFound, status.Code(err))
+	var trailer metadata.MD
+	_, err = client.GetDevice(ctx, &devicev1.GetDeviceRequest{Id: "missing"}, grpc.Trailer(&trailer))
+	require.Equal(t, codes.ResourceExhausted, status.Code(err))
+	require.Equal(t, []string{"1"}, trailer.Get("retry-after"))
+
+	_, err = client.CreateDevice(ctx, &devicev1.CreateDeviceRequest{Name: "iPhone", Brand: "Apple", State: "available"})
+	require.NoError(t, err)
+
+	// the bad keys take from the bucket of the IP before they are looked up
+	_, err = client.GetDevice(withKey(context.Background(), "dak_wrong"), &devicev1.GetDeviceRequest{Id: "missing"})
+	require.Equal(t, codes.Unauthenticated, status.Code(err))
+	_, err = client.GetDevice(withKey(context.Background(), "dak_wrong"), &devicev1.GetDeviceRequest{Id: "missing"})
+	require.Equal(t, codes.ResourceExhausted, status.Code(err))
+}
@@ -0,0 +1,205 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/raulsilva-tech/devices-api/internal/domain"
+	"github.com/raulsilva-tech/devices-api/internal/dto"
+	"github.com/raulsilva-tech/devices-api/internal/service"
+)
+
+type APIKeyHandler struct {
+	Service *service.APIKeyService
+}
+
+func NewAPIKeyHandler(svc *service.APIKeyService) *APIKeyHandler {
+	return &APIKeyHandler{
+		Service: svc,
+	}
+}
+
+// CreateAPIKey godoc
+// @Summary Issue an API key
+// @Description Issues an API key with the given scopes and roles, optionally bound to a tenant. A caller bound to a tenant can only issue keys of its tenant. The key is only returned in this response, send it as "Authorization: Bearer <key>" or "X-API-Key: <key>". Requires the admin scope.
+// @Tags API Keys
+// @Accept json
+// @Produce json
+// @Param request body dto.APIKeyRequest true "API key payload"
+// @Param Idempotency-Key header string false "Key that makes the retries of the request replay its first response"
+// @Success 201 {object} dto.APIKeyResponse
+// @Failure 400 {object} dto.ErrorResponse
+// @Failure 401 {object} dto.ErrorResponse
+// @Failure 403 {object} dto.ErrorResponse
+// @Failure 409 {object} dto.ErrorResponse
+// @Failure 422 {object} dto.ErrorResponse
+// @Failure 429 {object} dto.ErrorResponse
+// @Failure 500 {object} dto.ErrorResponse
+// @Security ApiKeyAuth
+// @Router /api-keys [post]
+func (h *APIKeyHandler) CreateAPIKey(w http.ResponseWriter, r *http.Request) {
+
+	var reqBody dto.APIKeyRequest
+	if err := json.NewDecoder(r.Body).Decode(&reqBody); err != nil {
+		writeJSONError(w, http.StatusBadRequest, "invalid JSON body")
+		return
+	}
+	defer r.Body.Close()
+
+	input := service.CreateAPIKeyInput{
+		Name:   reqBody.Name,
+		Scopes: make([]domain.Scope, len(reqBody.Scopes)),
+		Roles:  make([]domain.Role, len(reqBody.Roles)),
+
+		TenantID: reqBody.TenantID,
+	}
+	for i, s := range reqBody.Scopes {
+		input.Scopes[i] = domain.Scope(s)
+	}
+	for i, r := range reqBody.Roles {
+		input.Roles[i] = domain.Role(r)
+	}
+	if reqBody.ExpiresAt != nil {
+		input.ExpiresAt = *reqBody.ExpiresAt
+	}
+
+	key, err := h.Service.CreateAPIKey(r.Context(), input)
+	if err != nil {
+		switch {
+		case errors.Is(err, domain.ErrNameIsRequired),
+			errors.Is(err, domain.ErrScopeIsRequired),
+			errors.Is(err, domain.ErrInvalidScope),
+			errors.Is(err, domain.ErrRoleIsRequired),
+			errors.Is(err, domain.ErrInvalidRole),
+			errors.Is(err, domain.ErrInvalidExpiry),
+			errors.Is(err, domain.ErrInvalidTenant):
+			writeJSONError(w, http.StatusBadRequest, err.Error())
+		case errors.Is(err, domain.ErrTenantForbidden):
+			writeJSONError(w, http.StatusForbidden, err.Error())
+		default:
+			writeJSONError(w, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, mapServiceAPIKeyToDTO(*key))
+}
+
+// GetAPIKeys godoc
+// @Summary List API keys
+// @Description Returns every API key, including the revoked ones, without the keys themselves. A caller bound to a tenant only sees the keys of its tenant. Requires the admin scope.
+// @Tags API Keys
+// @Produce json
+// @Success 200 {array} dto.APIKeyResponse
+// @Failure 401 {object} dto.ErrorResponse
+// @Failure 403 {object} dto.ErrorResponse
+// @Failure 429 {object} dto.ErrorResponse
+// @Failure 500 {object} dto.ErrorResponse
+// @Security ApiKeyAuth
+// @Router /api-keys [get]
+func (h *APIKeyHandler) GetAPIKeys(w http.ResponseWriter, r *http.Request) {
+
+	keys, err := h.Service.GetAPIKeys(r.Context())
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	resultList := make([]dto.APIKeyResponse, len(keys))
+	for i, key := range keys {
+		resultList[i] = mapServiceAPIKeyToDTO(key)
+	}
+
+	writeJSON(w, http.StatusOK, resultList)
+}
+
+// RevokeAPIKey godoc
+// @Summary Revoke an API key
+// @Description Disables the key for good. Revoked keys stay listed. Requires the admin scope.
+// @Tags API Keys
+// @Param id path string true "API key ID"
+// @Param Idempotency-Key header string false "Key that makes the retries of the request replay its first response"
+// @Success 204
+// @Failure 401 {object} dto.ErrorResponse
+// @Failure 403 {object} dto.ErrorResponse
+// @Failure 404 {object} dto.ErrorResponse
+// @Failure 409 {object} dto.ErrorResponse
+// @Failure 422 {object} dto.ErrorResponse
+// @Failure 429 {object} dto.ErrorResponse
+// @Failure 500 {object} dto.ErrorResponse
+// @Security ApiKeyAuth
+// @Router /api-keys/{id} [delete]
+func (h *APIKeyHandler) RevokeAPIKey(w http.ResponseWriter, r *http.Request) {
+
+	if err := h.Service.RevokeAPIKey(r.Context(), r.PathValue("id")); err != nil {
+		switch {
+		case errors.Is(err, service.ErrAPIKeyNotFound):
+			writeJSONError(w, http.StatusNotFound, err.Error())
+		default:
+			writeJSONError(w, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RotateAPIKey godoc
+// @Summary Rotate an API key
+// @Description Replaces the key with a new one keeping its name, scopes and expiry. The previous key stops working at once. Requires the admin scope.
+// @Tags API Keys
+// @Produce json
+// @Param id path string true "API key ID"
+// @Param Idempotency-Key header string false "Key that makes the retries of the request replay its first response"
+// @Success 200 {object} dto.APIKeyResponse
+// @Failure 401 {object} dto.ErrorResponse
+// @Failure 403 {object} dto.ErrorResponse
+// @Failure 404 {object} dto.ErrorResponse
+// @Failure 409 {object} dto.ErrorResponse
+// @Failure 422 {object} dto.ErrorResponse
+// @Failure 429 {object} dto.ErrorResponse
+// @Failure 500 {object} dto.ErrorResponse
+// @Security ApiKeyAuth
+// @Router /api-keys/{id}/rotate [post]
+func (h *APIKeyHandler) RotateAPIKey(w http.ResponseWriter, r *http.Request) {
+
+	key, err := h.Service.RotateAPIKey(r.Context(), r.PathValue("id"))
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrAPIKeyNotFound):
+			writeJSONError(w, http.StatusNotFound, err.Error())
+		default:
+			writeJSONError(w, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	writeJSON(w, http.StatusOK, mapServiceAPIKeyToDTO(*key))
+}
+
+func mapServiceAPIKeyToDTO(key service.APIKeyOutput) dto.APIKeyResponse {
+
+	scopes := make([]string, len(key.Scopes))
+	for i, s := range key.Scopes {
+		scopes[i] = string(s)
+	}
+
+	roles := make([]string, len(key.Roles))
+	for i, r := range key.Roles {
+		roles[i] = string(r)
+	}
+
+	return dto.APIKeyResponse{
+		ID:        key.ID,
+		Name:      key.Name,
+		Prefix:    key.Prefix,
+		Key:       key.Key,
+		Scopes:    scopes,
+		Roles:     roles,
+		TenantID:  key.TenantID,
+		ExpiresAt: optionalTime(key.ExpiresAt),
+		CreatedAt: key.CreatedAt,
+		RevokedAt: optionalTime(key.RevokedAt),
+	}
+}
@@ -0,0 +1,287 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/raulsilva-tech/devices-api/internal/domain"
+	"github.com/raulsilva-tech/devices-api/internal/dto"
+	"github.com/raulsilva-tech/devices-api/internal/service"
+)
+
+type AssignmentHandler struct {
+	Service *service.AssignmentService
+}
+
+func NewAssignmentHandler(svc *service.AssignmentService) *AssignmentHandler {
+	return &AssignmentHandler{
+		Service: svc,
+	}
+}
+
+// CheckOut godoc
+// @Summary Check out a device
+// @Description Hands an available device to an assignee. The device goes in use and the assignment is recorded in the same transaction.
+// @Tags Assignments
+// @Accept json
+// @Produce json
+// @Param id path string true "Device ID"
+// @Param request body dto.CheckOutRequest true "Checkout payload"
+// @Param If-Match header string false "ETag of the device version the checkout is based on"
+// @Param Idempotency-Key header string false "Key that makes the retries of the request replay its first response"
+// @Success 201 {object} dto.CheckOutResponse
+// @Header 201 {string} ETag "Version of the updated device"
+// @Failure 400 {object} dto.ErrorResponse
+// @Failure 403 {object} dto.ErrorResponse
+// @Failure 404 {object} dto.ErrorResponse
+// @Failure 409 {object} dto.ErrorResponse
+// @Failure 412 {object} dto.ErrorResponse
+// @Failure 422 {object} dto.ErrorResponse
+// @Failure 429 {object} dto.ErrorResponse
+// @Failure 500 {object} dto.ErrorResponse
+// @Security ApiKeyAuth
+// @Router /devices/{id}/checkout [post]
+func (h *AssignmentHandler) CheckOut(w http.ResponseWriter, r *http.Request) {
+
+	id := r.PathValue("id")
+	if id == "" {
+		writeJSONError(w, http.StatusBadRequest, "id is required")
+		return
+	}
+
+	var reqBody dto.CheckOutRequest
+	if err := json.NewDecoder(r.Body).Decode(&reqBody); err != nil {
+		writeJSONError(w, http.StatusBadRequest, "invalid JSON body")
+		return
+	}
+	defer r.Body.Close()
+
+	if reqBody.Assignee == "" {
+		writeJSONError(w, http.StatusBadRequest, "assignee is required")
+		return
+	}
+
+	expectedVersion, err := parseIfMatch(r)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	input := service.CheckOutInput{
+		DeviceID:        id,
+		Assignee:        reqBody.Assignee,
+		Notes:           reqBody.Notes,
+		ExpectedVersion: expectedVersion,
+	}
+	if reqBody.ExpectedReturnAt != nil {
+		input.ExpectedReturnAt = *reqBody.ExpectedReturnAt
+	}
+
+	output, err := h.Service.CheckOut(r.Context(), input)
+	if err != nil {
+		writeAssignmentError(w, err, expectedVersion != nil)
+		return
+	}
+
+	w.Header().Set("ETag", formatETag(output.Device.Version))
+	writeJSON(w, http.StatusCreated, dto.CheckOutResponse{
+		Device:     mapServiceDeviceToDTO(output.Device),
+		Assignment: mapServiceAssignmentToDTO(output.Assignment),
+	})
+}
+
+// CheckIn godoc
+// @Summary Check in a device
+// @Description Returns a device in use. It becomes available again and its open assignment is closed in the same transaction.
+// @Tags Assignments
+// @Produce json
+// @Param id path string true "Device ID"
+// @Param If-Match header string false "ETag of the device version the check-in is based on"
+// @Param Idempotency-Key header string false "Key that makes the retries of the request replay its first response"
+// @Success 200 {object} dto.CheckInResponse
+// @Header 200 {string} ETag "Version of the updated device"
+// @Failure 400 {object} dto.ErrorResponse
+// @Failure 403 {object} dto.ErrorResponse
+// @Failure 404 {object} dto.ErrorResponse
+// @Failure 409 {object} dto.ErrorResponse
+// @Failure 412 {object} dto.ErrorResponse
+// @Failure 422 {object} dto.ErrorResponse
+// @Failure 429 {object} dto.ErrorResponse
+// @Failure 500 {object} dto.ErrorResponse
+// @Security ApiKeyAuth
+// @Router /devices/{id}/checkin [post]
+func (h *AssignmentHandler) CheckIn(w http.ResponseWriter, r *http.Request) {
+
+	id := r.PathValue("id")
+	if id == "" {
+		writeJSONError(w, http.StatusBadRequest, "id is required")
+		return
+	}
+
+	expectedVersion, err := parseIfMatch(r)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	output, err := h.Service.CheckIn(r.Context(), service.CheckInInput{
+		DeviceID:        id,
+		ExpectedVersion: expectedVersion,
+	})
+	if err != nil {
+		writeAssignmentError(w, err, expectedVersion != nil)
+		return
+	}
+
+	w.Header().Set("ETag", formatETag(output.Device.Version))
+	writeJSON(w, http.StatusOK, dto.CheckInResponse{
+		Device:     mapServiceDeviceToDTO(output.Device),
+		Assignment: mapServiceAssignmentToDTO(output.Assignment),
+	})
+}
+
+// GetAssignments godoc
+// @Summary List the assignments of a device
+// @Description Returns who held the device and when, most recent first
+// @Tags Assignments
+// @Produce json
+// @Param id path string true "Device ID"
+// @Param limit query int false "Page size (default 20, max 100)"
+// @Param offset query int false "Number of assignments to skip"
+// @Success 200 {array} dto.AssignmentResponse
+// @Failure 400 {object} dto.ErrorResponse
+// @Failure 403 {object} dto.ErrorResponse
+// @Failure 404 {object} dto.ErrorResponse
+// @Failure 429 {object} dto.ErrorResponse
+// @Failure 500 {object} dto.ErrorResponse
+// @Security ApiKeyAuth
+// @Router /devices/{id}/assignments [get]
+func (h *AssignmentHandler) GetAssignments(w http.ResponseWriter, r *http.Request) {
+
+	id := r.PathValue("id")
+	if id == "" {
+		writeJSONError(w, http.StatusBadRequest, "id is required")
+		return
+	}
+
+	limit, offset, err := parsePage(r)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	assignments, err := h.Service.GetAssignments(r.Context(), id, limit, offset)
+	if err != nil {
+		writeAssignmentError(w, err, false)
+		return
+	}
+
+	resultList := make([]dto.AssignmentResponse, len(assignments))
+	for i, assignment := range assignments {
+		resultList[i] = mapServiceAssignmentToDTO(assignment)
+	}
+
+	writeJSON(w, http.StatusOK, resultList)
+}
+
+// GetDevicesHeldBy godoc
+// @Summary List the devices held by an assignee
+// @Description Returns the devices currently checked out to the assignee, with their open assignment
+// @Tags Assignments
+// @Produce json
+// @Param assignee path string true "Assignee identifier"
+// @Success 200 {array} dto.HeldDeviceResponse
+// @Failure 400 {object} dto.ErrorResponse
+// @Failure 403 {object} dto.ErrorResponse
+// @Failure 429 {object} dto.ErrorResponse
+// @Failure 500 {object} dto.ErrorResponse
+// @Security ApiKeyAuth
+// @Router /assignees/{assignee}/devices [get]
+func (h *AssignmentHandler) GetDevicesHeldBy(w http.ResponseWriter, r *http.Request) {
+
+	assignee := r.PathValue("assignee")
+	if assignee == "" {
+		writeJSONError(w, http.StatusBadRequest, "assignee is required")
+		return
+	}
+
+	held, err := h.Service.GetDevicesHeldBy(r.Context(), assignee)
+	if err != nil {
+		writeAssignmentError(w, err, false)
+		return
+	}
+
+	resultList := make([]dto.HeldDeviceResponse, len(held))
+	for i, h := range held {
+		resultList[i] = dto.HeldDeviceResponse{
+			Device:     mapServiceDeviceToDTO(h.Device),
+			Assignment: mapServiceAssignmentToDTO(h.Assignment),
+		}
+	}
+
+	writeJSON(w, http.StatusOK, resultList)
+}
+
+func writeAssignmentError(w http.ResponseWriter, err error, ifMatch bool) {
+
+	switch {
+	case errors.Is(err, domain.ErrPermissionDenied):
+		writeJSONError(w, http.StatusForbidden, err.Error())
+	case errors.Is(err, service.ErrDeviceNotFound):
+		writeJSONError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, domain.ErrIllegalTransition):
+		writeJSONError(w, http.StatusConflict, err.Error())
+	case errors.Is(err, domain.ErrVersionMismatch):
+		writeVersionMismatch(w, ifMatch)
+	case errors.Is(err, domain.ErrAssigneeIsRequired),
+		errors.Is(err, domain.ErrInvalidExpectedReturn),
+		errors.Is(err, domain.ErrInvalidLimit),
+		errors.Is(err, domain.ErrInvalidOffset):
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+	default:
+		writeJSONError(w, http.StatusInternalServerError, err.Error())
+	}
+}
+
+// parsePage reads the limit and offset query params, leaving them at zero when absent
+func parsePage(r *http.Request) (int, int, error) {
+
+	var limit, offset int
+	var err error
+
+	if v := r.URL.Query().Get("limit"); v != "" {
+		if limit, err = strconv.Atoi(v); err != nil || limit <= 0 {
+			return 0, 0, errors.New("limit " + v + " is invalid")
+		}
+	}
+
+	if v := r.URL.Query().Get("offset"); v != "" {
+		if offset, err = strconv.Atoi(v); err != nil || offset < 0 {
+			return 0, 0, errors.New("offset " + v + " is invalid")
+		}
+	}
+
+	return limit, offset, nil
+}
+
+func mapServiceAssignmentToDTO(a service.AssignmentOutput) dto.AssignmentResponse {
+	return dto.AssignmentResponse{
+		ID:               a.ID,
+		DeviceID:         a.DeviceID,
+		Assignee:         a.Assignee,
+		CheckedOutAt:     a.CheckedOutAt,
+		ExpectedReturnAt: optionalTime(a.ExpectedReturnAt),
+		ReturnedAt:       optionalTime(a.ReturnedAt),
+		Notes:            a.Notes,
+	}
+}
+
+func optionalTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
@@ -0,0 +1,210 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/raulsilva-tech/devices-api/internal/domain"
+	"github.com/raulsilva-tech/devices-api/internal/dto"
+	"github.com/raulsilva-tech/devices-api/internal/service"
+)
+
+const (
+	batchModeAtomic  = "atomic"
+	batchModePartial = "partial"
+)
+
+// BatchDevices godoc
+// @Summary Run several device operations at once
+// @Description Runs a list of create, update (PUT semantics) and delete operations in order. Each result carries the status and body the single call would have returned.
+// @Description In atomic mode (default) the operations share one transaction and the first failure rolls back all of them; the others report 424 Failed Dependency.
+// @Description In partial mode each operation succeeds or fails on its own.
+// @Tags Devices
+// @Accept json
+// @Produce json
+// @Param request body dto.BatchRequest true "Batch payload"
+// @Param Idempotency-Key header string false "Key that makes the retries of the request replay its first response"
+// @Success 200 {object} dto.BatchResponse
+// @Failure 400 {object} dto.ErrorResponse
+// @Failure 409 {object} dto.ErrorResponse
+// @Failure 422 {object} dto.ErrorResponse
+// @Failure 429 {object} dto.ErrorResponse
+// @Failure 500 {object} dto.ErrorResponse
+// @Security ApiKeyAuth
+// @Router /devices:batch [post]
+func (h *DeviceHandler) BatchDevices(w http.ResponseWriter, r *http.Request) {
+
+	var reqBody dto.BatchRequest
+	if err := json.NewDecoder(r.Body).Decode(&reqBody); err != nil {
+		writeJSONError(w, http.StatusBadRequest, "invalid JSON body")
+		return
+	}
+	defer r.Body.Close()
+
+	if reqBody.Mode == "" {
+		reqBody.Mode = batchModeAtomic
+	}
+	if reqBody.Mode != batchModeAtomic && reqBody.Mode != batchModePartial {
+		writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("mode %s is invalid", reqBody.Mode))
+		return
+	}
+	if len(reqBody.Operations) == 0 {
+		writeJSONError(w, http.StatusBadRequest, service.ErrEmptyBatch.Error())
+		return
+	}
+	if len(reqBody.Operations) > service.MaxBatchOperations {
+		writeJSONError(w, http.StatusBadRequest, service.ErrTooManyBatchOperations.Error())
+		return
+	}
+
+	atomic := reqBody.Mode == batchModeAtomic
+	results := make([]dto.BatchOperationResponse, len(reqBody.Operations))
+
+	// operations rejected before reaching the service, as the single handlers would
+	var ops []service.BatchOperation
+	var positions []int
+	invalid := false
+
+	for i, opReq := range reqBody.Operations {
+		op, msg := parseBatchOperation(opReq)
+		if msg != "" {
+			results[i] = errorResult(http.StatusBadRequest, msg)
+			invalid = true
+			continue
+		}
+		ops = append(ops, op)
+		positions = append(positions, i)
+	}
+
+	if atomic && invalid {
+		for _, i := range positions {
+			results[i] = errorResult(deviceErrorResponse(service.ErrBatchRolledBack, false))
+		}
+		writeJSON(w, http.StatusOK, dto.BatchResponse{Mode: reqBody.Mode, Results: results})
+		return
+	}
+
+	if len(ops) > 0 {
+		batchResults, err := h.Service.BatchDevices(r.Context(), ops, atomic)
+		if err != nil {
+			writeJSONError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		for j, result := range batchResults {
+			i := positions[j]
+			results[i] = mapBatchResultToDTO(ops[j], reqBody.Operations[i].Version != nil, result)
+		}
+	}
+
+	writeJSON(w, http.StatusOK, dto.BatchResponse{Mode: reqBody.Mode, Results: results})
+}
+
+// parseBatchOperation validates an operation like the matching single handler, returning a message when it is rejected
+func parseBatchOperation(opReq dto.BatchOperationRequest) (service.BatchOperation, string) {
+
+	switch service.BatchOperationType(opReq.Op) {
+
+	case service.BatchCreate:
+		if opReq.Device == nil || opReq.Device.Name == "" || opReq.Device.Brand == "" || opReq.Device.State == "" {
+			return service.BatchOperation{}, "name, brand and state are required"
+		}
+		return service.BatchOperation{
+			Type: service.BatchCreate,
+			Create: service.CreateDeviceInput{
+				Name:  opReq.Device.Name,
+				Brand: opReq.Device.Brand,
+				State: domain.DeviceState(opReq.Device.State),
+			},
+		}, ""
+
+	case service.BatchUpdate:
+		if opReq.ID == "" || opReq.Device == nil || opReq.Device.Name == "" || opReq.Device.Brand == "" || opReq.Device.State == "" {
+			return service.BatchOperation{}, "id, name, brand and state are required"
+		}
+		state := domain.DeviceState(opReq.Device.State)
+		return service.BatchOperation{
+			Type: service.BatchUpdate,
+			Update: service.UpdateDeviceInput{
+				ID:              opReq.ID,
+				Name:            &opReq.Device.Name,
+				Brand:           &opReq.Device.Brand,
+				State:           &state,
+				ExpectedVersion: opReq.Version,
+			},
+		}, ""
+
+	case service.BatchDelete:
+		if opReq.ID == "" {
+			return service.BatchOperation{}, "id is required"
+		}
+		return service.BatchOperation{
+			Type: service.BatchDelete,
+			Delete: service.DeleteDeviceInput{
+				ID:              opReq.ID,
+				ExpectedVersion: opReq.Version,
+			},
+		}, ""
+	}
+
+	return service.BatchOperation{}, fmt.Sprintf("op %s is invalid", opReq.Op)
+}
+
+func mapBatchResultToDTO(op service.BatchOperation, ifMatch bool, result service.BatchResult) dto.BatchOperationResponse {
+
+	if result.Err != nil {
+		return errorResult(deviceErrorResponse(result.Err, ifMatch))
+	}
+
+	switch op.Type {
+	case service.BatchCreate:
+		return dto.BatchOperationResponse{
+			Status: http.StatusCreated,
+			Body:   dto.CreateDeviceResponse{ID: result.CreatedID},
+		}
+	case service.BatchUpdate:
+		return dto.BatchOperationResponse{
+			Status: http.StatusOK,
+			ETag:   formatETag(result.Updated.Device.Version),
+			Body:   mapUpdateOutputToDTO(*result.Updated),
+		}
+	}
+
+	return dto.BatchOperationResponse{Status: http.StatusNoContent}
+}
+
+func errorResult(status int, msg string) dto.BatchOperationResponse {
+	return dto.BatchOperationResponse{
+		Status: status,
+		Body:   dto.ErrorResponse{Error: msg},
+	}
+}
+
+// deviceErrorResponse maps a service error to the status and message the single device handlers answer with
+func deviceErrorResponse(err error, ifMatch bool) (int, string) {
+
+	switch {
+	case errors.Is(err, service.ErrDeviceNotFound):
+		return http.StatusNotFound, err.Error()
+	case errors.Is(err, domain.ErrVersionMismatch):
+		return versionMismatchError(ifMatch)
+	case errors.Is(err, domain.ErrDeleteDeviceInUse):
+		return http.StatusConflict, "device is in use and cannot be deleted"
+	case errors.Is(err, domain.ErrIllegalTransition):
+		return http.StatusConflict, err.Error()
+	case errors.Is(err, domain.ErrPermissionDenied):
+		return http.StatusForbidden, err.Error()
+	case errors.Is(err, service.ErrBatchRolledBack):
+		return http.StatusFailedDependency, err.Error()
+	case errors.Is(err, domain.ErrInvalidState),
+		errors.Is(err, domain.ErrStateIsRequired),
+		errors.Is(err, domain.ErrNameIsRequired),
+		errors.Is(err, domain.ErrBrandIsRequired),
+		errors.Is(err, domain.ErrInvalidID):
+		return http.StatusBadRequest, err.Error()
+	}
+
+	return http.StatusInternalServerError, err.Error()
+}
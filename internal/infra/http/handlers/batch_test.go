@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/raulsilva-tech/devices-api/internal/domain"
+	"github.com/raulsilva-tech/devices-api/internal/dto"
+	"github.com/raulsilva-tech/devices-api/internal/service"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseBatchOperation(t *testing.T) {
+	device := &dto.DeviceRequest{Name: "iPhone", Brand: "Apple", State: "available"}
+
+	op, msg := parseBatchOperation(dto.BatchOperationRequest{Op: "update", ID: "1", Version: ptrInt64(3), Device: device})
+	require.Empty(t, msg)
+	require.Equal(t, service.BatchUpdate, op.Type)
+	require.Equal(t, "iPhone", *op.Update.Name)
+	require.Equal(t, int64(3), *op.Update.ExpectedVersion)
+
+	op, msg = parseBatchOperation(dto.BatchOperationRequest{Op: "delete", ID: "1"})
+	require.Empty(t, msg)
+	require.Equal(t, "1", op.Delete.ID)
+
+	for _, opReq := range []dto.BatchOperationRequest{
+		{Op: "create"},
+		{Op: "create", Device: &dto.DeviceRequest{Name: "iPhone"}},
+		{Op: "update", Device: device},
+		{Op: "delete"},
+		{Op: "rename", ID: "1"},
+	} {
+		_, msg = parseBatchOperation(opReq)
+		require.NotEmpty(t, msg, opReq.Op)
+	}
+}
+
+func TestDeviceErrorResponse(t *testing.T) {
+	cases := []struct {
+		err     error
+		ifMatch bool
+		status  int
+	}{
+		{service.ErrDeviceNotFound, false, http.StatusNotFound},
+		{domain.ErrVersionMismatch, true, http.StatusPreconditionFailed},
+		{domain.ErrVersionMismatch, false, http.StatusConflict},
+		{domain.ErrDeleteDeviceInUse, false, http.StatusConflict},
+		{&domain.IllegalTransitionError{From: domain.DeviceInactive, To: domain.DeviceInUse}, false, http.StatusConflict},
+		{service.ErrBatchRolledBack, false, http.StatusFailedDependency},
+		{domain.ErrInvalidState, false, http.StatusBadRequest},
+	}
+
+	for _, c := range cases {
+		status, _ := deviceErrorResponse(c.err, c.ifMatch)
+		require.Equal(t, c.status, status, c.err.Error())
+	}
+}
+
+func ptrInt64(v int64) *int64 {
+	return &v
+}
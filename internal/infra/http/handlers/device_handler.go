@@ -4,7 +4,12 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"mime"
 	"net/http"
+	"net/url"
+	"strconv"
+	"time"
 
 	"github.com/raulsilva-tech/devices-api/internal/domain"
 	"github.com/raulsilva-tech/devices-api/internal/dto"
@@ -12,12 +17,14 @@ import (
 )
 
 type DeviceHandler struct {
-	Service *service.DeviceService
+	Service     *service.DeviceService
+	Assignments *service.AssignmentService // applies the check-out and return transitions
 }
 
-func NewDeviceHandler(svc *service.DeviceService) *DeviceHandler {
+func NewDeviceHandler(svc *service.DeviceService, assignments *service.AssignmentService) *DeviceHandler {
 	return &DeviceHandler{
-		Service: svc,
+		Service:     svc,
+		Assignments: assignments,
 	}
 }
 
@@ -28,9 +35,15 @@ func NewDeviceHandler(svc *service.DeviceService) *DeviceHandler {
 // @Accept json
 // @Produce json
 // @Param request body dto.DeviceRequest true "Device payload"
+// @Param Idempotency-Key header string false "Key that makes the retries of the request replay its first response"
 // @Success 201 {object} dto.CreateDeviceResponse
 // @Failure 400 {object} dto.ErrorResponse
+// @Failure 403 {object} dto.ErrorResponse
+// @Failure 409 {object} dto.ErrorResponse
+// @Failure 422 {object} dto.ErrorResponse
+// @Failure 429 {object} dto.ErrorResponse
 // @Failure 500 {object} dto.ErrorResponse
+// @Security ApiKeyAuth
 // @Router /devices [post]
 func (h *DeviceHandler) CreateDevice(w http.ResponseWriter, r *http.Request) {
 
@@ -53,10 +66,18 @@ func (h *DeviceHandler) CreateDevice(w http.ResponseWriter, r *http.Request) {
 		State: domain.DeviceState(reqBody.State),
 	})
 	if err != nil {
+		if errors.Is(err, domain.ErrPermissionDenied) {
+			writeJSONError(w, http.StatusForbidden, err.Error())
+			return
+		}
 		if errors.Is(err, domain.ErrInvalidState) {
 			writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("state %s is invalid", reqBody.State))
 			return
 		}
+		if errors.Is(err, domain.ErrIllegalTransition) {
+			writeJSONError(w, http.StatusConflict, err.Error())
+			return
+		}
 		writeJSONError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
@@ -74,10 +95,19 @@ func (h *DeviceHandler) CreateDevice(w http.ResponseWriter, r *http.Request) {
 // @Produce json
 // @Param id path string true "Device ID"
 // @Param request body dto.DeviceRequest true "Update payload"
+// @Param If-Match header string false "ETag of the device version the update is based on"
+// @Param Idempotency-Key header string false "Key that makes the retries of the request replay its first response"
 // @Success 200 {object} dto.UpdateDeviceResponse
+// @Header 200 {string} ETag "Version of the updated device"
 // @Failure 400 {object} dto.ErrorResponse
 // @Failure 404 {object} dto.ErrorResponse
+// @Failure 409 {object} dto.ErrorResponse
+// @Failure 412 {object} dto.ErrorResponse
+// @Failure 403 {object} dto.ErrorResponse
+// @Failure 422 {object} dto.ErrorResponse
+// @Failure 429 {object} dto.ErrorResponse
 // @Failure 500 {object} dto.ErrorResponse
+// @Security ApiKeyAuth
 // @Router /devices/{id} [put]
 func (h *DeviceHandler) UpdateDevice(w http.ResponseWriter, r *http.Request) {
 
@@ -100,47 +130,325 @@ func (h *DeviceHandler) UpdateDevice(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	expectedVersion, err := parseIfMatch(r)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	state := domain.DeviceState(reqBody.State)
 	output, err := h.Service.UpdateDevice(r.Context(), service.UpdateDeviceInput{
-		ID:    id,
-		Name:  reqBody.Name,
-		Brand: reqBody.Brand,
-		State: domain.DeviceState(reqBody.State),
+		ID:              id,
+		Name:            &reqBody.Name,
+		Brand:           &reqBody.Brand,
+		State:           &state,
+		ExpectedVersion: expectedVersion,
 	})
 	if err != nil {
+		if errors.Is(err, domain.ErrPermissionDenied) {
+			writeJSONError(w, http.StatusForbidden, err.Error())
+			return
+		}
 		if errors.Is(err, domain.ErrInvalidState) {
 			writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("state %s is invalid", reqBody.State))
 			return
 		}
+		if errors.Is(err, service.ErrDeviceNotFound) {
+			writeJSONError(w, http.StatusNotFound, err.Error())
+			return
+		}
+		if errors.Is(err, domain.ErrVersionMismatch) {
+			writeVersionMismatch(w, expectedVersion != nil)
+			return
+		}
+		if errors.Is(err, domain.ErrIllegalTransition) {
+			writeJSONError(w, http.StatusConflict, err.Error())
+			return
+		}
 		writeJSONError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
 
-	response := dto.UpdateDeviceResponse{
-		UpdatedFields: output.UpdatedFields,
-		IgnoredFields: output.IgnoredFields,
-		Device: dto.DeviceResponse{
-			ID:        output.Device.ID,
-			Name:      output.Device.Name,
-			Brand:     output.Device.Brand,
-			State:     string(output.Device.State),
-			CreatedAt: output.Device.CreatedAt,
-		},
+	w.Header().Set("ETag", formatETag(output.Device.Version))
+	writeJSON(w, http.StatusOK, mapUpdateOutputToDTO(*output))
+}
+
+// PatchDevice godoc
+// @Summary Partially update a device
+// @Description Applies a JSON Merge Patch (application/merge-patch+json, RFC 7396) or a JSON Patch (application/json-patch+json, RFC 6902)
+// @Description to the device. Only name, brand and state can change, and name and brand are ignored while the device is in use.
+// @Tags Devices
+// @Accept application/merge-patch+json
+// @Accept application/json-patch+json
+// @Produce json
+// @Param id path string true "Device ID"
+// @Param request body object true "Merge patch object or array of JSON Patch operations"
+// @Param If-Match header string false "ETag of the device version the patch is based on"
+// @Param Idempotency-Key header string false "Key that makes the retries of the request replay its first response"
+// @Success 200 {object} dto.UpdateDeviceResponse
+// @Header 200 {string} ETag "Version of the updated device"
+// @Failure 400 {object} dto.ErrorResponse
+// @Failure 404 {object} dto.ErrorResponse
+// @Failure 409 {object} dto.ErrorResponse
+// @Failure 412 {object} dto.ErrorResponse
+// @Failure 415 {object} dto.ErrorResponse
+// @Failure 403 {object} dto.ErrorResponse
+// @Failure 422 {object} dto.ErrorResponse
+// @Failure 429 {object} dto.ErrorResponse
+// @Failure 500 {object} dto.ErrorResponse
+// @Security ApiKeyAuth
+// @Router /devices/{id} [patch]
+func (h *DeviceHandler) PatchDevice(w http.ResponseWriter, r *http.Request) {
+
+	id := r.PathValue("id")
+	if id == "" {
+		writeJSONError(w, http.StatusBadRequest, "id is required")
+		return
+	}
+
+	contentType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if contentType != mergePatchContentType && contentType != jsonPatchContentType {
+		writeJSONError(w, http.StatusUnsupportedMediaType, fmt.Sprintf("content type must be %s or %s", mergePatchContentType, jsonPatchContentType))
+		return
+	}
+
+	expectedVersion, err := parseIfMatch(r)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, "invalid body")
+		return
+	}
+	defer r.Body.Close()
+
+	device, err := h.Service.GetDeviceById(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, service.ErrDeviceNotFound) {
+			writeJSONError(w, http.StatusNotFound, err.Error())
+			return
+		}
+		writeJSONError(w, http.StatusInternalServerError, err.Error())
+		return
 	}
 
-	writeJSON(w, http.StatusOK, response)
+	if expectedVersion != nil && *expectedVersion != device.Version {
+		writeVersionMismatch(w, true)
+		return
+	}
+
+	doc := deviceDocument(*device)
+	if contentType == mergePatchContentType {
+		err = applyMergePatch(doc, body)
+	} else {
+		err = applyJSONPatch(doc, body)
+	}
+	if err != nil {
+		if errors.Is(err, errPatchTestFailed) {
+			writeJSONError(w, http.StatusConflict, err.Error())
+			return
+		}
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	input, err := patchedDeviceInput(*device, doc)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	// the patch was computed against this exact version, so it must not land on a newer one
+	input.ExpectedVersion = &device.Version
+
+	output, err := h.Service.UpdateDevice(r.Context(), input)
+	if err != nil {
+		if errors.Is(err, domain.ErrPermissionDenied) {
+			writeJSONError(w, http.StatusForbidden, err.Error())
+			return
+		}
+		if errors.Is(err, domain.ErrInvalidState) {
+			writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("state %s is invalid", *input.State))
+			return
+		}
+		if errors.Is(err, domain.ErrNameIsRequired) || errors.Is(err, domain.ErrBrandIsRequired) {
+			writeJSONError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if errors.Is(err, service.ErrDeviceNotFound) {
+			writeJSONError(w, http.StatusNotFound, err.Error())
+			return
+		}
+		if errors.Is(err, domain.ErrVersionMismatch) {
+			writeVersionMismatch(w, expectedVersion != nil)
+			return
+		}
+		if errors.Is(err, domain.ErrIllegalTransition) {
+			writeJSONError(w, http.StatusConflict, err.Error())
+			return
+		}
+		writeJSONError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	w.Header().Set("ETag", formatETag(output.Device.Version))
+	writeJSON(w, http.StatusOK, mapUpdateOutputToDTO(*output))
+}
+
+// TransitionDevice godoc
+// @Summary Move a device through its lifecycle
+// @Description Applies a named action instead of writing a raw state: activate (inactive -> available),
+// @Description deactivate (available -> inactive), check-out (available -> in-use) or return (in-use -> available).
+// @Description check-out and return are a checkout and a check-in: check-out needs an assignee and opens an assignment, return closes it.
+// @Tags Devices
+// @Accept json
+// @Produce json
+// @Param id path string true "Device ID"
+// @Param request body dto.TransitionRequest true "Action to apply"
+// @Param If-Match header string false "ETag of the device version the transition is based on"
+// @Param Idempotency-Key header string false "Key that makes the retries of the request replay its first response"
+// @Success 200 {object} dto.DeviceResponse
+// @Header 200 {string} ETag "Version of the updated device"
+// @Failure 400 {object} dto.ErrorResponse
+// @Failure 403 {object} dto.ErrorResponse
+// @Failure 404 {object} dto.ErrorResponse
+// @Failure 409 {object} dto.ErrorResponse
+// @Failure 412 {object} dto.ErrorResponse
+// @Failure 422 {object} dto.ErrorResponse
+// @Failure 429 {object} dto.ErrorResponse
+// @Failure 500 {object} dto.ErrorResponse
+// @Security ApiKeyAuth
+// @Router /devices/{id}/transitions [post]
+func (h *DeviceHandler) TransitionDevice(w http.ResponseWriter, r *http.Request) {
+
+	id := r.PathValue("id")
+	if id == "" {
+		writeJSONError(w, http.StatusBadRequest, "id is required")
+		return
+	}
+
+	var reqBody dto.TransitionRequest
+	if err := json.NewDecoder(r.Body).Decode(&reqBody); err != nil {
+		writeJSONError(w, http.StatusBadRequest, "invalid JSON body")
+		return
+	}
+	defer r.Body.Close()
+
+	if reqBody.Action == "" {
+		writeJSONError(w, http.StatusBadRequest, "action is required")
+		return
+	}
+
+	expectedVersion, err := parseIfMatch(r)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	action := domain.DeviceAction(reqBody.Action)
+	if action.ChangesAssignment() {
+		h.transitionAssignment(w, r, id, action, reqBody, expectedVersion)
+		return
+	}
+
+	device, err := h.Service.TransitionDevice(r.Context(), service.TransitionDeviceInput{
+		ID:              id,
+		Action:          action,
+		ExpectedVersion: expectedVersion,
+	})
+	if err != nil {
+		if errors.Is(err, domain.ErrPermissionDenied) {
+			writeJSONError(w, http.StatusForbidden, err.Error())
+			return
+		}
+		if errors.Is(err, domain.ErrInvalidAction) {
+			writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("action %s is invalid", reqBody.Action))
+			return
+		}
+		if errors.Is(err, service.ErrDeviceNotFound) {
+			writeJSONError(w, http.StatusNotFound, err.Error())
+			return
+		}
+		if errors.Is(err, domain.ErrIllegalTransition) {
+			writeJSONError(w, http.StatusConflict, err.Error())
+			return
+		}
+		if errors.Is(err, domain.ErrVersionMismatch) {
+			writeVersionMismatch(w, expectedVersion != nil)
+			return
+		}
+		writeJSONError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	w.Header().Set("ETag", formatETag(device.Version))
+	writeJSON(w, http.StatusOK, mapServiceDeviceToDTO(*device))
+}
+
+// transitionAssignment applies check-out and return as a checkout and a check-in, so the
+// assignment of the device is opened or closed with the state change
+func (h *DeviceHandler) transitionAssignment(w http.ResponseWriter, r *http.Request, id string, action domain.DeviceAction, reqBody dto.TransitionRequest, expectedVersion *int64) {
+
+	var device service.DeviceOutput
+
+	if action == domain.ActionCheckOut {
+		if reqBody.Assignee == "" {
+			writeJSONError(w, http.StatusBadRequest, "assignee is required to check out a device")
+			return
+		}
+
+		input := service.CheckOutInput{
+			DeviceID:        id,
+			Assignee:        reqBody.Assignee,
+			Notes:           reqBody.Notes,
+			ExpectedVersion: expectedVersion,
+		}
+		if reqBody.ExpectedReturnAt != nil {
+			input.ExpectedReturnAt = *reqBody.ExpectedReturnAt
+		}
+
+		output, err := h.Assignments.CheckOut(r.Context(), input)
+		if err != nil {
+			writeAssignmentError(w, err, expectedVersion != nil)
+			return
+		}
+		device = output.Device
+	} else {
+		output, err := h.Assignments.CheckIn(r.Context(), service.CheckInInput{
+			DeviceID:        id,
+			ExpectedVersion: expectedVersion,
+		})
+		if err != nil {
+			writeAssignmentError(w, err, expectedVersion != nil)
+			return
+		}
+		device = output.Device
+	}
+
+	w.Header().Set("ETag", formatETag(device.Version))
+	writeJSON(w, http.StatusOK, mapServiceDeviceToDTO(device))
 }
 
 // DeleteDevice godoc
 // @Summary Delete a device
-// @Description Delete a device by ID
+// @Description Soft deletes a device by ID. It disappears from reads and lists but can be restored until it is purged.
 // @Tags Devices
 // @Produce json
 // @Param id path string true "Device ID"
+// @Param If-Match header string false "ETag of the device version the delete is based on"
+// @Param Idempotency-Key header string false "Key that makes the retries of the request replay its first response"
 // @Success 204 "No Content"
 // @Failure 400 {object} dto.ErrorResponse
+// @Failure 403 {object} dto.ErrorResponse
 // @Failure 404 {object} dto.ErrorResponse
 // @Failure 409 {object} dto.ErrorResponse
+// @Failure 412 {object} dto.ErrorResponse
+// @Failure 422 {object} dto.ErrorResponse
+// @Failure 429 {object} dto.ErrorResponse
 // @Failure 500 {object} dto.ErrorResponse
+// @Security ApiKeyAuth
 // @Router /devices/{id} [delete]
 func (h *DeviceHandler) DeleteDevice(w http.ResponseWriter, r *http.Request) {
 
@@ -150,8 +458,21 @@ func (h *DeviceHandler) DeleteDevice(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err := h.Service.DeleteDevice(r.Context(), id)
+	expectedVersion, err := parseIfMatch(r)
 	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	err = h.Service.DeleteDevice(r.Context(), service.DeleteDeviceInput{
+		ID:              id,
+		ExpectedVersion: expectedVersion,
+	})
+	if err != nil {
+		if errors.Is(err, domain.ErrPermissionDenied) {
+			writeJSONError(w, http.StatusForbidden, err.Error())
+			return
+		}
 		// If the service returns "not found", send 404 instead of 500
 		if errors.Is(err, domain.ErrDeleteDeviceInUse) {
 			writeJSONError(w, http.StatusConflict, "device is in use and cannot be deleted")
@@ -161,6 +482,10 @@ func (h *DeviceHandler) DeleteDevice(w http.ResponseWriter, r *http.Request) {
 			writeJSONError(w, http.StatusNotFound, err.Error())
 			return
 		}
+		if errors.Is(err, domain.ErrVersionMismatch) {
+			writeVersionMismatch(w, expectedVersion != nil)
+			return
+		}
 		writeJSONError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
@@ -168,15 +493,76 @@ func (h *DeviceHandler) DeleteDevice(w http.ResponseWriter, r *http.Request) {
 
 }
 
+// RestoreDevice godoc
+// @Summary Restore a deleted device
+// @Description Brings back a soft deleted device that has not been purged yet
+// @Tags Devices
+// @Produce json
+// @Param id path string true "Device ID"
+// @Param If-Match header string false "ETag of the deleted device version the restore is based on"
+// @Param Idempotency-Key header string false "Key that makes the retries of the request replay its first response"
+// @Success 200 {object} dto.DeviceResponse
+// @Header 200 {string} ETag "Version of the restored device"
+// @Failure 400 {object} dto.ErrorResponse
+// @Failure 403 {object} dto.ErrorResponse
+// @Failure 404 {object} dto.ErrorResponse
+// @Failure 409 {object} dto.ErrorResponse
+// @Failure 412 {object} dto.ErrorResponse
+// @Failure 422 {object} dto.ErrorResponse
+// @Failure 429 {object} dto.ErrorResponse
+// @Failure 500 {object} dto.ErrorResponse
+// @Security ApiKeyAuth
+// @Router /devices/{id}/restore [post]
+func (h *DeviceHandler) RestoreDevice(w http.ResponseWriter, r *http.Request) {
+
+	id := r.PathValue("id")
+	if id == "" {
+		writeJSONError(w, http.StatusBadRequest, "id is required")
+		return
+	}
+
+	expectedVersion, err := parseIfMatch(r)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	output, err := h.Service.RestoreDevice(r.Context(), service.RestoreDeviceInput{
+		ID:              id,
+		ExpectedVersion: expectedVersion,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, domain.ErrPermissionDenied):
+			writeJSONError(w, http.StatusForbidden, err.Error())
+		case errors.Is(err, service.ErrDeviceNotFound):
+			writeJSONError(w, http.StatusNotFound, err.Error())
+		case errors.Is(err, domain.ErrDeviceNotDeleted):
+			writeJSONError(w, http.StatusConflict, err.Error())
+		case errors.Is(err, domain.ErrVersionMismatch):
+			writeVersionMismatch(w, expectedVersion != nil)
+		default:
+			writeJSONError(w, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	w.Header().Set("ETag", formatETag(output.Version))
+	writeJSON(w, http.StatusOK, mapServiceDeviceToDTO(*output))
+}
+
 // GetDeviceByID godoc
 // @Summary Get a device by ID
 // @Tags Devices
 // @Produce json
 // @Param id path string true "Device ID"
 // @Success 200 {object} dto.DeviceResponse
+// @Header 200 {string} ETag "Version of the device, to be sent back in If-Match"
 // @Failure 400 {object} dto.ErrorResponse
 // @Failure 404 {object} dto.ErrorResponse
+// @Failure 429 {object} dto.ErrorResponse
 // @Failure 500 {object} dto.ErrorResponse
+// @Security ApiKeyAuth
 // @Router /devices/{id} [get]
 func (h *DeviceHandler) GetDeviceByID(w http.ResponseWriter, r *http.Request) {
 
@@ -196,49 +582,121 @@ func (h *DeviceHandler) GetDeviceByID(w http.ResponseWriter, r *http.Request) {
 		writeJSONError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
+	w.Header().Set("ETag", formatETag(device.Version))
 	writeJSON(w, http.StatusOK, mapServiceDeviceToDTO(*device))
 }
 
 // GetAllDevices godoc
 // @Summary List devices
-// @Description Returns all devices, or filter by brand/state query params
+// @Description Returns a page of devices. Filters can be combined and the total number of matches is returned alongside the page.
+// @Description When sorted by created_at, next_cursor can be passed back as cursor to fetch the following page.
 // @Tags Devices
 // @Produce json
 // @Param brand query string false "Filter by brand"
 // @Param state query string false "Filter by state"
-// @Success 200 {array} dto.DeviceResponse
+// @Param name query string false "Filter by name substring (case-insensitive)"
+// @Param created_from query string false "Only devices created at or after this RFC 3339 timestamp"
+// @Param created_to query string false "Only devices created at or before this RFC 3339 timestamp"
+// @Param sort query string false "Sort field: name, brand, state or created_at (default created_at)"
+// @Param order query string false "Sort order: asc or desc (default asc)"
+// @Param limit query int false "Page size (default 20, max 100)"
+// @Param offset query int false "Number of devices to skip"
+// @Param cursor query string false "Opaque next_cursor returned by the previous page (keyset pagination, sort must be created_at)"
+// @Param include_deleted query bool false "Also list soft deleted devices (admin)"
+// @Success 200 {object} dto.DeviceListResponse
+// @Failure 400 {object} dto.ErrorResponse
+// @Failure 403 {object} dto.ErrorResponse
+// @Failure 429 {object} dto.ErrorResponse
 // @Failure 500 {object} dto.ErrorResponse
+// @Security ApiKeyAuth
 // @Router /devices [get]
 func (h *DeviceHandler) GetAllDevices(w http.ResponseWriter, r *http.Request) {
 
-	brand := r.URL.Query().Get("brand")
-	if brand != "" {
-		devList, err := h.Service.GetDevicesByBrand(r.Context(), brand)
-		if err != nil {
-			writeJSONError(w, http.StatusInternalServerError, err.Error())
-			return
-		}
-		writeJSON(w, http.StatusOK, processDeviceList(devList))
+	input, err := parseListDevicesQuery(r.URL.Query())
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
 		return
 	}
 
-	state := r.URL.Query().Get("state")
-	if state != "" {
-		devList, err := h.Service.GetDevicesByState(r.Context(), state)
-		if err != nil {
-			writeJSONError(w, http.StatusInternalServerError, err.Error())
+	output, err := h.Service.GetDevices(r.Context(), input)
+	if err != nil {
+		if errors.Is(err, domain.ErrPermissionDenied) {
+			writeJSONError(w, http.StatusForbidden, err.Error())
 			return
 		}
-		writeJSON(w, http.StatusOK, processDeviceList(devList))
+		if isInvalidFilterError(err) {
+			writeJSONError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		writeJSONError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
 
-	devList, err := h.Service.GetDevices(r.Context())
-	if err != nil {
-		writeJSONError(w, http.StatusInternalServerError, err.Error())
-		return
+	writeJSON(w, http.StatusOK, dto.DeviceListResponse{
+		Items:      processDeviceList(output.Devices),
+		Total:      output.Total,
+		Limit:      output.Limit,
+		Offset:     output.Offset,
+		NextCursor: output.NextCursor,
+	})
+}
+
+func parseListDevicesQuery(query url.Values) (service.ListDevicesInput, error) {
+
+	input := service.ListDevicesInput{
+		Brand:  query.Get("brand"),
+		State:  domain.DeviceState(query.Get("state")),
+		Name:   query.Get("name"),
+		SortBy: query.Get("sort"),
+		Order:  query.Get("order"),
+		Cursor: query.Get("cursor"),
+	}
+
+	var err error
+
+	if v := query.Get("created_from"); v != "" {
+		if input.CreatedFrom, err = time.Parse(time.RFC3339, v); err != nil {
+			return input, fmt.Errorf("created_from %s is not a valid RFC 3339 timestamp", v)
+		}
+	}
+
+	if v := query.Get("created_to"); v != "" {
+		if input.CreatedTo, err = time.Parse(time.RFC3339, v); err != nil {
+			return input, fmt.Errorf("created_to %s is not a valid RFC 3339 timestamp", v)
+		}
+	}
+
+	if v := query.Get("limit"); v != "" {
+		if input.Limit, err = strconv.Atoi(v); err != nil || input.Limit <= 0 {
+			return input, fmt.Errorf("limit %s is invalid", v)
+		}
 	}
-	writeJSON(w, http.StatusOK, processDeviceList(devList))
+
+	if v := query.Get("offset"); v != "" {
+		if input.Offset, err = strconv.Atoi(v); err != nil || input.Offset < 0 {
+			return input, fmt.Errorf("offset %s is invalid", v)
+		}
+	}
+
+	if v := query.Get("include_deleted"); v != "" {
+		if input.IncludeDeleted, err = strconv.ParseBool(v); err != nil {
+			return input, fmt.Errorf("include_deleted %s is invalid", v)
+		}
+	}
+
+	return input, nil
+}
+
+func isInvalidFilterError(err error) bool {
+	return errors.Is(err, domain.ErrInvalidState) ||
+		errors.Is(err, domain.ErrInvalidSortField) ||
+		errors.Is(err, domain.ErrInvalidSortOrder) ||
+		errors.Is(err, domain.ErrInvalidLimit) ||
+		errors.Is(err, domain.ErrInvalidOffset) ||
+		errors.Is(err, domain.ErrInvalidDateRange) ||
+		errors.Is(err, domain.ErrInvalidCursor) ||
+		errors.Is(err, domain.ErrCursorSort) ||
+		errors.Is(err, domain.ErrCursorWithOffset)
 }
 
 func processDeviceList(devList []service.DeviceOutput) []dto.DeviceResponse {
@@ -259,7 +717,32 @@ func mapServiceDeviceToDTO(device service.DeviceOutput) dto.DeviceResponse {
 		Brand:     device.Brand,
 		State:     string(device.State),
 		CreatedAt: device.CreatedAt,
+		Version:   device.Version,
+		DeletedAt: optionalTime(device.DeletedAt),
+	}
+}
+
+func mapUpdateOutputToDTO(output service.UpdateDeviceOutput) dto.UpdateDeviceResponse {
+	return dto.UpdateDeviceResponse{
+		UpdatedFields: output.UpdatedFields,
+		IgnoredFields: output.IgnoredFields,
+		Device:        mapServiceDeviceToDTO(output.Device),
+	}
+}
+
+// writeVersionMismatch answers 412 when the client sent If-Match, and 409 when the
+// device was changed concurrently while the server was processing the request
+func writeVersionMismatch(w http.ResponseWriter, ifMatch bool) {
+	status, msg := versionMismatchError(ifMatch)
+	writeJSONError(w, status, msg)
+}
+
+// versionMismatchError is 412 when the client sent If-Match, and 409 when another writer won the race
+func versionMismatchError(ifMatch bool) (int, string) {
+	if ifMatch {
+		return http.StatusPreconditionFailed, "device version does not match If-Match"
 	}
+	return http.StatusConflict, "device was modified concurrently, retry the request"
 }
 
 func writeJSONError(w http.ResponseWriter, status int, msg string) {
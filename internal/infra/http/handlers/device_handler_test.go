@@ -0,0 +1,162 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/raulsilva-tech/devices-api/internal/domain"
+	"github.com/raulsilva-tech/devices-api/internal/dto"
+	"github.com/raulsilva-tech/devices-api/internal/infra/rbac"
+	"github.com/raulsilva-tech/devices-api/internal/service"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryDevices keeps the devices and their assignments in memory, the methods the tests do not
+// use panic through the nil interfaces
+type memoryDevices struct {
+	domain.DeviceRepository
+	domain.AssignmentRepository
+
+	mu          sync.Mutex
+	devices     map[string]domain.Device
+	assignments map[string]domain.Assignment // open assignment of each device
+}
+
+func newMemoryDevices(devices ...*domain.Device) *memoryDevices {
+	m := &memoryDevices{devices: map[string]domain.Device{}, assignments: map[string]domain.Assignment{}}
+	for _, device := range devices {
+		m.devices[device.ID] = *device
+	}
+	return m
+}
+
+func (m *memoryDevices) GetDeviceById(ctx context.Context, id string) (*domain.Device, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	device, ok := m.devices[id]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	return &device, nil
+}
+
+func (m *memoryDevices) UpdateDevice(ctx context.Context, device *domain.Device) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	device.Version++
+	m.devices[device.ID] = *device
+	return nil
+}
+
+func (m *memoryDevices) CheckOutDevice(ctx context.Context, device *domain.Device, assignment *domain.Assignment) error {
+	m.mu.Lock()
+	m.assignments[device.ID] = *assignment
+	m.mu.Unlock()
+	return m.UpdateDevice(ctx, device)
+}
+
+func (m *memoryDevices) CheckInDevice(ctx context.Context, device *domain.Device, returnedAt time.Time) error {
+	m.mu.Lock()
+	delete(m.assignments, device.ID)
+	m.mu.Unlock()
+	return m.UpdateDevice(ctx, device)
+}
+
+func (m *memoryDevices) GetOpenAssignment(ctx context.Context, deviceID string) (*domain.Assignment, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	assignment, ok := m.assignments[deviceID]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	return &assignment, nil
+}
+
+func newTestDeviceHandler(repo *memoryDevices) *DeviceHandler {
+	return NewDeviceHandler(service.NewDeviceService(repo, nil), service.NewAssignmentService(repo, repo, nil))
+}
+
+func TestTransitionDevice(t *testing.T) {
+
+	device, err := domain.NewDevice(uuid.New().String(), "iPhone", "Apple", domain.DeviceAvailable, time.Now())
+	require.NoError(t, err)
+	repo := newMemoryDevices(device)
+	handler := newTestDeviceHandler(repo)
+
+	transition := func(body string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodPost, "/devices/"+device.ID+"/transitions", bytes.NewBufferString(body))
+		r.SetPathValue("id", device.ID)
+		w := httptest.NewRecorder()
+		handler.TransitionDevice(w, r)
+		return w
+	}
+
+	require.Equal(t, http.StatusBadRequest, transition(`{"action":"fly"}`).Code)
+	require.Equal(t, http.StatusBadRequest, transition(`{"action":"check-out"}`).Code)
+
+	// check-out is a checkout: the device goes in use with an assignment saying who holds it
+	w := transition(`{"action":"check-out","assignee":"jane","notes":"demo"}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	var response dto.DeviceResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	require.Equal(t, "in-use", response.State)
+	require.Equal(t, `"2"`, w.Header().Get("ETag"))
+
+	open, err := repo.GetOpenAssignment(context.Background(), device.ID)
+	require.NoError(t, err)
+	require.Equal(t, "jane", open.Assignee)
+
+	require.Equal(t, http.StatusConflict, transition(`{"action":"deactivate"}`).Code)
+
+	// return is a check-in: the assignment is closed
+	w = transition(`{"action":"return"}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	require.Equal(t, "available", response.State)
+	_, err = repo.GetOpenAssignment(context.Background(), device.ID)
+	require.ErrorIs(t, err, sql.ErrNoRows)
+
+	require.Equal(t, http.StatusConflict, transition(`{"action":"return"}`).Code)
+}
+
+func TestDeviceHandler_PermissionDenied(t *testing.T) {
+
+	policy, err := rbac.LoadPolicy("")
+	require.NoError(t, err)
+
+	device, err := domain.NewDevice(uuid.New().String(), "iPhone", "Apple", domain.DeviceAvailable, time.Now())
+	require.NoError(t, err)
+	repo := newMemoryDevices(device)
+	handler := NewDeviceHandler(service.NewDeviceService(repo, policy), service.NewAssignmentService(repo, repo, policy))
+
+	viewer := &domain.Principal{Subject: "apikey:viewer", Roles: []domain.Role{domain.RoleViewer}}
+	cases := map[string]struct {
+		method, body string
+		handle       http.HandlerFunc
+	}{
+		"create":     {http.MethodPost, `{"name":"Pixel","brand":"Google","state":"available"}`, handler.CreateDevice},
+		"transition": {http.MethodPost, `{"action":"deactivate"}`, handler.TransitionDevice},
+		"check-out":  {http.MethodPost, `{"action":"check-out","assignee":"jane"}`, handler.TransitionDevice},
+		"delete":     {http.MethodDelete, ``, handler.DeleteDevice},
+		"restore":    {http.MethodPost, ``, handler.RestoreDevice},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			r := httptest.NewRequest(c.method, "/devices/"+device.ID, bytes.NewBufferString(c.body))
+			r = r.WithContext(domain.WithPrincipal(r.Context(), viewer))
+			r.SetPathValue("id", device.ID)
+			w := httptest.NewRecorder()
+			c.handle(w, r)
+			require.Equal(t, http.StatusForbidden, w.Code)
+		})
+	}
+}
@@ -0,0 +1,32 @@
+package handlers
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseIfMatch(t *testing.T) {
+	r := httptest.NewRequest("PUT", "/devices/1", nil)
+
+	version, err := parseIfMatch(r)
+	require.NoError(t, err)
+	require.Nil(t, version)
+
+	r.Header.Set("If-Match", "*")
+	version, err = parseIfMatch(r)
+	require.NoError(t, err)
+	require.Nil(t, version)
+
+	r.Header.Set("If-Match", formatETag(7))
+	version, err = parseIfMatch(r)
+	require.NoError(t, err)
+	require.Equal(t, int64(7), *version)
+
+	for _, header := range []string{`W/"7"`, `7`, `"seven"`, `""`} {
+		r.Header.Set("If-Match", header)
+		_, err = parseIfMatch(r)
+		require.ErrorIs(t, err, errInvalidIfMatch, header)
+	}
+}
@@ -0,0 +1,210 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/raulsilva-tech/devices-api/internal/domain"
+	"github.com/raulsilva-tech/devices-api/internal/dto"
+	"github.com/raulsilva-tech/devices-api/internal/service"
+)
+
+const (
+	exportFormatCSV    = "csv"
+	exportFormatNDJSON = "ndjson"
+
+	// exportFlushEvery is how many devices are written between flushes to the client
+	exportFlushEvery = 100
+)
+
+var exportCSVHeader = []string{"id", "name", "brand", "state", "created_at", "version", "deleted_at"}
+
+// ExportDevices godoc
+// @Summary Export devices as CSV or NDJSON
+// @Description Streams every device matching the filters of the list endpoint, without pagination. Rows are read from the database one at a time.
+// @Tags Devices
+// @Produce text/csv
+// @Produce application/x-ndjson
+// @Param format query string false "csv (default) or ndjson"
+// @Param brand query string false "Filter by brand"
+// @Param state query string false "Filter by state"
+// @Param name query string false "Filter by name substring (case-insensitive)"
+// @Param created_from query string false "Only devices created at or after this RFC 3339 timestamp"
+// @Param created_to query string false "Only devices created at or before this RFC 3339 timestamp"
+// @Param sort query string false "Sort field: name, brand, state or created_at (default created_at)"
+// @Param order query string false "Sort order: asc or desc (default asc)"
+// @Param include_deleted query bool false "Also export soft deleted devices (admin)"
+// @Success 200 {string} string "CSV with a header row, or one JSON device per line"
+// @Failure 400 {object} dto.ErrorResponse
+// @Failure 403 {object} dto.ErrorResponse
+// @Failure 429 {object} dto.ErrorResponse
+// @Failure 500 {object} dto.ErrorResponse
+// @Security ApiKeyAuth
+// @Router /devices/export [get]
+func (h *DeviceHandler) ExportDevices(w http.ResponseWriter, r *http.Request) {
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = exportFormatCSV
+	}
+	if format != exportFormatCSV && format != exportFormatNDJSON {
+		writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("format %s is invalid", format))
+		return
+	}
+
+	listInput, err := parseListDevicesQuery(r.URL.Query())
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if listInput.Limit != 0 || listInput.Offset != 0 || listInput.Cursor != "" {
+		writeJSONError(w, http.StatusBadRequest, "export is not paginated: limit, offset and cursor are not supported")
+		return
+	}
+
+	exporter := newDeviceExporter(w, format)
+
+	err = h.Service.ExportDevices(r.Context(), service.ExportDevicesInput{
+		Brand:          listInput.Brand,
+		State:          listInput.State,
+		Name:           listInput.Name,
+		CreatedFrom:    listInput.CreatedFrom,
+		CreatedTo:      listInput.CreatedTo,
+		SortBy:         listInput.SortBy,
+		Order:          listInput.Order,
+		IncludeDeleted: listInput.IncludeDeleted,
+	}, exporter.write)
+	if err == nil {
+		err = exporter.close()
+	}
+	if err != nil {
+		if exporter.started {
+			// the status is already sent, all we can do is cut the stream short
+			log.Printf("[%v] export aborted after %d devices: %v", domain.RequestIDFromContext(r.Context()), exporter.count, err)
+			return
+		}
+		if isInvalidFilterError(err) {
+			writeJSONError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if errors.Is(err, domain.ErrPermissionDenied) {
+			writeJSONError(w, http.StatusForbidden, err.Error())
+			return
+		}
+		writeJSONError(w, http.StatusInternalServerError, err.Error())
+	}
+}
+
+// deviceExporter writes devices to the response as they are read, sending the headers with the first one
+type deviceExporter struct {
+	w       http.ResponseWriter
+	rc      *http.ResponseController
+	format  string
+	csv     *csv.Writer
+	json    *json.Encoder
+	started bool
+	count   int
+}
+
+func newDeviceExporter(w http.ResponseWriter, format string) *deviceExporter {
+	return &deviceExporter{
+		w:      w,
+		rc:     http.NewResponseController(w),
+		format: format,
+	}
+}
+
+func (e *deviceExporter) start() error {
+
+	e.started = true
+	filename := fmt.Sprintf("devices-%s.%s", time.Now().UTC().Format("20060102T150405Z"), e.format)
+
+	e.w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+
+	if e.format == exportFormatNDJSON {
+		e.w.Header().Set("Content-Type", "application/x-ndjson")
+		e.w.WriteHeader(http.StatusOK)
+		e.json = json.NewEncoder(e.w)
+		return nil
+	}
+
+	e.w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	e.w.WriteHeader(http.StatusOK)
+	e.csv = csv.NewWriter(e.w)
+	return e.csv.Write(exportCSVHeader)
+}
+
+func (e *deviceExporter) write(device service.DeviceOutput) error {
+
+	if !e.started {
+		if err := e.start(); err != nil {
+			return err
+		}
+	}
+
+	if err := e.encode(mapServiceDeviceToDTO(device)); err != nil {
+		return err
+	}
+
+	e.count++
+	if e.count%exportFlushEvery == 0 {
+		return e.flush()
+	}
+	return nil
+}
+
+func (e *deviceExporter) encode(device dto.DeviceResponse) error {
+
+	if e.json != nil {
+		return e.json.Encode(device)
+	}
+
+	deletedAt := ""
+	if device.DeletedAt != nil {
+		deletedAt = device.DeletedAt.Format(time.RFC3339)
+	}
+
+	return e.csv.Write([]string{
+		device.ID,
+		device.Name,
+		device.Brand,
+		device.State,
+		device.CreatedAt.Format(time.RFC3339),
+		strconv.FormatInt(device.Version, 10),
+		deletedAt,
+	})
+}
+
+func (e *deviceExporter) flush() error {
+
+	if e.csv != nil {
+		e.csv.Flush()
+		if err := e.csv.Error(); err != nil {
+			return err
+		}
+	}
+
+	// not every writer can flush, the data is then sent when the handler returns
+	if err := e.rc.Flush(); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		return err
+	}
+	return nil
+}
+
+// close sends the headers if no device matched and flushes what is left
+func (e *deviceExporter) close() error {
+
+	if !e.started {
+		if err := e.start(); err != nil {
+			return err
+		}
+	}
+
+	return e.flush()
+}
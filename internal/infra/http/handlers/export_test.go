@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/raulsilva-tech/devices-api/internal/domain"
+	"github.com/raulsilva-tech/devices-api/internal/service"
+	"github.com/stretchr/testify/require"
+)
+
+func exportedDevice() service.DeviceOutput {
+	return service.DeviceOutput{
+		ID:        "49e6d977-58a6-4424-a058-8d025991b325",
+		Name:      "Galaxy S21, 256GB",
+		Brand:     "Samsung",
+		State:     domain.DeviceAvailable,
+		CreatedAt: time.Date(2025, 1, 10, 15, 4, 5, 0, time.UTC),
+		Version:   3,
+	}
+}
+
+func TestDeviceExporter_CSV(t *testing.T) {
+	w := httptest.NewRecorder()
+	e := newDeviceExporter(w, exportFormatCSV)
+
+	require.NoError(t, e.write(exportedDevice()))
+	require.NoError(t, e.close())
+
+	require.Equal(t, "text/csv; charset=utf-8", w.Header().Get("Content-Type"))
+	require.Contains(t, w.Header().Get("Content-Disposition"), ".csv")
+	require.Equal(t,
+		"id,name,brand,state,created_at,version,deleted_at\n"+
+			"49e6d977-58a6-4424-a058-8d025991b325,\"Galaxy S21, 256GB\",Samsung,available,2025-01-10T15:04:05Z,3,\n",
+		w.Body.String())
+}
+
+func TestDeviceExporter_NDJSON(t *testing.T) {
+	w := httptest.NewRecorder()
+	e := newDeviceExporter(w, exportFormatNDJSON)
+
+	require.NoError(t, e.write(exportedDevice()))
+	require.NoError(t, e.write(exportedDevice()))
+	require.NoError(t, e.close())
+
+	require.Equal(t, "application/x-ndjson", w.Header().Get("Content-Type"))
+	lines := strings.Split(strings.TrimSuffix(w.Body.String(), "\n"), "\n")
+	require.Len(t, lines, 2)
+	require.JSONEq(t, `{"id":"49e6d977-58a6-4424-a058-8d025991b325","name":"Galaxy S21, 256GB","brand":"Samsung","state":"available","created_at":"2025-01-10T15:04:05Z","version":3}`, lines[0])
+}
+
+func TestDeviceExporter_Empty(t *testing.T) {
+	w := httptest.NewRecorder()
+	e := newDeviceExporter(w, exportFormatCSV)
+
+	require.NoError(t, e.close())
+	require.Equal(t, 200, w.Code)
+	require.Equal(t, "id,name,brand,state,created_at,version,deleted_at\n", w.Body.String())
+}
@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/raulsilva-tech/devices-api/internal/domain"
+	"github.com/raulsilva-tech/devices-api/internal/dto"
+	"github.com/raulsilva-tech/devices-api/internal/service"
+)
+
+type HistoryHandler struct {
+	Service *service.HistoryService
+}
+
+func NewHistoryHandler(svc *service.HistoryService) *HistoryHandler {
+	return &HistoryHandler{
+		Service: svc,
+	}
+}
+
+// GetDeviceHistory godoc
+// @Summary List the history of a device
+// @Description Returns who changed the device, when and which fields, most recent first. The history is kept after the device is deleted.
+// @Tags Devices
+// @Produce json
+// @Param id path string true "Device ID"
+// @Param limit query int false "Page size (default 20, max 100)"
+// @Param offset query int false "Number of entries to skip"
+// @Success 200 {array} dto.DeviceChangeResponse
+// @Failure 400 {object} dto.ErrorResponse
+// @Failure 404 {object} dto.ErrorResponse
+// @Failure 429 {object} dto.ErrorResponse
+// @Failure 500 {object} dto.ErrorResponse
+// @Security ApiKeyAuth
+// @Router /devices/{id}/history [get]
+func (h *HistoryHandler) GetDeviceHistory(w http.ResponseWriter, r *http.Request) {
+
+	id := r.PathValue("id")
+	if id == "" {
+		writeJSONError(w, http.StatusBadRequest, "id is required")
+		return
+	}
+
+	limit, offset, err := parsePage(r)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	changes, err := h.Service.GetDeviceHistory(r.Context(), id, limit, offset)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrDeviceNotFound):
+			writeJSONError(w, http.StatusNotFound, err.Error())
+		case errors.Is(err, domain.ErrInvalidLimit), errors.Is(err, domain.ErrInvalidOffset):
+			writeJSONError(w, http.StatusBadRequest, err.Error())
+		default:
+			writeJSONError(w, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	resultList := make([]dto.DeviceChangeResponse, len(changes))
+	for i, change := range changes {
+		resultList[i] = mapServiceDeviceChangeToDTO(change)
+	}
+
+	writeJSON(w, http.StatusOK, resultList)
+}
+
+func mapServiceDeviceChangeToDTO(c service.DeviceChangeOutput) dto.DeviceChangeResponse {
+
+	changes := make(map[string]dto.FieldChangeResponse, len(c.Fields))
+	for name, f := range c.Fields {
+		changes[name] = dto.FieldChangeResponse{Old: f.Old, New: f.New}
+	}
+
+	return dto.DeviceChangeResponse{
+		ID:         c.ID,
+		DeviceID:   c.DeviceID,
+		Type:       c.Type,
+		Version:    c.Version,
+		Changes:    changes,
+		RequestID:  c.RequestID,
+		Actor:      c.Actor,
+		OnBehalfOf: c.OnBehalfOf,
+		OccurredAt: c.OccurredAt,
+	}
+}
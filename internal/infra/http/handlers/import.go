@@ -0,0 +1,169 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/raulsilva-tech/devices-api/internal/domain"
+	"github.com/raulsilva-tech/devices-api/internal/dto"
+	"github.com/raulsilva-tech/devices-api/internal/service"
+)
+
+const (
+	importModeAtomic     = "atomic"
+	importModeBestEffort = "best-effort"
+
+	maxImportBodyBytes = 1 << 20
+)
+
+// importColumns are the CSV columns an import must provide, in any order
+var importColumns = []string{"name", "brand", "state"}
+
+// ImportDevices godoc
+// @Summary Import devices from CSV
+// @Description Creates devices from a CSV file with a header row naming the name, brand and state columns.
+// @Description In atomic mode (default) any invalid row rejects the whole file with 422 and nothing is created.
+// @Description In best-effort mode the valid rows are created and the invalid ones reported.
+// @Tags Devices
+// @Accept text/csv
+// @Produce json
+// @Param mode query string false "atomic (default) or best-effort"
+// @Param file body string true "CSV with a name,brand,state header"
+// @Param Idempotency-Key header string false "Key that makes the retries of the request replay its first response"
+// @Success 200 {object} dto.ImportDevicesResponse "best-effort import"
+// @Success 201 {object} dto.ImportDevicesResponse "atomic import, every row created"
+// @Failure 400 {object} dto.ErrorResponse
+// @Failure 413 {object} dto.ErrorResponse
+// @Failure 422 {object} dto.ImportDevicesResponse "atomic import rejected, nothing created"
+// @Failure 409 {object} dto.ErrorResponse
+// @Failure 429 {object} dto.ErrorResponse
+// @Failure 500 {object} dto.ErrorResponse
+// @Security ApiKeyAuth
+// @Router /devices/import [post]
+func (h *DeviceHandler) ImportDevices(w http.ResponseWriter, r *http.Request) {
+
+	mode := r.URL.Query().Get("mode")
+	if mode == "" {
+		mode = importModeAtomic
+	}
+	if mode != importModeAtomic && mode != importModeBestEffort {
+		writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("mode %s is invalid", mode))
+		return
+	}
+
+	rows, err := parseDeviceCSV(http.MaxBytesReader(w, r.Body, maxImportBodyBytes))
+	defer r.Body.Close()
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			writeJSONError(w, http.StatusRequestEntityTooLarge, "import file is too large")
+			return
+		}
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	output, err := h.Service.ImportDevices(r.Context(), service.ImportDevicesInput{
+		Rows:   rows,
+		Atomic: mode == importModeAtomic,
+	})
+	if err != nil {
+		if errors.Is(err, service.ErrEmptyImport) || errors.Is(err, service.ErrTooManyImportRows) {
+			writeJSONError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		writeJSONError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	status := http.StatusOK
+	if output.Atomic {
+		status = http.StatusCreated
+		if output.Failed > 0 {
+			status = http.StatusUnprocessableEntity
+		}
+	}
+
+	writeJSON(w, status, mapImportOutputToDTO(mode, *output))
+}
+
+// parseDeviceCSV reads the rows of an import. The first record is the header; columns other
+// than name, brand and state are ignored.
+func parseDeviceCSV(body io.Reader) ([]service.ImportDeviceRow, error) {
+
+	reader := csv.NewReader(body)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, errors.New("import file is empty")
+	}
+	if err != nil {
+		return nil, csvError(err)
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, name := range importColumns {
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("header is missing the %s column", name)
+		}
+	}
+
+	var rows []service.ImportDeviceRow
+
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, csvError(err)
+		}
+
+		line, _ := reader.FieldPos(0)
+		rows = append(rows, service.ImportDeviceRow{
+			Line:  line,
+			Name:  strings.TrimSpace(record[columns["name"]]),
+			Brand: strings.TrimSpace(record[columns["brand"]]),
+			State: domain.DeviceState(strings.TrimSpace(record[columns["state"]])),
+		})
+	}
+
+	return rows, nil
+}
+
+func csvError(err error) error {
+	var parseErr *csv.ParseError
+	if errors.As(err, &parseErr) {
+		return fmt.Errorf("invalid CSV at line %d: %v", parseErr.Line, parseErr.Err)
+	}
+	return err
+}
+
+func mapImportOutputToDTO(mode string, output service.ImportDevicesOutput) dto.ImportDevicesResponse {
+
+	rows := make([]dto.ImportRowResponse, len(output.Rows))
+	for i, row := range output.Rows {
+		rows[i] = dto.ImportRowResponse{
+			Line: row.Line,
+			ID:   row.ID,
+		}
+		if row.Err != nil {
+			rows[i].Error = row.Err.Error()
+		}
+	}
+
+	return dto.ImportDevicesResponse{
+		Mode:    mode,
+		Created: output.Created,
+		Failed:  output.Failed,
+		Rows:    rows,
+	}
+}
@@ -0,0 +1,39 @@
+package handlers
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/raulsilva-tech/devices-api/internal/domain"
+	"github.com/raulsilva-tech/devices-api/internal/service"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseDeviceCSV(t *testing.T) {
+	body := "State, Name ,brand,serial\n" +
+		"available,iPhone 13,Apple,A1\n" +
+		"\"in-use\",\"Galaxy S21, 256GB\",Samsung,S1\n" +
+		"inactive,Pixel 8,,G1\n"
+
+	rows, err := parseDeviceCSV(strings.NewReader(body))
+	require.NoError(t, err)
+	require.Equal(t, []service.ImportDeviceRow{
+		{Line: 2, Name: "iPhone 13", Brand: "Apple", State: domain.DeviceAvailable},
+		{Line: 3, Name: "Galaxy S21, 256GB", Brand: "Samsung", State: domain.DeviceInUse},
+		{Line: 4, Name: "Pixel 8", Brand: "", State: domain.DeviceInactive},
+	}, rows)
+}
+
+func TestParseDeviceCSV_Invalid(t *testing.T) {
+	cases := map[string]string{
+		"empty":          "",
+		"missing column": "name,brand\niPhone,Apple\n",
+		"field count":    "name,brand,state\niPhone,Apple\n",
+		"bare quote":     "name,brand,state\niPh\"one,Apple,available\n",
+	}
+
+	for name, body := range cases {
+		_, err := parseDeviceCSV(strings.NewReader(body))
+		require.Error(t, err, name)
+	}
+}
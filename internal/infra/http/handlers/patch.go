@@ -0,0 +1,241 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/raulsilva-tech/devices-api/internal/domain"
+	"github.com/raulsilva-tech/devices-api/internal/service"
+)
+
+const (
+	mergePatchContentType = "application/merge-patch+json"
+	jsonPatchContentType  = "application/json-patch+json"
+)
+
+var errPatchTestFailed = errors.New("json patch test operation failed")
+
+// patchableFields are the device document members a patch is allowed to change
+var patchableFields = []string{"name", "brand", "state"}
+
+// readOnlyFields are present in the device document but can never be changed
+var readOnlyFields = map[string]bool{"id": true, "created_at": true, "version": true, "deleted_at": true}
+
+// jsonPatchOperation is a single RFC 6902 operation
+type jsonPatchOperation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	From  string          `json:"from"`
+	Value json.RawMessage `json:"value"`
+}
+
+// deviceDocument builds the JSON document a patch is applied to, mirroring dto.DeviceResponse,
+// so the body of a GET can be patched and sent back
+func deviceDocument(device service.DeviceOutput) map[string]any {
+
+	doc := map[string]any{
+		"id":         device.ID,
+		"name":       device.Name,
+		"brand":      device.Brand,
+		"state":      string(device.State),
+		"created_at": device.CreatedAt,
+		"version":    device.Version,
+	}
+	if !device.DeletedAt.IsZero() {
+		doc["deleted_at"] = device.DeletedAt
+	}
+
+	return doc
+}
+
+// applyMergePatch applies a JSON Merge Patch (RFC 7396) to the device document.
+// The document is flat, so a null member removes it and any other value replaces it.
+func applyMergePatch(doc map[string]any, body []byte) error {
+
+	var patch map[string]json.RawMessage
+	if err := json.Unmarshal(body, &patch); err != nil || patch == nil {
+		return errors.New("merge patch must be a JSON object")
+	}
+
+	for member, raw := range patch {
+		var value any
+		if err := json.Unmarshal(raw, &value); err != nil {
+			return fmt.Errorf("invalid value for %s", member)
+		}
+		if value == nil {
+			delete(doc, member)
+			continue
+		}
+		doc[member] = value
+	}
+
+	return nil
+}
+
+// applyJSONPatch applies a JSON Patch (RFC 6902) to the device document.
+// Operations are applied in order and the whole patch fails if any of them fails.
+func applyJSONPatch(doc map[string]any, body []byte) error {
+
+	var ops []jsonPatchOperation
+	if err := json.Unmarshal(body, &ops); err != nil {
+		return errors.New("json patch must be an array of operations")
+	}
+
+	for i, op := range ops {
+		if err := applyJSONPatchOperation(doc, op); err != nil {
+			if errors.Is(err, errPatchTestFailed) {
+				return err
+			}
+			return fmt.Errorf("operation %d (%s): %w", i, op.Op, err)
+		}
+	}
+
+	return nil
+}
+
+func applyJSONPatchOperation(doc map[string]any, op jsonPatchOperation) error {
+
+	member, err := parsePointer(op.Path)
+	if err != nil {
+		return err
+	}
+
+	switch op.Op {
+	case "add", "replace":
+		if op.Value == nil {
+			return errors.New("value is required")
+		}
+		if _, ok := doc[member]; !ok && op.Op == "replace" {
+			return fmt.Errorf("path %s does not exist", op.Path)
+		}
+		var value any
+		if err := json.Unmarshal(op.Value, &value); err != nil {
+			return errors.New("invalid value")
+		}
+		doc[member] = value
+
+	case "remove":
+		if _, ok := doc[member]; !ok {
+			return fmt.Errorf("path %s does not exist", op.Path)
+		}
+		delete(doc, member)
+
+	case "move", "copy":
+		from, err := parsePointer(op.From)
+		if err != nil {
+			return err
+		}
+		value, ok := doc[from]
+		if !ok {
+			return fmt.Errorf("path %s does not exist", op.From)
+		}
+		if op.Op == "move" {
+			delete(doc, from)
+		}
+		doc[member] = value
+
+	case "test":
+		var expected any
+		if err := json.Unmarshal(op.Value, &expected); err != nil {
+			return errors.New("invalid value")
+		}
+		if !reflect.DeepEqual(normalizeJSON(doc[member]), expected) {
+			return fmt.Errorf("%w: %s", errPatchTestFailed, op.Path)
+		}
+
+	default:
+		return fmt.Errorf("unsupported operation %q", op.Op)
+	}
+
+	return nil
+}
+
+// parsePointer resolves a JSON Pointer (RFC 6901) to a member of the flat device document
+func parsePointer(pointer string) (string, error) {
+
+	if !strings.HasPrefix(pointer, "/") {
+		return "", fmt.Errorf("invalid path %q", pointer)
+	}
+
+	token := pointer[1:]
+	if token == "" || strings.Contains(token, "/") {
+		return "", fmt.Errorf("unsupported path %q", pointer)
+	}
+
+	token = strings.ReplaceAll(token, "~1", "/")
+	token = strings.ReplaceAll(token, "~0", "~")
+
+	return token, nil
+}
+
+// normalizeJSON converts a document value into what encoding/json would decode it to,
+// so values built from Go types compare equal to values read from a patch
+func normalizeJSON(value any) any {
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return value
+	}
+	var normalized any
+	if err := json.Unmarshal(raw, &normalized); err != nil {
+		return value
+	}
+	return normalized
+}
+
+// patchedDeviceInput compares the patched document with the original device and
+// turns the differences into an update input with only the changed fields set
+func patchedDeviceInput(original service.DeviceOutput, doc map[string]any) (service.UpdateDeviceInput, error) {
+
+	before := deviceDocument(original)
+
+	for member, value := range doc {
+		if readOnlyFields[member] {
+			if !reflect.DeepEqual(normalizeJSON(value), normalizeJSON(before[member])) {
+				return service.UpdateDeviceInput{}, fmt.Errorf("%s cannot be updated", member)
+			}
+			continue
+		}
+		if _, ok := before[member]; !ok {
+			return service.UpdateDeviceInput{}, fmt.Errorf("unknown field %s", member)
+		}
+	}
+
+	for member := range readOnlyFields {
+		if _, ok := before[member]; !ok {
+			continue
+		}
+		if _, ok := doc[member]; !ok {
+			return service.UpdateDeviceInput{}, fmt.Errorf("%s cannot be removed", member)
+		}
+	}
+
+	values := make(map[string]*string, len(patchableFields))
+	for _, member := range patchableFields {
+		value, ok := doc[member]
+		if !ok {
+			return service.UpdateDeviceInput{}, fmt.Errorf("%s is required", member)
+		}
+		str, ok := value.(string)
+		if !ok {
+			return service.UpdateDeviceInput{}, fmt.Errorf("%s must be a string", member)
+		}
+		if str != before[member] {
+			values[member] = &str
+		}
+	}
+
+	input := service.UpdateDeviceInput{
+		ID:    original.ID,
+		Name:  values["name"],
+		Brand: values["brand"],
+	}
+	if state := values["state"]; state != nil {
+		s := domain.DeviceState(*state)
+		input.State = &s
+	}
+
+	return input, nil
+}
@@ -0,0 +1,160 @@
+package handlers
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/raulsilva-tech/devices-api/internal/domain"
+	"github.com/raulsilva-tech/devices-api/internal/service"
+	"github.com/stretchr/testify/require"
+)
+
+func makeDeviceOutput() service.DeviceOutput {
+	return service.DeviceOutput{
+		ID:        uuid.New().String(),
+		Name:      "Galaxy S21",
+		Brand:     "Samsung",
+		State:     domain.DeviceAvailable,
+		CreatedAt: time.Now().UTC(),
+	}
+}
+
+func TestMergePatch_OnlyChangedFieldsAreSet(t *testing.T) {
+	device := makeDeviceOutput()
+	doc := deviceDocument(device)
+
+	err := applyMergePatch(doc, []byte(`{"name":"Galaxy S22","brand":"Samsung"}`))
+	require.NoError(t, err)
+
+	input, err := patchedDeviceInput(device, doc)
+	require.NoError(t, err)
+	require.Equal(t, device.ID, input.ID)
+	require.Equal(t, "Galaxy S22", *input.Name)
+	require.Nil(t, input.Brand) // same value, nothing to update
+	require.Nil(t, input.State)
+}
+
+func TestMergePatch_Rejections(t *testing.T) {
+	cases := map[string]string{
+		"not an object":     `["name"]`,
+		"remove required":   `{"name":null}`,
+		"read only field":   `{"id":"other"}`,
+		"version":           `{"version":7}`,
+		"removed version":   `{"version":null}`,
+		"deleted_at":        `{"deleted_at":"2025-02-01T10:00:00Z"}`,
+		"unknown field":     `{"color":"black"}`,
+		"non string values": `{"state":1}`,
+	}
+
+	for name, body := range cases {
+		t.Run(name, func(t *testing.T) {
+			device := makeDeviceOutput()
+			doc := deviceDocument(device)
+
+			err := applyMergePatch(doc, []byte(body))
+			if err == nil {
+				_, err = patchedDeviceInput(device, doc)
+			}
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestMergePatch_GetBodyRoundTrip(t *testing.T) {
+	for name, deletedAt := range map[string]time.Time{"active": {}, "deleted": time.Now().UTC()} {
+		t.Run(name, func(t *testing.T) {
+			device := makeDeviceOutput()
+			device.Version = 3
+			device.DeletedAt = deletedAt
+
+			// the body of a GET, with the name edited, is a valid merge patch
+			var body map[string]any
+			raw, err := json.Marshal(mapServiceDeviceToDTO(device))
+			require.NoError(t, err)
+			require.NoError(t, json.Unmarshal(raw, &body))
+			body["name"] = "Galaxy S22"
+			raw, err = json.Marshal(body)
+			require.NoError(t, err)
+
+			doc := deviceDocument(device)
+			require.NoError(t, applyMergePatch(doc, raw))
+
+			input, err := patchedDeviceInput(device, doc)
+			require.NoError(t, err)
+			require.Equal(t, "Galaxy S22", *input.Name)
+			require.Nil(t, input.Brand)
+			require.Nil(t, input.State)
+		})
+	}
+}
+
+func TestJSONPatch_TestVersion(t *testing.T) {
+	device := makeDeviceOutput()
+	device.Version = 3
+
+	doc := deviceDocument(device)
+	require.NoError(t, applyJSONPatch(doc, []byte(`[
+		{"op":"test","path":"/version","value":3},
+		{"op":"replace","path":"/name","value":"Galaxy S22"}
+	]`)))
+	input, err := patchedDeviceInput(device, doc)
+	require.NoError(t, err)
+	require.Equal(t, "Galaxy S22", *input.Name)
+
+	err = applyJSONPatch(deviceDocument(device), []byte(`[{"op":"test","path":"/version","value":2}]`))
+	require.ErrorIs(t, err, errPatchTestFailed)
+
+	doc = deviceDocument(device)
+	require.NoError(t, applyJSONPatch(doc, []byte(`[{"op":"replace","path":"/version","value":4}]`)))
+	_, err = patchedDeviceInput(device, doc)
+	require.EqualError(t, err, "version cannot be updated")
+}
+
+func TestJSONPatch_Operations(t *testing.T) {
+	device := makeDeviceOutput()
+	doc := deviceDocument(device)
+
+	err := applyJSONPatch(doc, []byte(`[
+		{"op":"test","path":"/state","value":"available"},
+		{"op":"replace","path":"/state","value":"inactive"},
+		{"op":"copy","from":"/brand","path":"/name"}
+	]`))
+	require.NoError(t, err)
+
+	input, err := patchedDeviceInput(device, doc)
+	require.NoError(t, err)
+	require.Equal(t, "Samsung", *input.Name)
+	require.Nil(t, input.Brand)
+	require.Equal(t, domain.DeviceInactive, *input.State)
+}
+
+func TestJSONPatch_TestFailureAndInvalidOperations(t *testing.T) {
+	device := makeDeviceOutput()
+
+	err := applyJSONPatch(deviceDocument(device), []byte(`[{"op":"test","path":"/state","value":"in-use"}]`))
+	require.ErrorIs(t, err, errPatchTestFailed)
+
+	cases := map[string]string{
+		"not an array":      `{"op":"replace"}`,
+		"unknown operation": `[{"op":"increment","path":"/name"}]`,
+		"nested path":       `[{"op":"replace","path":"/name/first","value":"x"}]`,
+		"missing path":      `[{"op":"replace","path":"/color","value":"x"}]`,
+		"missing value":     `[{"op":"add","path":"/name"}]`,
+	}
+
+	for name, body := range cases {
+		t.Run(name, func(t *testing.T) {
+			err := applyJSONPatch(deviceDocument(device), []byte(body))
+			require.Error(t, err)
+			require.NotErrorIs(t, err, errPatchTestFailed)
+		})
+	}
+
+	// removing a required field is only detected once the patched document is mapped
+	doc := deviceDocument(device)
+	require.NoError(t, applyJSONPatch(doc, []byte(`[{"op":"remove","path":"/brand"}]`)))
+	_, err = patchedDeviceInput(device, doc)
+	require.EqualError(t, err, "brand is required")
+}
@@ -0,0 +1,132 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/raulsilva-tech/devices-api/internal/domain"
+	"github.com/raulsilva-tech/devices-api/internal/dto"
+	"github.com/raulsilva-tech/devices-api/internal/infra/stream"
+)
+
+// streamHeartbeat keeps idle connections from being closed by proxies
+const streamHeartbeat = 15 * time.Second
+
+type StreamHandler struct {
+	Hub *stream.Hub
+}
+
+func NewStreamHandler(hub *stream.Hub) *StreamHandler {
+	return &StreamHandler{
+		Hub: hub,
+	}
+}
+
+// StreamDevices godoc
+// @Summary Stream device changes
+// @Description Server-Sent Events stream of device changes. Each event is named after its type (device.created, device.updated, device.state_changed, device.deleted, device.restored), carries the device as data and the event ID as id. Send Last-Event-ID to resume after that event from the replay buffer; when it is no longer buffered the whole buffer is replayed.
+// @Tags Devices
+// @Produce text/event-stream
+// @Param brand query string false "Only devices of this brand"
+// @Param state query string false "Only devices in this state"
+// @Param Last-Event-ID header string false "ID of the last event received"
+// @Success 200 {object} dto.DeviceResponse "data of every event"
+// @Failure 400 {object} dto.ErrorResponse
+// @Failure 429 {object} dto.ErrorResponse
+// @Security ApiKeyAuth
+// @Router /devices/stream [get]
+func (h *StreamHandler) StreamDevices(w http.ResponseWriter, r *http.Request) {
+
+	brand := r.URL.Query().Get("brand")
+	state := domain.DeviceState(r.URL.Query().Get("state"))
+	if state != "" && !state.IsValid() {
+		writeJSONError(w, http.StatusBadRequest, domain.ErrInvalidState.Error())
+		return
+	}
+
+	// the hub carries the events of every tenant
+	tenant := domain.TenantFromContext(r.Context())
+	matches := func(event domain.Event) bool {
+		return event.Device.TenantID == tenant &&
+			(brand == "" || event.Device.Brand == brand) && (state == "" || event.Device.State == state)
+	}
+
+	sub, replay := h.Hub.Subscribe(r.Header.Get("Last-Event-ID"))
+	defer sub.Close()
+
+	rc := http.NewResponseController(w)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	for _, event := range replay {
+		if !matches(event) {
+			continue
+		}
+		if err := writeDeviceEvent(w, event); err != nil {
+			return
+		}
+	}
+	if err := rc.Flush(); err != nil {
+		return
+	}
+
+	heartbeat := time.NewTicker(streamHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+
+		case event, ok := <-sub.Events:
+			if !ok {
+				// dropped for being too slow or shutting down, the client reconnects
+				return
+			}
+			if !matches(event) {
+				continue
+			}
+			if err := writeDeviceEvent(w, event); err != nil {
+				return
+			}
+
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+		}
+
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+func writeDeviceEvent(w http.ResponseWriter, event domain.Event) error {
+
+	data, err := json.Marshal(mapDomainDeviceToDTO(event.Device))
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", event.ID, event.Type, data)
+	return err
+}
+
+func mapDomainDeviceToDTO(device domain.Device) dto.DeviceResponse {
+	return dto.DeviceResponse{
+		ID:        device.ID,
+		Name:      device.Name,
+		Brand:     device.Brand,
+		State:     string(device.State),
+		CreatedAt: device.CreatedAt,
+		Version:   device.Version,
+		DeletedAt: optionalTime(device.DeletedAt),
+	}
+}
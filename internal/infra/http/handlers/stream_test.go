@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/raulsilva-tech/devices-api/internal/domain"
+	"github.com/raulsilva-tech/devices-api/internal/infra/stream"
+	"github.com/stretchr/testify/require"
+)
+
+func streamEvent(t *testing.T, brand string, state domain.DeviceState) domain.Event {
+	device, err := domain.NewDevice(uuid.New().String(), "Device", brand, state, time.Now())
+	require.NoError(t, err)
+	device.TenantID = domain.DefaultTenant
+	return domain.NewEvent(domain.EventDeviceCreated, *device)
+}
+
+// readEvent reads the next event of the stream, skipping comments
+func readEvent(t *testing.T, reader *bufio.Reader) map[string]string {
+	fields := map[string]string{}
+	for {
+		line, err := reader.ReadString('\n')
+		require.NoError(t, err)
+		line = strings.TrimSuffix(line, "\n")
+		if line == "" {
+			if len(fields) > 0 {
+				return fields
+			}
+			continue
+		}
+		if strings.HasPrefix(line, ":") {
+			continue
+		}
+		name, value, _ := strings.Cut(line, ": ")
+		fields[name] = value
+	}
+}
+
+func TestStreamDevices(t *testing.T) {
+	hub := stream.NewHub(10)
+	server := httptest.NewServer(http.HandlerFunc(NewStreamHandler(hub).StreamDevices))
+	defer server.Close()
+
+	ctx := context.Background()
+	missed := streamEvent(t, "Apple", domain.DeviceAvailable)
+	otherBrand := streamEvent(t, "Samsung", domain.DeviceAvailable)
+	seen := streamEvent(t, "Apple", domain.DeviceInUse)
+	for _, e := range []domain.Event{seen, otherBrand, missed} {
+		require.NoError(t, hub.Publish(ctx, e))
+	}
+
+	req, err := http.NewRequest(http.MethodGet, server.URL+"?brand=Apple", nil)
+	require.NoError(t, err)
+	req.Header.Set("Last-Event-ID", seen.ID)
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	reader := bufio.NewReader(resp.Body)
+
+	// the event of another brand is filtered out of the replay
+	event := readEvent(t, reader)
+	require.Equal(t, missed.ID, event["id"])
+	require.Equal(t, "device.created", event["event"])
+	require.Contains(t, event["data"], `"id":"`+missed.Device.ID+`"`)
+
+	// the events of the other tenants are filtered out as well
+	otherTenant := streamEvent(t, "Apple", domain.DeviceInactive)
+	otherTenant.Device.TenantID = "globex"
+
+	live := streamEvent(t, "Apple", domain.DeviceInactive)
+	require.NoError(t, hub.Publish(ctx, streamEvent(t, "Samsung", domain.DeviceInactive)))
+	require.NoError(t, hub.Publish(ctx, otherTenant))
+	require.NoError(t, hub.Publish(ctx, live))
+
+	event = readEvent(t, reader)
+	require.Equal(t, live.ID, event["id"])
+	require.Contains(t, event["data"], `"state":"inactive"`)
+}
+
+func TestStreamDevices_InvalidState(t *testing.T) {
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/devices/stream?state=broken", nil)
+
+	NewStreamHandler(stream.NewHub(10)).StreamDevices(w, r)
+
+	require.Equal(t, http.StatusBadRequest, w.Code)
+}
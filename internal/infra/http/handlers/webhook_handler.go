@@ -0,0 +1,193 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/raulsilva-tech/devices-api/internal/domain"
+	"github.com/raulsilva-tech/devices-api/internal/dto"
+	"github.com/raulsilva-tech/devices-api/internal/service"
+)
+
+type WebhookHandler struct {
+	Service *service.WebhookService
+}
+
+func NewWebhookHandler(svc *service.WebhookService) *WebhookHandler {
+	return &WebhookHandler{
+		Service: svc,
+	}
+}
+
+// CreateWebhook godoc
+// @Summary Subscribe a URL to device events
+// @Description Registers a webhook and returns the secret that signs its deliveries. Every delivery is a POST of the event with the headers X-Webhook-Event, X-Webhook-Delivery, X-Webhook-Timestamp and X-Webhook-Signature ("sha256=" + hex HMAC-SHA256 of "timestamp.body"). Deliveries answered with other than 2xx are retried with exponential backoff.
+// @Tags Webhooks
+// @Accept json
+// @Produce json
+// @Param request body dto.WebhookRequest true "Webhook payload"
+// @Param Idempotency-Key header string false "Key that makes the retries of the request replay its first response"
+// @Success 201 {object} dto.WebhookResponse
+// @Failure 400 {object} dto.ErrorResponse
+// @Failure 409 {object} dto.ErrorResponse
+// @Failure 422 {object} dto.ErrorResponse
+// @Failure 429 {object} dto.ErrorResponse
+// @Failure 500 {object} dto.ErrorResponse
+// @Security ApiKeyAuth
+// @Router /webhooks [post]
+func (h *WebhookHandler) CreateWebhook(w http.ResponseWriter, r *http.Request) {
+
+	var reqBody dto.WebhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&reqBody); err != nil {
+		writeJSONError(w, http.StatusBadRequest, "invalid JSON body")
+		return
+	}
+	defer r.Body.Close()
+
+	events := make([]domain.EventType, len(reqBody.Events))
+	for i, e := range reqBody.Events {
+		events[i] = domain.EventType(e)
+	}
+
+	webhook, err := h.Service.CreateWebhook(r.Context(), service.CreateWebhookInput{
+		URL:    reqBody.URL,
+		Events: events,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, domain.ErrInvalidWebhookURL), errors.Is(err, domain.ErrPrivateWebhookURL), errors.Is(err, domain.ErrInvalidEventType):
+			writeJSONError(w, http.StatusBadRequest, err.Error())
+		default:
+			writeJSONError(w, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, mapServiceWebhookToDTO(*webhook))
+}
+
+// GetWebhooks godoc
+// @Summary List webhooks
+// @Description Returns every webhook subscription, without their secrets
+// @Tags Webhooks
+// @Produce json
+// @Success 200 {array} dto.WebhookResponse
+// @Failure 429 {object} dto.ErrorResponse
+// @Failure 500 {object} dto.ErrorResponse
+// @Security ApiKeyAuth
+// @Router /webhooks [get]
+func (h *WebhookHandler) GetWebhooks(w http.ResponseWriter, r *http.Request) {
+
+	webhooks, err := h.Service.GetWebhooks(r.Context())
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	resultList := make([]dto.WebhookResponse, len(webhooks))
+	for i, webhook := range webhooks {
+		resultList[i] = mapServiceWebhookToDTO(webhook)
+	}
+
+	writeJSON(w, http.StatusOK, resultList)
+}
+
+// DeleteWebhook godoc
+// @Summary Delete a webhook
+// @Description Stops the deliveries to the webhook and removes their records
+// @Tags Webhooks
+// @Param id path string true "Webhook ID"
+// @Param Idempotency-Key header string false "Key that makes the retries of the request replay its first response"
+// @Success 204
+// @Failure 404 {object} dto.ErrorResponse
+// @Failure 409 {object} dto.ErrorResponse
+// @Failure 422 {object} dto.ErrorResponse
+// @Failure 429 {object} dto.ErrorResponse
+// @Failure 500 {object} dto.ErrorResponse
+// @Security ApiKeyAuth
+// @Router /webhooks/{id} [delete]
+func (h *WebhookHandler) DeleteWebhook(w http.ResponseWriter, r *http.Request) {
+
+	if err := h.Service.DeleteWebhook(r.Context(), r.PathValue("id")); err != nil {
+		switch {
+		case errors.Is(err, service.ErrWebhookNotFound):
+			writeJSONError(w, http.StatusNotFound, err.Error())
+		default:
+			writeJSONError(w, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetDeliveries godoc
+// @Summary List the deliveries of a webhook
+// @Description Returns the deliveries of a webhook with the outcome of their latest attempt, most recent first
+// @Tags Webhooks
+// @Produce json
+// @Param id path string true "Webhook ID"
+// @Param limit query int false "Page size (default 20, max 100)"
+// @Param offset query int false "Number of entries to skip"
+// @Success 200 {array} dto.WebhookDeliveryResponse
+// @Failure 400 {object} dto.ErrorResponse
+// @Failure 404 {object} dto.ErrorResponse
+// @Failure 429 {object} dto.ErrorResponse
+// @Failure 500 {object} dto.ErrorResponse
+// @Security ApiKeyAuth
+// @Router /webhooks/{id}/deliveries [get]
+func (h *WebhookHandler) GetDeliveries(w http.ResponseWriter, r *http.Request) {
+
+	limit, offset, err := parsePage(r)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	deliveries, err := h.Service.GetDeliveries(r.Context(), r.PathValue("id"), limit, offset)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrWebhookNotFound):
+			writeJSONError(w, http.StatusNotFound, err.Error())
+		case errors.Is(err, domain.ErrInvalidLimit), errors.Is(err, domain.ErrInvalidOffset):
+			writeJSONError(w, http.StatusBadRequest, err.Error())
+		default:
+			writeJSONError(w, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	resultList := make([]dto.WebhookDeliveryResponse, len(deliveries))
+	for i, d := range deliveries {
+		resultList[i] = dto.WebhookDeliveryResponse{
+			ID:             d.ID,
+			EventID:        d.EventID,
+			EventType:      string(d.EventType),
+			Status:         string(d.Status),
+			Attempts:       d.Attempts,
+			LastStatusCode: d.LastStatusCode,
+			LastError:      d.LastError,
+			CreatedAt:      d.CreatedAt,
+			UpdatedAt:      d.UpdatedAt,
+		}
+	}
+
+	writeJSON(w, http.StatusOK, resultList)
+}
+
+func mapServiceWebhookToDTO(webhook service.WebhookOutput) dto.WebhookResponse {
+
+	events := make([]string, len(webhook.Events))
+	for i, e := range webhook.Events {
+		events[i] = string(e)
+	}
+
+	return dto.WebhookResponse{
+		ID:        webhook.ID,
+		URL:       webhook.URL,
+		Secret:    webhook.Secret,
+		Events:    events,
+		CreatedAt: webhook.CreatedAt,
+	}
+}
@@ -0,0 +1,81 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/raulsilva-tech/devices-api/internal/domain"
+	"github.com/raulsilva-tech/devices-api/internal/dto"
+	"github.com/raulsilva-tech/devices-api/internal/service"
+)
+
+// Authenticator finds the API key matching a secret, service.APIKeyService implements it
+type Authenticator interface {
+	Authenticate(ctx context.Context, secret string) (*domain.APIKey, error)
+}
+
+// APIKeyAuth rejects the requests without a valid API key, sent as "Authorization: Bearer <key>"
+// or "X-API-Key: <key>", and places the key on the request context as a domain.Principal.
+// Requests already authenticated, e.g. by JWTAuth, are let through.
+func APIKeyAuth(auth Authenticator) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+			if domain.PrincipalFromContext(r.Context()) != nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			key, err := auth.Authenticate(r.Context(), APIKeyFromRequest(r))
+			if err != nil {
+				if errors.Is(err, service.ErrInvalidAPIKey) {
+					w.Header().Set("WWW-Authenticate", "Bearer")
+					writeError(w, http.StatusUnauthorized, err.Error())
+					return
+				}
+				writeError(w, http.StatusInternalServerError, err.Error())
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(domain.WithPrincipal(r.Context(), key.Principal())))
+		})
+	}
+}
+
+// RequireScope only lets through the requests whose principal was granted the scope
+func RequireScope(scope domain.Scope) func(http.HandlerFunc) http.HandlerFunc {
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+
+			principal := domain.PrincipalFromContext(r.Context())
+			if principal == nil {
+				w.Header().Set("WWW-Authenticate", "Bearer")
+				writeError(w, http.StatusUnauthorized, "authentication required")
+				return
+			}
+			if !principal.HasScope(scope) {
+				writeError(w, http.StatusForbidden, "missing the "+string(scope)+" scope")
+				return
+			}
+
+			next(w, r)
+		}
+	}
+}
+
+// APIKeyFromRequest returns the key sent with the request, or an empty string
+func APIKeyFromRequest(r *http.Request) string {
+	if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		return strings.TrimSpace(token)
+	}
+	return r.Header.Get("X-API-Key")
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(dto.ErrorResponse{Error: msg})
+}
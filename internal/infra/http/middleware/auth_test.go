@@ -0,0 +1,218 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/raulsilva-tech/devices-api/internal/domain"
+	"github.com/raulsilva-tech/devices-api/internal/infra/jwtauth"
+	"github.com/raulsilva-tech/devices-api/internal/service"
+	"github.com/stretchr/testify/require"
+)
+
+type staticAuthenticator map[string]*domain.APIKey
+
+func (a staticAuthenticator) Authenticate(ctx context.Context, secret string) (*domain.APIKey, error) {
+	if key, ok := a[secret]; ok {
+		return key, nil
+	}
+	return nil, service.ErrInvalidAPIKey
+}
+
+func TestAPIKeyAuth(t *testing.T) {
+
+	auth := staticAuthenticator{
+		"dak_reader": {ID: "1", Name: "reader", Scopes: []domain.Scope{domain.ScopeDevicesRead}},
+	}
+
+	var actor string
+	handler := APIKeyAuth(auth)(RequireScope(domain.ScopeDevicesRead)(func(w http.ResponseWriter, r *http.Request) {
+		actor = domain.ActorFromContext(r.Context())
+	}))
+	writeHandler := APIKeyAuth(auth)(RequireScope(domain.ScopeDevicesWrite)(func(w http.ResponseWriter, r *http.Request) {}))
+
+	tests := []struct {
+		name    string
+		handler http.Handler
+		header  string
+		value   string
+		status  int
+	}{
+		{"bearer", handler, "Authorization", "Bearer dak_reader", http.StatusOK},
+		{"x-api-key", handler, "X-API-Key", "dak_reader", http.StatusOK},
+		{"missing", handler, "", "", http.StatusUnauthorized},
+		{"unknown", handler, "Authorization", "Bearer dak_unknown", http.StatusUnauthorized},
+		{"missing scope", writeHandler, "X-API-Key", "dak_reader", http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/devices", nil)
+			if tt.header != "" {
+				req.Header.Set(tt.header, tt.value)
+			}
+			rec := httptest.NewRecorder()
+
+			tt.handler.ServeHTTP(rec, req)
+
+			require.Equal(t, tt.status, rec.Code)
+			if tt.status == http.StatusUnauthorized {
+				require.Equal(t, "Bearer", rec.Header().Get("WWW-Authenticate"))
+			}
+		})
+	}
+
+	// changes are attributed to the key
+	require.Equal(t, "apikey:1", actor)
+}
+
+type staticVerifier map[string]*domain.Principal
+
+func (v staticVerifier) Verify(ctx context.Context, token string) (*domain.Principal, error) {
+	if principal, ok := v[token]; ok {
+		return principal, nil
+	}
+	return nil, fmt.Errorf("%w: signature is invalid", jwtauth.ErrInvalidToken)
+}
+
+func TestJWTAuth(t *testing.T) {
+
+	const token = "eyJhbGciOiJSUzI1NiJ9.eyJzdWIiOiJ1c2VyLTQyIn0.c2ln"
+	verifier := staticVerifier{token: {Subject: "user-42", Scopes: []domain.Scope{domain.ScopeDevicesWrite}}}
+	keys := staticAuthenticator{"dak_reader": {ID: "1", Name: "reader", Scopes: []domain.Scope{domain.ScopeDevicesRead}}}
+
+	var principal *domain.Principal
+	var actor string
+	handler := JWTAuth(verifier)(APIKeyAuth(keys)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		principal = domain.PrincipalFromContext(r.Context())
+		actor = domain.ActorFromContext(r.Context())
+	})))
+
+	serve := func(authorization string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/devices", nil)
+		req.Header.Set("Authorization", authorization)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := serve("Bearer " + token)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, "user-42", principal.Subject)
+	require.Equal(t, "user-42", actor)
+
+	// API keys are left to APIKeyAuth
+	rec = serve("Bearer dak_reader")
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, "apikey:1", principal.Subject)
+
+	rec = serve("Bearer eyJhbGciOiJSUzI1NiJ9.eyJzdWIiOiJtYWxsb3J5In0.Zm9yZ2Vk")
+	require.Equal(t, http.StatusUnauthorized, rec.Code)
+	require.Equal(t, `Bearer error="invalid_token"`, rec.Header().Get("WWW-Authenticate"))
+}
+
+func TestOnBehalfOf(t *testing.T) {
+
+	auth := staticAuthenticator{"dak_reader": {ID: "1", Name: "reader", Scopes: []domain.Scope{domain.ScopeDevicesRead}}}
+
+	var actor, onBehalfOf string
+	handler := OnBehalfOf(APIKeyAuth(auth)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		actor = domain.ActorFromContext(r.Context())
+		onBehalfOf = domain.OnBehalfOfFromContext(r.Context())
+	})))
+
+	req := httptest.NewRequest(http.MethodGet, "/devices", nil)
+	req.Header.Set("X-API-Key", "dak_reader")
+	req.Header.Set("X-On-Behalf-Of", "jane")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	// the header is recorded apart, it never replaces the authenticated actor
+	require.Equal(t, "apikey:1", actor)
+	require.Equal(t, "jane", onBehalfOf)
+}
+
+func TestRequirePermission(t *testing.T) {
+
+	policy, err := domain.NewPolicy(map[domain.Role][]domain.Permission{
+		domain.RoleViewer:   {domain.PermissionDevicesRead},
+		domain.RoleOperator: {domain.PermissionDevicesRead, domain.PermissionDevicesChangeState},
+	})
+	require.NoError(t, err)
+
+	handler := RequirePermission(policy, domain.PermissionDevicesUpdate, domain.PermissionDevicesChangeState)(func(w http.ResponseWriter, r *http.Request) {})
+
+	tests := []struct {
+		name      string
+		principal *domain.Principal
+		status    int
+	}{
+		{"one of the permissions", &domain.Principal{Subject: "op", Roles: []domain.Role{domain.RoleOperator}}, http.StatusOK},
+		{"none of the permissions", &domain.Principal{Subject: "viewer", Roles: []domain.Role{domain.RoleViewer}}, http.StatusForbidden},
+		{"without roles", &domain.Principal{Subject: "nobody"}, http.StatusForbidden},
+		{"unauthenticated", nil, http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPatch, "/devices/1", nil)
+			if tt.principal != nil {
+				req = req.WithContext(domain.WithPrincipal(req.Context(), tt.principal))
+			}
+			rec := httptest.NewRecorder()
+
+			handler(rec, req)
+
+			require.Equal(t, tt.status, rec.Code)
+		})
+	}
+}
+
+func TestTenant(t *testing.T) {
+
+	auth := staticAuthenticator{
+		"dak_unbound": {ID: "1", Name: "unbound", Scopes: []domain.Scope{domain.ScopeDevicesRead}},
+		"dak_acme":    {ID: "2", Name: "acme", Scopes: []domain.Scope{domain.ScopeDevicesRead}, TenantID: "acme"},
+		"dak_admin":   {ID: "3", Name: "admin", Scopes: []domain.Scope{domain.ScopeAdmin}},
+	}
+
+	var tenant string
+	handler := APIKeyAuth(auth)(Tenant(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tenant = domain.TenantFromContext(r.Context())
+	})))
+
+	tests := []struct {
+		name   string
+		key    string
+		header string
+		status int
+		tenant string
+	}{
+		{"unbound without header", "dak_unbound", "", http.StatusOK, domain.DefaultTenant},
+		{"unbound cannot pick the tenant", "dak_unbound", "globex", http.StatusForbidden, ""},
+		{"admin picks the tenant", "dak_admin", "globex", http.StatusOK, "globex"},
+		{"bound without header", "dak_acme", "", http.StatusOK, "acme"},
+		{"bound with its tenant", "dak_acme", "acme", http.StatusOK, "acme"},
+		{"bound with another tenant", "dak_acme", "globex", http.StatusForbidden, ""},
+		{"invalid tenant", "dak_unbound", "Globex Corp", http.StatusBadRequest, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tenant = ""
+			req := httptest.NewRequest(http.MethodGet, "/devices", nil)
+			req.Header.Set("X-API-Key", tt.key)
+			if tt.header != "" {
+				req.Header.Set(TenantHeader, tt.header)
+			}
+			rec := httptest.NewRecorder()
+
+			handler.ServeHTTP(rec, req)
+
+			require.Equal(t, tt.status, rec.Code)
+			require.Equal(t, tt.tenant, tenant)
+		})
+	}
+}
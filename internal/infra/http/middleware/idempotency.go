@@ -0,0 +1,150 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+
+	"github.com/raulsilva-tech/devices-api/internal/domain"
+)
+
+const (
+	// IdempotencyKeyHeader names the key a client sends to retry a mutation safely
+	IdempotencyKeyHeader = "Idempotency-Key"
+	// maxIdempotentBodyBytes bounds the bodies read whole to fingerprint the requests
+	maxIdempotentBodyBytes = 1 << 20
+)
+
+// replayedHeaders are the response headers replayed along with the status and the body
+var replayedHeaders = []string{"Content-Type", "Location", "ETag"}
+
+// IdempotencyStore remembers the responses of the requests by key, service.IdempotencyService implements it
+type IdempotencyStore interface {
+	Begin(ctx context.Context, client, key, fingerprint string) (*domain.IdempotentResponse, error)
+	Complete(ctx context.Context, client, key string, response domain.IdempotentResponse) error
+	Release(ctx context.Context, client, key string) error
+}
+
+// Idempotency makes the mutations sent with an Idempotency-Key run once: the retries of the request
+// get the original response, with Idempotent-Replayed: true. Reusing the key for another method, path
+// or body gets 422, and retrying while the first request still runs gets 409. The responses with a 5xx
+// status are not kept, so the retries run the request again. The keys are apart for each client and tenant.
+func Idempotency(store IdempotencyStore) func(http.HandlerFunc) http.HandlerFunc {
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+
+			key := r.Header.Get(IdempotencyKeyHeader)
+			if key == "" || r.Method == http.MethodGet || r.Method == http.MethodHead {
+				next(w, r)
+				return
+			}
+
+			body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxIdempotentBodyBytes))
+			if err != nil {
+				var tooLarge *http.MaxBytesError
+				if errors.As(err, &tooLarge) {
+					writeError(w, http.StatusRequestEntityTooLarge, "request body too large to be idempotent")
+					return
+				}
+				writeError(w, http.StatusBadRequest, "cannot read the request body")
+				return
+			}
+			r.Body.Close()
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			ctx := r.Context()
+			client := ClientKey(r)
+
+			replay, err := store.Begin(ctx, client, key, domain.RequestFingerprint(r.Method, r.URL.RequestURI(), body))
+			if err != nil {
+				switch {
+				case errors.Is(err, domain.ErrInvalidIdempotencyKey):
+					writeError(w, http.StatusBadRequest, err.Error())
+				case errors.Is(err, domain.ErrIdempotencyKeyReused):
+					writeError(w, http.StatusUnprocessableEntity, err.Error())
+				case errors.Is(err, domain.ErrIdempotencyKeyInProgress):
+					writeError(w, http.StatusConflict, err.Error())
+				default:
+					writeError(w, http.StatusInternalServerError, err.Error())
+				}
+				return
+			}
+
+			if replay != nil {
+				for name, value := range replay.Headers {
+					w.Header().Set(name, value)
+				}
+				w.Header().Set("Idempotent-Replayed", "true")
+				w.WriteHeader(replay.StatusCode)
+				w.Write(replay.Body)
+				return
+			}
+
+			// the outcome is stored even when the client went away, it is what its retry expects;
+			// a panic releases the key like a 5xx
+			ctx = context.WithoutCancel(ctx)
+			completed := false
+			defer func() {
+				if !completed {
+					if err := store.Release(ctx, client, key); err != nil {
+						log.Printf("[%v] idempotency: cannot release key: %v", domain.RequestIDFromContext(ctx), err)
+					}
+				}
+			}()
+
+			rec := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+			next(rec, r)
+
+			if rec.status >= http.StatusInternalServerError {
+				return
+			}
+			// from here on the request ran, releasing the key would let a retry run it twice
+			completed = true
+
+			response := domain.IdempotentResponse{
+				StatusCode: rec.status,
+				Headers:    map[string]string{},
+				Body:       rec.body.Bytes(),
+			}
+			for _, name := range replayedHeaders {
+				if value := w.Header().Get(name); value != "" {
+					response.Headers[name] = value
+				}
+			}
+
+			if err := store.Complete(ctx, client, key, response); err != nil {
+				log.Printf("[%v] idempotency: cannot store response: %v", domain.RequestIDFromContext(ctx), err)
+			}
+		}
+	}
+}
+
+// responseRecorder writes the response through while keeping a copy of its status and body
+type responseRecorder struct {
+	http.ResponseWriter
+	status      int
+	body        bytes.Buffer
+	wroteHeader bool
+}
+
+func (w *responseRecorder) WriteHeader(code int) {
+	if !w.wroteHeader {
+		w.status = code
+		w.wroteHeader = true
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *responseRecorder) Write(b []byte) (int, error) {
+	w.wroteHeader = true
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer
+func (w *responseRecorder) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
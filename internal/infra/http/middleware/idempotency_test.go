@@ -0,0 +1,132 @@
+package middleware
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/raulsilva-tech/devices-api/internal/domain"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryIdempotencyStore keeps the fingerprint and the response of each key
+type memoryIdempotencyStore struct {
+	fingerprints map[string]string
+	responses    map[string]*domain.IdempotentResponse
+}
+
+func newMemoryIdempotencyStore() *memoryIdempotencyStore {
+	return &memoryIdempotencyStore{
+		fingerprints: map[string]string{},
+		responses:    map[string]*domain.IdempotentResponse{},
+	}
+}
+
+func (s *memoryIdempotencyStore) Begin(ctx context.Context, client, key, fingerprint string) (*domain.IdempotentResponse, error) {
+	if !domain.IsValidIdempotencyKey(key) {
+		return nil, domain.ErrInvalidIdempotencyKey
+	}
+	id := client + "|" + key
+	stored, ok := s.fingerprints[id]
+	switch {
+	case !ok:
+		s.fingerprints[id] = fingerprint
+		return nil, nil
+	case stored != fingerprint:
+		return nil, domain.ErrIdempotencyKeyReused
+	case s.responses[id] == nil:
+		return nil, domain.ErrIdempotencyKeyInProgress
+	}
+	return s.responses[id], nil
+}
+
+func (s *memoryIdempotencyStore) Complete(ctx context.Context, client, key string, response domain.IdempotentResponse) error {
+	s.responses[client+"|"+key] = &response
+	return nil
+}
+
+func (s *memoryIdempotencyStore) Release(ctx context.Context, client, key string) error {
+	delete(s.fingerprints, client+"|"+key)
+	return nil
+}
+
+func TestIdempotency(t *testing.T) {
+
+	runs := 0
+	create := func(w http.ResponseWriter, r *http.Request) {
+		runs++
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Location", "/devices/1")
+		w.WriteHeader(http.StatusCreated)
+		w.Write(body)
+	}
+	handler := Idempotency(newMemoryIdempotencyStore())(create)
+
+	request := func(method, body, key string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/devices", strings.NewReader(body))
+		if key != "" {
+			req.Header.Set(IdempotencyKeyHeader, key)
+		}
+		rec := httptest.NewRecorder()
+		handler(rec, req)
+		return rec
+	}
+
+	rec := request(http.MethodPost, `{"name":"sensor"}`, "key-1")
+	require.Equal(t, http.StatusCreated, rec.Code)
+	require.Equal(t, `{"name":"sensor"}`, rec.Body.String())
+	require.Empty(t, rec.Header().Get("Idempotent-Replayed"))
+
+	// the retry gets the original response without running the handler
+	rec = request(http.MethodPost, `{"name":"sensor"}`, "key-1")
+	require.Equal(t, http.StatusCreated, rec.Code)
+	require.Equal(t, `{"name":"sensor"}`, rec.Body.String())
+	require.Equal(t, "/devices/1", rec.Header().Get("Location"))
+	require.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+	require.Equal(t, "true", rec.Header().Get("Idempotent-Replayed"))
+	require.Equal(t, 1, runs)
+
+	// the key reused with another body
+	rec = request(http.MethodPost, `{"name":"camera"}`, "key-1")
+	require.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+	require.Equal(t, 1, runs)
+
+	// the requests without a key and the reads always run
+	require.Equal(t, http.StatusCreated, request(http.MethodPost, `{"name":"sensor"}`, "").Code)
+	require.Equal(t, http.StatusCreated, request(http.MethodGet, "", "key-1").Code)
+	require.Equal(t, 3, runs)
+
+	require.Equal(t, http.StatusBadRequest, request(http.MethodPost, `{}`, "key\x01").Code)
+}
+
+func TestIdempotency_ServerErrorReleasesKey(t *testing.T) {
+
+	status := http.StatusServiceUnavailable
+	runs := 0
+	handler := Idempotency(newMemoryIdempotencyStore())(func(w http.ResponseWriter, r *http.Request) {
+		runs++
+		w.WriteHeader(status)
+	})
+
+	request := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/devices", strings.NewReader(`{}`))
+		req.Header.Set(IdempotencyKeyHeader, "key-1")
+		rec := httptest.NewRecorder()
+		handler(rec, req)
+		return rec
+	}
+
+	require.Equal(t, http.StatusServiceUnavailable, request().Code)
+
+	// the retry runs the request again, and its outcome is kept
+	status = http.StatusCreated
+	require.Equal(t, http.StatusCreated, request().Code)
+	rec := request()
+	require.Equal(t, http.StatusCreated, rec.Code)
+	require.Equal(t, "true", rec.Header().Get("Idempotent-Replayed"))
+	require.Equal(t, 2, runs)
+}
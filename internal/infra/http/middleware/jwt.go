@@ -0,0 +1,46 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/raulsilva-tech/devices-api/internal/domain"
+	"github.com/raulsilva-tech/devices-api/internal/infra/jwtauth"
+)
+
+// TokenVerifier validates a bearer token, jwtauth.Verifier implements it
+type TokenVerifier interface {
+	Verify(ctx context.Context, token string) (*domain.Principal, error)
+}
+
+// JWTAuth authenticates the requests carrying a JWT as bearer token and places its subject, scopes
+// and claims on the request context. Requests with other credentials, such as an API key, are left
+// to the next authentication middleware.
+func JWTAuth(verifier TokenVerifier) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+			token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			token = strings.TrimSpace(token)
+			if !ok || !jwtauth.IsJWT(token) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			principal, err := verifier.Verify(r.Context(), token)
+			if err != nil {
+				if errors.Is(err, jwtauth.ErrInvalidToken) {
+					w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+					writeError(w, http.StatusUnauthorized, err.Error())
+					return
+				}
+				writeError(w, http.StatusServiceUnavailable, err.Error())
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(domain.WithPrincipal(r.Context(), principal)))
+		})
+	}
+}
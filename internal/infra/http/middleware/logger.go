@@ -4,6 +4,8 @@ import (
 	"log"
 	"net/http"
 	"time"
+
+	"github.com/raulsilva-tech/devices-api/internal/domain"
 )
 
 type statusWriter struct {
@@ -16,6 +18,11 @@ func (w *statusWriter) WriteHeader(code int) {
 	w.ResponseWriter.WriteHeader(code)
 }
 
+// Unwrap lets http.ResponseController reach the underlying writer, e.g. to flush streamed responses
+func (w *statusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
 func Logger(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 
@@ -24,7 +31,7 @@ func Logger(next http.Handler) http.Handler {
 
 		next.ServeHTTP(sw, r)
 
-		reqID := r.Context().Value(RequestIDKey)
+		reqID := domain.RequestIDFromContext(r.Context())
 
 		log.Printf("[%v] %s %s %d %v",
 			reqID,
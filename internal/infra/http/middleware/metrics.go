@@ -0,0 +1,58 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/raulsilva-tech/devices-api/internal/infra/metrics"
+)
+
+// unmatchedRoute labels the requests that did not reach a route, e.g. rejected for lack of credentials
+const unmatchedRoute = "unmatched"
+
+type routeKey struct{}
+
+// Metrics counts the requests, in flight and served, and times them by route pattern and status.
+// The routes record their pattern with Route, as it is only known once the request is routed.
+func Metrics(m *metrics.HTTP) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+			start := time.Now()
+			m.Started()
+
+			r, route := withRoute(r)
+			sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+
+			next.ServeHTTP(sw, r)
+
+			pattern := unmatchedRoute
+			if p := route.Load(); p != nil {
+				pattern = *p
+			}
+			m.Finished(pattern, sw.status, time.Since(start))
+		})
+	}
+}
+
+// Route records the pattern of the route serving the request, e.g. "GET /devices/{id}", for Metrics and Tracing
+func Route(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if route, ok := r.Context().Value(routeKey{}).(*atomic.Pointer[string]); ok && r.Pattern != "" {
+			pattern := r.Pattern
+			route.Store(&pattern)
+		}
+		next(w, r)
+	}
+}
+
+// withRoute returns the request with a place for Route to record the pattern in, unless it already has one
+func withRoute(r *http.Request) (*http.Request, *atomic.Pointer[string]) {
+	if route, ok := r.Context().Value(routeKey{}).(*atomic.Pointer[string]); ok {
+		return r, route
+	}
+	route := &atomic.Pointer[string]{}
+	return r.WithContext(context.WithValue(r.Context(), routeKey{}, route)), route
+}
@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/raulsilva-tech/devices-api/internal/infra/metrics"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetrics(t *testing.T) {
+
+	reg := prometheus.NewRegistry()
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /devices/{id}", Route(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	handler := Metrics(metrics.NewHTTP(reg))(mux)
+
+	for _, path := range []string{"/devices/1", "/devices/2", "/unknown"} {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	// the requests are counted by route pattern, not by path
+	expected := `
+# HELP devices_api_http_requests_in_flight HTTP requests being served.
+# TYPE devices_api_http_requests_in_flight gauge
+devices_api_http_requests_in_flight 0
+# HELP devices_api_http_requests_total HTTP requests served, by route pattern and status code.
+# TYPE devices_api_http_requests_total counter
+devices_api_http_requests_total{route="GET /devices/{id}",status="404"} 2
+devices_api_http_requests_total{route="unmatched",status="404"} 1
+`
+	require.NoError(t, testutil.GatherAndCompare(reg, strings.NewReader(expected),
+		"devices_api_http_requests_total", "devices_api_http_requests_in_flight"))
+	require.Equal(t, 2, testutil.CollectAndCount(reg, "devices_api_http_request_duration_seconds"))
+}
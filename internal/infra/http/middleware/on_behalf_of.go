@@ -0,0 +1,22 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/raulsilva-tech/devices-api/internal/domain"
+)
+
+// OnBehalfOf places whom the caller says it acts for, sent in the X-On-Behalf-Of header, on the
+// request context. The changes are still attributed to the authenticated principal.
+func OnBehalfOf(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		onBehalfOf := r.Header.Get("X-On-Behalf-Of")
+		if onBehalfOf == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(domain.WithOnBehalfOf(r.Context(), onBehalfOf)))
+	})
+}
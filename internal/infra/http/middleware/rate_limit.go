@@ -0,0 +1,107 @@
+package middleware
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/raulsilva-tech/devices-api/internal/domain"
+	"github.com/raulsilva-tech/devices-api/internal/infra/ratelimit"
+)
+
+// RateLimit limits the requests of each client to the routes of group, e.g. the reads or the writes.
+// The clients are told apart by their API key or JWT subject, or by their IP when not authenticated.
+// Every response carries the RateLimit-Limit, RateLimit-Remaining and RateLimit-Reset headers, and the
+// requests over the limit get 429 with Retry-After. When the store fails the request is let through.
+func RateLimit(store ratelimit.Store, group string, limit ratelimit.Limit) func(http.HandlerFunc) http.HandlerFunc {
+	return func(next http.HandlerFunc) http.HandlerFunc {
+
+		if !limit.IsEnabled() {
+			return next
+		}
+		policy := policyOf(limit)
+
+		return func(w http.ResponseWriter, r *http.Request) {
+			if take(w, r, store, group+":"+ClientKey(r), limit, policy) {
+				next(w, r)
+			}
+		}
+	}
+}
+
+// IPRateLimit limits the requests of each IP before they are authenticated, so the clients sending
+// bad credentials cannot look them up without limit. It goes in front of APIKeyAuth and JWTAuth; the
+// RateLimit of the routes then sets the headers of the client's own bucket.
+func IPRateLimit(store ratelimit.Store, limit ratelimit.Limit) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+
+		if !limit.IsEnabled() {
+			return next
+		}
+		policy := policyOf(limit)
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if take(w, r, store, "ip:"+ClientIP(r), limit, policy) {
+				next.ServeHTTP(w, r)
+			}
+		})
+	}
+}
+
+// take removes a token from the bucket of key and sets the headers, answering 429 when it is empty
+func take(w http.ResponseWriter, r *http.Request, store ratelimit.Store, key string, limit ratelimit.Limit, policy string) bool {
+
+	result, err := store.Take(r.Context(), key, limit)
+	if err != nil {
+		log.Printf("[%v] rate limit: %v", domain.RequestIDFromContext(r.Context()), err)
+		return true
+	}
+
+	w.Header().Set("RateLimit-Policy", policy)
+	w.Header().Set("RateLimit-Limit", strconv.Itoa(result.Limit))
+	w.Header().Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+	w.Header().Set("RateLimit-Reset", seconds(result.ResetAfter))
+
+	if !result.Allowed {
+		w.Header().Set("Retry-After", seconds(result.RetryAfter))
+		writeError(w, http.StatusTooManyRequests, "rate limit exceeded")
+		return false
+	}
+
+	return true
+}
+
+func policyOf(limit ratelimit.Limit) string {
+	return fmt.Sprintf("%d;w=%d", limit.Requests, int(limit.Per.Seconds()))
+}
+
+// ClientKey identifies the caller of the request for the rate limits: the subject of its API key
+// or JWT, or its IP on the routes served without credentials.
+func ClientKey(r *http.Request) string {
+
+	if principal := domain.PrincipalFromContext(r.Context()); principal != nil {
+		return "sub:" + principal.Subject
+	}
+
+	return "ip:" + ClientIP(r)
+}
+
+// ClientIP is the IP the request comes from. X-Forwarded-For is not trusted, behind a proxy every
+// client shares its IP.
+func ClientIP(r *http.Request) string {
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// seconds rounds up, so clients waiting that long are sure to get a token
+func seconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
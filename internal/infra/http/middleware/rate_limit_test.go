@@ -0,0 +1,103 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/raulsilva-tech/devices-api/internal/domain"
+	"github.com/raulsilva-tech/devices-api/internal/infra/ratelimit"
+	"github.com/stretchr/testify/require"
+)
+
+type failingStore struct{}
+
+func (failingStore) Take(ctx context.Context, key string, limit ratelimit.Limit) (ratelimit.Result, error) {
+	return ratelimit.Result{}, errors.New("store unavailable")
+}
+
+func TestRateLimit(t *testing.T) {
+
+	store := ratelimit.NewMemoryStore()
+	limit := ratelimit.Limit{Requests: 60, Per: time.Minute, Burst: 2}
+	ok := func(w http.ResponseWriter, r *http.Request) {}
+	reads := RateLimit(store, "read", limit)(ok)
+	writes := RateLimit(store, "write", limit)(ok)
+
+	request := func(handler http.HandlerFunc, principal *domain.Principal, ip string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/devices", nil)
+		req.RemoteAddr = ip + ":1234"
+		if principal != nil {
+			req = req.WithContext(domain.WithPrincipal(req.Context(), principal))
+		}
+		rec := httptest.NewRecorder()
+		handler(rec, req)
+		return rec
+	}
+
+	script := &domain.Principal{Subject: "apikey:script"}
+
+	rec := request(reads, script, "10.0.0.1")
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, "2", rec.Header().Get("RateLimit-Limit"))
+	require.Equal(t, "1", rec.Header().Get("RateLimit-Remaining"))
+	require.Equal(t, "60;w=60", rec.Header().Get("RateLimit-Policy"))
+
+	require.Equal(t, http.StatusOK, request(reads, script, "10.0.0.1").Code)
+
+	rec = request(reads, script, "10.0.0.2")
+	require.Equal(t, http.StatusTooManyRequests, rec.Code)
+	require.Equal(t, "1", rec.Header().Get("Retry-After"))
+	require.Equal(t, "0", rec.Header().Get("RateLimit-Remaining"))
+
+	// the writes, the other principals and the anonymous clients have their own buckets
+	require.Equal(t, http.StatusOK, request(writes, script, "10.0.0.1").Code)
+	require.Equal(t, http.StatusOK, request(reads, &domain.Principal{Subject: "user-42"}, "10.0.0.1").Code)
+	require.Equal(t, http.StatusOK, request(reads, nil, "10.0.0.1").Code)
+	require.Equal(t, http.StatusOK, request(reads, nil, "10.0.0.1").Code)
+	require.Equal(t, http.StatusTooManyRequests, request(reads, nil, "10.0.0.1").Code)
+	require.Equal(t, http.StatusOK, request(reads, nil, "10.0.0.2").Code)
+
+	// a failing store lets the requests through
+	require.Equal(t, http.StatusOK, request(RateLimit(failingStore{}, "read", limit)(ok), script, "10.0.0.1").Code)
+
+	// a disabled limit sets no headers
+	rec = request(RateLimit(store, "read", ratelimit.Limit{})(ok), script, "10.0.0.1")
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Empty(t, rec.Header().Get("RateLimit-Limit"))
+}
+
+func TestIPRateLimit(t *testing.T) {
+
+	store := ratelimit.NewMemoryStore()
+	limit := ratelimit.Limit{Requests: 60, Per: time.Minute, Burst: 2}
+
+	authenticated := 0
+	handler := IPRateLimit(store, limit)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authenticated++
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+
+	request := func(ip string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/devices", nil)
+		req.RemoteAddr = ip + ":1234"
+		req.Header.Set("X-API-Key", "wrong")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	require.Equal(t, http.StatusUnauthorized, request("10.0.0.1").Code)
+	require.Equal(t, http.StatusUnauthorized, request("10.0.0.1").Code)
+
+	// the requests over the limit are not authenticated at all
+	rec := request("10.0.0.1")
+	require.Equal(t, http.StatusTooManyRequests, rec.Code)
+	require.Equal(t, "1", rec.Header().Get("Retry-After"))
+	require.Equal(t, 2, authenticated)
+
+	require.Equal(t, http.StatusUnauthorized, request("10.0.0.2").Code)
+}
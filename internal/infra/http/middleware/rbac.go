@@ -0,0 +1,33 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/raulsilva-tech/devices-api/internal/domain"
+)
+
+// RequirePermission only lets through the requests whose principal has a role granted one of the
+// permissions by the policy. The services check again the exact permissions of the operation, e.g.
+// a PATCH that only changes the state does not need devices.update.
+func RequirePermission(policy *domain.Policy, permissions ...domain.Permission) func(http.HandlerFunc) http.HandlerFunc {
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+
+			principal := domain.PrincipalFromContext(r.Context())
+			if principal == nil {
+				w.Header().Set("WWW-Authenticate", "Bearer")
+				writeError(w, http.StatusUnauthorized, "authentication required")
+				return
+			}
+
+			for _, p := range permissions {
+				if policy.Allows(principal.Roles, p) {
+					next(w, r)
+					return
+				}
+			}
+
+			writeError(w, http.StatusForbidden, "permission denied")
+		}
+	}
+}
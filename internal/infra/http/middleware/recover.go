@@ -3,6 +3,8 @@ package middleware
 import (
 	"log"
 	"net/http"
+
+	"github.com/raulsilva-tech/devices-api/internal/domain"
 )
 
 func Recover(next http.Handler) http.Handler {
@@ -11,7 +13,7 @@ func Recover(next http.Handler) http.Handler {
 		defer func() {
 			if rec := recover(); rec != nil {
 
-				reqID := r.Context().Value(RequestIDKey)
+				reqID := domain.RequestIDFromContext(r.Context())
 
 				log.Printf("[PANIC] requestID=%v err=%v", reqID, rec)
 
@@ -22,4 +24,3 @@ func Recover(next http.Handler) http.Handler {
 		next.ServeHTTP(w, r)
 	})
 }
-
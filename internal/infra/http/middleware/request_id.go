@@ -1,16 +1,12 @@
 package middleware
 
 import (
-	"context"
 	"net/http"
 
 	"github.com/google/uuid"
+	"github.com/raulsilva-tech/devices-api/internal/domain"
 )
 
-type contextKey string
-
-const RequestIDKey contextKey = "requestID"
-
 func RequestID(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 
@@ -21,8 +17,6 @@ func RequestID(next http.Handler) http.Handler {
 
 		w.Header().Set("X-Request-ID", id)
 
-		ctx := context.WithValue(r.Context(), RequestIDKey, id)
-
-		next.ServeHTTP(w, r.WithContext(ctx))
+		next.ServeHTTP(w, r.WithContext(domain.WithRequestID(r.Context(), id)))
 	})
 }
@@ -0,0 +1,30 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/raulsilva-tech/devices-api/internal/domain"
+)
+
+// TenantHeader lets the admin principals not bound to a tenant pick the tenant they work on
+const TenantHeader = "X-Tenant-ID"
+
+// Tenant scopes the request context to the tenant resolved by domain.ResolveTenant, from the
+// principal and the X-Tenant-ID header. It must run after the authentication.
+func Tenant(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		tenant, err := domain.ResolveTenant(domain.PrincipalFromContext(r.Context()), r.Header.Get(TenantHeader))
+		if err != nil {
+			if errors.Is(err, domain.ErrTenantForbidden) {
+				writeError(w, http.StatusForbidden, err.Error())
+				return
+			}
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(domain.WithTenant(r.Context(), tenant)))
+	})
+}
@@ -0,0 +1,56 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = otel.Tracer("github.com/raulsilva-tech/devices-api/internal/infra/http/middleware")
+
+// Tracing starts the server span of the request, continuing the trace of the caller when it sends
+// a W3C traceparent header. The span is named after the route pattern once known, see Route, and
+// is marked as failed on a 5xx status.
+func Tracing(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+		ctx, span := tracer.Start(ctx, r.Method,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				semconv.HTTPRequestMethodKey.String(r.Method),
+				semconv.URLPath(r.URL.Path),
+				semconv.UserAgentOriginal(r.UserAgent()),
+			),
+		)
+		defer span.End()
+
+		r, route := withRoute(r.WithContext(ctx))
+		sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+
+		next.ServeHTTP(sw, r)
+
+		if pattern := route.Load(); pattern != nil {
+			span.SetName(*pattern)
+			// the route is the path of the pattern, without its method
+			path := *pattern
+			if _, p, ok := strings.Cut(path, " "); ok {
+				path = p
+			}
+			span.SetAttributes(semconv.HTTPRoute(path))
+		}
+		if id := w.Header().Get("X-Request-ID"); id != "" {
+			span.SetAttributes(attribute.String("http.request.id", id))
+		}
+		span.SetAttributes(semconv.HTTPResponseStatusCode(sw.status))
+		if sw.status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(sw.status))
+		}
+	})
+}
@@ -0,0 +1,69 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+var (
+	spansOnce sync.Once
+	spans     *tracetest.InMemoryExporter
+)
+
+// recordSpans installs a tracer provider keeping the spans in memory. The provider can only
+// be installed once, as the tracer of the package binds to the first one.
+func recordSpans(t *testing.T) *tracetest.InMemoryExporter {
+	spansOnce.Do(func() {
+		spans = tracetest.NewInMemoryExporter()
+		otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(spans)))
+		otel.SetTextMapPropagator(propagation.TraceContext{})
+	})
+	spans.Reset()
+	return spans
+}
+
+func TestTracing(t *testing.T) {
+
+	exporter := recordSpans(t)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /devices/{id}", Route(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	handler := Tracing(mux)
+
+	req := httptest.NewRequest(http.MethodGet, "/devices/42", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	recorded := exporter.GetSpans()
+	require.Len(t, recorded, 1)
+	span := recorded[0]
+
+	// the span continues the trace of the caller and is named after the route
+	require.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", span.SpanContext.TraceID().String())
+	require.Equal(t, "00f067aa0ba902b7", span.Parent.SpanID().String())
+	require.Equal(t, "GET /devices/{id}", span.Name)
+	require.Equal(t, codes.Error, span.Status.Code)
+	require.Contains(t, span.Attributes, attribute.String("http.route", "/devices/{id}"))
+	require.Contains(t, span.Attributes, attribute.Int("http.response.status_code", http.StatusInternalServerError))
+
+	// without a traceparent a new trace starts
+	exporter.Reset()
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/unknown", nil))
+	recorded = exporter.GetSpans()
+	require.Len(t, recorded, 1)
+	require.False(t, recorded[0].Parent.IsValid())
+	require.Equal(t, http.MethodGet, recorded[0].Name)
+	require.Equal(t, codes.Unset, recorded[0].Status.Code)
+}
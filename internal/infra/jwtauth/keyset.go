@@ -0,0 +1,299 @@
+package jwtauth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math/big"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+var (
+	ErrUnknownKey = errors.New("no key in the jwks matches the token")
+	// ErrKeySetUnavailable means the JWKS could not be loaded, so no token can be verified
+	ErrKeySetUnavailable = errors.New("jwks unavailable")
+)
+
+const (
+	// maxKeySetSize bounds the JWKS documents read
+	maxKeySetSize = 1 << 20
+	// fetchTimeout bounds a reload, which is detached from the request that triggered it
+	fetchTimeout = 30 * time.Second
+)
+
+type KeySetConfig struct {
+	RefreshInterval    time.Duration // keys older than this are reloaded on the next lookup
+	MinRefreshInterval time.Duration // least time between two reloads caused by an unknown kid
+	Client             *http.Client
+}
+
+func DefaultKeySetConfig() KeySetConfig {
+	return KeySetConfig{
+		RefreshInterval:    time.Hour,
+		MinRefreshInterval: 30 * time.Second,
+		Client:             &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// KeySet caches the public keys of a JWKS read from a file or an http(s) URL. The keys are
+// reloaded once they are older than the refresh interval, and as soon as a token is signed with
+// an unknown kid, which is how a rotation at the identity provider shows up. The JWKS is fetched
+// without holding the lock and at most once at a time, the cached keys keep being served meanwhile.
+type KeySet struct {
+	source string
+	config KeySetConfig
+	fetch  singleflight.Group
+
+	mu        sync.RWMutex
+	keys      map[string]crypto.PublicKey
+	fetchedAt time.Time
+}
+
+func NewKeySet(source string, config KeySetConfig) *KeySet {
+	return &KeySet{
+		source: source,
+		config: config,
+	}
+}
+
+// Key returns the public key with the kid. An empty kid matches the only key of a JWKS holding one.
+func (s *KeySet) Key(ctx context.Context, kid string) (crypto.PublicKey, error) {
+
+	keys, fetchedAt := s.cached()
+
+	if keys == nil {
+		// nothing to serve yet, the first load is waited for; after a failed one the source is
+		// retried no sooner than the min refresh interval, like for an unknown kid
+		if time.Since(fetchedAt) < s.config.MinRefreshInterval {
+			return nil, ErrKeySetUnavailable
+		}
+		if err := s.refresh(ctx); err != nil {
+			return nil, err
+		}
+		keys, fetchedAt = s.cached()
+	} else if time.Since(fetchedAt) > s.config.RefreshInterval {
+		// the stale keys are better than none while they are reloaded
+		go func() {
+			if err := s.refresh(context.Background()); err != nil {
+				log.Printf("jwks: cannot refresh %s, keeping the cached keys: %v", s.source, err)
+			}
+		}()
+	}
+
+	if key, ok := lookup(keys, kid); ok {
+		return key, nil
+	}
+
+	if time.Since(fetchedAt) < s.config.MinRefreshInterval {
+		return nil, ErrUnknownKey
+	}
+	if err := s.refresh(ctx); err != nil {
+		return nil, err
+	}
+
+	keys, _ = s.cached()
+	if key, ok := lookup(keys, kid); ok {
+		return key, nil
+	}
+
+	return nil, ErrUnknownKey
+}
+
+func (s *KeySet) cached() (map[string]crypto.PublicKey, time.Time) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.keys, s.fetchedAt
+}
+
+func lookup(keys map[string]crypto.PublicKey, kid string) (crypto.PublicKey, bool) {
+	if kid == "" && len(keys) == 1 {
+		for _, key := range keys {
+			return key, true
+		}
+	}
+	key, ok := keys[kid]
+	return key, ok
+}
+
+// refresh reloads the JWKS, joining the reload in progress if there is one. The fetch outlives
+// a caller that gives up, as other callers may be waiting on it. The fetch time is recorded even
+// on failure, so an unreachable source is not hammered.
+func (s *KeySet) refresh(ctx context.Context) error {
+
+	result := s.fetch.DoChan("jwks", func() (any, error) {
+
+		s.mu.Lock()
+		s.fetchedAt = time.Now()
+		s.mu.Unlock()
+
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), fetchTimeout)
+		defer cancel()
+
+		data, err := s.read(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrKeySetUnavailable, err)
+		}
+
+		keys, err := parseKeySet(data)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrKeySetUnavailable, err)
+		}
+
+		s.mu.Lock()
+		s.keys = keys
+		s.mu.Unlock()
+
+		return nil, nil
+	})
+
+	select {
+	case r := <-result:
+		return r.Err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (s *KeySet) read(ctx context.Context) ([]byte, error) {
+
+	if !strings.HasPrefix(s.source, "http://") && !strings.HasPrefix(s.source, "https://") {
+		return os.ReadFile(s.source)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.source, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := s.config.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	return io.ReadAll(io.LimitReader(resp.Body, maxKeySetSize))
+}
+
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// parseKeySet reads the RSA and EC signing keys of a JWKS, the other keys are skipped
+func parseKeySet(data []byte) (map[string]crypto.PublicKey, error) {
+
+	var set struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, err
+	}
+
+	keys := map[string]crypto.PublicKey{}
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+
+		var (
+			key crypto.PublicKey
+			err error
+		)
+		switch jwk.Kty {
+		case "RSA":
+			key, err = parseRSAKey(jwk)
+		case "EC":
+			key, err = parseECKey(jwk)
+		default:
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("key %q: %w", jwk.Kid, err)
+		}
+
+		keys[jwk.Kid] = key
+	}
+
+	if len(keys) == 0 {
+		return nil, errors.New("no signing keys")
+	}
+
+	return keys, nil
+}
+
+func parseRSAKey(jwk jsonWebKey) (*rsa.PublicKey, error) {
+
+	n, err := base64.RawURLEncoding.DecodeString(jwk.N)
+	if err != nil {
+		return nil, err
+	}
+	e, err := base64.RawURLEncoding.DecodeString(jwk.E)
+	if err != nil {
+		return nil, err
+	}
+
+	exponent := new(big.Int).SetBytes(e)
+	if len(n) == 0 || !exponent.IsInt64() || exponent.Int64() > 1<<31-1 {
+		return nil, errors.New("invalid rsa key")
+	}
+
+	return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exponent.Int64())}, nil
+}
+
+func parseECKey(jwk jsonWebKey) (*ecdsa.PublicKey, error) {
+
+	var curve elliptic.Curve
+	switch jwk.Crv {
+	case "P-256":
+		curve = elliptic.P256()
+	case "P-384":
+		curve = elliptic.P384()
+	case "P-521":
+		curve = elliptic.P521()
+	default:
+		return nil, fmt.Errorf("unsupported curve %q", jwk.Crv)
+	}
+
+	x, err := base64.RawURLEncoding.DecodeString(jwk.X)
+	if err != nil {
+		return nil, err
+	}
+	y, err := base64.RawURLEncoding.DecodeString(jwk.Y)
+	if err != nil {
+		return nil, err
+	}
+
+	size := (curve.Params().BitSize + 7) / 8
+	if len(x) != size || len(y) != size {
+		return nil, errors.New("invalid ec key")
+	}
+
+	// the uncompressed point form, which also checks the point is on the curve
+	point := append([]byte{4}, append(x, y...)...)
+	return ecdsa.ParseUncompressedPublicKey(curve, point)
+}
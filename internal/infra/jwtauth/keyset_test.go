@@ -0,0 +1,238 @@
+package jwtauth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// jwksServer serves the public keys it holds and counts the fetches
+type jwksServer struct {
+	*httptest.Server
+
+	mu      sync.Mutex
+	keys    []map[string]string
+	fetches atomic.Int32
+}
+
+func newJWKSServer(t *testing.T) *jwksServer {
+	s := &jwksServer{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.fetches.Add(1)
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		json.NewEncoder(w).Encode(map[string]any{"keys": s.keys})
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *jwksServer) setKeys(keys ...map[string]string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.keys = keys
+}
+
+func rsaJWK(kid string, key *rsa.PrivateKey) map[string]string {
+	return map[string]string{
+		"kty": "RSA",
+		"kid": kid,
+		"use": "sig",
+		"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+		"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+	}
+}
+
+func ecJWK(kid string, key *ecdsa.PrivateKey) map[string]string {
+	point, _ := key.PublicKey.Bytes() // 0x04 || x || y
+	size := (len(point) - 1) / 2
+	return map[string]string{
+		"kty": "EC",
+		"kid": kid,
+		"crv": "P-256",
+		"x":   base64.RawURLEncoding.EncodeToString(point[1 : 1+size]),
+		"y":   base64.RawURLEncoding.EncodeToString(point[1+size:]),
+	}
+}
+
+func newRSAKey(t *testing.T) *rsa.PrivateKey {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	return key
+}
+
+func newECKey(t *testing.T) *ecdsa.PrivateKey {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	return key
+}
+
+func TestKeySet_CachesAndPicksUpRotatedKeys(t *testing.T) {
+
+	ctx := context.Background()
+	first, second := newRSAKey(t), newECKey(t)
+
+	server := newJWKSServer(t)
+	server.setKeys(rsaJWK("first", first))
+
+	keys := NewKeySet(server.URL, KeySetConfig{RefreshInterval: time.Hour, Client: server.Client()})
+
+	key, err := keys.Key(ctx, "first")
+	require.NoError(t, err)
+	require.True(t, first.PublicKey.Equal(key))
+
+	_, err = keys.Key(ctx, "first")
+	require.NoError(t, err)
+	require.Equal(t, int32(1), server.fetches.Load())
+
+	// the provider rotates: the unknown kid reloads the keys
+	server.setKeys(rsaJWK("first", first), ecJWK("second", second))
+	key, err = keys.Key(ctx, "second")
+	require.NoError(t, err)
+	require.True(t, second.PublicKey.Equal(key))
+	require.Equal(t, int32(2), server.fetches.Load())
+}
+
+func TestKeySet_LimitsReloadsForUnknownKeys(t *testing.T) {
+
+	server := newJWKSServer(t)
+	server.setKeys(rsaJWK("first", newRSAKey(t)))
+
+	keys := NewKeySet(server.URL, KeySetConfig{RefreshInterval: time.Hour, MinRefreshInterval: time.Hour, Client: server.Client()})
+
+	for range 3 {
+		_, err := keys.Key(context.Background(), "forged")
+		require.ErrorIs(t, err, ErrUnknownKey)
+	}
+	require.Equal(t, int32(1), server.fetches.Load())
+}
+
+func TestKeySet_LimitsReloadsWhenTheFirstLoadFails(t *testing.T) {
+
+	var fetches atomic.Int32
+	var healthy atomic.Bool
+	key := newRSAKey(t)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		if !healthy.Load() {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]any{"keys": []map[string]string{rsaJWK("first", key)}})
+	}))
+	t.Cleanup(server.Close)
+
+	keys := NewKeySet(server.URL, KeySetConfig{RefreshInterval: time.Hour, MinRefreshInterval: 50 * time.Millisecond, Client: server.Client()})
+
+	for range 3 {
+		_, err := keys.Key(context.Background(), "first")
+		require.ErrorIs(t, err, ErrKeySetUnavailable)
+	}
+	require.Equal(t, int32(1), fetches.Load())
+
+	// the source is retried once the min refresh interval has passed
+	healthy.Store(true)
+	time.Sleep(60 * time.Millisecond)
+	_, err := keys.Key(context.Background(), "first")
+	require.NoError(t, err)
+	require.Equal(t, int32(2), fetches.Load())
+}
+
+func TestKeySet_KeepsCachedKeysWhenTheSourceFails(t *testing.T) {
+
+	ctx := context.Background()
+	server := newJWKSServer(t)
+	server.setKeys(rsaJWK("first", newRSAKey(t)))
+
+	keys := NewKeySet(server.URL, KeySetConfig{RefreshInterval: time.Nanosecond, Client: server.Client()})
+	_, err := keys.Key(ctx, "first")
+	require.NoError(t, err)
+
+	server.Close()
+
+	_, err = keys.Key(ctx, "first")
+	require.NoError(t, err)
+}
+
+func TestKeySet_ServesCachedKeysDuringAReload(t *testing.T) {
+
+	ctx := context.Background()
+	first, second := newRSAKey(t), newECKey(t)
+
+	server := newJWKSServer(t)
+	server.setKeys(rsaJWK("first", first))
+
+	keys := NewKeySet(server.URL, KeySetConfig{RefreshInterval: time.Hour, Client: server.Client()})
+	_, err := keys.Key(ctx, "first")
+	require.NoError(t, err)
+
+	// the provider rotates and answers slowly: its handler waits for the lock held here
+	server.mu.Lock()
+	server.keys = []map[string]string{rsaJWK("first", first), ecJWK("second", second)}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 3)
+	for range 3 {
+		wg.Go(func() {
+			_, err := keys.Key(ctx, "second")
+			errs <- err
+		})
+	}
+	require.Eventually(t, func() bool { return server.fetches.Load() == 2 }, time.Second, time.Millisecond)
+
+	// the cached keys are still served while the reload is in flight
+	cached := make(chan error, 1)
+	go func() {
+		_, err := keys.Key(ctx, "first")
+		cached <- err
+	}()
+	select {
+	case err := <-cached:
+		require.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("a lookup of a cached key waited for the reload")
+	}
+
+	server.mu.Unlock()
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		require.NoError(t, err)
+	}
+}
+
+func TestKeySet_FromFile(t *testing.T) {
+
+	key := newECKey(t)
+	data, err := json.Marshal(map[string]any{"keys": []map[string]string{
+		ecJWK("only", key),
+		{"kty": "RSA", "kid": "encryption", "use": "enc"},
+	}})
+	require.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	require.NoError(t, os.WriteFile(path, data, 0o600))
+
+	// a token without kid matches the only signing key
+	found, err := NewKeySet(path, DefaultKeySetConfig()).Key(context.Background(), "")
+	require.NoError(t, err)
+	require.True(t, key.PublicKey.Equal(found))
+
+	_, err = NewKeySet(filepath.Join(t.TempDir(), "missing.json"), DefaultKeySetConfig()).Key(context.Background(), "only")
+	require.ErrorIs(t, err, ErrKeySetUnavailable)
+}
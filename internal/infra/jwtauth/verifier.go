@@ -0,0 +1,130 @@
+package jwtauth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/raulsilva-tech/devices-api/internal/domain"
+)
+
+// ErrInvalidToken wraps every reason a token is refused, except the JWKS being unavailable
+var ErrInvalidToken = errors.New("invalid token")
+
+type Config struct {
+	Issuer   string
+	Audience string
+	Leeway   time.Duration // clock skew tolerated on exp, nbf and iat
+}
+
+// Verifier validates the RS256 and ES256 tokens signed by the keys of a JWKS
+type Verifier struct {
+	keys   *KeySet
+	parser *jwt.Parser
+}
+
+func NewVerifier(keys *KeySet, config Config) *Verifier {
+
+	options := []jwt.ParserOption{
+		jwt.WithValidMethods([]string{jwt.SigningMethodRS256.Alg(), jwt.SigningMethodES256.Alg()}),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithLeeway(config.Leeway),
+	}
+	if config.Issuer != "" {
+		options = append(options, jwt.WithIssuer(config.Issuer))
+	}
+	if config.Audience != "" {
+		options = append(options, jwt.WithAudience(config.Audience))
+	}
+
+	return &Verifier{
+		keys:   keys,
+		parser: jwt.NewParser(options...),
+	}
+}
+
+// Verify checks the signature and the claims of the token and returns its subject as a principal.
+// The scopes come from the space separated "scope" claim or the "scp" list, the roles from the "roles"
+// list; unknown scopes and roles are ignored. A "tenant_id" claim binds the principal to that tenant.
+func (v *Verifier) Verify(ctx context.Context, token string) (*domain.Principal, error) {
+
+	claims := jwt.MapClaims{}
+	_, err := v.parser.ParseWithClaims(token, claims, func(t *jwt.Token) (any, error) {
+		kid, _ := t.Header["kid"].(string)
+		return v.keys.Key(ctx, kid)
+	})
+	if err != nil {
+		if errors.Is(err, ErrKeySetUnavailable) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+
+	subject, err := claims.GetSubject()
+	if err != nil || subject == "" {
+		return nil, fmt.Errorf("%w: subject is required", ErrInvalidToken)
+	}
+
+	// an unusable tenant fails the token, ignoring it would leave the principal unbound
+	tenant, _ := claims["tenant_id"].(string)
+	if _, ok := claims["tenant_id"]; ok && !domain.IsValidTenant(tenant) {
+		return nil, fmt.Errorf("%w: invalid tenant_id", ErrInvalidToken)
+	}
+
+	return &domain.Principal{
+		Subject: subject,
+		Scopes:  scopes(claims),
+		Roles:   roles(claims),
+		Tenant:  tenant,
+		Claims:  claims,
+	}, nil
+}
+
+// IsJWT tells a JWT from the other bearer tokens, such as the API keys
+func IsJWT(token string) bool {
+	return strings.Count(token, ".") == 2 && !strings.HasPrefix(token, domain.APIKeyPrefix)
+}
+
+func scopes(claims jwt.MapClaims) []domain.Scope {
+
+	var names []string
+	if scope, ok := claims["scope"].(string); ok {
+		names = strings.Fields(scope)
+	}
+	if list, ok := claims["scp"].([]any); ok {
+		for _, s := range list {
+			if name, ok := s.(string); ok {
+				names = append(names, name)
+			}
+		}
+	}
+
+	var result []domain.Scope
+	for _, name := range names {
+		if scope := domain.Scope(name); scope.IsValid() {
+			result = append(result, scope)
+		}
+	}
+
+	return result
+}
+
+func roles(claims jwt.MapClaims) []domain.Role {
+
+	list, _ := claims["roles"].([]any)
+
+	var result []domain.Role
+	for _, r := range list {
+		if name, ok := r.(string); ok {
+			if role := domain.Role(name); role.IsValid() {
+				result = append(result, role)
+			}
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,110 @@
+package jwtauth
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/raulsilva-tech/devices-api/internal/domain"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testIssuer   = "https://id.example.com/"
+	testAudience = "devices-api"
+)
+
+func validClaims() jwt.MapClaims {
+	now := time.Now()
+	return jwt.MapClaims{
+		"iss":   testIssuer,
+		"aud":   testAudience,
+		"sub":   "user-42",
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+		"scope": "devices:read devices:write openid",
+		"roles": []string{"operator", "owner"},
+
+		"tenant_id": "acme",
+	}
+}
+
+func sign(t *testing.T, method jwt.SigningMethod, kid string, key any, claims jwt.MapClaims) string {
+	token := jwt.NewWithClaims(method, claims)
+	token.Header["kid"] = kid
+	signed, err := token.SignedString(key)
+	require.NoError(t, err)
+	return signed
+}
+
+func TestVerifier(t *testing.T) {
+
+	rsaKey, ecKey := newRSAKey(t), newECKey(t)
+	server := newJWKSServer(t)
+	server.setKeys(rsaJWK("rsa", rsaKey), ecJWK("ec", ecKey))
+
+	keys := NewKeySet(server.URL, KeySetConfig{RefreshInterval: time.Hour, MinRefreshInterval: time.Hour, Client: server.Client()})
+	verifier := NewVerifier(keys, Config{Issuer: testIssuer, Audience: testAudience})
+
+	with := func(change func(jwt.MapClaims)) jwt.MapClaims {
+		claims := validClaims()
+		change(claims)
+		return claims
+	}
+
+	tests := []struct {
+		name  string
+		token string
+		valid bool
+	}{
+		{"rs256", sign(t, jwt.SigningMethodRS256, "rsa", rsaKey, validClaims()), true},
+		{"es256", sign(t, jwt.SigningMethodES256, "ec", ecKey, validClaims()), true},
+		{"wrong issuer", sign(t, jwt.SigningMethodRS256, "rsa", rsaKey, with(func(c jwt.MapClaims) { c["iss"] = "https://evil.example.com/" })), false},
+		{"wrong audience", sign(t, jwt.SigningMethodRS256, "rsa", rsaKey, with(func(c jwt.MapClaims) { c["aud"] = "other-api" })), false},
+		{"expired", sign(t, jwt.SigningMethodRS256, "rsa", rsaKey, with(func(c jwt.MapClaims) { c["exp"] = time.Now().Add(-time.Minute).Unix() })), false},
+		{"without expiry", sign(t, jwt.SigningMethodRS256, "rsa", rsaKey, with(func(c jwt.MapClaims) { delete(c, "exp") })), false},
+		{"without subject", sign(t, jwt.SigningMethodRS256, "rsa", rsaKey, with(func(c jwt.MapClaims) { delete(c, "sub") })), false},
+		{"without tenant", sign(t, jwt.SigningMethodRS256, "rsa", rsaKey, with(func(c jwt.MapClaims) { delete(c, "tenant_id") })), true},
+		{"invalid tenant", sign(t, jwt.SigningMethodRS256, "rsa", rsaKey, with(func(c jwt.MapClaims) { c["tenant_id"] = "Acme Corp" })), false},
+		{"signed by another key", sign(t, jwt.SigningMethodRS256, "rsa", newRSAKey(t), validClaims()), false},
+		{"unknown kid", sign(t, jwt.SigningMethodRS256, "other", rsaKey, validClaims()), false},
+		{"hs256", sign(t, jwt.SigningMethodHS256, "rsa", []byte("secret"), validClaims()), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			principal, err := verifier.Verify(context.Background(), tt.token)
+			if !tt.valid {
+				require.ErrorIs(t, err, ErrInvalidToken)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, "user-42", principal.Subject)
+			require.Equal(t, []domain.Scope{domain.ScopeDevicesRead, domain.ScopeDevicesWrite}, principal.Scopes)
+			require.Equal(t, []domain.Role{domain.RoleOperator}, principal.Roles)
+			require.Equal(t, testIssuer, principal.Claims["iss"])
+			if tenant, ok := principal.Claims["tenant_id"]; ok {
+				require.Equal(t, tenant, principal.Tenant)
+			}
+		})
+	}
+}
+
+func TestVerifier_WhenKeySetIsUnavailable(t *testing.T) {
+
+	server := newJWKSServer(t)
+	server.Close()
+
+	verifier := NewVerifier(NewKeySet(server.URL, DefaultKeySetConfig()), Config{Issuer: testIssuer, Audience: testAudience})
+
+	_, err := verifier.Verify(context.Background(), sign(t, jwt.SigningMethodRS256, "rsa", newRSAKey(t), validClaims()))
+	require.ErrorIs(t, err, ErrKeySetUnavailable)
+	require.NotErrorIs(t, err, ErrInvalidToken)
+}
+
+func TestIsJWT(t *testing.T) {
+	require.True(t, IsJWT("eyJhbGciOi.eyJzdWIiOi.c2lnbmF0dXJl"))
+	require.False(t, IsJWT(domain.APIKeyPrefix+"0123456789abcdef"))
+	require.False(t, IsJWT(""))
+}
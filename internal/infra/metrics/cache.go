@@ -0,0 +1,36 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/raulsilva-tech/devices-api/internal/infra/cache"
+)
+
+// CacheCollector reports the hits, misses and entries of the device cache
+type CacheCollector struct {
+	stats   func() cache.Stats
+	hits    *prometheus.Desc
+	misses  *prometheus.Desc
+	entries *prometheus.Desc
+}
+
+func NewCacheCollector(stats func() cache.Stats) *CacheCollector {
+	return &CacheCollector{
+		stats:   stats,
+		hits:    prometheus.NewDesc(namespace+"_device_cache_hits_total", "Device reads served from the cache.", nil, nil),
+		misses:  prometheus.NewDesc(namespace+"_device_cache_misses_total", "Device reads served from the database.", nil, nil),
+		entries: prometheus.NewDesc(namespace+"_device_cache_entries", "Devices and lists held in the cache.", nil, nil),
+	}
+}
+
+func (c *CacheCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.hits
+	ch <- c.misses
+	ch <- c.entries
+}
+
+func (c *CacheCollector) Collect(ch chan<- prometheus.Metric) {
+	stats := c.stats()
+	ch <- prometheus.MustNewConstMetric(c.hits, prometheus.CounterValue, float64(stats.Hits))
+	ch <- prometheus.MustNewConstMetric(c.misses, prometheus.CounterValue, float64(stats.Misses))
+	ch <- prometheus.MustNewConstMetric(c.entries, prometheus.GaugeValue, float64(stats.Entries))
+}
@@ -0,0 +1,110 @@
+package metrics
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/raulsilva-tech/devices-api/internal/domain"
+)
+
+// states are always reported, at 0 when no device is in them
+var states = []domain.DeviceState{domain.DeviceAvailable, domain.DeviceInUse, domain.DeviceInactive}
+
+// otherBrand is the label of the devices whose brand is not among the top brands
+const otherBrand = "other"
+
+// DeviceCollector reports the devices that are not deleted by state and by brand. The devices
+// are counted in the database on every scrape, across every tenant. Only the topBrands brands
+// with the most devices get their own series, the rest are summed under the brand "other", so
+// the brands cannot multiply the series; with topBrands at 0 the gauge by brand is not reported.
+type DeviceCollector struct {
+	repo      domain.DeviceStatsRepository
+	timeout   time.Duration
+	topBrands int
+	byState   *prometheus.Desc
+	byBrand   *prometheus.Desc
+}
+
+func NewDeviceCollector(repo domain.DeviceStatsRepository, timeout time.Duration, topBrands int) *DeviceCollector {
+	return &DeviceCollector{
+		repo:      repo,
+		timeout:   timeout,
+		topBrands: topBrands,
+		byState:   prometheus.NewDesc(namespace+"_devices", "Devices that are not deleted, by state.", []string{"state"}, nil),
+		byBrand:   prometheus.NewDesc(namespace+"_devices_by_brand", "Devices that are not deleted, by brand.", []string{"brand"}, nil),
+	}
+}
+
+func (c *DeviceCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.byState
+	if c.topBrands > 0 {
+		ch <- c.byBrand
+	}
+}
+
+func (c *DeviceCollector) Collect(ch chan<- prometheus.Metric) {
+
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+
+	byState, err := c.repo.CountDevicesByState(ctx)
+	if err != nil {
+		ch <- prometheus.NewInvalidMetric(c.byState, err)
+	} else {
+		for _, state := range states {
+			if _, ok := byState[state]; !ok {
+				byState[state] = 0
+			}
+		}
+		for state, total := range byState {
+			ch <- prometheus.MustNewConstMetric(c.byState, prometheus.GaugeValue, float64(total), string(state))
+		}
+	}
+
+	if c.topBrands <= 0 {
+		return
+	}
+
+	byBrand, err := c.repo.CountDevicesByBrand(ctx)
+	if err != nil {
+		ch <- prometheus.NewInvalidMetric(c.byBrand, err)
+		return
+	}
+	for brand, total := range topBrands(byBrand, c.topBrands) {
+		ch <- prometheus.MustNewConstMetric(c.byBrand, prometheus.GaugeValue, float64(total), brand)
+	}
+}
+
+// topBrands keeps the n brands with the most devices, ties broken by name, and sums the others
+// under otherBrand. A brand named "other" is always summed there, so the label is not reported twice.
+func topBrands(byBrand map[string]int64, n int) map[string]int64 {
+
+	brands := make([]string, 0, len(byBrand))
+	for brand := range byBrand {
+		if brand != otherBrand {
+			brands = append(brands, brand)
+		}
+	}
+	sort.Slice(brands, func(i, j int) bool {
+		if byBrand[brands[i]] != byBrand[brands[j]] {
+			return byBrand[brands[i]] > byBrand[brands[j]]
+		}
+		return brands[i] < brands[j]
+	})
+
+	top := make(map[string]int64, n+1)
+	for i, brand := range brands {
+		if i < n {
+			top[brand] = byBrand[brand]
+		} else {
+			top[otherBrand] += byBrand[brand]
+		}
+	}
+	if total, ok := byBrand[otherBrand]; ok {
+		top[otherBrand] += total
+	}
+
+	return top
+}
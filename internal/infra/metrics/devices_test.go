@@ -0,0 +1,94 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/raulsilva-tech/devices-api/internal/domain"
+	"github.com/raulsilva-tech/devices-api/internal/infra/cache"
+	"github.com/stretchr/testify/require"
+)
+
+type staticStats struct {
+	byState map[domain.DeviceState]int64
+	byBrand map[string]int64
+	err     error
+}
+
+func (s staticStats) CountDevicesByState(ctx context.Context) (map[domain.DeviceState]int64, error) {
+	return s.byState, s.err
+}
+
+func (s staticStats) CountDevicesByBrand(ctx context.Context) (map[string]int64, error) {
+	return s.byBrand, s.err
+}
+
+func TestDeviceCollector(t *testing.T) {
+
+	collector := NewDeviceCollector(staticStats{
+		byState: map[domain.DeviceState]int64{domain.DeviceAvailable: 3, domain.DeviceInUse: 1},
+		byBrand: map[string]int64{"acme": 3, "globex": 1},
+	}, time.Second, 20)
+
+	// the states without devices are reported at 0
+	expected := `
+# HELP devices_api_devices Devices that are not deleted, by state.
+# TYPE devices_api_devices gauge
+devices_api_devices{state="available"} 3
+devices_api_devices{state="in-use"} 1
+devices_api_devices{state="inactive"} 0
+# HELP devices_api_devices_by_brand Devices that are not deleted, by brand.
+# TYPE devices_api_devices_by_brand gauge
+devices_api_devices_by_brand{brand="acme"} 3
+devices_api_devices_by_brand{brand="globex"} 1
+`
+	require.NoError(t, testutil.CollectAndCompare(collector, strings.NewReader(expected)))
+
+	_, err := testutil.CollectAndLint(NewDeviceCollector(staticStats{err: errors.New("db down")}, time.Second, 20))
+	require.Error(t, err)
+}
+
+func TestDeviceCollector_TopBrands(t *testing.T) {
+
+	stats := staticStats{
+		byState: map[domain.DeviceState]int64{domain.DeviceAvailable: 14},
+		byBrand: map[string]int64{"acme": 5, "globex": 3, "initech": 3, "umbrella": 1, "other": 2},
+	}
+
+	// the brands past the top 2 are summed with the brand named "other"
+	expected := `
+# HELP devices_api_devices_by_brand Devices that are not deleted, by brand.
+# TYPE devices_api_devices_by_brand gauge
+devices_api_devices_by_brand{brand="acme"} 5
+devices_api_devices_by_brand{brand="globex"} 3
+devices_api_devices_by_brand{brand="other"} 6
+`
+	require.NoError(t, testutil.CollectAndCompare(NewDeviceCollector(stats, time.Second, 2), strings.NewReader(expected), namespace+"_devices_by_brand"))
+
+	// at 0 the gauge by brand is not reported
+	require.Equal(t, 3, testutil.CollectAndCount(NewDeviceCollector(stats, time.Second, 0)))
+}
+
+func TestCacheCollector(t *testing.T) {
+
+	collector := NewCacheCollector(func() cache.Stats {
+		return cache.Stats{Hits: 7, Misses: 2, Entries: 5}
+	})
+
+	expected := `
+# HELP devices_api_device_cache_entries Devices and lists held in the cache.
+# TYPE devices_api_device_cache_entries gauge
+devices_api_device_cache_entries 5
+# HELP devices_api_device_cache_hits_total Device reads served from the cache.
+# TYPE devices_api_device_cache_hits_total counter
+devices_api_device_cache_hits_total 7
+# HELP devices_api_device_cache_misses_total Device reads served from the database.
+# TYPE devices_api_device_cache_misses_total counter
+devices_api_device_cache_misses_total 2
+`
+	require.NoError(t, testutil.CollectAndCompare(collector, strings.NewReader(expected)))
+}
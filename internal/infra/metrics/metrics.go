@@ -0,0 +1,30 @@
+// Package metrics exposes the metrics of the API in the Prometheus text format.
+package metrics
+
+import (
+	"database/sql"
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "devices_api"
+
+// NewRegistry returns a registry holding the Go runtime and process metrics, and the stats of
+// the connection pool of db, labelled with dbName
+func NewRegistry(db *sql.DB, dbName string) *prometheus.Registry {
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		collectors.NewDBStatsCollector(db, dbName),
+	)
+	return reg
+}
+
+// Handler serves the metrics of the registry, to be scraped at /metrics
+func Handler(reg *prometheus.Registry) http.Handler {
+	return promhttp.HandlerFor(reg, promhttp.HandlerOpts{Registry: reg})
+}
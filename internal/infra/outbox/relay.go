@@ -0,0 +1,153 @@
+package outbox
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/raulsilva-tech/devices-api/internal/domain"
+)
+
+type Config struct {
+	PollInterval time.Duration // wait before looking for new messages once the outbox is drained
+	BatchSize    int           // messages read at a time
+	Lease        time.Duration // how long the relay holding the outbox keeps it from the other instances
+}
+
+func DefaultConfig() Config {
+	return Config{
+		PollInterval: time.Second,
+		BatchSize:    100,
+		Lease:        30 * time.Second,
+	}
+}
+
+// Relay drains the outbox to a publisher in the order the messages were written. A message is marked
+// as published only after the publisher accepts it, so a crash in between publishes it again:
+// delivery is at least once and publishers must tolerate duplicates, e.g. by the event ID.
+// With several instances only the relay holding the lease of the outbox drains it, so the events keep
+// their order; the others take over once it is released or expires.
+type Relay struct {
+	repo      domain.OutboxRepository
+	publisher domain.EventPublisher
+	config    Config
+	holder    string // identifies the relay in the lease
+
+	stop chan struct{}
+	done chan struct{}
+
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+func NewRelay(repo domain.OutboxRepository, publisher domain.EventPublisher, config Config) *Relay {
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &Relay{
+		repo:      repo,
+		publisher: publisher,
+		config:    config,
+		holder:    uuid.New().String(),
+		stop:      make(chan struct{}),
+		done:      make(chan struct{}),
+		ctx:       ctx,
+		cancel:    cancel,
+	}
+}
+
+// Start launches the goroutine that drains the outbox
+func (r *Relay) Start() {
+	go func() {
+		defer close(r.done)
+		for {
+			// a full batch means more messages are waiting
+			wait := r.config.PollInterval
+			if !r.drain() {
+				wait = 0
+			}
+
+			select {
+			case <-r.stop:
+				return
+			case <-time.After(wait):
+			}
+		}
+	}()
+}
+
+// Close stops the relay once the message being published is done, and releases its lease. When ctx
+// expires first that publish is abandoned; the message stays in the outbox and is published on the next start.
+func (r *Relay) Close(ctx context.Context) error {
+
+	close(r.stop)
+
+	var err error
+	select {
+	case <-r.done:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+	r.cancel()
+	<-r.done
+
+	if err := r.repo.ReleaseLease(context.WithoutCancel(ctx), r.holder, time.Now().UTC()); err != nil {
+		log.Printf("outbox: cannot release the lease: %v", err)
+	}
+
+	return err
+}
+
+// drain publishes a batch of pending messages while holding the lease. It reports whether the outbox
+// is drained, held by another relay, or whether publishing failed and should be retried after the poll interval.
+func (r *Relay) drain() bool {
+
+	now := time.Now().UTC()
+	leasedUntil := now.Add(r.config.Lease)
+	acquired, err := r.repo.AcquireLease(r.ctx, r.holder, now, leasedUntil)
+	if err != nil {
+		log.Printf("outbox: cannot acquire the lease: %v", err)
+		return true
+	}
+	if !acquired {
+		return true
+	}
+
+	messages, err := r.repo.GetPendingMessages(r.ctx, r.config.BatchSize)
+	if err != nil {
+		log.Printf("outbox: cannot read pending messages: %v", err)
+		return true
+	}
+
+	for _, message := range messages {
+
+		select {
+		case <-r.stop:
+			return true
+		default:
+		}
+
+		// another relay may have taken over, the lease is renewed before going on
+		if time.Now().After(leasedUntil) {
+			return false
+		}
+
+		if err := r.publisher.Publish(r.ctx, message.Event); err != nil {
+			log.Printf("outbox: cannot publish event %s %s: %v", message.Event.Type, message.Event.ID, err)
+			if err := r.repo.MarkFailed(context.WithoutCancel(r.ctx), message.ID, err.Error()); err != nil {
+				log.Printf("outbox: cannot record failure of message %d: %v", message.ID, err)
+			}
+			// the later messages wait, so events are published in order
+			return true
+		}
+
+		if err := r.repo.MarkPublished(context.WithoutCancel(r.ctx), message.ID, time.Now().UTC()); err != nil {
+			// the message will be published again
+			log.Printf("outbox: cannot mark message %d as published: %v", message.ID, err)
+			return true
+		}
+	}
+
+	return len(messages) < r.config.BatchSize
+}
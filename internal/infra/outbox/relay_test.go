@@ -0,0 +1,213 @@
+package outbox
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"errors"
+	"math"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/raulsilva-tech/devices-api/internal/domain"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryOutbox keeps the messages in memory
+type memoryOutbox struct {
+	mu        sync.Mutex
+	messages  []domain.OutboxMessage
+	published map[int64]bool
+
+	holder      string
+	leasedUntil time.Time
+	lastReads   int // reads of the last published message
+}
+
+func newMemoryOutbox(count int) *memoryOutbox {
+	m := &memoryOutbox{published: map[int64]bool{}}
+	for i := 1; i <= count; i++ {
+		device, _ := domain.NewDevice(uuid.New().String(), "Device", "Brand", domain.DeviceAvailable, time.Now())
+		m.messages = append(m.messages, domain.OutboxMessage{
+			ID:    int64(i),
+			Event: domain.NewEvent(domain.EventDeviceCreated, *device),
+		})
+	}
+	return m
+}
+
+func (m *memoryOutbox) GetPendingMessages(ctx context.Context, limit int) ([]domain.OutboxMessage, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var pending []domain.OutboxMessage
+	for _, message := range m.messages {
+		if !m.published[message.ID] && len(pending) < limit {
+			pending = append(pending, message)
+		}
+	}
+	return pending, nil
+}
+func (m *memoryOutbox) MarkPublished(ctx context.Context, id int64, publishedAt time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.published[id] = true
+	for i := range m.messages {
+		if m.messages[i].ID == id {
+			m.messages[i].PublishedAt = publishedAt
+		}
+	}
+	return nil
+}
+func (m *memoryOutbox) GetPublishedMessages(ctx context.Context, after domain.OutboxMessage, limit int) ([]domain.OutboxMessage, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var published []domain.OutboxMessage
+	for _, message := range m.messages {
+		if !message.PublishedAt.IsZero() && (message.PublishedAt.After(after.PublishedAt) ||
+			message.PublishedAt.Equal(after.PublishedAt) && message.ID > after.ID) {
+			published = append(published, message)
+		}
+	}
+	slices.SortFunc(published, func(a, b domain.OutboxMessage) int {
+		return cmp.Or(a.PublishedAt.Compare(b.PublishedAt), cmp.Compare(a.ID, b.ID))
+	})
+	return published[:min(limit, len(published))], nil
+}
+func (m *memoryOutbox) GetLastPublishedMessage(ctx context.Context) (*domain.OutboxMessage, error) {
+	published, _ := m.GetPublishedMessages(ctx, domain.OutboxMessage{}, math.MaxInt)
+	m.mu.Lock()
+	m.lastReads++
+	m.mu.Unlock()
+	if len(published) == 0 {
+		return nil, sql.ErrNoRows
+	}
+	return &published[len(published)-1], nil
+}
+func (m *memoryOutbox) MarkFailed(ctx context.Context, id int64, reason string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i := range m.messages {
+		if m.messages[i].ID == id {
+			m.messages[i].Attempts++
+		}
+	}
+	return nil
+}
+func (m *memoryOutbox) PurgePublishedMessages(ctx context.Context, publishedBefore time.Time) (int64, error) {
+	return 0, nil
+}
+func (m *memoryOutbox) AcquireLease(ctx context.Context, holder string, now, until time.Time) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.holder != holder && now.Before(m.leasedUntil) {
+		return false, nil
+	}
+	m.holder, m.leasedUntil = holder, until
+	return true, nil
+}
+func (m *memoryOutbox) ReleaseLease(ctx context.Context, holder string, now time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.holder == holder {
+		m.leasedUntil = now
+	}
+	return nil
+}
+
+// flakyPublisher refuses the events until failures runs out
+type flakyPublisher struct {
+	mu       sync.Mutex
+	failures int
+	events   []domain.Event
+}
+
+func (p *flakyPublisher) Publish(ctx context.Context, event domain.Event) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.failures > 0 {
+		p.failures--
+		return errors.New("queue full")
+	}
+	p.events = append(p.events, event)
+	return nil
+}
+
+func (p *flakyPublisher) count() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.events)
+}
+
+func TestRelay_PublishesInOrderAndRetries(t *testing.T) {
+
+	repo := newMemoryOutbox(5)
+	publisher := &flakyPublisher{failures: 2}
+
+	relay := NewRelay(repo, publisher, Config{PollInterval: time.Millisecond, BatchSize: 2, Lease: time.Minute})
+	relay.Start()
+
+	require.Eventually(t, func() bool { return publisher.count() == 5 }, time.Second, time.Millisecond)
+	require.NoError(t, relay.Close(context.Background()))
+
+	for i, event := range publisher.events {
+		require.Equal(t, repo.messages[i].Event.ID, event.ID)
+	}
+
+	// the first message was refused twice before being published
+	require.Equal(t, 2, repo.messages[0].Attempts)
+	for _, message := range repo.messages {
+		require.True(t, repo.published[message.ID])
+	}
+}
+
+func TestRelay_CloseKeepsPendingMessages(t *testing.T) {
+
+	repo := newMemoryOutbox(3)
+	publisher := &flakyPublisher{failures: 1000}
+
+	relay := NewRelay(repo, publisher, Config{PollInterval: time.Hour, BatchSize: 10, Lease: time.Minute})
+	relay.Start()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	require.NoError(t, relay.Close(ctx))
+
+	pending, err := repo.GetPendingMessages(context.Background(), 10)
+	require.NoError(t, err)
+	require.Len(t, pending, 3)
+	require.Empty(t, publisher.events)
+}
+
+func TestRelay_OneRelayDrainsAtATime(t *testing.T) {
+
+	repo := newMemoryOutbox(3)
+	first := &flakyPublisher{}
+	second := &flakyPublisher{}
+	config := Config{PollInterval: time.Millisecond, BatchSize: 10, Lease: time.Minute}
+
+	relay := NewRelay(repo, first, config)
+	relay.Start()
+	require.Eventually(t, func() bool { return first.count() == 3 }, time.Second, time.Millisecond)
+
+	// the other instance waits for the lease while the first relay runs
+	other := NewRelay(repo, second, config)
+	other.Start()
+	repo.mu.Lock()
+	device, _ := domain.NewDevice(uuid.New().String(), "Device", "Brand", domain.DeviceAvailable, time.Now())
+	repo.messages = append(repo.messages, domain.OutboxMessage{ID: 4, Event: domain.NewEvent(domain.EventDeviceCreated, *device)})
+	repo.mu.Unlock()
+	require.Eventually(t, func() bool { return first.count() == 4 }, time.Second, time.Millisecond)
+	require.Zero(t, second.count())
+
+	// it takes over once the lease is released
+	require.NoError(t, relay.Close(context.Background()))
+	repo.mu.Lock()
+	repo.messages = append(repo.messages, domain.OutboxMessage{ID: 5, Event: domain.NewEvent(domain.EventDeviceDeleted, *device)})
+	repo.mu.Unlock()
+	require.Eventually(t, func() bool { return second.count() == 1 }, time.Second, time.Millisecond)
+	require.NoError(t, other.Close(context.Background()))
+	require.Equal(t, 4, first.count())
+}
@@ -0,0 +1,127 @@
+package outbox
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/raulsilva-tech/devices-api/internal/domain"
+)
+
+// Tail follows the messages the relay publishes and hands them to a publisher in the same order,
+// starting with the ones published after it starts. Every instance runs one, so its live streams see
+// the events of every change, whichever instance holds the outbox lease. The publisher must not fail:
+// an event it refuses is skipped.
+type Tail struct {
+	repo      domain.OutboxRepository
+	publisher domain.EventPublisher
+	config    Config
+
+	stop chan struct{}
+	done chan struct{}
+
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+func NewTail(repo domain.OutboxRepository, publisher domain.EventPublisher, config Config) *Tail {
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &Tail{
+		repo:      repo,
+		publisher: publisher,
+		config:    config,
+		stop:      make(chan struct{}),
+		done:      make(chan struct{}),
+		ctx:       ctx,
+		cancel:    cancel,
+	}
+}
+
+// Start launches the goroutine that follows the outbox
+func (t *Tail) Start() {
+	go func() {
+		defer close(t.done)
+
+		// the last message published when the tail starts, nil until it is read
+		var last *domain.OutboxMessage
+
+		for {
+			wait := t.config.PollInterval
+			if last == nil {
+				last = t.start()
+			} else if !t.follow(last) {
+				// a full batch means more messages were published
+				wait = 0
+			}
+
+			select {
+			case <-t.stop:
+				return
+			case <-time.After(wait):
+			}
+		}
+	}()
+}
+
+// Close stops the tail once the message being handed over is done
+func (t *Tail) Close(ctx context.Context) error {
+
+	close(t.stop)
+
+	select {
+	case <-t.done:
+		t.cancel()
+		return nil
+	case <-ctx.Done():
+		t.cancel()
+		<-t.done
+		return ctx.Err()
+	}
+}
+
+// start reads the message published last, the tail follows the ones published after it.
+// It returns nil when reading it failed and should be retried after the poll interval.
+func (t *Tail) start() *domain.OutboxMessage {
+
+	last, err := t.repo.GetLastPublishedMessage(t.ctx)
+	if errors.Is(err, sql.ErrNoRows) {
+		return &domain.OutboxMessage{}
+	}
+	if err != nil {
+		log.Printf("outbox: cannot read the last published message: %v", err)
+		return nil
+	}
+
+	return last
+}
+
+// follow hands over a batch of the messages published after last, and moves last to the latest one.
+// It reports whether no more messages are waiting, or whether reading them failed.
+func (t *Tail) follow(last *domain.OutboxMessage) bool {
+
+	messages, err := t.repo.GetPublishedMessages(t.ctx, *last, t.config.BatchSize)
+	if err != nil {
+		log.Printf("outbox: cannot read published messages: %v", err)
+		return true
+	}
+
+	for _, message := range messages {
+
+		select {
+		case <-t.stop:
+			return true
+		default:
+		}
+
+		if err := t.publisher.Publish(t.ctx, message.Event); err != nil {
+			log.Printf("outbox: cannot hand over event %s %s: %v", message.Event.Type, message.Event.ID, err)
+		}
+		*last = message
+	}
+
+	return len(messages) < t.config.BatchSize
+}
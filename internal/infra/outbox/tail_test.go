@@ -0,0 +1,42 @@
+package outbox
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTail_FollowsThePublishedMessages(t *testing.T) {
+
+	repo := newMemoryOutbox(5)
+	config := Config{PollInterval: time.Millisecond, BatchSize: 2, Lease: time.Minute}
+
+	// the messages published before the tail starts are not handed over
+	published := time.Now().UTC()
+	require.NoError(t, repo.MarkPublished(context.Background(), 1, published))
+	require.NoError(t, repo.MarkPublished(context.Background(), 2, published))
+
+	streams := &flakyPublisher{}
+	tail := NewTail(repo, streams, config)
+	tail.Start()
+	require.Eventually(t, func() bool {
+		repo.mu.Lock()
+		defer repo.mu.Unlock()
+		return repo.lastReads > 0
+	}, time.Second, time.Millisecond)
+	time.Sleep(10 * time.Millisecond)
+	require.Zero(t, streams.count())
+
+	// the ones the relay publishes next follow, in the same order
+	relay := NewRelay(repo, &flakyPublisher{failures: 1}, config)
+	relay.Start()
+	require.Eventually(t, func() bool { return streams.count() == 3 }, time.Second, time.Millisecond)
+	require.NoError(t, relay.Close(context.Background()))
+	require.NoError(t, tail.Close(context.Background()))
+
+	for i, event := range streams.events {
+		require.Equal(t, repo.messages[i+2].Event.ID, event.ID)
+	}
+}
@@ -0,0 +1,86 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// sweepInterval is how often the buckets full again are dropped, so idle clients do not pile up
+const sweepInterval = time.Minute
+
+// MemoryStore keeps the buckets in memory, each instance of the API limits its clients on its own
+type MemoryStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+type bucket struct {
+	tokens  float64
+	updated time.Time
+	full    time.Time // when the bucket is full again
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		buckets: map[string]*bucket{},
+		now:     time.Now,
+	}
+}
+
+func (s *MemoryStore) Take(ctx context.Context, key string, limit Limit) (Result, error) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	s.sweep(now)
+
+	size := limit.burst()
+	interval := limit.interval()
+
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(size), updated: now}
+		s.buckets[key] = b
+	}
+
+	// refill the tokens earned since the last request
+	b.tokens += float64(now.Sub(b.updated)) / float64(interval)
+	if b.tokens > float64(size) {
+		b.tokens = float64(size)
+	}
+	b.updated = now
+
+	result := Result{Limit: size}
+
+	if b.tokens >= 1 {
+		b.tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = time.Duration((1 - b.tokens) * float64(interval))
+	}
+
+	result.Remaining = int(b.tokens)
+	result.ResetAfter = time.Duration((float64(size) - b.tokens) * float64(interval))
+	b.full = now.Add(result.ResetAfter)
+
+	return result, nil
+}
+
+// sweep drops the buckets that are full again, they would be created the same on the next request
+func (s *MemoryStore) sweep(now time.Time) {
+
+	if now.Sub(s.lastSweep) < sweepInterval {
+		return
+	}
+	s.lastSweep = now
+
+	for key, b := range s.buckets {
+		if !now.Before(b.full) {
+			delete(s.buckets, key)
+		}
+	}
+}
@@ -0,0 +1,48 @@
+// Package ratelimit counts the requests of each client with token buckets.
+package ratelimit
+
+import (
+	"context"
+	"time"
+)
+
+// Limit is a token bucket: a client can send Burst requests at once, and the bucket is refilled
+// with Requests tokens every Per. A zero Requests disables the limit.
+type Limit struct {
+	Requests int
+	Per      time.Duration
+	Burst    int // defaults to Requests
+}
+
+// IsEnabled tells whether the limit restricts anything
+func (l Limit) IsEnabled() bool {
+	return l.Requests > 0 && l.Per > 0
+}
+
+func (l Limit) burst() int {
+	if l.Burst > 0 {
+		return l.Burst
+	}
+	return l.Requests
+}
+
+// interval is how long it takes to refill one token
+func (l Limit) interval() time.Duration {
+	return l.Per / time.Duration(l.Requests)
+}
+
+// Result is the state of the bucket of a client after taking a token
+type Result struct {
+	Allowed    bool
+	Limit      int           // size of the bucket
+	Remaining  int           // tokens left in the bucket
+	RetryAfter time.Duration // how long to wait for the next token, zero when allowed
+	ResetAfter time.Duration // how long until the bucket is full again
+}
+
+// Store keeps the buckets of the clients. MemoryStore keeps them in the process; a store shared by
+// every instance of the API, e.g. on Redis, makes the limits hold across instances.
+type Store interface {
+	// Take removes a token from the bucket of key, creating a full bucket when it does not exist
+	Take(ctx context.Context, key string, limit Limit) (Result, error)
+}
@@ -0,0 +1,54 @@
+// Package rbac loads the policy granting permissions to the roles from a YAML file.
+package rbac
+
+import (
+	"bytes"
+	_ "embed"
+	"fmt"
+	"os"
+
+	"github.com/raulsilva-tech/devices-api/internal/domain"
+	"gopkg.in/yaml.v3"
+)
+
+// defaultPolicy is used when no policy file is configured
+//
+//go:embed policy.yaml
+var defaultPolicy []byte
+
+type policyFile struct {
+	Roles map[domain.Role][]domain.Permission `yaml:"roles"`
+}
+
+// LoadPolicy reads the policy from the file at path, or returns the default policy when path is empty
+func LoadPolicy(path string) (*domain.Policy, error) {
+
+	if path == "" {
+		return ParsePolicy(defaultPolicy)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	policy, err := ParsePolicy(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return policy, nil
+}
+
+// ParsePolicy decodes a policy, rejecting unknown fields, roles and permissions
+func ParsePolicy(data []byte) (*domain.Policy, error) {
+
+	var file policyFile
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&file); err != nil {
+		return nil, fmt.Errorf("invalid policy: %w", err)
+	}
+
+	return domain.NewPolicy(file.Roles)
+}
@@ -0,0 +1,49 @@
+package rbac
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/raulsilva-tech/devices-api/internal/domain"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDefaultPolicy(t *testing.T) {
+
+	policy, err := LoadPolicy("")
+	require.NoError(t, err)
+
+	viewer := []domain.Role{domain.RoleViewer}
+	operator := []domain.Role{domain.RoleOperator}
+	admin := []domain.Role{domain.RoleAdmin}
+
+	require.True(t, policy.Allows(viewer, domain.PermissionDevicesRead))
+	require.False(t, policy.Allows(viewer, domain.PermissionDevicesChangeState))
+
+	require.True(t, policy.Allows(operator, domain.PermissionDevicesChangeState))
+	require.False(t, policy.Allows(operator, domain.PermissionDevicesDelete))
+
+	require.True(t, policy.Allows(admin, domain.PermissionDevicesDelete))
+	require.True(t, policy.Allows(admin, domain.PermissionAPIKeysManage))
+}
+
+func TestLoadPolicy(t *testing.T) {
+
+	path := filepath.Join(t.TempDir(), "policy.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("roles:\n  viewer: [devices.read, devices.delete]\n"), 0o600))
+
+	policy, err := LoadPolicy(path)
+	require.NoError(t, err)
+	require.True(t, policy.Allows([]domain.Role{domain.RoleViewer}, domain.PermissionDevicesDelete))
+	require.False(t, policy.Allows([]domain.Role{domain.RoleAdmin}, domain.PermissionDevicesRead))
+
+	_, err = ParsePolicy([]byte("roles:\n  viewer: [devices.purge]\n"))
+	require.ErrorIs(t, err, domain.ErrInvalidPermission)
+
+	_, err = ParsePolicy([]byte("rules:\n  viewer: [devices.read]\n"))
+	require.Error(t, err)
+
+	_, err = LoadPolicy(filepath.Join(t.TempDir(), "missing.yaml"))
+	require.Error(t, err)
+}
@@ -0,0 +1,131 @@
+package stream
+
+import (
+	"context"
+	"sync"
+
+	"github.com/raulsilva-tech/devices-api/internal/domain"
+)
+
+// subscriberBuffer is how many events a subscriber can fall behind before it is disconnected
+const subscriberBuffer = 64
+
+// Hub is an EventPublisher that fans the device events out to live subscribers and keeps the
+// latest ones so a subscriber that reconnects can resume where it stopped.
+type Hub struct {
+	mu          sync.Mutex
+	size        int
+	replay      []domain.Event
+	ids         map[string]struct{} // IDs of the events in replay
+	subscribers map[*Subscription]struct{}
+	closed      bool
+}
+
+// Subscription receives the events published after it was opened. Events is closed when the
+// subscriber falls too far behind or the hub is closed.
+type Subscription struct {
+	Events <-chan domain.Event
+
+	events chan domain.Event
+	hub    *Hub
+}
+
+// NewHub creates a hub that remembers the last size events
+func NewHub(size int) *Hub {
+	return &Hub{
+		size:        size,
+		ids:         map[string]struct{}{},
+		subscribers: map[*Subscription]struct{}{},
+	}
+}
+
+// Publish never fails. Events already in the replay buffer are ignored, since the outbox can
+// publish the same event more than once.
+func (h *Hub) Publish(ctx context.Context, event domain.Event) error {
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if _, ok := h.ids[event.ID]; ok {
+		return nil
+	}
+
+	h.replay = append(h.replay, event)
+	h.ids[event.ID] = struct{}{}
+	if len(h.replay) > h.size {
+		delete(h.ids, h.replay[0].ID)
+		h.replay = h.replay[1:]
+	}
+
+	for sub := range h.subscribers {
+		select {
+		case sub.events <- event:
+		default:
+			// a subscriber that cannot keep up is dropped rather than slowing down everyone;
+			// it can reconnect with the ID of the last event it got
+			h.remove(sub)
+		}
+	}
+
+	return nil
+}
+
+// Subscribe opens a subscription and returns the buffered events published after lastEventID.
+// An empty lastEventID replays nothing; an ID no longer in the buffer replays all of it.
+func (h *Hub) Subscribe(lastEventID string) (*Subscription, []domain.Event) {
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	events := make(chan domain.Event, subscriberBuffer)
+	sub := &Subscription{Events: events, events: events, hub: h}
+
+	if h.closed {
+		close(events)
+		return sub, nil
+	}
+	h.subscribers[sub] = struct{}{}
+
+	if lastEventID == "" {
+		return sub, nil
+	}
+
+	start := 0
+	for i, event := range h.replay {
+		if event.ID == lastEventID {
+			start = i + 1
+			break
+		}
+	}
+
+	replay := make([]domain.Event, len(h.replay)-start)
+	copy(replay, h.replay[start:])
+
+	return sub, replay
+}
+
+// Close stops the subscription
+func (s *Subscription) Close() {
+	s.hub.mu.Lock()
+	defer s.hub.mu.Unlock()
+	s.hub.remove(s)
+}
+
+// Close ends every subscription, so the streams can finish on shutdown
+func (h *Hub) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.closed = true
+	for sub := range h.subscribers {
+		h.remove(sub)
+	}
+}
+
+// remove must be called with the lock held
+func (h *Hub) remove(sub *Subscription) {
+	if _, ok := h.subscribers[sub]; ok {
+		delete(h.subscribers, sub)
+		close(sub.events)
+	}
+}
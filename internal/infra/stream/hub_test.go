@@ -0,0 +1,95 @@
+package stream
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/raulsilva-tech/devices-api/internal/domain"
+	"github.com/stretchr/testify/require"
+)
+
+func testEvents(count int) []domain.Event {
+	events := make([]domain.Event, count)
+	for i := range events {
+		device, _ := domain.NewDevice(uuid.New().String(), "Device", "Brand", domain.DeviceAvailable, time.Now())
+		events[i] = domain.NewEvent(domain.EventDeviceCreated, *device)
+	}
+	return events
+}
+
+func TestHub_Replay(t *testing.T) {
+	ctx := context.Background()
+	hub := NewHub(3)
+	events := testEvents(5)
+
+	for _, e := range events {
+		require.NoError(t, hub.Publish(ctx, e))
+	}
+
+	// without an ID nothing is replayed
+	sub, replay := hub.Subscribe("")
+	require.Empty(t, replay)
+	sub.Close()
+
+	// resumes after the given event
+	sub, replay = hub.Subscribe(events[3].ID)
+	require.Equal(t, []domain.Event{events[4]}, replay)
+	sub.Close()
+
+	// the first events were evicted, so everything still buffered is replayed
+	sub, replay = hub.Subscribe(events[0].ID)
+	require.Equal(t, events[2:], replay)
+	sub.Close()
+}
+
+func TestHub_LiveEventsAndDuplicates(t *testing.T) {
+	ctx := context.Background()
+	hub := NewHub(10)
+	events := testEvents(2)
+
+	sub, _ := hub.Subscribe("")
+	defer sub.Close()
+
+	require.NoError(t, hub.Publish(ctx, events[0]))
+	require.NoError(t, hub.Publish(ctx, events[0]))
+	require.NoError(t, hub.Publish(ctx, events[1]))
+
+	require.Equal(t, events[0], <-sub.Events)
+	require.Equal(t, events[1], <-sub.Events)
+	require.Empty(t, sub.Events)
+}
+
+func TestHub_DropsSlowSubscribers(t *testing.T) {
+	ctx := context.Background()
+	hub := NewHub(1000)
+
+	sub, _ := hub.Subscribe("")
+	for _, e := range testEvents(subscriberBuffer + 1) {
+		require.NoError(t, hub.Publish(ctx, e))
+	}
+
+	count := 0
+	for range sub.Events {
+		count++
+	}
+	require.Equal(t, subscriberBuffer, count)
+
+	// closing a dropped subscription is harmless
+	sub.Close()
+}
+
+func TestHub_Close(t *testing.T) {
+	hub := NewHub(10)
+
+	sub, _ := hub.Subscribe("")
+	hub.Close()
+
+	_, ok := <-sub.Events
+	require.False(t, ok)
+
+	late, _ := hub.Subscribe("")
+	_, ok = <-late.Events
+	require.False(t, ok)
+}
@@ -0,0 +1,24 @@
+package tracing
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetup(t *testing.T) {
+
+	ctx := context.Background()
+
+	shutdown, err := Setup(ctx, ExporterNone, "devices-api")
+	require.NoError(t, err)
+	require.NoError(t, shutdown(ctx))
+
+	_, err = Setup(ctx, "jaeger", "devices-api")
+	require.ErrorIs(t, err, ErrUnknownExporter)
+
+	shutdown, err = Setup(ctx, ExporterStdout, "devices-api")
+	require.NoError(t, err)
+	require.NoError(t, shutdown(ctx))
+}
@@ -0,0 +1,362 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/raulsilva-tech/devices-api/internal/domain"
+	"github.com/raulsilva-tech/devices-api/internal/dto"
+)
+
+const (
+	HeaderEvent     = "X-Webhook-Event"
+	HeaderDelivery  = "X-Webhook-Delivery"
+	HeaderTimestamp = "X-Webhook-Timestamp"
+	HeaderSignature = "X-Webhook-Signature"
+)
+
+var (
+	ErrDispatcherClosed = errors.New("webhook dispatcher is closed")
+	// ErrPrivateAddress means the webhook host resolved to an address deliveries must not reach
+	ErrPrivateAddress = errors.New("webhook address is not public")
+)
+
+type Config struct {
+	Workers      int           // deliveries attempted concurrently
+	BatchSize    int           // due deliveries read at a time
+	PollInterval time.Duration // wait before looking for due deliveries once none is left
+	MaxAttempts  int           // attempts per delivery before it is marked as failed
+	BaseDelay    time.Duration // wait before the first retry, doubled on every retry
+	MaxDelay     time.Duration // cap of the wait between retries
+	Timeout      time.Duration // timeout of each attempt
+	Lease        time.Duration // how long a delivery being attempted is kept from the other instances, longer than Timeout
+}
+
+func DefaultConfig() Config {
+	return Config{
+		Workers:      4,
+		BatchSize:    100,
+		PollInterval: time.Second,
+		MaxAttempts:  5,
+		BaseDelay:    time.Second,
+		MaxDelay:     time.Minute,
+		Timeout:      10 * time.Second,
+		Lease:        time.Minute,
+	}
+}
+
+// Dispatcher is an EventPublisher that delivers the events to the subscribed webhooks in the background.
+// Publishing stores the deliveries, which are then attempted from the repository: a delivery survives
+// a restart and a slow or dead webhook never makes an event wait in the outbox.
+// Every delivery is signed, retried with exponential backoff and recorded with its latest outcome.
+// Each attempt claims its delivery first, so with several instances a delivery is attempted by one of them.
+type Dispatcher struct {
+	repo   domain.WebhookRepository
+	client *http.Client
+	config Config
+
+	mu     sync.RWMutex
+	closed bool
+
+	wake chan struct{} // deliveries were stored, they are attempted without waiting for the poll interval
+	stop chan struct{}
+	done chan struct{}
+
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+func NewDispatcher(repo domain.WebhookRepository, config Config) *Dispatcher {
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &Dispatcher{
+		repo:   repo,
+		client: newClient(config.Timeout),
+		config: config,
+		wake:   make(chan struct{}, 1),
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+		ctx:    ctx,
+		cancel: cancel,
+	}
+}
+
+// newClient returns the client of the deliveries. Its dialer checks every address it connects to,
+// after the name was resolved, so a webhook host rebound to an internal address is refused too.
+// The environment proxy is not used, the dialer would only see the address of the proxy.
+func newClient(timeout time.Duration) *http.Client {
+
+	dialer := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+		Control:   refusePrivateAddress,
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+
+	return &http.Client{Timeout: timeout, Transport: transport}
+}
+
+func refusePrivateAddress(network, address string, _ syscall.RawConn) error {
+
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil || !domain.IsPublicAddress(addrPort.Addr()) {
+		return fmt.Errorf("%w: %s", ErrPrivateAddress, address)
+	}
+
+	return nil
+}
+
+// Start launches the goroutine that attempts the due deliveries, starting with the ones left pending
+// by the previous run
+func (d *Dispatcher) Start() {
+	go func() {
+		defer close(d.done)
+		for {
+			// a full batch means more deliveries are due
+			wait := d.config.PollInterval
+			if !d.attemptDue() {
+				wait = 0
+			}
+
+			select {
+			case <-d.stop:
+				return
+			case <-d.wake:
+			case <-time.After(wait):
+			}
+		}
+	}()
+}
+
+// Publish stores a pending delivery of the event for every webhook of the tenant of the device subscribed
+// to its type, and returns once they are stored so the event can leave the outbox. An event published
+// again gets no second delivery.
+func (d *Dispatcher) Publish(ctx context.Context, event domain.Event) error {
+
+	d.mu.RLock()
+	closed := d.closed
+	d.mu.RUnlock()
+
+	if closed {
+		return ErrDispatcherClosed
+	}
+
+	webhooks, err := d.repo.GetWebhooks(domain.WithTenant(ctx, event.Device.TenantID))
+	if err != nil {
+		return err
+	}
+
+	body, err := json.Marshal(NewPayload(event))
+	if err != nil {
+		return err
+	}
+
+	for _, webhook := range webhooks {
+		if !webhook.Matches(event.Type) {
+			continue
+		}
+		if err := d.repo.CreateDelivery(ctx, domain.NewWebhookDelivery(webhook.ID, event, string(body))); err != nil {
+			return err
+		}
+	}
+
+	select {
+	case d.wake <- struct{}{}:
+	default:
+	}
+
+	return nil
+}
+
+// Close stops the dispatcher once the attempts in progress are done. When ctx expires first they are
+// abandoned; the deliveries stay pending and are attempted again once their claim expires.
+func (d *Dispatcher) Close(ctx context.Context) error {
+
+	d.mu.Lock()
+	if !d.closed {
+		d.closed = true
+		close(d.stop)
+	}
+	d.mu.Unlock()
+
+	select {
+	case <-d.done:
+		d.cancel()
+		return nil
+	case <-ctx.Done():
+		d.cancel()
+		<-d.done
+		return ctx.Err()
+	}
+}
+
+// attemptDue makes one attempt of a batch of due deliveries. It reports whether none is left, or
+// whether reading them failed and should be retried after the poll interval.
+func (d *Dispatcher) attemptDue() bool {
+
+	due, err := d.repo.GetDueDeliveries(d.ctx, time.Now().UTC(), d.config.BatchSize)
+	if err != nil {
+		log.Printf("webhook: cannot read due deliveries: %v", err)
+		return true
+	}
+
+	workers := make(chan struct{}, d.config.Workers)
+	var wg sync.WaitGroup
+
+	for _, delivery := range due {
+
+		select {
+		case <-d.stop:
+			wg.Wait()
+			return true
+		case workers <- struct{}{}:
+		}
+
+		wg.Add(1)
+		go func(delivery domain.DueDelivery) {
+			defer func() {
+				<-workers
+				wg.Done()
+			}()
+			d.attempt(delivery.Webhook, &delivery.Delivery)
+		}(delivery)
+	}
+	wg.Wait()
+
+	return len(due) < d.config.BatchSize
+}
+
+// attempt claims the delivery, posts it to its webhook and records the outcome. A failed delivery is
+// attempted again after the backoff until the attempts run out.
+func (d *Dispatcher) attempt(webhook domain.Webhook, delivery *domain.WebhookDelivery) {
+
+	now := time.Now().UTC()
+	claimed, err := d.repo.ClaimDelivery(d.ctx, delivery.ID, now, now.Add(d.config.Lease))
+	if err != nil {
+		log.Printf("webhook: cannot claim delivery %s: %v", delivery.ID, err)
+		return
+	}
+	if !claimed {
+		// attempted by another instance
+		return
+	}
+
+	statusCode, err := d.send(webhook, delivery, []byte(delivery.Payload))
+	if d.ctx.Err() != nil {
+		// interrupted by the shutdown, the attempt does not count
+		return
+	}
+
+	now = time.Now().UTC()
+	delivery.Attempts++
+	delivery.LastStatusCode = statusCode
+	delivery.LastError = ""
+	delivery.UpdatedAt = now
+
+	switch {
+	case err == nil:
+		delivery.Status = domain.DeliverySucceeded
+	case delivery.Attempts >= d.config.MaxAttempts:
+		delivery.Status = domain.DeliveryFailed
+		delivery.LastError = err.Error()
+	default:
+		delivery.LastError = err.Error()
+		delivery.NextAttemptAt = now.Add(d.backoff(delivery.Attempts))
+	}
+
+	if err := d.repo.UpdateDelivery(d.ctx, delivery); err != nil {
+		// the delivery stays due and is attempted again
+		log.Printf("webhook: cannot record attempt of delivery %s: %v", delivery.ID, err)
+	}
+}
+
+// send makes one attempt, any status other than 2xx is a failure
+func (d *Dispatcher) send(webhook domain.Webhook, delivery *domain.WebhookDelivery, body []byte) (int, error) {
+
+	req, err := http.NewRequestWithContext(d.ctx, http.MethodPost, webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderEvent, string(delivery.EventType))
+	req.Header.Set(HeaderDelivery, delivery.ID)
+	req.Header.Set(HeaderTimestamp, timestamp)
+	req.Header.Set(HeaderSignature, Sign(webhook.Secret, timestamp, body))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	return resp.StatusCode, nil
+}
+
+// backoff returns the wait after the given number of failed attempts
+func (d *Dispatcher) backoff(attempts int) time.Duration {
+	delay := d.config.BaseDelay
+	for i := 1; i < attempts && delay < d.config.MaxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, d.config.MaxDelay)
+}
+
+// Sign computes the signature of a delivery: the hex HMAC-SHA256 of "timestamp.body" keyed by the webhook secret.
+// Receivers recompute it to check the delivery came from this API and was not altered.
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// NewPayload builds the body of the deliveries of an event
+func NewPayload(event domain.Event) dto.DeviceEventResponse {
+
+	device := dto.DeviceResponse{
+		ID:        event.Device.ID,
+		Name:      event.Device.Name,
+		Brand:     event.Device.Brand,
+		State:     string(event.Device.State),
+		CreatedAt: event.Device.CreatedAt,
+		Version:   event.Device.Version,
+	}
+	if !event.Device.DeletedAt.IsZero() {
+		deletedAt := event.Device.DeletedAt
+		device.DeletedAt = &deletedAt
+	}
+
+	return dto.DeviceEventResponse{
+		ID:            event.ID,
+		Type:          string(event.Type),
+		OccurredAt:    event.OccurredAt,
+		PreviousState: string(event.PreviousState),
+		Data:          device,
+	}
+}
@@ -0,0 +1,392 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/raulsilva-tech/devices-api/internal/domain"
+	"github.com/raulsilva-tech/devices-api/internal/dto"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryRepo keeps webhooks and the latest state of every delivery in memory
+type memoryRepo struct {
+	mu         sync.Mutex
+	webhooks   []domain.Webhook
+	deliveries map[string]domain.WebhookDelivery
+}
+
+func newMemoryRepo(webhooks ...*domain.Webhook) *memoryRepo {
+	repo := &memoryRepo{deliveries: map[string]domain.WebhookDelivery{}}
+	for _, w := range webhooks {
+		repo.webhooks = append(repo.webhooks, *w)
+	}
+	return repo
+}
+
+func (m *memoryRepo) CreateWebhook(ctx context.Context, webhook *domain.Webhook) error { return nil }
+func (m *memoryRepo) DeleteWebhook(ctx context.Context, id string) error               { return nil }
+func (m *memoryRepo) GetWebhookById(ctx context.Context, id string) (*domain.Webhook, error) {
+	return nil, nil
+}
+func (m *memoryRepo) GetWebhooks(ctx context.Context) ([]domain.Webhook, error) {
+	return m.webhooks, nil
+}
+func (m *memoryRepo) CreateDelivery(ctx context.Context, delivery *domain.WebhookDelivery) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, d := range m.deliveries {
+		if d.WebhookID == delivery.WebhookID && d.EventID == delivery.EventID {
+			return nil
+		}
+	}
+	m.deliveries[delivery.ID] = *delivery
+	return nil
+}
+func (m *memoryRepo) UpdateDelivery(ctx context.Context, delivery *domain.WebhookDelivery) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.deliveries[delivery.ID] = *delivery
+	return nil
+}
+func (m *memoryRepo) GetDeliveries(ctx context.Context, webhookID string, limit, offset int) ([]domain.WebhookDelivery, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var result []domain.WebhookDelivery
+	for _, d := range m.deliveries {
+		if d.WebhookID == webhookID {
+			result = append(result, d)
+		}
+	}
+	return result, nil
+}
+
+func (m *memoryRepo) GetDueDeliveries(ctx context.Context, now time.Time, limit int) ([]domain.DueDelivery, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var result []domain.DueDelivery
+	for _, d := range m.deliveries {
+		if d.Status != domain.DeliveryPending || d.NextAttemptAt.After(now) || len(result) == limit {
+			continue
+		}
+		for _, w := range m.webhooks {
+			if w.ID == d.WebhookID {
+				result = append(result, domain.DueDelivery{Webhook: w, Delivery: d})
+			}
+		}
+	}
+	return result, nil
+}
+
+func (m *memoryRepo) ClaimDelivery(ctx context.Context, id string, now, until time.Time) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	d, ok := m.deliveries[id]
+	if !ok || d.Status != domain.DeliveryPending || d.NextAttemptAt.After(now) {
+		return false, nil
+	}
+	d.NextAttemptAt = until
+	m.deliveries[id] = d
+	return true, nil
+}
+
+// delivery returns the only delivery to the webhook once it is no longer pending
+func (m *memoryRepo) delivery(webhookID string) (domain.WebhookDelivery, bool) {
+	deliveries, _ := m.GetDeliveries(context.Background(), webhookID, 10, 0)
+	if len(deliveries) != 1 || deliveries[0].Status == domain.DeliveryPending {
+		return domain.WebhookDelivery{}, false
+	}
+	return deliveries[0], true
+}
+
+func testConfig() Config {
+	config := DefaultConfig()
+	config.MaxAttempts = 3
+	config.BaseDelay = time.Millisecond
+	config.MaxDelay = 5 * time.Millisecond
+	config.PollInterval = time.Millisecond
+	return config
+}
+
+// localWebhook subscribes the receiver, a test server on a loopback address that the
+// registration would refuse
+func localWebhook(receiver *httptest.Server, path string, events ...domain.EventType) *domain.Webhook {
+	return &domain.Webhook{ID: uuid.New().String(), URL: receiver.URL + path, Secret: "secret", Events: events}
+}
+
+// newLocalDispatcher is a dispatcher allowed to reach the test servers
+func newLocalDispatcher(repo domain.WebhookRepository, config Config) *Dispatcher {
+	dispatcher := NewDispatcher(repo, config)
+	dispatcher.client = &http.Client{Timeout: config.Timeout}
+	return dispatcher
+}
+
+func testEvent(t *testing.T) domain.Event {
+	device, err := domain.NewDevice(uuid.New().String(), "Device", "Brand", domain.DeviceInUse, time.Now())
+	require.NoError(t, err)
+	event := domain.NewEvent(domain.EventDeviceStateChanged, *device)
+	event.PreviousState = domain.DeviceAvailable
+	return event
+}
+
+func TestDispatcher_SignsAndRetries(t *testing.T) {
+
+	var mu sync.Mutex
+	var received []*http.Request
+	var bodies [][]byte
+
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		defer mu.Unlock()
+		received = append(received, r)
+		bodies = append(bodies, body)
+		// the first attempt fails
+		if len(received) == 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer receiver.Close()
+
+	subscribed := localWebhook(receiver, "", domain.EventDeviceStateChanged)
+	other := localWebhook(receiver, "/other", domain.EventDeviceDeleted)
+
+	repo := newMemoryRepo(subscribed, other)
+	dispatcher := newLocalDispatcher(repo, testConfig())
+	dispatcher.Start()
+
+	event := testEvent(t)
+	require.NoError(t, dispatcher.Publish(context.Background(), event))
+
+	var delivery domain.WebhookDelivery
+	require.Eventually(t, func() (done bool) {
+		delivery, done = repo.delivery(subscribed.ID)
+		return done
+	}, time.Second, time.Millisecond)
+	require.NoError(t, dispatcher.Close(context.Background()))
+
+	require.Len(t, received, 2)
+	for i, r := range received {
+		require.Equal(t, "/", r.URL.Path)
+		require.Equal(t, string(domain.EventDeviceStateChanged), r.Header.Get(HeaderEvent))
+		require.Equal(t, Sign(subscribed.Secret, r.Header.Get(HeaderTimestamp), bodies[i]), r.Header.Get(HeaderSignature))
+	}
+	require.Equal(t, received[0].Header.Get(HeaderDelivery), received[1].Header.Get(HeaderDelivery))
+
+	var payload dto.DeviceEventResponse
+	require.NoError(t, json.Unmarshal(bodies[1], &payload))
+	require.Equal(t, event.ID, payload.ID)
+	require.Equal(t, "available", payload.PreviousState)
+	require.Equal(t, event.Device.ID, payload.Data.ID)
+	require.Equal(t, "in-use", payload.Data.State)
+
+	require.Equal(t, domain.DeliverySucceeded, delivery.Status)
+	require.Equal(t, 2, delivery.Attempts)
+	require.Equal(t, http.StatusNoContent, delivery.LastStatusCode)
+	require.Empty(t, delivery.LastError)
+
+	deliveries, err := repo.GetDeliveries(context.Background(), other.ID, 10, 0)
+	require.NoError(t, err)
+	require.Empty(t, deliveries)
+}
+
+func TestDispatcher_AttemptsEachDeliveryOnceAcrossInstances(t *testing.T) {
+
+	var hits atomic.Int32
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		time.Sleep(20 * time.Millisecond)
+	}))
+	defer receiver.Close()
+
+	webhook := localWebhook(receiver, "")
+
+	// both instances poll the same deliveries
+	repo := newMemoryRepo(webhook)
+	first := newLocalDispatcher(repo, testConfig())
+	second := newLocalDispatcher(repo, testConfig())
+	first.Start()
+	second.Start()
+
+	for range 5 {
+		require.NoError(t, first.Publish(context.Background(), testEvent(t)))
+	}
+
+	require.Eventually(t, func() bool {
+		deliveries, _ := repo.GetDeliveries(context.Background(), webhook.ID, 10, 0)
+		for _, delivery := range deliveries {
+			if delivery.Status != domain.DeliverySucceeded {
+				return false
+			}
+		}
+		return len(deliveries) == 5
+	}, 2*time.Second, time.Millisecond)
+	require.NoError(t, first.Close(context.Background()))
+	require.NoError(t, second.Close(context.Background()))
+
+	require.Equal(t, int32(5), hits.Load())
+}
+
+func TestDispatcher_FailsAfterMaxAttempts(t *testing.T) {
+
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer receiver.Close()
+
+	webhook := localWebhook(receiver, "")
+
+	repo := newMemoryRepo(webhook)
+	dispatcher := newLocalDispatcher(repo, testConfig())
+	dispatcher.Start()
+
+	require.NoError(t, dispatcher.Publish(context.Background(), testEvent(t)))
+
+	var delivery domain.WebhookDelivery
+	require.Eventually(t, func() (done bool) {
+		delivery, done = repo.delivery(webhook.ID)
+		return done
+	}, time.Second, time.Millisecond)
+	require.NoError(t, dispatcher.Close(context.Background()))
+
+	require.Equal(t, domain.DeliveryFailed, delivery.Status)
+	require.Equal(t, 3, delivery.Attempts)
+	require.Equal(t, http.StatusServiceUnavailable, delivery.LastStatusCode)
+	require.Equal(t, "unexpected status 503", delivery.LastError)
+}
+
+func TestDispatcher_RefusesPrivateAddresses(t *testing.T) {
+
+	var hits atomic.Int32
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+	}))
+	defer receiver.Close()
+
+	// e.g. a public name rebound to a loopback address after the registration
+	webhook := localWebhook(receiver, "")
+
+	config := testConfig()
+	config.MaxAttempts = 1
+	repo := newMemoryRepo(webhook)
+	dispatcher := NewDispatcher(repo, config)
+	dispatcher.Start()
+
+	require.NoError(t, dispatcher.Publish(context.Background(), testEvent(t)))
+
+	var delivery domain.WebhookDelivery
+	require.Eventually(t, func() (done bool) {
+		delivery, done = repo.delivery(webhook.ID)
+		return done
+	}, time.Second, time.Millisecond)
+	require.NoError(t, dispatcher.Close(context.Background()))
+
+	require.Zero(t, hits.Load())
+	require.Equal(t, domain.DeliveryFailed, delivery.Status)
+	require.Contains(t, delivery.LastError, ErrPrivateAddress.Error())
+}
+
+func TestDispatcher_StoresDeliveriesBeforeAttemptingThem(t *testing.T) {
+
+	var hits atomic.Int32
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+	}))
+	defer receiver.Close()
+
+	webhook := localWebhook(receiver, "")
+	repo := newMemoryRepo(webhook)
+
+	// the event is stored once published, even if the API stops before attempting it
+	stopped := newLocalDispatcher(repo, testConfig())
+	event := testEvent(t)
+	require.NoError(t, stopped.Publish(context.Background(), event))
+	require.NoError(t, stopped.Publish(context.Background(), event))
+
+	deliveries, err := repo.GetDeliveries(context.Background(), webhook.ID, 10, 0)
+	require.NoError(t, err)
+	require.Len(t, deliveries, 1)
+	require.Equal(t, domain.DeliveryPending, deliveries[0].Status)
+	require.Zero(t, hits.Load())
+
+	// the next run resumes it
+	dispatcher := newLocalDispatcher(repo, testConfig())
+	dispatcher.Start()
+
+	var delivery domain.WebhookDelivery
+	require.Eventually(t, func() (done bool) {
+		delivery, done = repo.delivery(webhook.ID)
+		return done
+	}, time.Second, time.Millisecond)
+	require.NoError(t, dispatcher.Close(context.Background()))
+
+	require.Equal(t, domain.DeliverySucceeded, delivery.Status)
+	require.Equal(t, int32(1), hits.Load())
+	require.ErrorIs(t, dispatcher.Publish(context.Background(), testEvent(t)), ErrDispatcherClosed)
+}
+
+func TestDispatcher_DeadWebhookDoesNotHoldEvents(t *testing.T) {
+
+	var hits atomic.Int32
+	release := make(chan struct{})
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer receiver.Close()
+	defer close(release)
+
+	webhook := localWebhook(receiver, "")
+	repo := newMemoryRepo(webhook)
+
+	config := testConfig()
+	config.Workers = 1
+	config.Timeout = time.Minute
+	dispatcher := newLocalDispatcher(repo, config)
+	dispatcher.Start()
+
+	// the webhook holds the only worker, the events are accepted all the same
+	for range 100 {
+		require.NoError(t, dispatcher.Publish(context.Background(), testEvent(t)))
+	}
+	require.Eventually(t, func() bool { return hits.Load() == 1 }, time.Second, time.Millisecond)
+
+	// the attempt in progress is abandoned at shutdown without being counted
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	require.ErrorIs(t, dispatcher.Close(ctx), context.DeadlineExceeded)
+
+	deliveries, err := repo.GetDeliveries(context.Background(), webhook.ID, 100, 0)
+	require.NoError(t, err)
+	require.Len(t, deliveries, 100)
+	for _, delivery := range deliveries {
+		require.Equal(t, domain.DeliveryPending, delivery.Status)
+		require.Zero(t, delivery.Attempts)
+	}
+}
+
+func TestDispatcher_Backoff(t *testing.T) {
+	config := DefaultConfig()
+	config.BaseDelay = time.Second
+	config.MaxDelay = 5 * time.Second
+	d := NewDispatcher(newMemoryRepo(), config)
+
+	require.Equal(t, time.Second, d.backoff(1))
+	require.Equal(t, 2*time.Second, d.backoff(2))
+	require.Equal(t, 4*time.Second, d.backoff(3))
+	require.Equal(t, 5*time.Second, d.backoff(4))
+	require.Equal(t, 5*time.Second, d.backoff(40))
+}
@@ -0,0 +1,227 @@
+package service
+
+import (
+	"context"
+	"crypto/subtle"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/raulsilva-tech/devices-api/internal/domain"
+)
+
+var (
+	ErrAPIKeyNotFound = errors.New("api key not found")
+	// ErrInvalidAPIKey does not tell unknown, expired and revoked keys apart on purpose
+	ErrInvalidAPIKey = errors.New("invalid or expired api key")
+)
+
+// bootstrapKeyID identifies the bootstrap key, which is not stored and cannot be revoked or rotated
+const bootstrapKeyID = "bootstrap"
+
+type APIKeyService struct {
+	repo         domain.APIKeyRepository
+	bootstrapKey string
+}
+
+// NewAPIKeyService creates the service. A non-empty bootstrapKey is accepted as an admin key,
+// so the first keys can be issued; it should be unset once they are.
+func NewAPIKeyService(repo domain.APIKeyRepository, bootstrapKey string) *APIKeyService {
+	return &APIKeyService{
+		repo:         repo,
+		bootstrapKey: bootstrapKey,
+	}
+}
+
+type CreateAPIKeyInput struct {
+	Name      string
+	Scopes    []domain.Scope
+	Roles     []domain.Role
+	TenantID  string    // empty leaves the key unbound, forced to the tenant of a bound caller
+	ExpiresAt time.Time // zero never expires
+}
+
+type APIKeyOutput struct {
+	ID        string
+	Name      string
+	Prefix    string
+	Key       string // only returned when the key is created or rotated
+	Scopes    []domain.Scope
+	Roles     []domain.Role
+	TenantID  string
+	ExpiresAt time.Time
+	CreatedAt time.Time
+	RevokedAt time.Time
+}
+
+// CreateAPIKey issues a key. The key is only returned here, the service keeps its hash.
+// A caller bound to a tenant can only issue keys bound to the same tenant.
+func (s *APIKeyService) CreateAPIKey(ctx context.Context, input CreateAPIKeyInput) (*APIKeyOutput, error) {
+
+	tenant, err := keyTenant(ctx, input.TenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	key, secret, err := domain.NewAPIKey(input.Name, input.Scopes, input.Roles, input.ExpiresAt)
+	if err != nil {
+		return nil, err
+	}
+	key.TenantID = tenant
+
+	if err := s.repo.CreateAPIKey(ctx, key); err != nil {
+		return nil, err
+	}
+
+	output := mapDomainToServiceAPIKey(*key)
+	output.Key = secret
+
+	return &output, nil
+}
+
+// GetAPIKeys lists the keys, only the ones of its tenant for a caller bound to a tenant
+func (s *APIKeyService) GetAPIKeys(ctx context.Context) ([]APIKeyOutput, error) {
+
+	keys, err := s.repo.GetAPIKeys(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	resultList := make([]APIKeyOutput, 0, len(keys))
+	for _, key := range keys {
+		if canManageKey(ctx, &key) {
+			resultList = append(resultList, mapDomainToServiceAPIKey(key))
+		}
+	}
+
+	return resultList, nil
+}
+
+// RevokeAPIKey disables the key for good, it stays listed with its revocation time
+func (s *APIKeyService) RevokeAPIKey(ctx context.Context, id string) error {
+
+	if principal := domain.PrincipalFromContext(ctx); principal != nil && principal.Tenant != "" {
+		key, err := s.repo.GetAPIKeyById(ctx, id)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return ErrAPIKeyNotFound
+			}
+			return err
+		}
+		if !canManageKey(ctx, key) {
+			return ErrAPIKeyNotFound
+		}
+	}
+
+	if err := s.repo.RevokeAPIKey(ctx, id, time.Now().UTC()); err != nil {
+		if err == sql.ErrNoRows {
+			return ErrAPIKeyNotFound
+		}
+		return err
+	}
+
+	return nil
+}
+
+// RotateAPIKey issues a new key with the same name, scopes, roles and expiry. The previous key stops working at once.
+func (s *APIKeyService) RotateAPIKey(ctx context.Context, id string) (*APIKeyOutput, error) {
+
+	key, err := s.repo.GetAPIKeyById(ctx, id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrAPIKeyNotFound
+		}
+		return nil, err
+	}
+
+	if !key.RevokedAt.IsZero() || !canManageKey(ctx, key) {
+		return nil, ErrAPIKeyNotFound
+	}
+
+	secret, err := key.Rotate()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.RotateAPIKey(ctx, key); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrAPIKeyNotFound
+		}
+		return nil, err
+	}
+
+	output := mapDomainToServiceAPIKey(*key)
+	output.Key = secret
+
+	return &output, nil
+}
+
+// Authenticate returns the active key matching the secret
+func (s *APIKeyService) Authenticate(ctx context.Context, secret string) (*domain.APIKey, error) {
+
+	if secret == "" {
+		return nil, ErrInvalidAPIKey
+	}
+
+	if s.bootstrapKey != "" && subtle.ConstantTimeCompare([]byte(secret), []byte(s.bootstrapKey)) == 1 {
+		return &domain.APIKey{
+			ID:     bootstrapKeyID,
+			Name:   bootstrapKeyID,
+			Scopes: []domain.Scope{domain.ScopeAdmin},
+			Roles:  []domain.Role{domain.RoleAdmin},
+		}, nil
+	}
+
+	key, err := s.repo.GetAPIKeyByHash(ctx, domain.HashAPIKey(secret))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrInvalidAPIKey
+		}
+		return nil, err
+	}
+
+	if !key.IsActive(time.Now()) {
+		return nil, ErrInvalidAPIKey
+	}
+
+	return key, nil
+}
+
+// keyTenant returns the tenant a new key is bound to: the tenant of a bound caller,
+// otherwise the requested one, empty for an unbound key
+func keyTenant(ctx context.Context, requested string) (string, error) {
+
+	if requested != "" && !domain.IsValidTenant(requested) {
+		return "", domain.ErrInvalidTenant
+	}
+
+	if principal := domain.PrincipalFromContext(ctx); principal != nil && principal.Tenant != "" {
+		if requested != "" && requested != principal.Tenant {
+			return "", domain.ErrTenantForbidden
+		}
+		return principal.Tenant, nil
+	}
+
+	return requested, nil
+}
+
+// canManageKey tells whether the caller may see and change the key. The keys of a tenant are
+// hidden from the callers bound to another tenant, unbound callers see every key.
+func canManageKey(ctx context.Context, key *domain.APIKey) bool {
+	principal := domain.PrincipalFromContext(ctx)
+	return principal == nil || principal.Tenant == "" || principal.Tenant == key.TenantID
+}
+
+func mapDomainToServiceAPIKey(k domain.APIKey) APIKeyOutput {
+	return APIKeyOutput{
+		ID:        k.ID,
+		Name:      k.Name,
+		Prefix:    k.Prefix,
+		Scopes:    k.Scopes,
+		Roles:     k.Roles,
+		TenantID:  k.TenantID,
+		ExpiresAt: k.ExpiresAt,
+		CreatedAt: k.CreatedAt,
+		RevokedAt: k.RevokedAt,
+	}
+}
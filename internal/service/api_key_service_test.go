@@ -0,0 +1,194 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/raulsilva-tech/devices-api/internal/domain"
+	"github.com/stretchr/testify/require"
+)
+
+type mockAPIKeyRepo struct {
+	CreateAPIKeyFunc    func(ctx context.Context, key *domain.APIKey) error
+	GetAPIKeyByIdFunc   func(ctx context.Context, id string) (*domain.APIKey, error)
+	GetAPIKeyByHashFunc func(ctx context.Context, hash string) (*domain.APIKey, error)
+	GetAPIKeysFunc      func(ctx context.Context) ([]domain.APIKey, error)
+	RevokeAPIKeyFunc    func(ctx context.Context, id string, revokedAt time.Time) error
+	RotateAPIKeyFunc    func(ctx context.Context, key *domain.APIKey) error
+}
+
+func (m *mockAPIKeyRepo) CreateAPIKey(ctx context.Context, key *domain.APIKey) error {
+	return m.CreateAPIKeyFunc(ctx, key)
+}
+func (m *mockAPIKeyRepo) GetAPIKeyById(ctx context.Context, id string) (*domain.APIKey, error) {
+	return m.GetAPIKeyByIdFunc(ctx, id)
+}
+func (m *mockAPIKeyRepo) GetAPIKeyByHash(ctx context.Context, hash string) (*domain.APIKey, error) {
+	return m.GetAPIKeyByHashFunc(ctx, hash)
+}
+func (m *mockAPIKeyRepo) GetAPIKeys(ctx context.Context) ([]domain.APIKey, error) {
+	return m.GetAPIKeysFunc(ctx)
+}
+func (m *mockAPIKeyRepo) RevokeAPIKey(ctx context.Context, id string, revokedAt time.Time) error {
+	return m.RevokeAPIKeyFunc(ctx, id, revokedAt)
+}
+func (m *mockAPIKeyRepo) RotateAPIKey(ctx context.Context, key *domain.APIKey) error {
+	return m.RotateAPIKeyFunc(ctx, key)
+}
+
+// keysByHash stores the created keys and finds them by hash
+func keysByHash() *mockAPIKeyRepo {
+	keys := map[string]*domain.APIKey{}
+	return &mockAPIKeyRepo{
+		CreateAPIKeyFunc: func(ctx context.Context, key *domain.APIKey) error {
+			keys[key.Hash] = key
+			return nil
+		},
+		GetAPIKeyByHashFunc: func(ctx context.Context, hash string) (*domain.APIKey, error) {
+			key, ok := keys[hash]
+			if !ok {
+				return nil, sql.ErrNoRows
+			}
+			return key, nil
+		},
+	}
+}
+
+func TestCreateAPIKey(t *testing.T) {
+	ctx := context.Background()
+	svc := NewAPIKeyService(keysByHash(), "")
+
+	output, err := svc.CreateAPIKey(ctx, CreateAPIKeyInput{Name: "ci", Scopes: []domain.Scope{domain.ScopeDevicesRead}, Roles: []domain.Role{domain.RoleViewer}})
+	require.NoError(t, err)
+	require.NotEmpty(t, output.Key)
+
+	key, err := svc.Authenticate(ctx, output.Key)
+	require.NoError(t, err)
+	require.Equal(t, output.ID, key.ID)
+
+	_, err = svc.CreateAPIKey(ctx, CreateAPIKeyInput{Name: "ci", Scopes: []domain.Scope{"everything"}, Roles: []domain.Role{domain.RoleViewer}})
+	require.ErrorIs(t, err, domain.ErrInvalidScope)
+
+	_, err = svc.CreateAPIKey(ctx, CreateAPIKeyInput{Name: "ci", Scopes: []domain.Scope{domain.ScopeDevicesRead}})
+	require.ErrorIs(t, err, domain.ErrRoleIsRequired)
+}
+
+func TestAuthenticate(t *testing.T) {
+	ctx := context.Background()
+	repo := keysByHash()
+	svc := NewAPIKeyService(repo, "dak_bootstrap")
+
+	revoked, revokedSecret, err := domain.NewAPIKey("revoked", []domain.Scope{domain.ScopeDevicesRead}, []domain.Role{domain.RoleViewer}, time.Time{})
+	require.NoError(t, err)
+	revoked.RevokedAt = time.Now()
+	require.NoError(t, repo.CreateAPIKey(ctx, revoked))
+
+	expired, expiredSecret, err := domain.NewAPIKey("expired", []domain.Scope{domain.ScopeDevicesRead}, []domain.Role{domain.RoleViewer}, time.Now().Add(time.Hour))
+	require.NoError(t, err)
+	expired.ExpiresAt = time.Now().Add(-time.Minute)
+	require.NoError(t, repo.CreateAPIKey(ctx, expired))
+
+	for _, secret := range []string{"", "dak_unknown", revokedSecret, expiredSecret} {
+		_, err := svc.Authenticate(ctx, secret)
+		require.ErrorIs(t, err, ErrInvalidAPIKey, secret)
+	}
+
+	key, err := svc.Authenticate(ctx, "dak_bootstrap")
+	require.NoError(t, err)
+	require.True(t, key.HasScope(domain.ScopeAdmin))
+}
+
+func TestRotateAPIKey(t *testing.T) {
+	ctx := context.Background()
+
+	key, secret, err := domain.NewAPIKey("ci", []domain.Scope{domain.ScopeDevicesRead}, []domain.Role{domain.RoleViewer}, time.Time{})
+	require.NoError(t, err)
+	revoked := *key
+	revoked.RevokedAt = time.Now()
+
+	var stored *domain.APIKey
+	svc := NewAPIKeyService(&mockAPIKeyRepo{
+		GetAPIKeyByIdFunc: func(ctx context.Context, id string) (*domain.APIKey, error) {
+			switch id {
+			case key.ID:
+				return key, nil
+			case "revoked":
+				return &revoked, nil
+			}
+			return nil, sql.ErrNoRows
+		},
+		RotateAPIKeyFunc: func(ctx context.Context, key *domain.APIKey) error {
+			stored = key
+			return nil
+		},
+	}, "")
+
+	output, err := svc.RotateAPIKey(ctx, key.ID)
+	require.NoError(t, err)
+	require.NotEqual(t, secret, output.Key)
+	require.Equal(t, domain.HashAPIKey(output.Key), stored.Hash)
+
+	_, err = svc.RotateAPIKey(ctx, "revoked")
+	require.ErrorIs(t, err, ErrAPIKeyNotFound)
+
+	_, err = svc.RotateAPIKey(ctx, "missing")
+	require.ErrorIs(t, err, ErrAPIKeyNotFound)
+}
+
+func TestRevokeAPIKey_WhenNotFound(t *testing.T) {
+	svc := NewAPIKeyService(&mockAPIKeyRepo{
+		RevokeAPIKeyFunc: func(ctx context.Context, id string, revokedAt time.Time) error {
+			return sql.ErrNoRows
+		},
+	}, "")
+
+	require.ErrorIs(t, svc.RevokeAPIKey(context.Background(), "missing"), ErrAPIKeyNotFound)
+}
+
+func TestAPIKeys_BoundToTenant(t *testing.T) {
+	ctx := context.Background()
+	repo := keysByHash()
+	svc := NewAPIKeyService(repo, "")
+
+	acme := domain.WithPrincipal(ctx, &domain.Principal{Subject: "apikey:acme-admin", Tenant: "acme"})
+
+	// a bound caller can only issue keys of its tenant
+	output, err := svc.CreateAPIKey(acme, CreateAPIKeyInput{Name: "ci", Scopes: []domain.Scope{domain.ScopeDevicesRead}, Roles: []domain.Role{domain.RoleViewer}})
+	require.NoError(t, err)
+	require.Equal(t, "acme", output.TenantID)
+
+	key, err := svc.Authenticate(ctx, output.Key)
+	require.NoError(t, err)
+	require.Equal(t, "acme", key.Principal().Tenant)
+
+	_, err = svc.CreateAPIKey(acme, CreateAPIKeyInput{Name: "ci", Scopes: []domain.Scope{domain.ScopeDevicesRead}, Roles: []domain.Role{domain.RoleViewer}, TenantID: "globex"})
+	require.ErrorIs(t, err, domain.ErrTenantForbidden)
+
+	_, err = svc.CreateAPIKey(ctx, CreateAPIKeyInput{Name: "ci", Scopes: []domain.Scope{domain.ScopeDevicesRead}, Roles: []domain.Role{domain.RoleViewer}, TenantID: "Globex Corp"})
+	require.ErrorIs(t, err, domain.ErrInvalidTenant)
+
+	// and cannot see nor change the keys of the other tenants
+	globex := domain.APIKey{ID: "globex-key", Name: "globex", TenantID: "globex"}
+	unbound := domain.APIKey{ID: "unbound-key", Name: "ops"}
+	repo.GetAPIKeysFunc = func(ctx context.Context) ([]domain.APIKey, error) {
+		return []domain.APIKey{*key, globex, unbound}, nil
+	}
+	repo.GetAPIKeyByIdFunc = func(ctx context.Context, id string) (*domain.APIKey, error) {
+		return &globex, nil
+	}
+
+	keys, err := svc.GetAPIKeys(acme)
+	require.NoError(t, err)
+	require.Len(t, keys, 1)
+	require.Equal(t, key.ID, keys[0].ID)
+
+	keys, err = svc.GetAPIKeys(ctx)
+	require.NoError(t, err)
+	require.Len(t, keys, 3)
+
+	require.ErrorIs(t, svc.RevokeAPIKey(acme, globex.ID), ErrAPIKeyNotFound)
+	_, err = svc.RotateAPIKey(acme, globex.ID)
+	require.ErrorIs(t, err, ErrAPIKeyNotFound)
+}
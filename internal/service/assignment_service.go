@@ -0,0 +1,217 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/raulsilva-tech/devices-api/internal/domain"
+)
+
+type AssignmentService struct {
+	devices     domain.DeviceRepository
+	assignments domain.AssignmentRepository
+	policy      *domain.Policy
+}
+
+// NewAssignmentService creates the service, the policy is applied as in NewDeviceService
+func NewAssignmentService(devices domain.DeviceRepository, assignments domain.AssignmentRepository, policy *domain.Policy) *AssignmentService {
+	return &AssignmentService{
+		devices:     devices,
+		assignments: assignments,
+		policy:      policy,
+	}
+}
+
+type CheckOutInput struct {
+	DeviceID         string
+	Assignee         string
+	ExpectedReturnAt time.Time
+	Notes            string
+	ExpectedVersion  *int64
+}
+
+type CheckInInput struct {
+	DeviceID        string
+	ExpectedVersion *int64
+}
+
+type AssignmentOutput struct {
+	ID               string
+	DeviceID         string
+	Assignee         string
+	CheckedOutAt     time.Time
+	ExpectedReturnAt time.Time
+	ReturnedAt       time.Time
+	Notes            string
+}
+
+type CheckOutOutput struct {
+	Device     DeviceOutput
+	Assignment AssignmentOutput
+}
+
+type CheckInOutput struct {
+	Device     DeviceOutput
+	Assignment AssignmentOutput
+}
+
+type HeldDeviceOutput struct {
+	Device     DeviceOutput
+	Assignment AssignmentOutput
+}
+
+// CheckOut hands the device to the assignee: the device goes in use and the assignment is opened atomically
+func (s *AssignmentService) CheckOut(ctx context.Context, input CheckOutInput) (*CheckOutOutput, error) {
+
+	if err := authorize(ctx, s.policy, domain.PermissionDevicesChangeState); err != nil {
+		return nil, err
+	}
+
+	device, err := s.getDevice(ctx, input.DeviceID, input.ExpectedVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	assignment, err := domain.NewAssignment(device.ID, input.Assignee, time.Now(), input.ExpectedReturnAt, input.Notes)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := device.Apply(domain.ActionCheckOut); err != nil {
+		return nil, err
+	}
+
+	if err := s.assignments.CheckOutDevice(ctx, device, assignment); err != nil {
+		return nil, err
+	}
+
+	return &CheckOutOutput{
+		Device:     mapDomainToServiceDevice(*device),
+		Assignment: mapDomainToServiceAssignment(*assignment),
+	}, nil
+}
+
+// CheckIn returns the device: it becomes available again and its open assignment is closed atomically
+func (s *AssignmentService) CheckIn(ctx context.Context, input CheckInInput) (*CheckInOutput, error) {
+
+	if err := authorize(ctx, s.policy, domain.PermissionDevicesChangeState); err != nil {
+		return nil, err
+	}
+
+	device, err := s.getDevice(ctx, input.DeviceID, input.ExpectedVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := device.Apply(domain.ActionReturn); err != nil {
+		return nil, err
+	}
+
+	assignment, err := s.assignments.GetOpenAssignment(ctx, device.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	returnedAt := time.Now()
+	if err := s.assignments.CheckInDevice(ctx, device, returnedAt); err != nil {
+		return nil, err
+	}
+
+	assignment.ReturnedAt = returnedAt
+
+	return &CheckInOutput{
+		Device:     mapDomainToServiceDevice(*device),
+		Assignment: mapDomainToServiceAssignment(*assignment),
+	}, nil
+}
+
+// GetAssignments lists the assignments of a device, most recent first
+func (s *AssignmentService) GetAssignments(ctx context.Context, deviceID string, limit, offset int) ([]AssignmentOutput, error) {
+
+	if err := authorize(ctx, s.policy, domain.PermissionDevicesRead); err != nil {
+		return nil, err
+	}
+
+	if limit == 0 {
+		limit = domain.DefaultPageLimit
+	}
+	if limit < 0 || limit > domain.MaxPageLimit {
+		return nil, domain.ErrInvalidLimit
+	}
+	if offset < 0 {
+		return nil, domain.ErrInvalidOffset
+	}
+
+	if _, err := s.getDevice(ctx, deviceID, nil); err != nil {
+		return nil, err
+	}
+
+	assignments, err := s.assignments.GetAssignmentsByDevice(ctx, deviceID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+
+	resultList := make([]AssignmentOutput, len(assignments))
+	for i, assignment := range assignments {
+		resultList[i] = mapDomainToServiceAssignment(assignment)
+	}
+
+	return resultList, nil
+}
+
+// GetDevicesHeldBy lists the devices the assignee currently holds
+func (s *AssignmentService) GetDevicesHeldBy(ctx context.Context, assignee string) ([]HeldDeviceOutput, error) {
+
+	if err := authorize(ctx, s.policy, domain.PermissionDevicesRead); err != nil {
+		return nil, err
+	}
+
+	if assignee == "" {
+		return nil, domain.ErrAssigneeIsRequired
+	}
+
+	held, err := s.assignments.GetDevicesHeldBy(ctx, assignee)
+	if err != nil {
+		return nil, err
+	}
+
+	resultList := make([]HeldDeviceOutput, len(held))
+	for i, h := range held {
+		resultList[i] = HeldDeviceOutput{
+			Device:     mapDomainToServiceDevice(h.Device),
+			Assignment: mapDomainToServiceAssignment(h.Assignment),
+		}
+	}
+
+	return resultList, nil
+}
+
+func (s *AssignmentService) getDevice(ctx context.Context, id string, expectedVersion *int64) (*domain.Device, error) {
+
+	device, err := s.devices.GetDeviceById(ctx, id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrDeviceNotFound
+		}
+		return nil, err
+	}
+
+	if expectedVersion != nil && *expectedVersion != device.Version {
+		return nil, domain.ErrVersionMismatch
+	}
+
+	return device, nil
+}
+
+func mapDomainToServiceAssignment(a domain.Assignment) AssignmentOutput {
+	return AssignmentOutput{
+		ID:               a.ID,
+		DeviceID:         a.DeviceID,
+		Assignee:         a.Assignee,
+		CheckedOutAt:     a.CheckedOutAt,
+		ExpectedReturnAt: a.ExpectedReturnAt,
+		ReturnedAt:       a.ReturnedAt,
+		Notes:            a.Notes,
+	}
+}
@@ -0,0 +1,137 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/raulsilva-tech/devices-api/internal/domain"
+	"github.com/stretchr/testify/require"
+)
+
+type mockAssignmentRepo struct {
+	CheckOutDeviceFunc         func(ctx context.Context, device *domain.Device, assignment *domain.Assignment) error
+	CheckInDeviceFunc          func(ctx context.Context, device *domain.Device, returnedAt time.Time) error
+	GetOpenAssignmentFunc      func(ctx context.Context, deviceID string) (*domain.Assignment, error)
+	GetAssignmentsByDeviceFunc func(ctx context.Context, deviceID string, limit, offset int) ([]domain.Assignment, error)
+	GetDevicesHeldByFunc       func(ctx context.Context, assignee string) ([]domain.HeldDevice, error)
+}
+
+func (m *mockAssignmentRepo) CheckOutDevice(ctx context.Context, device *domain.Device, assignment *domain.Assignment) error {
+	return m.CheckOutDeviceFunc(ctx, device, assignment)
+}
+func (m *mockAssignmentRepo) CheckInDevice(ctx context.Context, device *domain.Device, returnedAt time.Time) error {
+	return m.CheckInDeviceFunc(ctx, device, returnedAt)
+}
+func (m *mockAssignmentRepo) GetOpenAssignment(ctx context.Context, deviceID string) (*domain.Assignment, error) {
+	return m.GetOpenAssignmentFunc(ctx, deviceID)
+}
+func (m *mockAssignmentRepo) GetAssignmentsByDevice(ctx context.Context, deviceID string, limit, offset int) ([]domain.Assignment, error) {
+	return m.GetAssignmentsByDeviceFunc(ctx, deviceID, limit, offset)
+}
+func (m *mockAssignmentRepo) GetDevicesHeldBy(ctx context.Context, assignee string) ([]domain.HeldDevice, error) {
+	return m.GetDevicesHeldByFunc(ctx, assignee)
+}
+
+func TestCheckOut(t *testing.T) {
+	ctx := context.Background()
+
+	orig := makeDeviceWithState(domain.DeviceAvailable)
+	devices := &mockDeviceRepo{
+		GetDeviceByIdFunc: func(ctx context.Context, id string) (*domain.Device, error) {
+			copy := *orig
+			return &copy, nil
+		},
+	}
+
+	var savedDevice *domain.Device
+	var savedAssignment *domain.Assignment
+	assignments := &mockAssignmentRepo{
+		CheckOutDeviceFunc: func(ctx context.Context, device *domain.Device, assignment *domain.Assignment) error {
+			savedDevice, savedAssignment = device, assignment
+			return nil
+		},
+	}
+	svc := NewAssignmentService(devices, assignments, nil)
+
+	due := time.Now().Add(48 * time.Hour)
+	out, err := svc.CheckOut(ctx, CheckOutInput{DeviceID: orig.ID, Assignee: "jane", ExpectedReturnAt: due, Notes: "demo"})
+	require.NoError(t, err)
+	require.Equal(t, domain.DeviceInUse, out.Device.State)
+	require.Equal(t, "jane", out.Assignment.Assignee)
+	require.Equal(t, due, out.Assignment.ExpectedReturnAt)
+
+	require.Equal(t, domain.DeviceInUse, savedDevice.State)
+	require.Equal(t, orig.ID, savedAssignment.DeviceID)
+
+	// missing assignee is rejected before anything is stored
+	savedDevice = nil
+	_, err = svc.CheckOut(ctx, CheckOutInput{DeviceID: orig.ID})
+	require.ErrorIs(t, err, domain.ErrAssigneeIsRequired)
+	require.Nil(t, savedDevice)
+
+	// a device that is already in use cannot be checked out again
+	orig.State = domain.DeviceInUse
+	_, err = svc.CheckOut(ctx, CheckOutInput{DeviceID: orig.ID, Assignee: "john"})
+	require.ErrorIs(t, err, domain.ErrIllegalTransition)
+}
+
+func TestCheckIn(t *testing.T) {
+	ctx := context.Background()
+
+	orig := makeDeviceWithState(domain.DeviceInUse)
+	devices := &mockDeviceRepo{
+		GetDeviceByIdFunc: func(ctx context.Context, id string) (*domain.Device, error) {
+			copy := *orig
+			return &copy, nil
+		},
+	}
+
+	open, _ := domain.NewAssignment(orig.ID, "jane", time.Now().Add(-time.Hour), time.Time{}, "")
+	var savedDevice *domain.Device
+	assignments := &mockAssignmentRepo{
+		GetOpenAssignmentFunc: func(ctx context.Context, deviceID string) (*domain.Assignment, error) {
+			return open, nil
+		},
+		CheckInDeviceFunc: func(ctx context.Context, device *domain.Device, returnedAt time.Time) error {
+			savedDevice = device
+			return nil
+		},
+	}
+	svc := NewAssignmentService(devices, assignments, nil)
+
+	out, err := svc.CheckIn(ctx, CheckInInput{DeviceID: orig.ID})
+	require.NoError(t, err)
+	require.Equal(t, domain.DeviceAvailable, out.Device.State)
+	require.Equal(t, domain.DeviceAvailable, savedDevice.State)
+	require.Equal(t, "jane", out.Assignment.Assignee)
+	require.False(t, out.Assignment.ReturnedAt.IsZero())
+
+	// every device in use has an open assignment, a missing one is not papered over
+	savedDevice = nil
+	assignments.GetOpenAssignmentFunc = func(ctx context.Context, deviceID string) (*domain.Assignment, error) {
+		return nil, sql.ErrNoRows
+	}
+	_, err = svc.CheckIn(ctx, CheckInInput{DeviceID: orig.ID})
+	require.ErrorIs(t, err, sql.ErrNoRows)
+	require.Nil(t, savedDevice)
+
+	// stale If-Match
+	_, err = svc.CheckIn(ctx, CheckInInput{DeviceID: orig.ID, ExpectedVersion: ptr(int64(9))})
+	require.ErrorIs(t, err, domain.ErrVersionMismatch)
+}
+
+func TestGetAssignments_DeviceNotFound(t *testing.T) {
+	ctx := context.Background()
+
+	devices := &mockDeviceRepo{
+		GetDeviceByIdFunc: func(ctx context.Context, id string) (*domain.Device, error) {
+			return nil, sql.ErrNoRows
+		},
+	}
+	svc := NewAssignmentService(devices, &mockAssignmentRepo{}, nil)
+
+	_, err := svc.GetAssignments(ctx, "x", 0, 0)
+	require.ErrorIs(t, err, ErrDeviceNotFound)
+}
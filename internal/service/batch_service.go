@@ -0,0 +1,115 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"github.com/raulsilva-tech/devices-api/internal/domain"
+)
+
+// MaxBatchOperations bounds the number of operations of a single batch
+const MaxBatchOperations = 100
+
+var (
+	ErrEmptyBatch              = errors.New("batch has no operations")
+	ErrTooManyBatchOperations  = errors.New("batch has too many operations")
+	ErrInvalidBatchOperation   = errors.New("invalid batch operation")
+	ErrBatchRolledBack         = errors.New("not applied, another operation of the atomic batch failed")
+	ErrTransactionsUnsupported = errors.New("repository does not support transactions")
+
+	// errBatchFailed rolls back an atomic batch, the failure itself is reported on the operation
+	errBatchFailed = errors.New("batch failed")
+)
+
+type BatchOperationType string
+
+const (
+	BatchCreate BatchOperationType = "create"
+	BatchUpdate BatchOperationType = "update"
+	BatchDelete BatchOperationType = "delete"
+)
+
+// BatchOperation is one create, update or delete; only the input matching Type is used
+type BatchOperation struct {
+	Type   BatchOperationType
+	Create CreateDeviceInput
+	Update UpdateDeviceInput
+	Delete DeleteDeviceInput
+}
+
+type BatchResult struct {
+	CreatedID string
+	Updated   *UpdateDeviceOutput
+	Err       error
+}
+
+// BatchDevices runs the operations in order with the same rules as the single calls. In atomic mode
+// they share one transaction: the first failure rolls everything back and the other operations
+// report ErrBatchRolledBack. Otherwise each operation succeeds or fails on its own.
+func (s *DeviceService) BatchDevices(ctx context.Context, ops []BatchOperation, atomic bool) (_ []BatchResult, err error) {
+
+	ctx, span := startSpan(ctx, "DeviceService.BatchDevices")
+	defer func() { endSpan(span, err) }()
+
+	if len(ops) == 0 {
+		return nil, ErrEmptyBatch
+	}
+	if len(ops) > MaxBatchOperations {
+		return nil, ErrTooManyBatchOperations
+	}
+
+	results := make([]BatchResult, len(ops))
+
+	if !atomic {
+		for i, op := range ops {
+			results[i] = s.runBatchOperation(ctx, op)
+		}
+		return results, nil
+	}
+
+	transactor, ok := s.repo.(domain.Transactor)
+	if !ok {
+		return nil, ErrTransactionsUnsupported
+	}
+
+	failed := -1
+	err = transactor.WithinTx(ctx, func(ctx context.Context) error {
+		for i, op := range ops {
+			results[i] = s.runBatchOperation(ctx, op)
+			if results[i].Err != nil {
+				failed = i
+				return errBatchFailed
+			}
+		}
+		return nil
+	})
+	if err != nil && failed < 0 {
+		return nil, err
+	}
+
+	if failed >= 0 {
+		for i := range results {
+			if i != failed {
+				results[i] = BatchResult{Err: ErrBatchRolledBack}
+			}
+		}
+	}
+
+	return results, nil
+}
+
+func (s *DeviceService) runBatchOperation(ctx context.Context, op BatchOperation) BatchResult {
+
+	switch op.Type {
+	case BatchCreate:
+		id, err := s.CreateDevice(ctx, op.Create)
+		return BatchResult{CreatedID: id, Err: err}
+	case BatchUpdate:
+		output, err := s.UpdateDevice(ctx, op.Update)
+		return BatchResult{Updated: output, Err: err}
+	case BatchDelete:
+		return BatchResult{Err: s.DeleteDevice(ctx, op.Delete)}
+	}
+
+	return BatchResult{Err: ErrInvalidBatchOperation}
+}
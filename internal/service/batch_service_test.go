@@ -0,0 +1,103 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/raulsilva-tech/devices-api/internal/domain"
+	"github.com/stretchr/testify/require"
+)
+
+// mockTxDeviceRepo adds transactions to the mock repository; rollbacks are only recorded
+type mockTxDeviceRepo struct {
+	*mockDeviceRepo
+	rolledBack bool
+}
+
+func (m *mockTxDeviceRepo) WithinTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	err := fn(ctx)
+	m.rolledBack = err != nil
+	return err
+}
+
+func batchOps(inUse *domain.Device) []BatchOperation {
+	return []BatchOperation{
+		{Type: BatchCreate, Create: CreateDeviceInput{Name: "New", Brand: "Brand", State: domain.DeviceAvailable}},
+		{Type: BatchDelete, Delete: DeleteDeviceInput{ID: inUse.ID}},
+		{Type: BatchUpdate, Update: UpdateDeviceInput{ID: inUse.ID, Name: ptr("Renamed")}},
+	}
+}
+
+func batchRepo(inUse *domain.Device) *mockDeviceRepo {
+	return &mockDeviceRepo{
+		CreateDeviceFunc: func(ctx context.Context, device *domain.Device) (string, error) {
+			return device.ID, nil
+		},
+		GetDeviceByIdFunc: func(ctx context.Context, id string) (*domain.Device, error) {
+			copy := *inUse
+			return &copy, nil
+		},
+		UpdateDeviceFunc: func(ctx context.Context, device *domain.Device) error {
+			device.Version++
+			return nil
+		},
+	}
+}
+
+func TestBatchDevices_Atomic(t *testing.T) {
+	ctx := context.Background()
+
+	inUse := makeDeviceWithState(domain.DeviceInUse)
+	repo := &mockTxDeviceRepo{mockDeviceRepo: batchRepo(inUse)}
+	svc := NewDeviceService(repo, nil)
+
+	// deleting a device in use fails, so the create is rolled back and the update never runs
+	results, err := svc.BatchDevices(ctx, batchOps(inUse), true)
+	require.NoError(t, err)
+	require.True(t, repo.rolledBack)
+	require.ErrorIs(t, results[0].Err, ErrBatchRolledBack)
+	require.Empty(t, results[0].CreatedID)
+	require.ErrorIs(t, results[1].Err, domain.ErrDeleteDeviceInUse)
+	require.ErrorIs(t, results[2].Err, ErrBatchRolledBack)
+
+	ops := batchOps(inUse)
+	results, err = svc.BatchDevices(ctx, []BatchOperation{ops[0], ops[2]}, true)
+	require.NoError(t, err)
+	require.False(t, repo.rolledBack)
+	require.NotEmpty(t, results[0].CreatedID)
+	require.Equal(t, []string{"name"}, results[1].Updated.IgnoredFields)
+
+	// atomic batches need a repository with transactions
+	_, err = NewDeviceService(batchRepo(inUse), nil).BatchDevices(ctx, ops, true)
+	require.ErrorIs(t, err, ErrTransactionsUnsupported)
+}
+
+func TestBatchDevices_Partial(t *testing.T) {
+	ctx := context.Background()
+
+	inUse := makeDeviceWithState(domain.DeviceInUse)
+	svc := NewDeviceService(batchRepo(inUse), nil)
+
+	results, err := svc.BatchDevices(ctx, batchOps(inUse), false)
+	require.NoError(t, err)
+	require.NoError(t, results[0].Err)
+	require.NotEmpty(t, results[0].CreatedID)
+	require.ErrorIs(t, results[1].Err, domain.ErrDeleteDeviceInUse)
+	require.NoError(t, results[2].Err)
+	require.Equal(t, []string{"name"}, results[2].Updated.IgnoredFields)
+}
+
+func TestBatchDevices_Limits(t *testing.T) {
+	svc := NewDeviceService(&mockDeviceRepo{}, nil)
+
+	_, err := svc.BatchDevices(context.Background(), nil, false)
+	require.ErrorIs(t, err, ErrEmptyBatch)
+
+	_, err = svc.BatchDevices(context.Background(), make([]BatchOperation, MaxBatchOperations+1), false)
+	require.ErrorIs(t, err, ErrTooManyBatchOperations)
+
+	results, err := svc.BatchDevices(context.Background(), []BatchOperation{{Type: "rename"}}, false)
+	require.NoError(t, err)
+	require.True(t, errors.Is(results[0].Err, ErrInvalidBatchOperation))
+}
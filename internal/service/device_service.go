@@ -4,22 +4,77 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/raulsilva-tech/devices-api/internal/domain"
 )
 
-var ErrDeviceNotFound = errors.New("device not found")
+var (
+	ErrDeviceNotFound   = errors.New("device not found")
+	ErrInvalidRetention = errors.New("retention must not be negative")
+)
 
 type DeviceService struct {
-	repo domain.DeviceRepository
+	repo   domain.DeviceRepository
+	policy *domain.Policy
 }
 
-func NewDeviceService(repo domain.DeviceRepository) *DeviceService {
+// NewDeviceService creates the service. The policy guards every operation against the principal
+// on the context; without a policy, e.g. in the purge command, nothing is checked.
+func NewDeviceService(repo domain.DeviceRepository, policy *domain.Policy) *DeviceService {
 	return &DeviceService{
-		repo: repo,
+		repo:   repo,
+		policy: policy,
+	}
+}
+
+// Authorize checks the permission the same way the service operations do, for the callers that
+// do not go through them, such as the live streams of device changes
+func (s *DeviceService) Authorize(ctx context.Context, permission domain.Permission) error {
+	return authorize(ctx, s.policy, permission)
+}
+
+func authorize(ctx context.Context, policy *domain.Policy, permissions ...domain.Permission) error {
+	if policy == nil {
+		return nil
+	}
+	for _, p := range permissions {
+		if err := policy.Authorize(ctx, p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// updatePermissions is what an update needs: changing the state is a permission of its own.
+// An update without fields still needs the update permission.
+func updatePermissions(input UpdateDeviceInput) []domain.Permission {
+	var permissions []domain.Permission
+	if input.Name != nil || input.Brand != nil || input.State == nil {
+		permissions = append(permissions, domain.PermissionDevicesUpdate)
+	}
+	if input.State != nil {
+		permissions = append(permissions, domain.PermissionDevicesChangeState)
 	}
+	return permissions
+}
+
+// listPermissions is what a listing needs, the deleted devices are only listed to who may restore them
+func listPermissions(includeDeleted bool) []domain.Permission {
+	if includeDeleted {
+		return []domain.Permission{domain.PermissionDevicesRead, domain.PermissionDevicesRestore}
+	}
+	return []domain.Permission{domain.PermissionDevicesRead}
+}
+
+// likeEscaper escapes the LIKE wildcards, the queries declare '\' as their escape character
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// escapeLike makes the name filter match its text literally, "50%" only matches names containing "50%"
+func escapeLike(s string) string {
+	return likeEscaper.Replace(s)
 }
 
 type CreateDeviceInput struct {
@@ -28,11 +83,30 @@ type CreateDeviceInput struct {
 	State domain.DeviceState
 }
 
+// UpdateDeviceInput carries the fields to change; nil fields are left untouched.
+// When ExpectedVersion is set the update only happens if the device is still at that version.
 type UpdateDeviceInput struct {
-	ID    string
-	Name  string
-	Brand string
-	State domain.DeviceState
+	ID              string
+	Name            *string
+	Brand           *string
+	State           *domain.DeviceState
+	ExpectedVersion *int64
+}
+
+type TransitionDeviceInput struct {
+	ID              string
+	Action          domain.DeviceAction
+	ExpectedVersion *int64
+}
+
+type DeleteDeviceInput struct {
+	ID              string
+	ExpectedVersion *int64
+}
+
+type RestoreDeviceInput struct {
+	ID              string
+	ExpectedVersion *int64
 }
 
 type UpdateDeviceOutput struct {
@@ -41,17 +115,61 @@ type UpdateDeviceOutput struct {
 	Device        DeviceOutput
 }
 
+type ListDevicesInput struct {
+	Brand       string
+	State       domain.DeviceState
+	Name        string
+	CreatedFrom time.Time
+	CreatedTo   time.Time
+	SortBy      string
+	Order       string
+	Limit       int
+	Offset      int
+	Cursor      string
+
+	IncludeDeleted bool
+}
+
+// ExportDevicesInput holds the same filters as ListDevicesInput, without the pagination
+type ExportDevicesInput struct {
+	Brand          string
+	State          domain.DeviceState
+	Name           string
+	CreatedFrom    time.Time
+	CreatedTo      time.Time
+	SortBy         string
+	Order          string
+	IncludeDeleted bool
+}
+
+type ListDevicesOutput struct {
+	Devices    []DeviceOutput
+	Total      *int64 // nil when paginating with a cursor
+	Limit      int
+	Offset     int
+	NextCursor string
+}
+
 type DeviceOutput struct {
 	ID        string
 	Name      string
 	Brand     string
 	State     domain.DeviceState
 	CreatedAt time.Time
+	Version   int64
+	DeletedAt time.Time
 }
 
-func (s *DeviceService) CreateDevice(ctx context.Context, input CreateDeviceInput) (string, error) {
+func (s *DeviceService) CreateDevice(ctx context.Context, input CreateDeviceInput) (_ string, err error) {
+
+	ctx, span := startSpan(ctx, "DeviceService.CreateDevice")
+	defer func() { endSpan(span, err) }()
 
-	device, err := domain.NewDevice(uuid.New().String(), input.Name, input.Brand, input.State, time.Now())
+	if err := s.Authorize(ctx, domain.PermissionDevicesCreate); err != nil {
+		return "", err
+	}
+
+	device, err := newDevice(input.Name, input.Brand, input.State, time.Now())
 	if err != nil {
 		return "", err
 	}
@@ -63,19 +181,46 @@ func (s *DeviceService) CreateDevice(ctx context.Context, input CreateDeviceInpu
 	return id, nil
 }
 
-func (s *DeviceService) UpdateDevice(ctx context.Context, input UpdateDeviceInput) (*UpdateDeviceOutput, error) {
+// newDevice builds a device to create. It cannot start in use: only a checkout puts a device in use,
+// recording who holds it.
+func newDevice(name, brand string, state domain.DeviceState, createdAt time.Time) (*domain.Device, error) {
+
+	device, err := domain.NewDevice(uuid.New().String(), name, brand, state, createdAt)
+	if err != nil {
+		return nil, err
+	}
+	if device.State == domain.DeviceInUse {
+		return nil, domain.ErrStateNeedsAssignment
+	}
+
+	return device, nil
+}
+
+func (s *DeviceService) UpdateDevice(ctx context.Context, input UpdateDeviceInput) (_ *UpdateDeviceOutput, err error) {
+
+	ctx, span := startSpan(ctx, "DeviceService.UpdateDevice")
+	defer func() { endSpan(span, err) }()
 
 	// • Creation time cannot be updated: UpdateDeviceInput does not offer createdAt field be changed
 
+	if err := authorize(ctx, s.policy, updatePermissions(input)...); err != nil {
+		return nil, err
+	}
+
 	// getting device by id to check state
 	device, err := s.repo.GetDeviceById(ctx, input.ID)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, ErrDeviceNotFound
 		}
+		return nil, err
 	}
 
-	if device.State != input.State && !input.State.IsValid() {
+	if input.ExpectedVersion != nil && *input.ExpectedVersion != device.Version {
+		return nil, domain.ErrVersionMismatch
+	}
+
+	if input.State != nil && device.State != *input.State && !input.State.IsValid() {
 		return nil, domain.ErrInvalidState
 	}
 
@@ -87,57 +232,118 @@ func (s *DeviceService) UpdateDevice(ctx context.Context, input UpdateDeviceInpu
 	// • Name and brand properties cannot be updated if the device is in use.
 	if device.State == domain.DeviceInUse {
 
-		// Only State can change
-		if input.State != device.State {
-			device.State = input.State
+		// Only a check-in takes the device out of use, SetState refuses any other state
+		if input.State != nil && *input.State != device.State {
+			if err := device.SetState(*input.State); err != nil {
+				return nil, err
+			}
 			output.UpdatedFields = append(output.UpdatedFields, "state")
 		}
 
-		if input.Brand != device.Brand {
+		if input.Brand != nil && *input.Brand != device.Brand {
 			output.IgnoredFields = append(output.IgnoredFields, "brand")
 		}
 
-		if input.Name != device.Name {
+		if input.Name != nil && *input.Name != device.Name {
 			output.IgnoredFields = append(output.IgnoredFields, "name")
 		}
 
 	} else {
-		if input.Name != device.Name {
-			device.Name = input.Name
+		if input.Name != nil && *input.Name != device.Name {
+			device.Name = *input.Name
 			output.UpdatedFields = append(output.UpdatedFields, "name")
 		}
 
-		if input.Brand != device.Brand {
-			device.Brand = input.Brand
+		if input.Brand != nil && *input.Brand != device.Brand {
+			device.Brand = *input.Brand
 			output.UpdatedFields = append(output.UpdatedFields, "brand")
 		}
 
-		if input.State != device.State {
-			device.State = input.State
+		if input.State != nil && *input.State != device.State {
+			if err := device.SetState(*input.State); err != nil {
+				return nil, err
+			}
 			output.UpdatedFields = append(output.UpdatedFields, "state")
 		}
 	}
 
-	err = s.repo.UpdateDevice(ctx, device)
-	if err != nil {
+	if err := device.Validate(); err != nil {
 		return nil, err
 	}
 
+	// nothing changed, the version is kept and no history or event is written
+	if len(output.UpdatedFields) > 0 {
+		if err := s.repo.UpdateDevice(ctx, device); err != nil {
+			return nil, err
+		}
+	}
+
 	output.Device = DeviceOutput{
 		ID:        device.ID,
 		Name:      device.Name,
 		Brand:     device.Brand,
 		State:     device.State,
 		CreatedAt: device.CreatedAt,
+		Version:   device.Version,
 	}
 
 	return output, nil
 }
 
-func (s *DeviceService) DeleteDevice(ctx context.Context, id string) error {
+// TransitionDevice moves the device through the state machine by naming the action instead of the target state
+func (s *DeviceService) TransitionDevice(ctx context.Context, input TransitionDeviceInput) (_ *DeviceOutput, err error) {
+
+	ctx, span := startSpan(ctx, "DeviceService.TransitionDevice")
+	defer func() { endSpan(span, err) }()
+
+	if err := s.Authorize(ctx, domain.PermissionDevicesChangeState); err != nil {
+		return nil, err
+	}
+
+	if !input.Action.IsValid() {
+		return nil, domain.ErrInvalidAction
+	}
+
+	// check-out and return open and close an assignment, they are applied by AssignmentService.CheckOut and CheckIn
+	if input.Action.ChangesAssignment() {
+		return nil, domain.ErrStateNeedsAssignment
+	}
+
+	device, err := s.repo.GetDeviceById(ctx, input.ID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrDeviceNotFound
+		}
+		return nil, err
+	}
+
+	if input.ExpectedVersion != nil && *input.ExpectedVersion != device.Version {
+		return nil, domain.ErrVersionMismatch
+	}
+
+	if err := device.Apply(input.Action); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.UpdateDevice(ctx, device); err != nil {
+		return nil, err
+	}
+
+	output := mapDomainToServiceDevice(*device)
+	return &output, nil
+}
+
+func (s *DeviceService) DeleteDevice(ctx context.Context, input DeleteDeviceInput) (err error) {
+
+	ctx, span := startSpan(ctx, "DeviceService.DeleteDevice")
+	defer func() { endSpan(span, err) }()
+
+	if err := s.Authorize(ctx, domain.PermissionDevicesDelete); err != nil {
+		return err
+	}
 
 	// getting device by id to check state
-	device, err := s.repo.GetDeviceById(ctx, id)
+	device, err := s.repo.GetDeviceById(ctx, input.ID)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return ErrDeviceNotFound
@@ -145,15 +351,78 @@ func (s *DeviceService) DeleteDevice(ctx context.Context, id string) error {
 		return err
 	}
 
+	if input.ExpectedVersion != nil && *input.ExpectedVersion != device.Version {
+		return domain.ErrVersionMismatch
+	}
+
 	if device.State == domain.DeviceInUse {
 		return domain.ErrDeleteDeviceInUse
 
 	}
 
-	return s.repo.DeleteDevice(ctx, id)
+	// the version guards against the device being put in use between the read and the delete
+	return s.repo.DeleteDevice(ctx, device.ID, device.Version)
+}
+
+// RestoreDevice brings back a soft deleted device
+func (s *DeviceService) RestoreDevice(ctx context.Context, input RestoreDeviceInput) (_ *DeviceOutput, err error) {
+
+	ctx, span := startSpan(ctx, "DeviceService.RestoreDevice")
+	defer func() { endSpan(span, err) }()
+
+	if err := s.Authorize(ctx, domain.PermissionDevicesRestore); err != nil {
+		return nil, err
+	}
+
+	device, err := s.repo.GetDeviceByIdIncludingDeleted(ctx, input.ID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrDeviceNotFound
+		}
+		return nil, err
+	}
+
+	if input.ExpectedVersion != nil && *input.ExpectedVersion != device.Version {
+		return nil, domain.ErrVersionMismatch
+	}
+
+	if !device.IsDeleted() {
+		return nil, domain.ErrDeviceNotDeleted
+	}
+
+	if err := s.repo.RestoreDevice(ctx, device); err != nil {
+		return nil, err
+	}
+
+	output := mapDomainToServiceDevice(*device)
+	return &output, nil
 }
 
-func (s *DeviceService) GetDeviceById(ctx context.Context, id string) (*DeviceOutput, error) {
+// PurgeDeletedDevices permanently removes the devices that have been soft deleted for longer than retention
+func (s *DeviceService) PurgeDeletedDevices(ctx context.Context, retention time.Duration) (_ int64, err error) {
+
+	ctx, span := startSpan(ctx, "DeviceService.PurgeDeletedDevices")
+	defer func() { endSpan(span, err) }()
+
+	if err := s.Authorize(ctx, domain.PermissionDevicesDelete); err != nil {
+		return 0, err
+	}
+
+	if retention < 0 {
+		return 0, ErrInvalidRetention
+	}
+
+	return s.repo.PurgeDeletedDevices(ctx, time.Now().Add(-retention))
+}
+
+func (s *DeviceService) GetDeviceById(ctx context.Context, id string) (_ *DeviceOutput, err error) {
+
+	ctx, span := startSpan(ctx, "DeviceService.GetDeviceById")
+	defer func() { endSpan(span, err) }()
+
+	if err := s.Authorize(ctx, domain.PermissionDevicesRead); err != nil {
+		return nil, err
+	}
 
 	device, err := s.repo.GetDeviceById(ctx, id)
 	if err != nil {
@@ -169,31 +438,140 @@ func (s *DeviceService) GetDeviceById(ctx context.Context, id string) (*DeviceOu
 		Brand:     device.Brand,
 		State:     device.State,
 		CreatedAt: device.CreatedAt,
+		Version:   device.Version,
 	}, nil
 }
 
-func (s *DeviceService) GetDevices(ctx context.Context) ([]DeviceOutput, error) {
-	devList, err := s.repo.GetDevices(ctx)
-	if err != nil {
-		return []DeviceOutput{}, err
+// ExportDevices calls fn for every device matching the filters, streaming them from the repository.
+// The filters are validated before fn is first called.
+func (s *DeviceService) ExportDevices(ctx context.Context, input ExportDevicesInput, fn func(DeviceOutput) error) (err error) {
+
+	ctx, span := startSpan(ctx, "DeviceService.ExportDevices")
+	defer func() { endSpan(span, err) }()
+
+	if err := authorize(ctx, s.policy, listPermissions(input.IncludeDeleted)...); err != nil {
+		return err
 	}
-	return processDeviceList(devList)
+
+	filter := domain.DeviceFilter{
+		Brand:          input.Brand,
+		State:          input.State,
+		Name:           escapeLike(input.Name),
+		CreatedFrom:    input.CreatedFrom,
+		CreatedTo:      input.CreatedTo,
+		SortBy:         input.SortBy,
+		Order:          domain.SortOrder(input.Order),
+		IncludeDeleted: input.IncludeDeleted,
+	}
+	if err := filter.Normalize(); err != nil {
+		return err
+	}
+
+	return s.repo.StreamDevices(ctx, filter, func(device domain.Device) error {
+		return fn(mapDomainToServiceDevice(device))
+	})
 }
 
-func (s *DeviceService) GetDevicesByBrand(ctx context.Context, brand string) ([]DeviceOutput, error) {
-	devList, err := s.repo.GetDevicesByBrand(ctx, brand)
+func (s *DeviceService) GetDevices(ctx context.Context, input ListDevicesInput) (_ *ListDevicesOutput, err error) {
+
+	ctx, span := startSpan(ctx, "DeviceService.GetDevices")
+	defer func() { endSpan(span, err) }()
+
+	if err := authorize(ctx, s.policy, listPermissions(input.IncludeDeleted)...); err != nil {
+		return nil, err
+	}
+
+	filter := domain.DeviceFilter{
+		Brand:       input.Brand,
+		State:       input.State,
+		Name:        escapeLike(input.Name),
+		CreatedFrom: input.CreatedFrom,
+		CreatedTo:   input.CreatedTo,
+		SortBy:      input.SortBy,
+		Order:       domain.SortOrder(input.Order),
+		Limit:       input.Limit,
+		Offset:      input.Offset,
+
+		IncludeDeleted: input.IncludeDeleted,
+	}
+	if err := filter.Normalize(); err != nil {
+		return nil, err
+	}
+
+	if input.Cursor != "" {
+		return s.getDevicesAfterCursor(ctx, filter, input.Cursor)
+	}
+
+	devList, err := s.repo.GetDevices(ctx, filter)
 	if err != nil {
-		return []DeviceOutput{}, err
+		return nil, err
+	}
+
+	total, err := s.repo.CountDevices(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	devices, err := processDeviceList(devList)
+	if err != nil {
+		return nil, err
+	}
+
+	output := &ListDevicesOutput{
+		Devices: devices,
+		Total:   &total,
+		Limit:   filter.Limit,
+		Offset:  filter.Offset,
+	}
+
+	// a page sorted by creation time can be continued with a cursor instead of an offset
+	if filter.SortBy == "created_at" && len(devList) > 0 && int64(filter.Offset+len(devList)) < total {
+		output.NextCursor = domain.CursorAfter(devList[len(devList)-1]).Encode()
 	}
-	return processDeviceList(devList)
+
+	return output, nil
 }
 
-func (s *DeviceService) GetDevicesByState(ctx context.Context, state string) ([]DeviceOutput, error) {
-	devList, err := s.repo.GetDevicesByState(ctx, state)
+// getDevicesAfterCursor lists the page that follows the cursor using keyset pagination.
+// The total is not counted because it would cost a full scan on every page.
+func (s *DeviceService) getDevicesAfterCursor(ctx context.Context, filter domain.DeviceFilter, token string) (*ListDevicesOutput, error) {
+
+	if filter.SortBy != "created_at" {
+		return nil, domain.ErrCursorSort
+	}
+	if filter.Offset != 0 {
+		return nil, domain.ErrCursorWithOffset
+	}
+
+	cursor, err := domain.DecodeDeviceCursor(token)
+	if err != nil {
+		return nil, err
+	}
+
+	// fetching one extra device tells whether there is a next page
+	pageFilter := filter
+	pageFilter.Limit = filter.Limit + 1
+
+	devList, err := s.repo.GetDevicesAfterCursor(ctx, pageFilter, cursor)
 	if err != nil {
-		return []DeviceOutput{}, err
+		return nil, err
+	}
+
+	output := &ListDevicesOutput{
+		Limit: filter.Limit,
 	}
-	return processDeviceList(devList)
+
+	if len(devList) > filter.Limit {
+		devList = devList[:filter.Limit]
+		output.NextCursor = domain.CursorAfter(devList[len(devList)-1]).Encode()
+	}
+
+	output.Devices, err = processDeviceList(devList)
+	if err != nil {
+		return nil, err
+	}
+
+	return output, nil
 }
 
 func processDeviceList(devList []domain.Device) ([]DeviceOutput, error) {
@@ -218,5 +596,7 @@ func mapDomainToServiceDevice(device domain.Device) DeviceOutput {
 		Brand:     device.Brand,
 		State:     device.State,
 		CreatedAt: device.CreatedAt,
+		Version:   device.Version,
+		DeletedAt: device.DeletedAt,
 	}
 }
@@ -14,35 +14,56 @@ import (
 
 // --- Mock repository (manual, lightweight) ---
 type mockDeviceRepo struct {
-	CreateDeviceFunc      func(ctx context.Context, device *domain.Device) (string, error)
-	UpdateDeviceFunc      func(ctx context.Context, device *domain.Device) error
-	DeleteDeviceFunc      func(ctx context.Context, id string) error
-	GetDeviceByIdFunc     func(ctx context.Context, id string) (*domain.Device, error)
-	GetDevicesFunc        func(ctx context.Context) ([]domain.Device, error)
-	GetDevicesByBrandFunc func(ctx context.Context, brand string) ([]domain.Device, error)
-	GetDevicesByStateFunc func(ctx context.Context, state string) ([]domain.Device, error)
+	CreateDeviceFunc                  func(ctx context.Context, device *domain.Device) (string, error)
+	CreateDevicesFunc                 func(ctx context.Context, devices []*domain.Device) error
+	UpdateDeviceFunc                  func(ctx context.Context, device *domain.Device) error
+	DeleteDeviceFunc                  func(ctx context.Context, id string, version int64) error
+	RestoreDeviceFunc                 func(ctx context.Context, device *domain.Device) error
+	PurgeDeletedDevicesFunc           func(ctx context.Context, deletedBefore time.Time) (int64, error)
+	GetDeviceByIdFunc                 func(ctx context.Context, id string) (*domain.Device, error)
+	GetDeviceByIdIncludingDeletedFunc func(ctx context.Context, id string) (*domain.Device, error)
+	GetDevicesFunc                    func(ctx context.Context, filter domain.DeviceFilter) ([]domain.Device, error)
+	CountDevicesFunc                  func(ctx context.Context, filter domain.DeviceFilter) (int64, error)
+	StreamDevicesFunc                 func(ctx context.Context, filter domain.DeviceFilter, fn func(domain.Device) error) error
+	GetDevicesAfterCursorFunc         func(ctx context.Context, filter domain.DeviceFilter, cursor domain.DeviceCursor) ([]domain.Device, error)
 }
 
 func (m *mockDeviceRepo) CreateDevice(ctx context.Context, device *domain.Device) (string, error) {
 	return m.CreateDeviceFunc(ctx, device)
 }
+func (m *mockDeviceRepo) CreateDevices(ctx context.Context, devices []*domain.Device) error {
+	return m.CreateDevicesFunc(ctx, devices)
+}
 func (m *mockDeviceRepo) UpdateDevice(ctx context.Context, device *domain.Device) error {
 	return m.UpdateDeviceFunc(ctx, device)
 }
-func (m *mockDeviceRepo) DeleteDevice(ctx context.Context, id string) error {
-	return m.DeleteDeviceFunc(ctx, id)
+func (m *mockDeviceRepo) DeleteDevice(ctx context.Context, id string, version int64) error {
+	return m.DeleteDeviceFunc(ctx, id, version)
+}
+func (m *mockDeviceRepo) RestoreDevice(ctx context.Context, device *domain.Device) error {
+	return m.RestoreDeviceFunc(ctx, device)
+}
+func (m *mockDeviceRepo) PurgeDeletedDevices(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	return m.PurgeDeletedDevicesFunc(ctx, deletedBefore)
 }
 func (m *mockDeviceRepo) GetDeviceById(ctx context.Context, id string) (*domain.Device, error) {
 	return m.GetDeviceByIdFunc(ctx, id)
 }
-func (m *mockDeviceRepo) GetDevices(ctx context.Context) ([]domain.Device, error) {
-	return m.GetDevicesFunc(ctx)
+func (m *mockDeviceRepo) GetDeviceByIdIncludingDeleted(ctx context.Context, id string) (*domain.Device, error) {
+	return m.GetDeviceByIdIncludingDeletedFunc(ctx, id)
+}
+func (m *mockDeviceRepo) GetDevices(ctx context.Context, filter domain.DeviceFilter) ([]domain.Device, error) {
+	return m.GetDevicesFunc(ctx, filter)
 }
-func (m *mockDeviceRepo) GetDevicesByBrand(ctx context.Context, brand string) ([]domain.Device, error) {
-	return m.GetDevicesByBrandFunc(ctx, brand)
+func (m *mockDeviceRepo) CountDevices(ctx context.Context, filter domain.DeviceFilter) (int64, error) {
+	return m.CountDevicesFunc(ctx, filter)
 }
-func (m *mockDeviceRepo) GetDevicesByState(ctx context.Context, state string) ([]domain.Device, error) {
-	return m.GetDevicesByStateFunc(ctx, state)
+func (m *mockDeviceRepo) GetDevicesAfterCursor(ctx context.Context, filter domain.DeviceFilter, cursor domain.DeviceCursor) ([]domain.Device, error) {
+	return m.GetDevicesAfterCursorFunc(ctx, filter, cursor)
+}
+
+func (m *mockDeviceRepo) StreamDevices(ctx context.Context, filter domain.DeviceFilter, fn func(domain.Device) error) error {
+	return m.StreamDevicesFunc(ctx, filter, fn)
 }
 
 // --- helpers ---
@@ -53,7 +74,11 @@ func makeDeviceWithState(state domain.DeviceState) *domain.Device {
 }
 
 func deviceServiceWithMock(m *mockDeviceRepo) *DeviceService {
-	return NewDeviceService(m)
+	return NewDeviceService(m, nil)
+}
+
+func ptr[T any](v T) *T {
+	return &v
 }
 
 // -------------------- Tests --------------------
@@ -94,7 +119,33 @@ func TestCreateDevice_SuccessAndRepoError(t *testing.T) {
 	require.ErrorIs(t, err, mockErr)
 }
 
-func TestUpdateDevice_InUse_AllowsOnlyName(t *testing.T) {
+func TestCreateDevice_InUse(t *testing.T) {
+	ctx := context.Background()
+
+	created := false
+	mock := &mockDeviceRepo{
+		CreateDeviceFunc: func(ctx context.Context, device *domain.Device) (string, error) {
+			created = true
+			return device.ID, nil
+		},
+	}
+	svc := deviceServiceWithMock(mock)
+
+	// only a checkout puts a device in use, so it says who holds it
+	_, err := svc.CreateDevice(ctx, CreateDeviceInput{Name: "Device A", Brand: "Brand A", State: domain.DeviceInUse})
+	require.ErrorIs(t, err, domain.ErrStateNeedsAssignment)
+	require.False(t, created)
+
+	// the batch creates go through the same rule
+	results, err := svc.BatchDevices(ctx, []BatchOperation{
+		{Type: BatchCreate, Create: CreateDeviceInput{Name: "Device A", Brand: "Brand A", State: domain.DeviceInUse}},
+	}, false)
+	require.NoError(t, err)
+	require.ErrorIs(t, results[0].Err, domain.ErrStateNeedsAssignment)
+	require.False(t, created)
+}
+
+func TestUpdateDevice_InUse_IgnoresNameAndBrand(t *testing.T) {
 	ctx := context.Background()
 
 	orig := makeDeviceWithState(domain.DeviceInUse)
@@ -105,12 +156,11 @@ func TestUpdateDevice_InUse_AllowsOnlyName(t *testing.T) {
 
 	mock := &mockDeviceRepo{
 		GetDeviceByIdFunc: func(ctx context.Context, id string) (*domain.Device, error) {
-			return orig, nil
+			copy := *orig
+			return &copy, nil
 		},
 		UpdateDeviceFunc: func(ctx context.Context, device *domain.Device) error {
-			// capture what was saved
-			copy := *device
-			updatedSaved = &copy
+			updatedSaved = device
 			return nil
 		},
 	}
@@ -119,27 +169,24 @@ func TestUpdateDevice_InUse_AllowsOnlyName(t *testing.T) {
 
 	out, err := svc.UpdateDevice(ctx, UpdateDeviceInput{
 		ID:    orig.ID,
-		Name:  "NewName",
-		Brand: "NewBrand",             // should be ignored
-		State: domain.DeviceAvailable, // should be ignored
+		Name:  ptr("NewName"),  // should be ignored
+		Brand: ptr("NewBrand"), // should be ignored
 	})
 	require.NoError(t, err)
 	require.NotNil(t, out)
-	// only name changed
-	require.Equal(t, "NewName", out.Device.Name)
+	require.Equal(t, "Old", out.Device.Name)
 	require.Equal(t, "OrigBrand", out.Device.Brand)
 	require.Equal(t, domain.DeviceInUse, out.Device.State)
-
-	// persisted values
-	require.NotNil(t, updatedSaved)
-	require.Equal(t, "NewName", updatedSaved.Name)
-	require.Equal(t, "OrigBrand", updatedSaved.Brand)
-	require.Equal(t, domain.DeviceInUse, updatedSaved.State)
-
-	// ensure ignored fields list contains brand and state
 	require.Contains(t, out.IgnoredFields, "brand")
-	require.Contains(t, out.IgnoredFields, "state")
-	require.Contains(t, out.UpdatedFields, "name")
+	require.Contains(t, out.IgnoredFields, "name")
+	require.Empty(t, out.UpdatedFields)
+	require.Nil(t, updatedSaved)
+
+	// only a check-in takes the device out of use, so its open assignment gets closed
+	_, err = svc.UpdateDevice(ctx, UpdateDeviceInput{ID: orig.ID, State: ptr(domain.DeviceAvailable)})
+	require.ErrorIs(t, err, domain.ErrStateNeedsAssignment)
+	require.ErrorIs(t, err, domain.ErrIllegalTransition)
+	require.Nil(t, updatedSaved)
 }
 
 func TestUpdateDevice_NotInUse_AllFieldsChange(t *testing.T) {
@@ -166,16 +213,16 @@ func TestUpdateDevice_NotInUse_AllFieldsChange(t *testing.T) {
 
 	out, err := svc.UpdateDevice(ctx, UpdateDeviceInput{
 		ID:    orig.ID,
-		Name:  "NewName",
-		Brand: "NewBrand",
-		State: domain.DeviceInUse,
+		Name:  ptr("NewName"),
+		Brand: ptr("NewBrand"),
+		State: ptr(domain.DeviceInactive),
 	})
 	require.NoError(t, err)
 	require.NotNil(t, out)
 
 	require.Equal(t, "NewName", out.Device.Name)
 	require.Equal(t, "NewBrand", out.Device.Brand)
-	require.Equal(t, domain.DeviceInUse, out.Device.State)
+	require.Equal(t, domain.DeviceInactive, out.Device.State)
 
 	// persisted equals output
 	require.NotNil(t, updatedSaved)
@@ -184,6 +231,43 @@ func TestUpdateDevice_NotInUse_AllFieldsChange(t *testing.T) {
 	require.Equal(t, out.Device.State, updatedSaved.State)
 }
 
+func TestUpdateDevice_PartialInputKeepsOtherFields(t *testing.T) {
+	ctx := context.Background()
+
+	orig := makeDeviceWithState(domain.DeviceAvailable)
+	orig.Name = "Old"
+	orig.Brand = "OrigBrand"
+
+	mock := &mockDeviceRepo{
+		GetDeviceByIdFunc: func(ctx context.Context, id string) (*domain.Device, error) {
+			return orig, nil
+		},
+		UpdateDeviceFunc: func(ctx context.Context, device *domain.Device) error {
+			return nil
+		},
+	}
+
+	svc := deviceServiceWithMock(mock)
+
+	out, err := svc.UpdateDevice(ctx, UpdateDeviceInput{
+		ID:    orig.ID,
+		Brand: ptr("NewBrand"),
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{"brand"}, out.UpdatedFields)
+	require.Empty(t, out.IgnoredFields)
+	require.Equal(t, "Old", out.Device.Name)
+	require.Equal(t, "NewBrand", out.Device.Brand)
+	require.Equal(t, domain.DeviceAvailable, out.Device.State)
+
+	// blanking a required field is rejected
+	_, err = svc.UpdateDevice(ctx, UpdateDeviceInput{
+		ID:   orig.ID,
+		Name: ptr(""),
+	})
+	require.ErrorIs(t, err, domain.ErrNameIsRequired)
+}
+
 func TestUpdateDevice_InvalidStateProvided(t *testing.T) {
 	ctx := context.Background()
 
@@ -199,9 +283,9 @@ func TestUpdateDevice_InvalidStateProvided(t *testing.T) {
 
 	_, err := svc.UpdateDevice(ctx, UpdateDeviceInput{
 		ID:    orig.ID,
-		Name:  "X",
-		Brand: "B",
-		State: domain.DeviceState("invalid-state"),
+		Name:  ptr("X"),
+		Brand: ptr("B"),
+		State: ptr(domain.DeviceState("invalid-state")),
 	})
 	require.ErrorIs(t, err, domain.ErrInvalidState)
 }
@@ -232,13 +316,163 @@ func TestUpdateDevice_RepoGetErrorAndRepoUpdateError(t *testing.T) {
 	svcUpdateErr := deviceServiceWithMock(mockUpdateErr)
 	_, err = svcUpdateErr.UpdateDevice(ctx, UpdateDeviceInput{
 		ID:    orig.ID,
-		Name:  "New",
-		Brand: "B",
-		State: domain.DeviceInUse,
+		Name:  ptr("New"),
+		Brand: ptr("B"),
+		State: ptr(domain.DeviceInactive),
 	})
 	require.Error(t, err)
 }
 
+func TestUpdateDevice_ExpectedVersion(t *testing.T) {
+	ctx := context.Background()
+
+	orig := makeDeviceWithState(domain.DeviceAvailable)
+	orig.Version = 3
+
+	var savedVersion int64
+	mock := &mockDeviceRepo{
+		GetDeviceByIdFunc: func(ctx context.Context, id string) (*domain.Device, error) {
+			copy := *orig
+			return &copy, nil
+		},
+		UpdateDeviceFunc: func(ctx context.Context, device *domain.Device) error {
+			savedVersion = device.Version
+			device.Version++
+			return nil
+		},
+	}
+	svc := deviceServiceWithMock(mock)
+
+	// stale If-Match is refused before touching the repository
+	_, err := svc.UpdateDevice(ctx, UpdateDeviceInput{ID: orig.ID, Name: ptr("New"), ExpectedVersion: ptr(int64(2))})
+	require.ErrorIs(t, err, domain.ErrVersionMismatch)
+	require.Zero(t, savedVersion)
+
+	// matching version is passed down for the compare-and-swap and the new version is returned
+	out, err := svc.UpdateDevice(ctx, UpdateDeviceInput{ID: orig.ID, Name: ptr("New"), ExpectedVersion: ptr(int64(3))})
+	require.NoError(t, err)
+	require.Equal(t, int64(3), savedVersion)
+	require.Equal(t, int64(4), out.Device.Version)
+
+	// a concurrent write detected by the repository surfaces as a version mismatch too
+	mock.UpdateDeviceFunc = func(ctx context.Context, device *domain.Device) error {
+		return domain.ErrVersionMismatch
+	}
+	_, err = svc.UpdateDevice(ctx, UpdateDeviceInput{ID: orig.ID, Name: ptr("Other")})
+	require.ErrorIs(t, err, domain.ErrVersionMismatch)
+}
+
+func TestUpdateDevice_NothingChanged(t *testing.T) {
+	ctx := context.Background()
+
+	orig := makeDeviceWithState(domain.DeviceAvailable)
+	mock := &mockDeviceRepo{
+		GetDeviceByIdFunc: func(ctx context.Context, id string) (*domain.Device, error) {
+			copy := *orig
+			return &copy, nil
+		},
+		UpdateDeviceFunc: func(ctx context.Context, device *domain.Device) error {
+			t.Fatal("an update without changes must not reach the repository")
+			return nil
+		},
+	}
+	svc := deviceServiceWithMock(mock)
+
+	out, err := svc.UpdateDevice(ctx, UpdateDeviceInput{ID: orig.ID})
+	require.NoError(t, err)
+	require.Empty(t, out.UpdatedFields)
+
+	out, err = svc.UpdateDevice(ctx, UpdateDeviceInput{ID: orig.ID, Name: ptr(orig.Name), State: ptr(orig.State)})
+	require.NoError(t, err)
+	require.Empty(t, out.UpdatedFields)
+	require.Equal(t, orig.Version, out.Device.Version)
+}
+
+func TestDeleteDevice_InUseAndExpectedVersion(t *testing.T) {
+	ctx := context.Background()
+
+	orig := makeDeviceWithState(domain.DeviceAvailable)
+	orig.Version = 5
+
+	var deletedVersion int64
+	mock := &mockDeviceRepo{
+		GetDeviceByIdFunc: func(ctx context.Context, id string) (*domain.Device, error) {
+			return orig, nil
+		},
+		DeleteDeviceFunc: func(ctx context.Context, id string, version int64) error {
+			deletedVersion = version
+			return nil
+		},
+	}
+	svc := deviceServiceWithMock(mock)
+
+	err := svc.DeleteDevice(ctx, DeleteDeviceInput{ID: orig.ID, ExpectedVersion: ptr(int64(4))})
+	require.ErrorIs(t, err, domain.ErrVersionMismatch)
+
+	err = svc.DeleteDevice(ctx, DeleteDeviceInput{ID: orig.ID})
+	require.NoError(t, err)
+	require.Equal(t, int64(5), deletedVersion)
+
+	orig.State = domain.DeviceInUse
+	err = svc.DeleteDevice(ctx, DeleteDeviceInput{ID: orig.ID})
+	require.ErrorIs(t, err, domain.ErrDeleteDeviceInUse)
+}
+
+func TestUpdateDevice_IllegalTransition(t *testing.T) {
+	ctx := context.Background()
+
+	orig := makeDeviceWithState(domain.DeviceInactive)
+	mock := &mockDeviceRepo{
+		GetDeviceByIdFunc: func(ctx context.Context, id string) (*domain.Device, error) {
+			return orig, nil
+		},
+	}
+	svc := deviceServiceWithMock(mock)
+
+	// an inactive device must be activated before it can be used
+	_, err := svc.UpdateDevice(ctx, UpdateDeviceInput{ID: orig.ID, State: ptr(domain.DeviceInUse)})
+	require.ErrorIs(t, err, domain.ErrIllegalTransition)
+}
+
+func TestTransitionDevice(t *testing.T) {
+	ctx := context.Background()
+
+	orig := makeDeviceWithState(domain.DeviceAvailable)
+
+	var saved *domain.Device
+	mock := &mockDeviceRepo{
+		GetDeviceByIdFunc: func(ctx context.Context, id string) (*domain.Device, error) {
+			copy := *orig
+			return &copy, nil
+		},
+		UpdateDeviceFunc: func(ctx context.Context, device *domain.Device) error {
+			saved = device
+			device.Version++
+			return nil
+		},
+	}
+	svc := deviceServiceWithMock(mock)
+
+	out, err := svc.TransitionDevice(ctx, TransitionDeviceInput{ID: orig.ID, Action: domain.ActionDeactivate})
+	require.NoError(t, err)
+	require.Equal(t, domain.DeviceInactive, out.State)
+	require.Equal(t, domain.DeviceInactive, saved.State)
+	require.Equal(t, int64(2), out.Version)
+
+	saved = nil
+	_, err = svc.TransitionDevice(ctx, TransitionDeviceInput{ID: orig.ID, Action: domain.ActionActivate})
+	require.ErrorIs(t, err, domain.ErrIllegalTransition)
+	require.Nil(t, saved)
+
+	// check-out and return go through the assignments
+	_, err = svc.TransitionDevice(ctx, TransitionDeviceInput{ID: orig.ID, Action: domain.ActionCheckOut})
+	require.ErrorIs(t, err, domain.ErrStateNeedsAssignment)
+	require.Nil(t, saved)
+
+	_, err = svc.TransitionDevice(ctx, TransitionDeviceInput{ID: orig.ID, Action: "fly"})
+	require.ErrorIs(t, err, domain.ErrInvalidAction)
+}
+
 func TestGetDeviceById_SuccessAndNotFound(t *testing.T) {
 	ctx := context.Background()
 
@@ -262,8 +496,7 @@ func TestGetDeviceById_SuccessAndNotFound(t *testing.T) {
 	}
 	svcNotFound := deviceServiceWithMock(mockNotFound)
 	_, err = svcNotFound.GetDeviceById(ctx, "x")
-	require.Error(t, err)
-	require.Contains(t, err.Error(), "device id x not found")
+	require.ErrorIs(t, err, ErrDeviceNotFound)
 }
 
 func TestGetDevices_ListAndEmpty(t *testing.T) {
@@ -275,57 +508,323 @@ func TestGetDevices_ListAndEmpty(t *testing.T) {
 	dev2.Name = "B"
 
 	mock := &mockDeviceRepo{
-		GetDevicesFunc: func(ctx context.Context) ([]domain.Device, error) {
+		GetDevicesFunc: func(ctx context.Context, filter domain.DeviceFilter) ([]domain.Device, error) {
 			return []domain.Device{dev1, dev2}, nil
 		},
+		CountDevicesFunc: func(ctx context.Context, filter domain.DeviceFilter) (int64, error) {
+			return 2, nil
+		},
 	}
 	svc := deviceServiceWithMock(mock)
 
-	list, err := svc.GetDevices(ctx)
+	out, err := svc.GetDevices(ctx, ListDevicesInput{})
 	require.NoError(t, err)
-	require.Len(t, list, 2)
-	require.Equal(t, "A", list[0].Name)
-	require.Equal(t, "B", list[1].Name)
+	require.Len(t, out.Devices, 2)
+	require.Equal(t, "A", out.Devices[0].Name)
+	require.Equal(t, "B", out.Devices[1].Name)
+	require.Equal(t, int64(2), *out.Total)
+	require.Empty(t, out.NextCursor)
+	require.Equal(t, domain.DefaultPageLimit, out.Limit)
 
 	// empty list
 	mockEmpty := &mockDeviceRepo{
-		GetDevicesFunc: func(ctx context.Context) ([]domain.Device, error) {
+		GetDevicesFunc: func(ctx context.Context, filter domain.DeviceFilter) ([]domain.Device, error) {
 			return []domain.Device{}, nil
 		},
+		CountDevicesFunc: func(ctx context.Context, filter domain.DeviceFilter) (int64, error) {
+			return 0, nil
+		},
 	}
 	svcEmpty := deviceServiceWithMock(mockEmpty)
-	list2, err := svcEmpty.GetDevices(ctx)
+	out2, err := svcEmpty.GetDevices(ctx, ListDevicesInput{})
 	require.NoError(t, err)
-	require.Len(t, list2, 0)
+	require.Len(t, out2.Devices, 0)
+	require.Equal(t, int64(0), *out2.Total)
 }
 
-func TestGetDevicesByBrandAndState(t *testing.T) {
+func TestGetDevices_CombinedFilter(t *testing.T) {
 	ctx := context.Background()
 
 	dev := *makeDeviceWithState(domain.DeviceAvailable)
 	dev.Brand = "Acme"
+	from := time.Now().Add(-time.Hour)
 
-	mockByBrand := &mockDeviceRepo{
-		GetDevicesByBrandFunc: func(ctx context.Context, brand string) ([]domain.Device, error) {
-			require.Equal(t, "Acme", brand)
+	var received domain.DeviceFilter
+	mock := &mockDeviceRepo{
+		GetDevicesFunc: func(ctx context.Context, filter domain.DeviceFilter) ([]domain.Device, error) {
+			received = filter
 			return []domain.Device{dev}, nil
 		},
+		CountDevicesFunc: func(ctx context.Context, filter domain.DeviceFilter) (int64, error) {
+			require.Equal(t, received, filter)
+			return 7, nil
+		},
 	}
-	svcBrand := deviceServiceWithMock(mockByBrand)
-	list, err := svcBrand.GetDevicesByBrand(ctx, "Acme")
+	svc := deviceServiceWithMock(mock)
+
+	out, err := svc.GetDevices(ctx, ListDevicesInput{
+		Brand:       "Acme",
+		State:       domain.DeviceAvailable,
+		Name:        "dev",
+		CreatedFrom: from,
+		SortBy:      "name",
+		Order:       "desc",
+		Limit:       1,
+		Offset:      3,
+	})
 	require.NoError(t, err)
-	require.Len(t, list, 1)
-	require.Equal(t, "Acme", list[0].Brand)
+	require.Len(t, out.Devices, 1)
+	require.Equal(t, int64(7), *out.Total)
+
+	require.Equal(t, "Acme", received.Brand)
+	require.Equal(t, domain.DeviceAvailable, received.State)
+	require.Equal(t, "dev", received.Name)
+	require.Equal(t, from, received.CreatedFrom)
+	require.Equal(t, "name", received.SortBy)
+	require.Equal(t, domain.SortDesc, received.Order)
+	require.Equal(t, 1, received.Limit)
+	require.Equal(t, 3, received.Offset)
+}
 
-	mockByState := &mockDeviceRepo{
-		GetDevicesByStateFunc: func(ctx context.Context, state string) ([]domain.Device, error) {
-			require.Equal(t, string(domain.DeviceAvailable), state)
-			return []domain.Device{dev}, nil
+func TestGetDevices_NameWildcardsAreEscaped(t *testing.T) {
+	ctx := context.Background()
+
+	var received domain.DeviceFilter
+	mock := &mockDeviceRepo{
+		GetDevicesFunc: func(ctx context.Context, filter domain.DeviceFilter) ([]domain.Device, error) {
+			received = filter
+			return nil, nil
+		},
+		CountDevicesFunc: func(ctx context.Context, filter domain.DeviceFilter) (int64, error) {
+			return 0, nil
 		},
 	}
-	svcState := deviceServiceWithMock(mockByState)
-	list2, err := svcState.GetDevicesByState(ctx, string(domain.DeviceAvailable))
+	svc := deviceServiceWithMock(mock)
+
+	_, err := svc.GetDevices(ctx, ListDevicesInput{Name: `50%_\`})
 	require.NoError(t, err)
-	require.Len(t, list2, 1)
-	require.Equal(t, domain.DeviceAvailable, list2[0].State)
+	require.Equal(t, `50\%\_\\`, received.Name)
+}
+
+func TestGetDevices_InvalidFilter(t *testing.T) {
+	ctx := context.Background()
+
+	svc := deviceServiceWithMock(&mockDeviceRepo{})
+
+	_, err := svc.GetDevices(ctx, ListDevicesInput{State: "broken"})
+	require.ErrorIs(t, err, domain.ErrInvalidState)
+
+	_, err = svc.GetDevices(ctx, ListDevicesInput{SortBy: "id"})
+	require.ErrorIs(t, err, domain.ErrInvalidSortField)
+
+	_, err = svc.GetDevices(ctx, ListDevicesInput{Order: "up"})
+	require.ErrorIs(t, err, domain.ErrInvalidSortOrder)
+
+	_, err = svc.GetDevices(ctx, ListDevicesInput{Limit: domain.MaxPageLimit + 1})
+	require.ErrorIs(t, err, domain.ErrInvalidLimit)
+}
+
+func TestGetDevices_NextCursor(t *testing.T) {
+	ctx := context.Background()
+
+	dev1 := *makeDeviceWithState(domain.DeviceAvailable)
+	dev2 := *makeDeviceWithState(domain.DeviceAvailable)
+	dev3 := *makeDeviceWithState(domain.DeviceAvailable)
+
+	mock := &mockDeviceRepo{
+		GetDevicesFunc: func(ctx context.Context, filter domain.DeviceFilter) ([]domain.Device, error) {
+			return []domain.Device{dev1, dev2}, nil
+		},
+		CountDevicesFunc: func(ctx context.Context, filter domain.DeviceFilter) (int64, error) {
+			return 5, nil
+		},
+		GetDevicesAfterCursorFunc: func(ctx context.Context, filter domain.DeviceFilter, cursor domain.DeviceCursor) ([]domain.Device, error) {
+			// one extra device is requested to detect the next page
+			require.Equal(t, 3, filter.Limit)
+			require.Equal(t, dev2.ID, cursor.ID)
+			return []domain.Device{dev3}, nil
+		},
+	}
+	svc := deviceServiceWithMock(mock)
+
+	// the first page is offset based and hands out the cursor of its last device
+	out, err := svc.GetDevices(ctx, ListDevicesInput{Limit: 2})
+	require.NoError(t, err)
+	require.NotEmpty(t, out.NextCursor)
+
+	// following the cursor returns the last page, without total and without next cursor
+	out2, err := svc.GetDevices(ctx, ListDevicesInput{Limit: 2, Cursor: out.NextCursor})
+	require.NoError(t, err)
+	require.Len(t, out2.Devices, 1)
+	require.Equal(t, dev3.ID, out2.Devices[0].ID)
+	require.Nil(t, out2.Total)
+	require.Empty(t, out2.NextCursor)
+}
+
+func TestGetDevices_InvalidCursor(t *testing.T) {
+	ctx := context.Background()
+
+	svc := deviceServiceWithMock(&mockDeviceRepo{})
+	token := domain.CursorAfter(*makeDeviceWithState(domain.DeviceAvailable)).Encode()
+
+	_, err := svc.GetDevices(ctx, ListDevicesInput{Cursor: "not-a-cursor"})
+	require.ErrorIs(t, err, domain.ErrInvalidCursor)
+
+	_, err = svc.GetDevices(ctx, ListDevicesInput{Cursor: token, SortBy: "name"})
+	require.ErrorIs(t, err, domain.ErrCursorSort)
+
+	_, err = svc.GetDevices(ctx, ListDevicesInput{Cursor: token, Offset: 10})
+	require.ErrorIs(t, err, domain.ErrCursorWithOffset)
+}
+
+func TestRestoreDevice(t *testing.T) {
+	ctx := context.Background()
+
+	orig := makeDeviceWithState(domain.DeviceAvailable)
+	orig.DeletedAt = time.Now().Add(-time.Hour)
+	orig.Version = 2
+
+	var restored *domain.Device
+	mock := &mockDeviceRepo{
+		GetDeviceByIdIncludingDeletedFunc: func(ctx context.Context, id string) (*domain.Device, error) {
+			copy := *orig
+			return &copy, nil
+		},
+		RestoreDeviceFunc: func(ctx context.Context, device *domain.Device) error {
+			restored = device
+			device.DeletedAt = time.Time{}
+			device.Version++
+			return nil
+		},
+	}
+	svc := deviceServiceWithMock(mock)
+
+	_, err := svc.RestoreDevice(ctx, RestoreDeviceInput{ID: orig.ID, ExpectedVersion: ptr(int64(1))})
+	require.ErrorIs(t, err, domain.ErrVersionMismatch)
+	require.Nil(t, restored)
+
+	out, err := svc.RestoreDevice(ctx, RestoreDeviceInput{ID: orig.ID, ExpectedVersion: ptr(int64(2))})
+	require.NoError(t, err)
+	require.Equal(t, int64(3), out.Version)
+	require.True(t, out.DeletedAt.IsZero())
+	require.Equal(t, orig.ID, restored.ID)
+
+	// restoring a device that was not deleted is a conflict
+	orig.DeletedAt = time.Time{}
+	_, err = svc.RestoreDevice(ctx, RestoreDeviceInput{ID: orig.ID})
+	require.ErrorIs(t, err, domain.ErrDeviceNotDeleted)
+
+	mock.GetDeviceByIdIncludingDeletedFunc = func(ctx context.Context, id string) (*domain.Device, error) {
+		return nil, sql.ErrNoRows
+	}
+	_, err = svc.RestoreDevice(ctx, RestoreDeviceInput{ID: orig.ID})
+	require.ErrorIs(t, err, ErrDeviceNotFound)
+}
+
+func TestPurgeDeletedDevices(t *testing.T) {
+	ctx := context.Background()
+
+	var gotBefore time.Time
+	mock := &mockDeviceRepo{
+		PurgeDeletedDevicesFunc: func(ctx context.Context, deletedBefore time.Time) (int64, error) {
+			gotBefore = deletedBefore
+			return 3, nil
+		},
+	}
+	svc := deviceServiceWithMock(mock)
+
+	n, err := svc.PurgeDeletedDevices(ctx, 24*time.Hour)
+	require.NoError(t, err)
+	require.Equal(t, int64(3), n)
+	require.WithinDuration(t, time.Now().Add(-24*time.Hour), gotBefore, time.Minute)
+
+	_, err = svc.PurgeDeletedDevices(ctx, -time.Hour)
+	require.ErrorIs(t, err, ErrInvalidRetention)
+}
+
+func TestExportDevices(t *testing.T) {
+	ctx := context.Background()
+
+	var gotFilter domain.DeviceFilter
+	mock := &mockDeviceRepo{
+		StreamDevicesFunc: func(ctx context.Context, filter domain.DeviceFilter, fn func(domain.Device) error) error {
+			gotFilter = filter
+			for _, state := range []domain.DeviceState{domain.DeviceAvailable, domain.DeviceInactive} {
+				if err := fn(*makeDeviceWithState(state)); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	}
+	svc := deviceServiceWithMock(mock)
+
+	var states []domain.DeviceState
+	err := svc.ExportDevices(ctx, ExportDevicesInput{Brand: "Brand", Order: "desc"}, func(d DeviceOutput) error {
+		states = append(states, d.State)
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, []domain.DeviceState{domain.DeviceAvailable, domain.DeviceInactive}, states)
+	require.Equal(t, "Brand", gotFilter.Brand)
+	require.Equal(t, "created_at", gotFilter.SortBy)
+	require.Equal(t, domain.SortDesc, gotFilter.Order)
+
+	// invalid filters are rejected before anything is streamed
+	err = svc.ExportDevices(ctx, ExportDevicesInput{SortBy: "version"}, func(d DeviceOutput) error {
+		t.Fatal("nothing should be exported")
+		return nil
+	})
+	require.ErrorIs(t, err, domain.ErrInvalidSortField)
+}
+
+func TestDeviceService_Policy(t *testing.T) {
+	policy, err := domain.NewPolicy(map[domain.Role][]domain.Permission{
+		domain.RoleViewer:   {domain.PermissionDevicesRead},
+		domain.RoleOperator: {domain.PermissionDevicesRead, domain.PermissionDevicesChangeState},
+	})
+	require.NoError(t, err)
+
+	device := makeDeviceWithState(domain.DeviceAvailable)
+	svc := NewDeviceService(&mockDeviceRepo{
+		GetDeviceByIdFunc: func(ctx context.Context, id string) (*domain.Device, error) {
+			copy := *device
+			return &copy, nil
+		},
+		UpdateDeviceFunc: func(ctx context.Context, device *domain.Device) error { return nil },
+		DeleteDeviceFunc: func(ctx context.Context, id string, version int64) error { return nil },
+	}, policy)
+
+	as := func(role domain.Role) context.Context {
+		return domain.WithPrincipal(context.Background(), &domain.Principal{Subject: "user-1", Roles: []domain.Role{role}})
+	}
+
+	// viewers only read
+	_, err = svc.GetDeviceById(as(domain.RoleViewer), device.ID)
+	require.NoError(t, err)
+	_, err = svc.TransitionDevice(as(domain.RoleViewer), TransitionDeviceInput{ID: device.ID, Action: domain.ActionDeactivate})
+	require.ErrorIs(t, err, domain.ErrPermissionDenied)
+	_, err = svc.GetDevices(as(domain.RoleViewer), ListDevicesInput{IncludeDeleted: true})
+	require.ErrorIs(t, err, domain.ErrPermissionDenied)
+	_, err = svc.UpdateDevice(as(domain.RoleViewer), UpdateDeviceInput{ID: device.ID})
+	require.ErrorIs(t, err, domain.ErrPermissionDenied)
+
+	// operators change the state, but not the name, and cannot delete
+	_, err = svc.UpdateDevice(as(domain.RoleOperator), UpdateDeviceInput{ID: device.ID, State: ptr(domain.DeviceInactive)})
+	require.NoError(t, err)
+	_, err = svc.UpdateDevice(as(domain.RoleOperator), UpdateDeviceInput{ID: device.ID, Name: ptr("Renamed")})
+	require.ErrorIs(t, err, domain.ErrPermissionDenied)
+	require.ErrorIs(t, svc.DeleteDevice(as(domain.RoleOperator), DeleteDeviceInput{ID: device.ID}), domain.ErrPermissionDenied)
+
+	// batches check every operation
+	results, err := svc.BatchDevices(as(domain.RoleOperator), []BatchOperation{
+		{Type: BatchUpdate, Update: UpdateDeviceInput{ID: device.ID, State: ptr(domain.DeviceInactive)}},
+		{Type: BatchDelete, Delete: DeleteDeviceInput{ID: device.ID}},
+	}, false)
+	require.NoError(t, err)
+	require.NoError(t, results[0].Err)
+	require.ErrorIs(t, results[1].Err, domain.ErrPermissionDenied)
+
+	// calls without a principal come from inside the process
+	require.NoError(t, svc.DeleteDevice(context.Background(), DeleteDeviceInput{ID: device.ID}))
 }
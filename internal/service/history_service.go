@@ -0,0 +1,95 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/raulsilva-tech/devices-api/internal/domain"
+)
+
+type HistoryService struct {
+	devices domain.DeviceRepository
+	history domain.DeviceHistoryRepository
+}
+
+func NewHistoryService(devices domain.DeviceRepository, history domain.DeviceHistoryRepository) *HistoryService {
+	return &HistoryService{
+		devices: devices,
+		history: history,
+	}
+}
+
+type FieldChangeOutput struct {
+	Old string
+	New string
+}
+
+type DeviceChangeOutput struct {
+	ID         int64
+	DeviceID   string
+	Type       string
+	Version    int64
+	Fields     map[string]FieldChangeOutput
+	RequestID  string
+	Actor      string
+	OnBehalfOf string
+	OccurredAt time.Time
+}
+
+// GetDeviceHistory lists the changes of a device, most recent first.
+// The history of a deleted device can still be read.
+func (s *HistoryService) GetDeviceHistory(ctx context.Context, deviceID string, limit, offset int) ([]DeviceChangeOutput, error) {
+
+	if limit == 0 {
+		limit = domain.DefaultPageLimit
+	}
+	if limit < 0 || limit > domain.MaxPageLimit {
+		return nil, domain.ErrInvalidLimit
+	}
+	if offset < 0 {
+		return nil, domain.ErrInvalidOffset
+	}
+
+	changes, err := s.history.GetDeviceHistory(ctx, deviceID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+
+	// an empty first page tells apart an unknown device from a device without history
+	if len(changes) == 0 && offset == 0 {
+		if _, err := s.devices.GetDeviceById(ctx, deviceID); err != nil {
+			if err == sql.ErrNoRows {
+				return nil, ErrDeviceNotFound
+			}
+			return nil, err
+		}
+	}
+
+	resultList := make([]DeviceChangeOutput, len(changes))
+	for i, change := range changes {
+		resultList[i] = mapDomainToServiceDeviceChange(change)
+	}
+
+	return resultList, nil
+}
+
+func mapDomainToServiceDeviceChange(c domain.DeviceChange) DeviceChangeOutput {
+
+	fields := make(map[string]FieldChangeOutput, len(c.Fields))
+	for name, f := range c.Fields {
+		fields[name] = FieldChangeOutput{Old: f.Old, New: f.New}
+	}
+
+	return DeviceChangeOutput{
+		ID:         c.ID,
+		DeviceID:   c.DeviceID,
+		Type:       string(c.Type),
+		Version:    c.Version,
+		Fields:     fields,
+		RequestID:  c.RequestID,
+		Actor:      c.Actor,
+		OnBehalfOf: c.OnBehalfOf,
+		OccurredAt: c.OccurredAt,
+	}
+}
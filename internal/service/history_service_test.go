@@ -0,0 +1,74 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/raulsilva-tech/devices-api/internal/domain"
+	"github.com/stretchr/testify/require"
+)
+
+type mockHistoryRepo struct {
+	GetDeviceHistoryFunc func(ctx context.Context, deviceID string, limit, offset int) ([]domain.DeviceChange, error)
+}
+
+func (m *mockHistoryRepo) GetDeviceHistory(ctx context.Context, deviceID string, limit, offset int) ([]domain.DeviceChange, error) {
+	return m.GetDeviceHistoryFunc(ctx, deviceID, limit, offset)
+}
+
+func TestGetDeviceHistory(t *testing.T) {
+	ctx := context.Background()
+
+	var gotLimit int
+	history := &mockHistoryRepo{
+		GetDeviceHistoryFunc: func(ctx context.Context, deviceID string, limit, offset int) ([]domain.DeviceChange, error) {
+			gotLimit = limit
+			return []domain.DeviceChange{{
+				ID:       2,
+				DeviceID: deviceID,
+				Type:     domain.ChangeDeleted,
+				Version:  1,
+				Fields:   map[string]domain.FieldChange{"state": {Old: "available"}},
+				Actor:    "jane",
+			}}, nil
+		},
+	}
+	// the device was deleted, the history is still there
+	devices := &mockDeviceRepo{
+		GetDeviceByIdFunc: func(ctx context.Context, id string) (*domain.Device, error) {
+			return nil, sql.ErrNoRows
+		},
+	}
+	svc := NewHistoryService(devices, history)
+
+	out, err := svc.GetDeviceHistory(ctx, "dev-1", 0, 0)
+	require.NoError(t, err)
+	require.Equal(t, domain.DefaultPageLimit, gotLimit)
+	require.Len(t, out, 1)
+	require.Equal(t, "deleted", out[0].Type)
+	require.Equal(t, FieldChangeOutput{Old: "available"}, out[0].Fields["state"])
+	require.Equal(t, "jane", out[0].Actor)
+
+	_, err = svc.GetDeviceHistory(ctx, "dev-1", domain.MaxPageLimit+1, 0)
+	require.ErrorIs(t, err, domain.ErrInvalidLimit)
+}
+
+func TestGetDeviceHistory_DeviceNotFound(t *testing.T) {
+	ctx := context.Background()
+
+	history := &mockHistoryRepo{
+		GetDeviceHistoryFunc: func(ctx context.Context, deviceID string, limit, offset int) ([]domain.DeviceChange, error) {
+			return nil, nil
+		},
+	}
+	devices := &mockDeviceRepo{
+		GetDeviceByIdFunc: func(ctx context.Context, id string) (*domain.Device, error) {
+			return nil, sql.ErrNoRows
+		},
+	}
+	svc := NewHistoryService(devices, history)
+
+	_, err := svc.GetDeviceHistory(ctx, "missing", 0, 0)
+	require.ErrorIs(t, err, ErrDeviceNotFound)
+}
@@ -0,0 +1,91 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/raulsilva-tech/devices-api/internal/domain"
+)
+
+// IdempotencyService remembers the responses of the mutations sent with an Idempotency-Key
+type IdempotencyService struct {
+	repo domain.IdempotencyRepository
+	ttl  time.Duration
+}
+
+// NewIdempotencyService creates the service. A key can be used again for another request ttl after its first use.
+func NewIdempotencyService(repo domain.IdempotencyRepository, ttl time.Duration) *IdempotencyService {
+	return &IdempotencyService{
+		repo: repo,
+		ttl:  ttl,
+	}
+}
+
+// Begin claims the key of the client for the request with the fingerprint. It returns nil when the
+// request has to run, and the response to replay when it already ran. A key used for another request
+// fails with ErrIdempotencyKeyReused, and one whose request is still running with ErrIdempotencyKeyInProgress.
+func (s *IdempotencyService) Begin(ctx context.Context, client, key, fingerprint string) (*domain.IdempotentResponse, error) {
+
+	record, err := domain.NewIdempotencyRecord(client, key, fingerprint, s.ttl)
+	if err != nil {
+		return nil, err
+	}
+
+	// the second attempt follows the removal of an expired record
+	for range 2 {
+
+		created, err := s.repo.CreateIdempotencyRecord(ctx, record)
+		if err != nil {
+			return nil, err
+		}
+		if created {
+			return nil, nil
+		}
+
+		existing, err := s.repo.GetIdempotencyRecord(ctx, client, key)
+		if err == sql.ErrNoRows {
+			// released meanwhile
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if existing.IsExpired(record.CreatedAt) {
+			if err := s.repo.DeleteExpiredIdempotencyRecord(ctx, client, key, record.CreatedAt); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if existing.Fingerprint != fingerprint {
+			return nil, domain.ErrIdempotencyKeyReused
+		}
+		if existing.Response == nil {
+			return nil, domain.ErrIdempotencyKeyInProgress
+		}
+
+		return existing.Response, nil
+	}
+
+	return nil, domain.ErrIdempotencyKeyInProgress
+}
+
+// Complete stores the response of the request claimed by Begin, to be replayed to its retries
+func (s *IdempotencyService) Complete(ctx context.Context, client, key string, response domain.IdempotentResponse) error {
+	return s.repo.CompleteIdempotencyRecord(ctx, &domain.IdempotencyRecord{
+		Client:   client,
+		Key:      key,
+		Response: &response,
+	})
+}
+
+// Release frees the key claimed by Begin without storing a response, so a retry runs the request again
+func (s *IdempotencyService) Release(ctx context.Context, client, key string) error {
+	return s.repo.DeleteIdempotencyRecord(ctx, client, key)
+}
+
+// PurgeExpired deletes the expired keys of every tenant and returns how many were removed
+func (s *IdempotencyService) PurgeExpired(ctx context.Context) (int64, error) {
+	return s.repo.PurgeExpiredIdempotencyRecords(ctx, time.Now().UTC())
+}
@@ -0,0 +1,133 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/raulsilva-tech/devices-api/internal/domain"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryIdempotencyRepo keeps the records of a single tenant in a map
+type memoryIdempotencyRepo struct {
+	records map[string]*domain.IdempotencyRecord
+}
+
+func newMemoryIdempotencyRepo() *memoryIdempotencyRepo {
+	return &memoryIdempotencyRepo{records: map[string]*domain.IdempotencyRecord{}}
+}
+
+func (m *memoryIdempotencyRepo) CreateIdempotencyRecord(ctx context.Context, record *domain.IdempotencyRecord) (bool, error) {
+	if _, ok := m.records[record.Client+"|"+record.Key]; ok {
+		return false, nil
+	}
+	stored := *record
+	m.records[record.Client+"|"+record.Key] = &stored
+	return true, nil
+}
+func (m *memoryIdempotencyRepo) GetIdempotencyRecord(ctx context.Context, client, key string) (*domain.IdempotencyRecord, error) {
+	record, ok := m.records[client+"|"+key]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	stored := *record
+	return &stored, nil
+}
+func (m *memoryIdempotencyRepo) CompleteIdempotencyRecord(ctx context.Context, record *domain.IdempotencyRecord) error {
+	if stored, ok := m.records[record.Client+"|"+record.Key]; ok {
+		stored.Response = record.Response
+	}
+	return nil
+}
+func (m *memoryIdempotencyRepo) DeleteIdempotencyRecord(ctx context.Context, client, key string) error {
+	delete(m.records, client+"|"+key)
+	return nil
+}
+func (m *memoryIdempotencyRepo) DeleteExpiredIdempotencyRecord(ctx context.Context, client, key string, now time.Time) error {
+	if record, ok := m.records[client+"|"+key]; ok && record.IsExpired(now) {
+		delete(m.records, client+"|"+key)
+	}
+	return nil
+}
+func (m *memoryIdempotencyRepo) PurgeExpiredIdempotencyRecords(ctx context.Context, now time.Time) (int64, error) {
+	var purged int64
+	for id, record := range m.records {
+		if record.IsExpired(now) {
+			delete(m.records, id)
+			purged++
+		}
+	}
+	return purged, nil
+}
+
+func TestIdempotencyService(t *testing.T) {
+
+	ctx := context.Background()
+	svc := NewIdempotencyService(newMemoryIdempotencyRepo(), time.Hour)
+
+	replay, err := svc.Begin(ctx, "sub:ci", "key-1", "create")
+	require.NoError(t, err)
+	require.Nil(t, replay)
+
+	// a retry while the request runs
+	_, err = svc.Begin(ctx, "sub:ci", "key-1", "create")
+	require.ErrorIs(t, err, domain.ErrIdempotencyKeyInProgress)
+
+	response := domain.IdempotentResponse{StatusCode: http.StatusCreated, Body: []byte(`{"id":"1"}`)}
+	require.NoError(t, svc.Complete(ctx, "sub:ci", "key-1", response))
+
+	replay, err = svc.Begin(ctx, "sub:ci", "key-1", "create")
+	require.NoError(t, err)
+	require.Equal(t, &response, replay)
+
+	// the key used for another request
+	_, err = svc.Begin(ctx, "sub:ci", "key-1", "update")
+	require.ErrorIs(t, err, domain.ErrIdempotencyKeyReused)
+
+	// another client may use the same key
+	replay, err = svc.Begin(ctx, "sub:other", "key-1", "update")
+	require.NoError(t, err)
+	require.Nil(t, replay)
+
+	_, err = svc.Begin(ctx, "sub:ci", "", "create")
+	require.ErrorIs(t, err, domain.ErrInvalidIdempotencyKey)
+}
+
+func TestIdempotencyService_Release(t *testing.T) {
+
+	ctx := context.Background()
+	svc := NewIdempotencyService(newMemoryIdempotencyRepo(), time.Hour)
+
+	_, err := svc.Begin(ctx, "sub:ci", "key-1", "create")
+	require.NoError(t, err)
+	require.NoError(t, svc.Release(ctx, "sub:ci", "key-1"))
+
+	// the retry runs the request again
+	replay, err := svc.Begin(ctx, "sub:ci", "key-1", "create")
+	require.NoError(t, err)
+	require.Nil(t, replay)
+}
+
+func TestIdempotencyService_ExpiredKey(t *testing.T) {
+
+	ctx := context.Background()
+	repo := newMemoryIdempotencyRepo()
+	svc := NewIdempotencyService(repo, 0)
+
+	_, err := svc.Begin(ctx, "sub:ci", "key-1", "create")
+	require.NoError(t, err)
+	require.NoError(t, svc.Complete(ctx, "sub:ci", "key-1", domain.IdempotentResponse{StatusCode: http.StatusCreated}))
+
+	// an expired key is free for another request
+	replay, err := svc.Begin(ctx, "sub:ci", "key-1", "update")
+	require.NoError(t, err)
+	require.Nil(t, replay)
+	require.Equal(t, "update", repo.records["sub:ci|key-1"].Fingerprint)
+
+	purged, err := svc.PurgeExpired(ctx)
+	require.NoError(t, err)
+	require.EqualValues(t, 1, purged)
+}
@@ -0,0 +1,116 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/raulsilva-tech/devices-api/internal/domain"
+)
+
+// MaxImportRows bounds the number of devices a single import can create
+const MaxImportRows = 1000
+
+var (
+	ErrEmptyImport       = errors.New("import has no rows")
+	ErrTooManyImportRows = errors.New("import has too many rows")
+)
+
+type ImportDeviceRow struct {
+	Line  int // position of the row in the source file, used in the report
+	Name  string
+	Brand string
+	State domain.DeviceState
+}
+
+type ImportDevicesInput struct {
+	Rows []ImportDeviceRow
+	// Atomic creates every device or none of them; otherwise valid rows are created and invalid ones skipped
+	Atomic bool
+}
+
+type ImportRowResult struct {
+	Line int
+	ID   string // set when the device was created
+	Err  error  // set when the row was rejected
+}
+
+type ImportDevicesOutput struct {
+	Atomic  bool
+	Created int
+	Failed  int
+	Rows    []ImportRowResult
+}
+
+// ImportDevices validates every row as a new device and creates the valid ones. In atomic mode
+// a single invalid row rejects the whole import and nothing is created.
+func (s *DeviceService) ImportDevices(ctx context.Context, input ImportDevicesInput) (_ *ImportDevicesOutput, err error) {
+
+	ctx, span := startSpan(ctx, "DeviceService.ImportDevices")
+	defer func() { endSpan(span, err) }()
+
+	if err := s.Authorize(ctx, domain.PermissionDevicesCreate); err != nil {
+		return nil, err
+	}
+
+	if len(input.Rows) == 0 {
+		return nil, ErrEmptyImport
+	}
+	if len(input.Rows) > MaxImportRows {
+		return nil, ErrTooManyImportRows
+	}
+
+	output := &ImportDevicesOutput{
+		Atomic: input.Atomic,
+		Rows:   make([]ImportRowResult, len(input.Rows)),
+	}
+
+	devices := make([]*domain.Device, len(input.Rows))
+	now := time.Now()
+
+	for i, row := range input.Rows {
+		output.Rows[i].Line = row.Line
+
+		device, err := newDevice(row.Name, row.Brand, row.State, now)
+		if err != nil {
+			output.Rows[i].Err = err
+			output.Failed++
+			continue
+		}
+		devices[i] = device
+	}
+
+	if input.Atomic {
+		if output.Failed > 0 {
+			return output, nil
+		}
+
+		if err := s.repo.CreateDevices(ctx, devices); err != nil {
+			return nil, err
+		}
+
+		for i, device := range devices {
+			output.Rows[i].ID = device.ID
+		}
+		output.Created = len(devices)
+
+		return output, nil
+	}
+
+	for i, device := range devices {
+		if device == nil {
+			continue
+		}
+
+		if _, err := s.repo.CreateDevice(ctx, device); err != nil {
+			output.Rows[i].Err = err
+			output.Failed++
+			continue
+		}
+
+		output.Rows[i].ID = device.ID
+		output.Created++
+	}
+
+	return output, nil
+}
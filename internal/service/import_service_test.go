@@ -0,0 +1,112 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/raulsilva-tech/devices-api/internal/domain"
+	"github.com/stretchr/testify/require"
+)
+
+func importRows() []ImportDeviceRow {
+	return []ImportDeviceRow{
+		{Line: 2, Name: "iPhone 13", Brand: "Apple", State: domain.DeviceAvailable},
+		{Line: 3, Name: "Galaxy S21", Brand: "", State: domain.DeviceAvailable},
+		{Line: 4, Name: "Pixel 8", Brand: "Google", State: "broken"},
+		{Line: 5, Name: "Moto G", Brand: "Motorola", State: domain.DeviceInactive},
+	}
+}
+
+func TestImportDevices_Atomic(t *testing.T) {
+	ctx := context.Background()
+
+	var stored []*domain.Device
+	mock := &mockDeviceRepo{
+		CreateDevicesFunc: func(ctx context.Context, devices []*domain.Device) error {
+			stored = devices
+			return nil
+		},
+	}
+	svc := deviceServiceWithMock(mock)
+
+	// one invalid row rejects everything
+	out, err := svc.ImportDevices(ctx, ImportDevicesInput{Rows: importRows(), Atomic: true})
+	require.NoError(t, err)
+	require.Equal(t, 0, out.Created)
+	require.Equal(t, 2, out.Failed)
+	require.Nil(t, stored)
+	require.ErrorIs(t, out.Rows[1].Err, domain.ErrBrandIsRequired)
+	require.ErrorIs(t, out.Rows[2].Err, domain.ErrInvalidState)
+	require.Empty(t, out.Rows[0].ID)
+
+	rows := importRows()
+	rows = []ImportDeviceRow{rows[0], rows[3]}
+	out, err = svc.ImportDevices(ctx, ImportDevicesInput{Rows: rows, Atomic: true})
+	require.NoError(t, err)
+	require.Equal(t, 2, out.Created)
+	require.Len(t, stored, 2)
+	require.Equal(t, stored[0].ID, out.Rows[0].ID)
+	require.Equal(t, 5, out.Rows[1].Line)
+
+	mock.CreateDevicesFunc = func(ctx context.Context, devices []*domain.Device) error {
+		return errors.New("db down")
+	}
+	_, err = svc.ImportDevices(ctx, ImportDevicesInput{Rows: rows, Atomic: true})
+	require.Error(t, err)
+}
+
+func TestImportDevices_BestEffort(t *testing.T) {
+	ctx := context.Background()
+
+	var created []string
+	mock := &mockDeviceRepo{
+		CreateDeviceFunc: func(ctx context.Context, device *domain.Device) (string, error) {
+			if device.Name == "Moto G" {
+				return "", errors.New("db down")
+			}
+			created = append(created, device.ID)
+			return device.ID, nil
+		},
+	}
+	svc := deviceServiceWithMock(mock)
+
+	out, err := svc.ImportDevices(ctx, ImportDevicesInput{Rows: importRows()})
+	require.NoError(t, err)
+	require.Equal(t, 1, out.Created)
+	require.Equal(t, 3, out.Failed)
+	require.Equal(t, created, []string{out.Rows[0].ID})
+	require.ErrorIs(t, out.Rows[1].Err, domain.ErrBrandIsRequired)
+	require.Error(t, out.Rows[3].Err)
+}
+
+func TestImportDevices_InUse(t *testing.T) {
+	ctx := context.Background()
+
+	svc := deviceServiceWithMock(&mockDeviceRepo{
+		CreateDeviceFunc: func(ctx context.Context, device *domain.Device) (string, error) {
+			return device.ID, nil
+		},
+	})
+
+	rows := []ImportDeviceRow{
+		{Line: 2, Name: "iPhone 13", Brand: "Apple", State: domain.DeviceInUse},
+		{Line: 3, Name: "Moto G", Brand: "Motorola", State: domain.DeviceAvailable},
+	}
+	out, err := svc.ImportDevices(ctx, ImportDevicesInput{Rows: rows})
+	require.NoError(t, err)
+	require.Equal(t, 1, out.Created)
+	require.ErrorIs(t, out.Rows[0].Err, domain.ErrStateNeedsAssignment)
+	require.Empty(t, out.Rows[0].ID)
+	require.NotEmpty(t, out.Rows[1].ID)
+}
+
+func TestImportDevices_Limits(t *testing.T) {
+	svc := deviceServiceWithMock(&mockDeviceRepo{})
+
+	_, err := svc.ImportDevices(context.Background(), ImportDevicesInput{})
+	require.ErrorIs(t, err, ErrEmptyImport)
+
+	_, err = svc.ImportDevices(context.Background(), ImportDevicesInput{Rows: make([]ImportDeviceRow, MaxImportRows+1)})
+	require.ErrorIs(t, err, ErrTooManyImportRows)
+}
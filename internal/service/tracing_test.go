@@ -0,0 +1,38 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/raulsilva-tech/devices-api/internal/domain"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestDeviceService_Spans(t *testing.T) {
+
+	exporter := tracetest.NewInMemoryExporter()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)))
+
+	svc := NewDeviceService(&mockDeviceRepo{
+		GetDeviceByIdFunc: func(ctx context.Context, id string) (*domain.Device, error) {
+			return nil, sql.ErrNoRows
+		},
+	}, nil)
+
+	ctx, parent := otel.Tracer("test").Start(context.Background(), "request")
+	_, err := svc.GetDeviceById(ctx, "42")
+	require.ErrorIs(t, err, ErrDeviceNotFound)
+	parent.End()
+
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 2)
+	require.Equal(t, "DeviceService.GetDeviceById", spans[0].Name)
+	require.Equal(t, parent.SpanContext().SpanID(), spans[0].Parent.SpanID())
+	require.Equal(t, codes.Error, spans[0].Status.Code)
+	require.Equal(t, ErrDeviceNotFound.Error(), spans[0].Status.Description)
+}
@@ -0,0 +1,147 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/raulsilva-tech/devices-api/internal/domain"
+)
+
+var ErrWebhookNotFound = errors.New("webhook not found")
+
+type WebhookService struct {
+	repo domain.WebhookRepository
+}
+
+func NewWebhookService(repo domain.WebhookRepository) *WebhookService {
+	return &WebhookService{
+		repo: repo,
+	}
+}
+
+type CreateWebhookInput struct {
+	URL    string
+	Events []domain.EventType // empty subscribes to every event
+}
+
+type WebhookOutput struct {
+	ID        string
+	URL       string
+	Secret    string // only returned on creation
+	Events    []domain.EventType
+	CreatedAt time.Time
+}
+
+type WebhookDeliveryOutput struct {
+	ID             string
+	WebhookID      string
+	EventID        string
+	EventType      domain.EventType
+	Status         domain.DeliveryStatus
+	Attempts       int
+	LastStatusCode int
+	LastError      string
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+}
+
+// CreateWebhook registers the URL and returns the secret used to sign its deliveries
+func (s *WebhookService) CreateWebhook(ctx context.Context, input CreateWebhookInput) (*WebhookOutput, error) {
+
+	webhook, err := domain.NewWebhook(input.URL, input.Events)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.CreateWebhook(ctx, webhook); err != nil {
+		return nil, err
+	}
+
+	output := mapDomainToServiceWebhook(*webhook)
+	output.Secret = webhook.Secret
+
+	return &output, nil
+}
+
+func (s *WebhookService) GetWebhooks(ctx context.Context) ([]WebhookOutput, error) {
+
+	webhooks, err := s.repo.GetWebhooks(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	resultList := make([]WebhookOutput, len(webhooks))
+	for i, webhook := range webhooks {
+		resultList[i] = mapDomainToServiceWebhook(webhook)
+	}
+
+	return resultList, nil
+}
+
+// DeleteWebhook stops the deliveries to the webhook and drops their records
+func (s *WebhookService) DeleteWebhook(ctx context.Context, id string) error {
+
+	if err := s.repo.DeleteWebhook(ctx, id); err != nil {
+		if err == sql.ErrNoRows {
+			return ErrWebhookNotFound
+		}
+		return err
+	}
+
+	return nil
+}
+
+// GetDeliveries lists the deliveries of a webhook, most recent first
+func (s *WebhookService) GetDeliveries(ctx context.Context, webhookID string, limit, offset int) ([]WebhookDeliveryOutput, error) {
+
+	if limit == 0 {
+		limit = domain.DefaultPageLimit
+	}
+	if limit < 0 || limit > domain.MaxPageLimit {
+		return nil, domain.ErrInvalidLimit
+	}
+	if offset < 0 {
+		return nil, domain.ErrInvalidOffset
+	}
+
+	if _, err := s.repo.GetWebhookById(ctx, webhookID); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrWebhookNotFound
+		}
+		return nil, err
+	}
+
+	deliveries, err := s.repo.GetDeliveries(ctx, webhookID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+
+	resultList := make([]WebhookDeliveryOutput, len(deliveries))
+	for i, d := range deliveries {
+		resultList[i] = WebhookDeliveryOutput{
+			ID:             d.ID,
+			WebhookID:      d.WebhookID,
+			EventID:        d.EventID,
+			EventType:      d.EventType,
+			Status:         d.Status,
+			Attempts:       d.Attempts,
+			LastStatusCode: d.LastStatusCode,
+			LastError:      d.LastError,
+			CreatedAt:      d.CreatedAt,
+			UpdatedAt:      d.UpdatedAt,
+		}
+	}
+
+	return resultList, nil
+}
+
+func mapDomainToServiceWebhook(w domain.Webhook) WebhookOutput {
+	return WebhookOutput{
+		ID:        w.ID,
+		URL:       w.URL,
+		Events:    w.Events,
+		CreatedAt: w.CreatedAt,
+	}
+}
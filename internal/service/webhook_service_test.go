@@ -0,0 +1,112 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/raulsilva-tech/devices-api/internal/domain"
+	"github.com/stretchr/testify/require"
+)
+
+type mockWebhookRepo struct {
+	CreateWebhookFunc    func(ctx context.Context, webhook *domain.Webhook) error
+	DeleteWebhookFunc    func(ctx context.Context, id string) error
+	GetWebhookByIdFunc   func(ctx context.Context, id string) (*domain.Webhook, error)
+	GetWebhooksFunc      func(ctx context.Context) ([]domain.Webhook, error)
+	CreateDeliveryFunc   func(ctx context.Context, delivery *domain.WebhookDelivery) error
+	UpdateDeliveryFunc   func(ctx context.Context, delivery *domain.WebhookDelivery) error
+	GetDeliveriesFunc    func(ctx context.Context, webhookID string, limit, offset int) ([]domain.WebhookDelivery, error)
+	GetDueDeliveriesFunc func(ctx context.Context, now time.Time, limit int) ([]domain.DueDelivery, error)
+	ClaimDeliveryFunc    func(ctx context.Context, id string, now, until time.Time) (bool, error)
+}
+
+func (m *mockWebhookRepo) CreateWebhook(ctx context.Context, webhook *domain.Webhook) error {
+	return m.CreateWebhookFunc(ctx, webhook)
+}
+func (m *mockWebhookRepo) DeleteWebhook(ctx context.Context, id string) error {
+	return m.DeleteWebhookFunc(ctx, id)
+}
+func (m *mockWebhookRepo) GetWebhookById(ctx context.Context, id string) (*domain.Webhook, error) {
+	return m.GetWebhookByIdFunc(ctx, id)
+}
+func (m *mockWebhookRepo) GetWebhooks(ctx context.Context) ([]domain.Webhook, error) {
+	return m.GetWebhooksFunc(ctx)
+}
+func (m *mockWebhookRepo) CreateDelivery(ctx context.Context, delivery *domain.WebhookDelivery) error {
+	return m.CreateDeliveryFunc(ctx, delivery)
+}
+func (m *mockWebhookRepo) UpdateDelivery(ctx context.Context, delivery *domain.WebhookDelivery) error {
+	return m.UpdateDeliveryFunc(ctx, delivery)
+}
+func (m *mockWebhookRepo) GetDeliveries(ctx context.Context, webhookID string, limit, offset int) ([]domain.WebhookDelivery, error) {
+	return m.GetDeliveriesFunc(ctx, webhookID, limit, offset)
+}
+func (m *mockWebhookRepo) GetDueDeliveries(ctx context.Context, now time.Time, limit int) ([]domain.DueDelivery, error) {
+	return m.GetDueDeliveriesFunc(ctx, now, limit)
+}
+func (m *mockWebhookRepo) ClaimDelivery(ctx context.Context, id string, now, until time.Time) (bool, error) {
+	return m.ClaimDeliveryFunc(ctx, id, now, until)
+}
+
+func TestCreateWebhook(t *testing.T) {
+	ctx := context.Background()
+
+	var stored *domain.Webhook
+	svc := NewWebhookService(&mockWebhookRepo{
+		CreateWebhookFunc: func(ctx context.Context, webhook *domain.Webhook) error {
+			stored = webhook
+			return nil
+		},
+	})
+
+	output, err := svc.CreateWebhook(ctx, CreateWebhookInput{URL: "https://example.com/hook", Events: []domain.EventType{domain.EventDeviceCreated}})
+	require.NoError(t, err)
+	require.Equal(t, stored.ID, output.ID)
+	require.Equal(t, stored.Secret, output.Secret)
+	require.NotEmpty(t, output.Secret)
+
+	_, err = svc.CreateWebhook(ctx, CreateWebhookInput{URL: "not a url"})
+	require.ErrorIs(t, err, domain.ErrInvalidWebhookURL)
+
+	_, err = svc.CreateWebhook(ctx, CreateWebhookInput{URL: "https://example.com/hook", Events: []domain.EventType{"device.exploded"}})
+	require.ErrorIs(t, err, domain.ErrInvalidEventType)
+}
+
+func TestGetWebhooks_HidesSecrets(t *testing.T) {
+	webhook, err := domain.NewWebhook("https://example.com/hook", nil)
+	require.NoError(t, err)
+
+	svc := NewWebhookService(&mockWebhookRepo{
+		GetWebhooksFunc: func(ctx context.Context) ([]domain.Webhook, error) {
+			return []domain.Webhook{*webhook}, nil
+		},
+	})
+
+	list, err := svc.GetWebhooks(context.Background())
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+	require.Empty(t, list[0].Secret)
+}
+
+func TestWebhook_NotFound(t *testing.T) {
+	ctx := context.Background()
+
+	svc := NewWebhookService(&mockWebhookRepo{
+		DeleteWebhookFunc: func(ctx context.Context, id string) error {
+			return sql.ErrNoRows
+		},
+		GetWebhookByIdFunc: func(ctx context.Context, id string) (*domain.Webhook, error) {
+			return nil, sql.ErrNoRows
+		},
+	})
+
+	require.ErrorIs(t, svc.DeleteWebhook(ctx, "missing"), ErrWebhookNotFound)
+
+	_, err := svc.GetDeliveries(ctx, "missing", 0, 0)
+	require.ErrorIs(t, err, ErrWebhookNotFound)
+
+	_, err = svc.GetDeliveries(ctx, "missing", domain.MaxPageLimit+1, 0)
+	require.ErrorIs(t, err, domain.ErrInvalidLimit)
+}